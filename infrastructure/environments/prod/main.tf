@@ -245,15 +245,17 @@ module "ecs" {
   enable_container_insights = true
 
   environment_variables = {
-    ENV_NAME             = var.environment
-    LOG_LEVEL            = "info"
-    AWS_REGION           = var.aws_region
-    DYNAMODB_TABLE       = module.dynamodb.table_name
-    EVENTBRIDGE_ENABLED  = "true"
-    EVENTBRIDGE_BUS_NAME = module.eventbridge.event_bus_name
-    AWS_XRAY_ENABLED     = "true"
-    REDIS_ENABLED        = tostring(var.enable_redis)
-    REDIS_URL            = var.enable_redis ? "redis://${module.elasticache.endpoint}:6379" : ""
+    ENV_NAME              = var.environment
+    LOG_LEVEL             = "info"
+    AWS_REGION            = var.aws_region
+    DYNAMODB_TABLE        = module.dynamodb.table_name
+    EVENTBRIDGE_ENABLED   = "true"
+    EVENTBRIDGE_BUS_NAME  = module.eventbridge.event_bus_name
+    AWS_XRAY_ENABLED      = "true"
+    REDIS_ENABLED         = tostring(var.enable_redis)
+    REDIS_URL             = var.enable_redis ? "redis://${module.elasticache.endpoint}:6379" : ""
+    CART_EXPIRATION       = "720h"
+    GUEST_CART_EXPIRATION = "24h"
   }
 
   secrets = var.enable_redis && var.redis_auth_token != "" ? {
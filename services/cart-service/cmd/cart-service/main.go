@@ -0,0 +1,299 @@
+// Package main is the entry point for the cart service.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/graphql"
+	apimiddleware "github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/middleware"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/v1/handlers"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/app"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/config"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/encryption"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events/eventbridge"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events/kafka"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/features/appconfig"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/jobs"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/dynamodb"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/server"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/tracing"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// Create base context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Initialize logger
+	logger := logging.New(logging.Config{
+		Level:       cfg.LogLevel,
+		ServiceName: cfg.ServiceName,
+		Environment: cfg.Environment,
+	})
+
+	logger.Info("Starting cart service...")
+	logger.Infof("Environment: %s, Port: %d", cfg.Environment, cfg.Port)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName:  cfg.ServiceName,
+		Environment:  cfg.Environment,
+		OTLPEndpoint: cfg.OTELExporterEndpoint,
+		SampleRatio:  cfg.OTELSampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	// Initialize DynamoDB client
+	dbClient, err := dynamodb.NewClient(ctx, dynamodb.ClientConfig{
+		Region:    cfg.AWSRegion,
+		Endpoint:  cfg.DynamoDBEndpoint,
+		TableName: cfg.DynamoDBTable,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create DynamoDB client: %w", err)
+	}
+	logger.Infof("Connected to DynamoDB table: %s", cfg.DynamoDBTable)
+
+	// Create repository, encrypting free-text fields when configured
+	retryCfg := dynamodb.DefaultRetryConfig()
+	retryCfg.MaxAttempts = cfg.RetryMaxAttempts
+	retryCfg.InitialDelay = cfg.RetryInitialDelay
+	retryCfg.MaxDelay = cfg.RetryMaxDelay
+	repoOpts := []dynamodb.RepositoryOption{
+		dynamodb.WithRetryConfig(retryCfg),
+		dynamodb.WithTimeouts(cfg.DynamoDBReadTimeout, cfg.DynamoDBWriteTimeout),
+		dynamodb.WithLogger(logger),
+	}
+	if cfg.FieldEncryptionEnabled {
+		if cfg.FieldEncryptionKMSKeyID == "" {
+			logger.Warn("Field encryption is enabled but FIELD_ENCRYPTION_KMS_KEY_ID is not set; storing gift messages and item attributes in plaintext")
+		} else {
+			encryptor, err := encryption.NewKMSEncryptor(ctx, encryption.KMSConfig{
+				Region: cfg.AWSRegion,
+				KeyID:  cfg.FieldEncryptionKMSKeyID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create field encryptor: %w", err)
+			}
+			repoOpts = append(repoOpts, dynamodb.WithEncryptor(encryptor))
+			logger.Info("Field encryption enabled")
+		}
+	}
+	repo := dynamodb.NewRepository(dbClient, repoOpts...)
+
+	appOpts := []app.Option{
+		app.WithConfig(cfg),
+		app.WithLogger(logger),
+		app.WithRepository(repo),
+	}
+
+	// Responses kept for retried requests carrying an Idempotency-Key
+	var idempotencyStore apimiddleware.IdempotencyStore
+	if cfg.IdempotencyEnabled {
+		idempotencyStore = apimiddleware.NewInMemoryIdempotencyStore()
+		appOpts = append(appOpts, app.WithIdempotencyStore(idempotencyStore))
+	}
+
+	// Feature flags from AppConfig
+	var flags *appconfig.Flags
+	if cfg.FeatureFlagsEnabled && cfg.AppConfigApplication != "" {
+		flags, err = appconfig.New(ctx, appconfig.Config{
+			Region:       cfg.AWSRegion,
+			Application:  cfg.AppConfigApplication,
+			Environment:  cfg.AppConfigEnvironment,
+			Profile:      cfg.AppConfigProfile,
+			PollInterval: cfg.AppConfigPollInterval,
+		}, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create feature flags: %w", err)
+		}
+		appOpts = append(appOpts, app.WithFeatureFlags(flags))
+		logger.Infof("Feature flags from AppConfig %s/%s/%s (poll interval: %s)", cfg.AppConfigApplication, cfg.AppConfigEnvironment, cfg.AppConfigProfile, cfg.AppConfigPollInterval)
+	}
+
+	// Initialize application container
+	application, err := app.New(ctx, appOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	application.RegisterShutdown(shutdownTracing)
+	if flags != nil {
+		application.RegisterShutdown(func(context.Context) error {
+			return flags.Close()
+		})
+	}
+
+	// Create event publisher for background jobs and event replay
+	var publisher events.Publisher
+	if cfg.EventBridgeEnabled || cfg.ExpiryWarningEnabled || cfg.EventPublishMode == "outbox" {
+		publisher, err = newEventPublisher(ctx, cfg, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create event publisher: %w", err)
+		}
+		application.RegisterShutdown(func(context.Context) error {
+			return publisher.Close()
+		})
+		if checker, ok := publisher.(events.HealthChecker); ok {
+			application.RegisterDeepReadinessCheck("publisher", checker.HealthCheck)
+		}
+	}
+
+	// Start cart expiry warnings
+	if cfg.ExpiryWarningEnabled {
+		notifier := jobs.NewExpiryNotifier(repo, repo, eventbridge.NewCartEventPublisherFor(publisher, cfg.EventBridgeSource), jobs.ExpiryNotifierConfig{
+			Window:   cfg.ExpiryWarningWindow,
+			Interval: cfg.ExpiryWarningInterval,
+		}, logger)
+
+		jobCtx, stopJob := context.WithCancel(ctx)
+		go notifier.Run(jobCtx)
+		application.RegisterShutdown(func(context.Context) error {
+			stopJob()
+			return nil
+		})
+		logger.Infof("Cart expiry warnings enabled (window: %s)", cfg.ExpiryWarningWindow)
+	}
+
+	// Start outbox dispatcher
+	if cfg.EventPublishMode == "outbox" {
+		dispatcher := jobs.NewOutboxDispatcher(repo, publisher, jobs.OutboxDispatcherConfig{
+			Interval:  cfg.OutboxPollInterval,
+			BatchSize: cfg.OutboxBatchSize,
+		}, logger)
+
+		dispatcher.Start(ctx)
+		application.RegisterShutdown(func(context.Context) error {
+			dispatcher.Stop()
+			return nil
+		})
+		logger.Infof("Event outbox dispatcher started (interval: %s)", cfg.OutboxPollInterval)
+	}
+
+	// Cart service behind the /v1 cart and internal admin routes
+	var cartEvents cart.EventPublisher
+	if publisher != nil {
+		cartEvents = eventbridge.NewCartEventPublisherFor(publisher, cfg.EventBridgeSource)
+	}
+	serviceOpts := []cart.ServiceOption{cart.WithCartScanner(repo), cart.WithProductCartFinder(repo)}
+	if flags != nil {
+		// Flags override the static optimistic locking and publishing settings per user
+		serviceOpts = append(serviceOpts, cart.WithFeatureFlags(flags))
+	}
+	if len(cfg.ProductAllowlist) > 0 || len(cfg.ProductDenylist) > 0 {
+		serviceOpts = append(serviceOpts, cart.WithProductPolicy(cart.NewStaticProductPolicy(cfg.ProductAllowlist, cfg.ProductDenylist)))
+	}
+	if cfg.EventPublishMode == "outbox" {
+		serviceOpts = append(serviceOpts, cart.WithOutbox(repo, func(p events.Publisher) cart.EventPublisher {
+			return eventbridge.NewCartEventPublisherFor(p, cfg.EventBridgeSource)
+		}))
+	}
+	cartService := cart.NewService(repo, cartEvents, app.CartServiceConfig(cfg), serviceOpts...)
+
+	graphqlHandler, err := graphql.NewHandler(cartService, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create GraphQL handler: %w", err)
+	}
+
+	// Initialize server
+	srv, err := server.New(server.Config{
+		Port:             cfg.Port,
+		ReadTimeout:      15 * time.Second,
+		WriteTimeout:     15 * time.Second,
+		IdleTimeout:      60 * time.Second,
+		MaxHeaderBytes:   1 << 20, // 1 MB
+		PreDrainDelay:    cfg.ShutdownPreDrainDelay,
+		Cart:             handlers.NewCartHandler(cartService, logger),
+		Admin:            handlers.NewAdminHandler(cartService, logger),
+		GraphQL:          graphqlHandler,
+		IdempotencyStore: idempotencyStore,
+	}, application)
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+
+	// Start server in goroutine
+	serverErrors := make(chan error, 1)
+	go func() {
+		logger.Infof("Server listening on port %d", cfg.Port)
+		serverErrors <- srv.ListenAndServe()
+	}()
+
+	// Wait for shutdown signal
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErrors:
+		if err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("server error: %w", err)
+		}
+	case sig := <-shutdown:
+		logger.Infof("Received signal: %v, initiating graceful shutdown", sig)
+
+		// Create shutdown context with timeout
+		shutdownCtx, shutdownCancel := context.WithTimeout(ctx, 30*time.Second)
+		defer shutdownCancel()
+
+		// Fail readiness, wait for the load balancer to notice, then drain
+		logger.Infof("Draining: readiness failing, waiting %s before shutdown", cfg.ShutdownPreDrainDelay)
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.WithError(err).Error("Server shutdown error")
+			// Force close if graceful shutdown fails
+			if closeErr := srv.Close(); closeErr != nil {
+				logger.WithError(closeErr).Error("Server close error")
+			}
+		}
+
+		// Shutdown application dependencies
+		if err := application.Shutdown(shutdownCtx); err != nil {
+			logger.WithError(err).Error("Application shutdown error")
+			return fmt.Errorf("application shutdown error: %w", err)
+		}
+	}
+
+	logger.Info("Cart service stopped")
+	return nil
+}
+
+// newEventPublisher creates the publisher for the configured event bus.
+func newEventPublisher(ctx context.Context, cfg *config.Config, logger *logging.Logger) (events.Publisher, error) {
+	if cfg.EventBus == "kafka" {
+		return kafka.NewPublisher(kafka.PublisherConfig{
+			Brokers:     cfg.KafkaBrokers,
+			TopicPrefix: cfg.KafkaTopicPrefix,
+			Source:      cfg.EventBridgeSource,
+			Format:      events.Format(cfg.EventFormat),
+		}, logger)
+	}
+
+	return eventbridge.NewPublisher(ctx, eventbridge.PublisherConfig{
+		Region:  cfg.AWSRegion,
+		BusName: cfg.EventBridgeBusName,
+		Source:  cfg.EventBridgeSource,
+		Format:  events.Format(cfg.EventFormat),
+	}, logger)
+}
@@ -0,0 +1,206 @@
+// Package main is cartctl, a command for operators to inspect, seed and
+// delete carts in the configured repository.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/app"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/config"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/encryption"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/dynamodb"
+)
+
+const usage = `Usage: cartctl <command> <userID> [flags]
+
+Commands:
+  get <userID>                     Print the user's cart
+  delete <userID>                  Delete the user's cart
+  seed <userID> --items <items>    Add items to the user's cart and print it
+
+Flags:
+  --tenant <tenantID>              Address the cart in a tenant other than the default
+
+Items are comma separated productID:quantity:unitPrice, with the unit price in
+cents, e.g. --items prod-1:2:1999,prod-2:1:500.
+
+The repository and cart settings are configured from the same environment as
+the cart service, e.g. DYNAMODB_ENDPOINT=http://localhost:8000 for DynamoDB
+Local.
+`
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout, newService); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command in args on the service created by newService,
+// writing its result to out.
+func run(ctx context.Context, args []string, out io.Writer, newService func(context.Context) (*cart.Service, error)) error {
+	if len(args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("a command and a user ID are required")
+	}
+	command, userID := args[0], args[1]
+
+	// Errors are returned, and printed once by main
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	tenantID := fs.String("tenant", "", "tenant of the cart (default tenant when empty)")
+	var itemsFlag *string
+	switch command {
+	case "get", "delete":
+	case "seed":
+		itemsFlag = fs.String("items", "", "comma separated productID:quantity:unitPrice")
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	var items []cart.AddItemRequest
+	if itemsFlag != nil {
+		var err error
+		if items, err = parseItems(*itemsFlag); err != nil {
+			return err
+		}
+	}
+
+	service, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = cart.WithTenantID(ctx, *tenantID)
+
+	switch command {
+	case "get":
+		c, err := service.GetCart(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return printJSON(out, newCartOutput(c))
+	case "delete":
+		if err := service.DeleteCart(ctx, userID); err != nil {
+			return err
+		}
+		return printJSON(out, map[string]any{"user_id": userID, "tenant_id": cart.NormalizeTenantID(*tenantID), "deleted": true})
+	default:
+		var c *cart.Cart
+		for _, item := range items {
+			if c, err = service.AddItem(ctx, userID, item); err != nil {
+				return fmt.Errorf("failed to add %s: %w", item.ProductID, err)
+			}
+		}
+		return printJSON(out, newCartOutput(c))
+	}
+}
+
+// newService creates a cart service on the configured DynamoDB repository.
+// It doesn't publish events.
+func newService(ctx context.Context) (*cart.Service, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Logs would interleave with the output, so only errors go to stderr
+	logger := logging.New(logging.Config{
+		Level:       "error",
+		ServiceName: "cartctl",
+		Environment: cfg.Environment,
+		Output:      os.Stderr,
+	})
+
+	dbClient, err := dynamodb.NewClient(ctx, dynamodb.ClientConfig{
+		Region:    cfg.AWSRegion,
+		Endpoint:  cfg.DynamoDBEndpoint,
+		TableName: cfg.DynamoDBTable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DynamoDB client: %w", err)
+	}
+
+	retryCfg := dynamodb.DefaultRetryConfig()
+	retryCfg.MaxAttempts = cfg.RetryMaxAttempts
+	retryCfg.InitialDelay = cfg.RetryInitialDelay
+	retryCfg.MaxDelay = cfg.RetryMaxDelay
+	repoOpts := []dynamodb.RepositoryOption{
+		dynamodb.WithRetryConfig(retryCfg),
+		dynamodb.WithTimeouts(cfg.DynamoDBReadTimeout, cfg.DynamoDBWriteTimeout),
+		dynamodb.WithLogger(logger),
+	}
+	if cfg.FieldEncryptionEnabled && cfg.FieldEncryptionKMSKeyID != "" {
+		encryptor, err := encryption.NewKMSEncryptor(ctx, encryption.KMSConfig{
+			Region: cfg.AWSRegion,
+			KeyID:  cfg.FieldEncryptionKMSKeyID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create field encryptor: %w", err)
+		}
+		repoOpts = append(repoOpts, dynamodb.WithEncryptor(encryptor))
+	}
+
+	return cart.NewService(dynamodb.NewRepository(dbClient, repoOpts...), nil, app.CartServiceConfig(cfg)), nil
+}
+
+// parseItems parses comma separated productID:quantity:unitPrice items.
+func parseItems(s string) ([]cart.AddItemRequest, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("--items is required")
+	}
+
+	var items []cart.AddItemRequest
+	for _, entry := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid item %q: want productID:quantity:unitPrice", entry)
+		}
+		quantity, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity in item %q: %w", entry, err)
+		}
+		unitPrice, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unit price in item %q: %w", entry, err)
+		}
+		items = append(items, cart.AddItemRequest{
+			ProductID: parts[0],
+			Quantity:  quantity,
+			UnitPrice: unitPrice,
+		})
+	}
+	return items, nil
+}
+
+// cartOutput is a cart with its computed summary.
+type cartOutput struct {
+	Cart    *cart.Cart       `json:"cart"`
+	Summary cart.CartSummary `json:"summary"`
+}
+
+func newCartOutput(c *cart.Cart) cartOutput {
+	return cartOutput{Cart: c, Summary: c.Summary()}
+}
+
+// printJSON writes v as indented JSON.
+func printJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseItems(t *testing.T) {
+	items, err := parseItems("prod-1:2:1999, prod-2:1:500")
+	require.NoError(t, err)
+	assert.Equal(t, []cart.AddItemRequest{
+		{ProductID: "prod-1", Quantity: 2, UnitPrice: 1999},
+		{ProductID: "prod-2", Quantity: 1, UnitPrice: 500},
+	}, items)
+
+	for _, s := range []string{"", "prod-1", "prod-1:2", ":2:1999", "prod-1:two:1999", "prod-1:2:19.99"} {
+		_, err := parseItems(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	service := cart.NewService(inmemory.NewRepository(), nil, cart.ServiceConfig{CartExpiration: 48 * time.Hour})
+	newService := func(context.Context) (*cart.Service, error) { return service, nil }
+
+	exec := func(args ...string) map[string]any {
+		t.Helper()
+		var out bytes.Buffer
+		require.NoError(t, run(ctx, args, &out, newService))
+		var result map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		return result
+	}
+
+	seeded := exec("seed", "user-123", "--items", "prod-1:2:1999,prod-2:1:500", "--tenant", "acme")
+	assert.Equal(t, float64(4498), seeded["summary"].(map[string]any)["total_price"])
+	seededCart := seeded["cart"].(map[string]any)
+	assert.Equal(t, "acme", seededCart["tenant_id"])
+	expiresAt, err := time.Parse(time.RFC3339Nano, seededCart["expires_at"].(string))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), expiresAt, time.Minute)
+
+	got := exec("get", "user-123", "--tenant", "acme")
+	assert.Len(t, got["cart"].(map[string]any)["items"], 2)
+
+	// Other tenants don't see the cart
+	err = run(ctx, []string{"get", "user-123"}, io.Discard, newService)
+	assert.True(t, errors.IsCode(err, errors.CodeCartNotFound))
+
+	assert.Equal(t, true, exec("delete", "user-123", "--tenant", "acme")["deleted"])
+	err = run(ctx, []string{"get", "user-123", "--tenant", "acme"}, io.Discard, newService)
+	assert.True(t, errors.IsCode(err, errors.CodeCartNotFound))
+
+	// Invalid arguments fail before the service is created
+	for _, args := range [][]string{{"get"}, {"list", "user-123"}, {"get", "user-123", "extra"}, {"seed", "user-123"}, {"get", "user-123", "--items", "prod-1:1:1"}} {
+		assert.Error(t, run(ctx, args, io.Discard, nil), args)
+	}
+}
@@ -0,0 +1,9 @@
+// Package docs embeds the cart service API documentation.
+package docs
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document of the cart API (swagger.yaml).
+//
+//go:embed swagger.yaml
+var OpenAPI []byte
@@ -0,0 +1,90 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+
+	graphqlgo "github.com/graphql-go/graphql"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+)
+
+// Request is a GraphQL request body.
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// Handler serves POST /v1/graphql.
+type Handler struct {
+	schema graphqlgo.Schema
+	logger *logging.Logger
+}
+
+// NewHandler creates a GraphQL handler backed by the cart service.
+func NewHandler(service *cart.Service, logger *logging.Logger) (*Handler, error) {
+	schema, err := NewSchema(service)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{schema: schema, logger: logger}, nil
+}
+
+// ServeHTTP executes a GraphQL request. Field errors are reported in the
+// response's errors array with a 200 status, per GraphQL convention.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errors.New(errors.CodeInvalidRequest, "Request body must contain a GraphQL query"))
+		return
+	}
+
+	result := graphqlgo.Do(graphqlgo.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+	if result.HasErrors() {
+		h.logger.WithContext(ctx).WithField("errors", len(result.Errors)).Debug("GraphQL request returned errors")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// appError exposes an AppError's code and details as GraphQL error extensions.
+type appError struct {
+	*errors.AppError
+}
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e appError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code}
+	if len(e.Details) > 0 {
+		extensions["details"] = e.Details
+	}
+	return extensions
+}
+
+// Error returns the client-facing message; causes are not exposed.
+func (e appError) Error() string {
+	return e.Message
+}
+
+// wrapError converts a service error into a GraphQL error carrying its code.
+func wrapError(err error) error {
+	appErr, ok := errors.IsAppError(err)
+	if !ok {
+		appErr = errors.ErrInternal(err)
+	}
+	return appError{appErr}
+}
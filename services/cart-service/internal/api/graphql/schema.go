@@ -0,0 +1,246 @@
+// Package graphql provides a GraphQL API for the cart, alongside the REST API.
+package graphql
+
+import (
+	"sort"
+
+	graphqlgo "github.com/graphql-go/graphql"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/v1/handlers"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+)
+
+// Types resolve against handlers.CartResponse; the default resolver matches
+// camelCase field names to its fields case-insensitively.
+var attributeType = graphqlgo.NewObject(graphqlgo.ObjectConfig{
+	Name: "ProductAttribute",
+	Fields: graphqlgo.Fields{
+		"key":   &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.String)},
+		"value": &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.String)},
+	},
+})
+
+var cartItemType = graphqlgo.NewObject(graphqlgo.ObjectConfig{
+	Name: "CartItem",
+	Fields: graphqlgo.Fields{
+		"itemId":    &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.String)},
+		"productId": &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.String)},
+		"quantity":  &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.Int)},
+		"unitPrice": &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.Int)},
+		"subtotal":  &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.Int)},
+		"addedAt":   &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.DateTime)},
+		"name":      &graphqlgo.Field{Type: graphqlgo.String},
+		"imageUrl":  &graphqlgo.Field{Type: graphqlgo.String},
+		"sku":       &graphqlgo.Field{Type: graphqlgo.String},
+		"attributes": &graphqlgo.Field{
+			Type:    graphqlgo.NewNonNull(graphqlgo.NewList(graphqlgo.NewNonNull(attributeType))),
+			Resolve: resolveAttributes,
+		},
+	},
+})
+
+// resolveAttributes lists an item's attributes as key/value pairs sorted by key.
+func resolveAttributes(p graphqlgo.ResolveParams) (interface{}, error) {
+	item, _ := p.Source.(handlers.CartItemResponse)
+	keys := make([]string, 0, len(item.Attributes))
+	for key := range item.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attributes := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		attributes[i] = map[string]string{"key": key, "value": item.Attributes[key]}
+	}
+	return attributes, nil
+}
+
+var cartType = graphqlgo.NewObject(graphqlgo.ObjectConfig{
+	Name: "Cart",
+	Fields: graphqlgo.Fields{
+		"id":            &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.String)},
+		"userId":        &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.String)},
+		"items":         &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.NewList(graphqlgo.NewNonNull(cartItemType)))},
+		"itemCount":     &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.Int)},
+		"totalQuantity": &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.Int)},
+		"totalPrice":    &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.Int)},
+		"version":       &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.Int)},
+		"createdAt":     &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.DateTime)},
+		"updatedAt":     &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.DateTime)},
+		"expiresAt":     &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.DateTime)},
+	},
+})
+
+// NewSchema builds the GraphQL schema backed by the cart service.
+func NewSchema(service *cart.Service) (graphqlgo.Schema, error) {
+	r := &resolver{service: service}
+
+	userIDArg := &graphqlgo.ArgumentConfig{Type: graphqlgo.NewNonNull(graphqlgo.String)}
+	itemIDArg := &graphqlgo.ArgumentConfig{Type: graphqlgo.NewNonNull(graphqlgo.String)}
+
+	query := graphqlgo.NewObject(graphqlgo.ObjectConfig{
+		Name: "Query",
+		Fields: graphqlgo.Fields{
+			"cart": &graphqlgo.Field{
+				Type:    graphqlgo.NewNonNull(cartType),
+				Args:    graphqlgo.FieldConfigArgument{"userID": userIDArg},
+				Resolve: r.cart,
+			},
+		},
+	})
+
+	mutation := graphqlgo.NewObject(graphqlgo.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphqlgo.Fields{
+			"addItem": &graphqlgo.Field{
+				Type: graphqlgo.NewNonNull(cartType),
+				Args: graphqlgo.FieldConfigArgument{
+					"userID":    userIDArg,
+					"productId": &graphqlgo.ArgumentConfig{Type: graphqlgo.NewNonNull(graphqlgo.String)},
+					"quantity":  &graphqlgo.ArgumentConfig{Type: graphqlgo.NewNonNull(graphqlgo.Int)},
+					"unitPrice": &graphqlgo.ArgumentConfig{Type: graphqlgo.Int, DefaultValue: 0},
+					"name":      &graphqlgo.ArgumentConfig{Type: graphqlgo.String},
+					"imageUrl":  &graphqlgo.ArgumentConfig{Type: graphqlgo.String},
+					"sku":       &graphqlgo.ArgumentConfig{Type: graphqlgo.String},
+				},
+				Resolve: r.addItem,
+			},
+			"updateItem": &graphqlgo.Field{
+				Type: graphqlgo.NewNonNull(cartType),
+				Args: graphqlgo.FieldConfigArgument{
+					"userID":   userIDArg,
+					"itemId":   itemIDArg,
+					"quantity": &graphqlgo.ArgumentConfig{Type: graphqlgo.NewNonNull(graphqlgo.Int)},
+					"version":  &graphqlgo.ArgumentConfig{Type: graphqlgo.Int, DefaultValue: 0},
+				},
+				Resolve: r.updateItem,
+			},
+			"removeItem": &graphqlgo.Field{
+				Type: graphqlgo.NewNonNull(cartType),
+				Args: graphqlgo.FieldConfigArgument{
+					"userID": userIDArg,
+					"itemId": itemIDArg,
+				},
+				Resolve: r.removeItem,
+			},
+			"clearCart": &graphqlgo.Field{
+				Type:    graphqlgo.NewNonNull(graphqlgo.Boolean),
+				Args:    graphqlgo.FieldConfigArgument{"userID": userIDArg},
+				Resolve: r.clearCart,
+			},
+		},
+	})
+
+	return graphqlgo.NewSchema(graphqlgo.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+// resolver maps GraphQL fields onto cart service calls, reusing the REST
+// request validation.
+type resolver struct {
+	service *cart.Service
+}
+
+func (r *resolver) cart(p graphqlgo.ResolveParams) (interface{}, error) {
+	userID, _ := p.Args["userID"].(string)
+	if err := handlers.ValidateUserID(userID); err != nil {
+		return nil, wrapError(err)
+	}
+
+	c, err := r.service.ReadCart(p.Context, userID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return handlers.NewCartResponse(c), nil
+}
+
+func (r *resolver) addItem(p graphqlgo.ResolveParams) (interface{}, error) {
+	userID, _ := p.Args["userID"].(string)
+	if err := handlers.ValidateUserID(userID); err != nil {
+		return nil, wrapError(err)
+	}
+
+	req := handlers.AddItemRequest{}
+	req.ProductID, _ = p.Args["productId"].(string)
+	req.Quantity, _ = p.Args["quantity"].(int)
+	unitPrice, _ := p.Args["unitPrice"].(int)
+	req.UnitPrice = int64(unitPrice)
+	req.Name, _ = p.Args["name"].(string)
+	req.ImageURL, _ = p.Args["imageUrl"].(string)
+	req.SKU, _ = p.Args["sku"].(string)
+	if err := req.Validate(); err != nil {
+		return nil, wrapError(err)
+	}
+
+	c, err := r.service.AddItem(p.Context, userID, cart.AddItemRequest{
+		ProductID: req.ProductID,
+		Quantity:  req.Quantity,
+		UnitPrice: req.UnitPrice,
+		Name:      req.Name,
+		ImageURL:  req.ImageURL,
+		SKU:       req.SKU,
+	})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return handlers.NewCartResponse(c), nil
+}
+
+func (r *resolver) updateItem(p graphqlgo.ResolveParams) (interface{}, error) {
+	userID, _ := p.Args["userID"].(string)
+	itemID, _ := p.Args["itemId"].(string)
+	if err := handlers.ValidateUserID(userID); err != nil {
+		return nil, wrapError(err)
+	}
+	if err := handlers.ValidateItemID(itemID); err != nil {
+		return nil, wrapError(err)
+	}
+
+	req := handlers.UpdateQuantityRequest{}
+	req.Quantity, _ = p.Args["quantity"].(int)
+	version, _ := p.Args["version"].(int)
+	req.Version = int64(version)
+	if err := req.Validate(); err != nil {
+		return nil, wrapError(err)
+	}
+
+	c, err := r.service.UpdateItemQuantity(p.Context, userID, cart.UpdateItemRequest{
+		ItemID:          itemID,
+		Quantity:        req.Quantity,
+		ExpectedVersion: req.Version,
+	})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return handlers.NewCartResponse(c), nil
+}
+
+func (r *resolver) removeItem(p graphqlgo.ResolveParams) (interface{}, error) {
+	userID, _ := p.Args["userID"].(string)
+	itemID, _ := p.Args["itemId"].(string)
+	if err := handlers.ValidateUserID(userID); err != nil {
+		return nil, wrapError(err)
+	}
+	if err := handlers.ValidateItemID(itemID); err != nil {
+		return nil, wrapError(err)
+	}
+
+	c, err := r.service.RemoveItem(p.Context, userID, itemID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return handlers.NewCartResponse(c), nil
+}
+
+func (r *resolver) clearCart(p graphqlgo.ResolveParams) (interface{}, error) {
+	userID, _ := p.Args["userID"].(string)
+	if err := handlers.ValidateUserID(userID); err != nil {
+		return nil, wrapError(err)
+	}
+
+	if err := r.service.ClearCart(p.Context, userID); err != nil {
+		return nil, wrapError(err)
+	}
+	return true, nil
+}
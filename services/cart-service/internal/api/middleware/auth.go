@@ -3,9 +3,12 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
@@ -17,6 +20,26 @@ type AuthConfig struct {
 	JWTIssuer    string
 	JWTAudience  string
 	SkipPaths    []string // Paths to skip authentication
+
+	// JWKS configuration for RS256 tokens. When JWKSEndpoint is set, tokens must be
+	// RS256-signed by a key from the endpoint; otherwise HMAC with JWTSecretKey is used.
+	JWKSEndpoint        string
+	JWKSRefreshInterval time.Duration // Zero defers to the endpoint's Cache-Control
+
+	// ClockSkewLeeway is the tolerance applied to exp/nbf/iat checks (default 30s).
+	ClockSkewLeeway time.Duration
+}
+
+// DefaultClockSkewLeeway is the leeway used when AuthConfig.ClockSkewLeeway is zero.
+const DefaultClockSkewLeeway = 30 * time.Second
+
+// parserOptions returns the JWT parser options for the config.
+func (c AuthConfig) parserOptions() []jwt.ParserOption {
+	leeway := c.ClockSkewLeeway
+	if leeway == 0 {
+		leeway = DefaultClockSkewLeeway
+	}
+	return []jwt.ParserOption{jwt.WithLeeway(leeway)}
 }
 
 // UserClaims represents the claims in a JWT token.
@@ -32,7 +55,8 @@ type UserClaims struct {
 type contextKey string
 
 const (
-	userContextKey contextKey = "user"
+	userContextKey    contextKey = "user"
+	serviceContextKey contextKey = "service"
 )
 
 // JWTAuth provides JWT authentication middleware.
@@ -41,6 +65,8 @@ func JWTAuth(config AuthConfig) func(next http.Handler) http.Handler {
 	for _, path := range config.SkipPaths {
 		skipPaths[path] = true
 	}
+	keys := newKeyResolver(config)
+	parserOpts := config.parserOptions()
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -68,15 +94,14 @@ func JWTAuth(config AuthConfig) func(next http.Handler) http.Handler {
 
 			// Parse and validate token
 			claims := &UserClaims{}
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				// Validate signing method
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, errors.ErrUnauthorized("Invalid signing method")
-				}
-				return []byte(config.JWTSecretKey), nil
-			})
+			token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc(r.Context()), parserOpts...)
 
 			if err != nil {
+				// Expired tokens get a distinct reason so clients know to refresh
+				if stderrors.Is(err, jwt.ErrTokenExpired) {
+					writeTokenExpiredError(w)
+					return
+				}
 				writeAuthError(w, "Invalid token")
 				return
 			}
@@ -114,7 +139,7 @@ func JWTAuth(config AuthConfig) func(next http.Handler) http.Handler {
 			// Add user to context
 			ctx := context.WithValue(r.Context(), userContextKey, claims)
 			ctx = logging.ContextWithUserID(ctx, claims.UserID)
-			
+
 			// Set user ID header for downstream use
 			r.Header.Set("X-User-ID", claims.UserID)
 
@@ -126,6 +151,9 @@ func JWTAuth(config AuthConfig) func(next http.Handler) http.Handler {
 // OptionalJWTAuth provides optional JWT authentication.
 // It will set user context if token is present and valid, but won't reject if missing.
 func OptionalJWTAuth(config AuthConfig) func(next http.Handler) http.Handler {
+	keys := newKeyResolver(config)
+	parserOpts := config.parserOptions()
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -142,12 +170,7 @@ func OptionalJWTAuth(config AuthConfig) func(next http.Handler) http.Handler {
 
 			tokenString := parts[1]
 			claims := &UserClaims{}
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, errors.ErrUnauthorized("Invalid signing method")
-				}
-				return []byte(config.JWTSecretKey), nil
-			})
+			token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc(r.Context()), parserOpts...)
 
 			if err == nil && token.Valid {
 				ctx := context.WithValue(r.Context(), userContextKey, claims)
@@ -161,6 +184,43 @@ func OptionalJWTAuth(config AuthConfig) func(next http.Handler) http.Handler {
 	}
 }
 
+// keyResolver resolves token verification keys for the configured signing scheme.
+type keyResolver struct {
+	secret []byte
+	jwks   *JWKSClient
+}
+
+func newKeyResolver(config AuthConfig) *keyResolver {
+	resolver := &keyResolver{secret: []byte(config.JWTSecretKey)}
+	if config.JWKSEndpoint != "" {
+		resolver.jwks = NewJWKSClient(config.JWKSEndpoint, config.JWKSRefreshInterval)
+	}
+	return resolver
+}
+
+// keyFunc returns a jwt.Keyfunc that validates the signing method and returns the
+// verification key: the JWKS key matching the token's kid for RS256, or the shared
+// secret for HMAC.
+func (k *keyResolver) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if k.jwks != nil {
+			if token.Method != jwt.SigningMethodRS256 {
+				return nil, errors.ErrUnauthorized("Invalid signing method")
+			}
+			kid, _ := token.Header["kid"].(string)
+			if kid == "" {
+				return nil, errors.ErrUnauthorized("Missing key ID")
+			}
+			return k.jwks.Key(ctx, kid)
+		}
+
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.ErrUnauthorized("Invalid signing method")
+		}
+		return k.secret, nil
+	}
+}
+
 // APIKeyAuth provides API key authentication for service-to-service calls.
 func APIKeyAuth(validKeys map[string]string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -177,9 +237,96 @@ func APIKeyAuth(validKeys map[string]string) func(next http.Handler) http.Handle
 				return
 			}
 
-			// Set service name in header
+			// Set service name in header and context
 			r.Header.Set("X-Service-Name", serviceName)
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), serviceContextKey, serviceName)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OwnershipConfig holds configuration for the ownership guard.
+type OwnershipConfig struct {
+	PathParam   string   // URL parameter holding the owner ID, defaults to "userID"
+	AdminGroups []string // Groups allowed to access any user's resources
+}
+
+// OwnershipGuard ensures the authenticated user owns the resource identified by the
+// path parameter. Requests authenticated with a service API key and users in one of
+// the admin groups are allowed through. Must be mounted after JWTAuth/APIKeyAuth and
+// on a route that defines the path parameter.
+func OwnershipGuard(config OwnershipConfig) func(next http.Handler) http.Handler {
+	pathParam := config.PathParam
+	if pathParam == "" {
+		pathParam = "userID"
+	}
+
+	adminGroups := make(map[string]bool)
+	for _, group := range config.AdminGroups {
+		adminGroups[group] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Service-to-service calls are trusted
+			if GetServiceFromContext(r.Context()) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims := GetUserFromContext(r.Context())
+			if claims == nil {
+				writeAuthError(w, "Authentication is required")
+				return
+			}
+
+			if claims.UserID == chi.URLParam(r, pathParam) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, group := range claims.Groups {
+				if adminGroups[group] {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			writeForbiddenError(w, "Access to this resource is not allowed")
+		})
+	}
+}
+
+// RequireGroups allows only requests authenticated with a service API key or by
+// a user in one of groups, e.g. for admin endpoints. Must be mounted after
+// JWTAuth/APIKeyAuth.
+func RequireGroups(groups []string) func(next http.Handler) http.Handler {
+	allowed := make(map[string]bool)
+	for _, group := range groups {
+		allowed[group] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetServiceFromContext(r.Context()) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims := GetUserFromContext(r.Context())
+			if claims == nil {
+				writeAuthError(w, "Authentication is required")
+				return
+			}
+
+			for _, group := range claims.Groups {
+				if allowed[group] {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			writeForbiddenError(w, "Access to this resource is not allowed")
 		})
 	}
 }
@@ -192,6 +339,14 @@ func GetUserFromContext(ctx context.Context) *UserClaims {
 	return nil
 }
 
+// GetServiceFromContext retrieves the calling service name set by APIKeyAuth.
+func GetServiceFromContext(ctx context.Context) string {
+	if serviceName, ok := ctx.Value(serviceContextKey).(string); ok {
+		return serviceName
+	}
+	return ""
+}
+
 func writeAuthError(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("WWW-Authenticate", "Bearer")
@@ -201,3 +356,25 @@ func writeAuthError(w http.ResponseWriter, message string) {
 		"message": message,
 	})
 }
+
+func writeTokenExpiredError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="token expired"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    errors.CodeUnauthorized,
+		"message": "Invalid token",
+		"details": map[string]interface{}{
+			"reason": "token expired",
+		},
+	})
+}
+
+func writeForbiddenError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    errors.CodeForbidden,
+		"message": message,
+	})
+}
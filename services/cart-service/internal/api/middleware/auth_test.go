@@ -0,0 +1,322 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOwnershipGuard(t *testing.T) {
+	tests := []struct {
+		name       string
+		claims     *UserClaims
+		service    string
+		pathUserID string
+		wantStatus int
+	}{
+		{
+			name:       "owner is allowed",
+			claims:     &UserClaims{UserID: "user-123"},
+			pathUserID: "user-123",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "other user is forbidden",
+			claims:     &UserClaims{UserID: "user-456"},
+			pathUserID: "user-123",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "admin group is allowed",
+			claims:     &UserClaims{UserID: "support-1", Groups: []string{"cart-admins"}},
+			pathUserID: "user-123",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "service API key bypasses check",
+			service:    "order-service",
+			pathUserID: "user-123",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unauthenticated request is rejected",
+			pathUserID: "user-123",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					ctx := req.Context()
+					if tt.claims != nil {
+						ctx = context.WithValue(ctx, userContextKey, tt.claims)
+					}
+					if tt.service != "" {
+						ctx = context.WithValue(ctx, serviceContextKey, tt.service)
+					}
+					next.ServeHTTP(w, req.WithContext(ctx))
+				})
+			})
+			r.Route("/v1/cart/{userID}", func(r chi.Router) {
+				r.Use(OwnershipGuard(OwnershipConfig{AdminGroups: []string{"cart-admins"}}))
+				r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/cart/"+tt.pathUserID, nil)
+			// A spoofed header must not bypass the guard
+			req.Header.Set("X-Service-Name", "spoofed")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestRequireGroups(t *testing.T) {
+	tests := []struct {
+		name       string
+		claims     *UserClaims
+		service    string
+		wantStatus int
+	}{
+		{
+			name:       "group member is allowed",
+			claims:     &UserClaims{UserID: "support-1", Groups: []string{"viewer", "cart-admins"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "other user is forbidden",
+			claims:     &UserClaims{UserID: "user-123", Groups: []string{"viewer"}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "service API key is allowed",
+			service:    "pricing-service",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unauthenticated request is rejected",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireGroups([]string{"cart-admins"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/products/product-1/reprice", nil)
+			ctx := req.Context()
+			if tt.claims != nil {
+				ctx = context.WithValue(ctx, userContextKey, tt.claims)
+			}
+			if tt.service != "" {
+				ctx = context.WithValue(ctx, serviceContextKey, tt.service)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestJWTAuth_JWKS(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// The JWKS initially only publishes the old key; rotation adds the new one
+	var rotated atomic.Bool
+	var fetches atomic.Int32
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		keys := []map[string]string{jwkFor("old", &oldKey.PublicKey)}
+		if rotated.Load() {
+			keys = append(keys, jwkFor("new", &newKey.PublicKey))
+		}
+		w.Header().Set("Cache-Control", "max-age=300")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer jwksServer.Close()
+
+	handler := JWTAuth(AuthConfig{JWKSEndpoint: jwksServer.URL})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	call := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(signRS256(t, oldKey, "old")))
+	assert.Equal(t, http.StatusOK, call(signRS256(t, oldKey, "old")))
+	assert.Equal(t, int32(1), fetches.Load(), "keys should be served from cache")
+
+	// HMAC tokens are rejected when JWKS is configured
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, call(hmacToken))
+
+	// An unknown kid triggers a refetch that picks up the rotated key
+	rotated.Store(true)
+	assert.Equal(t, http.StatusUnauthorized, call(signRS256(t, newKey, "new")), "refetch is rate limited")
+
+	client := NewJWKSClient(jwksServer.URL, 0)
+	client.minRefetchInterval = 0
+	key, err := client.Key(context.Background(), "new")
+	require.NoError(t, err)
+	assert.Equal(t, newKey.PublicKey.N, key.N)
+}
+
+func TestJWKSClient_RefreshOutsideLock(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		w.Header().Set("Cache-Control", "max-age=300")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{jwkFor("old", &key.PublicKey)}})
+	}))
+	defer jwksServer.Close()
+
+	client := NewJWKSClient(jwksServer.URL, 0)
+	client.minRefetchInterval = 0
+	_, err = client.Key(context.Background(), "old")
+	require.NoError(t, err)
+
+	// Unknown kids share a single refetch, which blocks in the endpoint
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := client.Key(context.Background(), "new")
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return fetches.Load() == 2 }, time.Second, time.Millisecond)
+
+	// Cached keys are served while the refetch is in flight
+	done := make(chan struct{})
+	go func() {
+		_, err := client.Key(context.Background(), "old")
+		assert.NoError(t, err)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cached key lookup blocked on the refetch")
+	}
+
+	assert.Equal(t, int32(2), fetches.Load(), "lookups wait for the refetch in flight")
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		assert.Error(t, <-errs)
+	}
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, testClaims())
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func testClaims() *UserClaims {
+	return &UserClaims{
+		UserID: "user-123",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+func jwkFor(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kid": kid,
+		"kty": "RSA",
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestJWTAuth_ClockSkewLeeway(t *testing.T) {
+	secret := []byte("test-secret")
+	handler := JWTAuth(AuthConfig{JWTSecretKey: "test-secret"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	tests := []struct {
+		name       string
+		expiresIn  time.Duration
+		wantStatus int
+		wantReason string
+	}{
+		{name: "valid token", expiresIn: time.Hour, wantStatus: http.StatusOK},
+		{name: "expired within leeway", expiresIn: -10 * time.Second, wantStatus: http.StatusOK},
+		{name: "expired beyond leeway", expiresIn: -2 * time.Minute, wantStatus: http.StatusUnauthorized, wantReason: "token expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &UserClaims{
+				UserID: "user-123",
+				RegisteredClaims: jwt.RegisteredClaims{
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(tt.expiresIn)),
+				},
+			}
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantReason != "" {
+				var body struct {
+					Details map[string]string `json:"details"`
+				}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, tt.wantReason, body.Details["reason"])
+			}
+		})
+	}
+}
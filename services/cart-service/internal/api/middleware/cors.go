@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OriginMatcher decides whether a CORS origin is allowed. Origins are matched
+// exactly, or against wildcard patterns such as "https://*.example.com" that
+// allow any subdomain with the same scheme (and port, when given). A "*" entry
+// allows every origin.
+type OriginMatcher struct {
+	any      bool
+	exact    map[string]bool
+	patterns []originPattern
+}
+
+// originPattern is a parsed "scheme://*.suffix" origin pattern.
+type originPattern struct {
+	scheme string
+	suffix string // ".example.com", including any port
+}
+
+// NewOriginMatcher parses allowed origins. Wildcards are only supported as the
+// leftmost host label, after an explicit scheme.
+func NewOriginMatcher(origins []string) (*OriginMatcher, error) {
+	m := &OriginMatcher{exact: make(map[string]bool)}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "":
+			continue
+		case origin == "*":
+			m.any = true
+		case strings.Contains(origin, "*"):
+			pattern, err := parseOriginPattern(origin)
+			if err != nil {
+				return nil, err
+			}
+			m.patterns = append(m.patterns, pattern)
+		default:
+			m.exact[origin] = true
+		}
+	}
+	return m, nil
+}
+
+func parseOriginPattern(origin string) (originPattern, error) {
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || (scheme != "http" && scheme != "https") {
+		return originPattern{}, fmt.Errorf("CORS origin %q: wildcard patterns need an http or https scheme", origin)
+	}
+	suffix, ok := strings.CutPrefix(host, "*.")
+	if !ok || suffix == "" || strings.ContainsAny(suffix, "*/") || !strings.Contains(suffix, ".") {
+		return originPattern{}, fmt.Errorf("CORS origin %q: only a leading \"*.\" wildcard on a registrable domain is supported", origin)
+	}
+	return originPattern{scheme: scheme, suffix: "." + suffix}, nil
+}
+
+// AllowsAll reports whether "*" was configured.
+func (m *OriginMatcher) AllowsAll() bool {
+	return m.any
+}
+
+// Allow reports whether origin may make cross-origin requests. Its signature
+// matches cors.Options.AllowOriginFunc.
+func (m *OriginMatcher) Allow(r *http.Request, origin string) bool {
+	if m.any {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if m.exact[origin] {
+		return true
+	}
+	if len(m.patterns) == 0 {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+	for _, p := range m.patterns {
+		if u.Scheme != p.scheme {
+			continue
+		}
+		if sub, ok := strings.CutSuffix(u.Host, p.suffix); ok && validSubdomain(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// validSubdomain reports whether sub is one or more DNS labels.
+func validSubdomain(sub string) bool {
+	if sub == "" {
+		return false
+	}
+	for _, label := range strings.Split(sub, ".") {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOriginMatcher(t *testing.T) {
+	matcher, err := NewOriginMatcher([]string{"https://shop.example.org", "https://*.app.example.com", "http://*.localhost.test:3000"})
+	require.NoError(t, err)
+	assert.False(t, matcher.AllowsAll())
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://shop.example.org", want: true},
+		{origin: "https://SHOP.example.org", want: true},
+		{origin: "https://pr-123.app.example.com", want: true},
+		{origin: "https://a.b.app.example.com", want: true},
+		{origin: "http://web.localhost.test:3000", want: true},
+		{origin: "https://app.example.com"},
+		{origin: "http://pr-123.app.example.com"},
+		{origin: "https://pr-123.app.example.com.evil.com"},
+		{origin: "https://evilapp.example.com"},
+		{origin: "https://pr-123.app.example.com/path"},
+		{origin: "https://user@pr-123.app.example.com"},
+		{origin: "https://-bad.app.example.com"},
+		{origin: "http://web.localhost.test:4000"},
+		{origin: "https://other.example.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			assert.Equal(t, tt.want, matcher.Allow(httptest.NewRequest("GET", "/", nil), tt.origin))
+		})
+	}
+}
+
+func TestOriginMatcher_AllowsAll(t *testing.T) {
+	matcher, err := NewOriginMatcher([]string{"*"})
+	require.NoError(t, err)
+	assert.True(t, matcher.AllowsAll())
+	assert.True(t, matcher.Allow(httptest.NewRequest("GET", "/", nil), "https://anything.test"))
+}
+
+func TestNewOriginMatcher_InvalidPatterns(t *testing.T) {
+	for _, origin := range []string{"*.example.com", "ftp://*.example.com", "https://foo*.example.com", "https://*.com", "https://*.*.example.com"} {
+		t.Run(origin, func(t *testing.T) {
+			_, err := NewOriginMatcher([]string{origin})
+			assert.Error(t, err)
+		})
+	}
+}
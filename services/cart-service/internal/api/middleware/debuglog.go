@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+)
+
+// HeaderDebugLog requests debug-level logs for a single request.
+const HeaderDebugLog = "X-Debug-Log"
+
+// DebugLogConfig controls who may enable per-request debug logging.
+type DebugLogConfig struct {
+	APIKeys     []string // X-API-Key values allowed to enable debug logs
+	AdminGroups []string // JWT groups allowed to enable debug logs
+}
+
+// DebugLogging raises the log level to debug for requests sending
+// "X-Debug-Log: true" from an allowlisted API key or an admin user. The header
+// is ignored for everyone else, so it can't be used to flood the logs. Must be
+// mounted after JWTAuth for the admin group check to apply.
+func DebugLogging(config DebugLogConfig) func(next http.Handler) http.Handler {
+	adminGroups := make(map[string]bool)
+	for _, group := range config.AdminGroups {
+		adminGroups[group] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if enabled, _ := strconv.ParseBool(r.Header.Get(HeaderDebugLog)); !enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !debugLogAllowed(r, config.APIKeys, adminGroups) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(HeaderDebugLog, "enabled")
+			ctx := logging.ContextWithLogLevel(r.Context(), "debug")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// debugLogAllowed reports whether the caller may enable debug logging.
+func debugLogAllowed(r *http.Request, apiKeys []string, adminGroups map[string]bool) bool {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		for _, allowed := range apiKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(allowed)) == 1 {
+				return true
+			}
+		}
+	}
+
+	if claims := GetUserFromContext(r.Context()); claims != nil {
+		for _, group := range claims.Groups {
+			if adminGroups[group] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(logging.Config{Level: "info", Output: &buf})
+
+	handler := DebugLogging(DebugLogConfig{
+		APIKeys:     []string{"debug-key"},
+		AdminGroups: []string{"admin"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.WithContext(r.Context()).Debug("verbose details")
+	}))
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		groups  []string
+		enabled bool
+	}{
+		{name: "allowlisted API key", apiKey: "debug-key", enabled: true},
+		{name: "admin user", groups: []string{"admin"}, enabled: true},
+		{name: "unknown API key", apiKey: "other-key"},
+		{name: "regular user", groups: []string{"customers"}},
+		{name: "unauthenticated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/v1/cart/user-123", nil)
+			req.Header.Set(HeaderDebugLog, "true")
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			if tt.groups != nil {
+				req = req.WithContext(context.WithValue(req.Context(), userContextKey, &UserClaims{UserID: "user-123", Groups: tt.groups}))
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.enabled, bytes.Contains(buf.Bytes(), []byte("verbose details")))
+			assert.Equal(t, tt.enabled, rec.Header().Get(HeaderDebugLog) == "enabled")
+		})
+	}
+}
@@ -13,9 +13,12 @@ import (
 )
 
 // IdempotencyStore defines the interface for storing idempotency records.
+// HealthCheck backs the readiness probe; stores behind a network should make
+// a cheap round-trip.
 type IdempotencyStore interface {
 	Get(ctx context.Context, key string) (*IdempotencyRecord, error)
 	Set(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) error
+	HealthCheck(ctx context.Context) error
 }
 
 // IdempotencyRecord represents a stored idempotency response.
@@ -31,6 +34,15 @@ type IdempotencyConfig struct {
 	Enabled bool
 	TTL     time.Duration
 	Store   IdempotencyStore
+
+	// IncludeDelete also deduplicates DELETE requests that carry an
+	// Idempotency-Key, so a retried removal replays the original success
+	// instead of returning 404.
+	IncludeDelete bool
+
+	// Namespace prefixes stored keys, e.g. "cart-service:prod", so a store
+	// shared by several services or environments keeps their keys apart.
+	Namespace string
 }
 
 // Idempotency provides idempotency middleware for safe retries.
@@ -38,7 +50,7 @@ func Idempotency(config IdempotencyConfig) func(next http.Handler) http.Handler
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Only apply to methods that modify state
-			if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+			if !config.coversMethod(r.Method) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -62,7 +74,7 @@ func Idempotency(config IdempotencyConfig) func(next http.Handler) http.Handler
 			}
 
 			// Create scoped key
-			scopedKey := userID + ":" + idempotencyKey
+			scopedKey := config.scopedKey(userID, idempotencyKey)
 
 			// Check for existing record
 			record, err := config.Store.Get(r.Context(), scopedKey)
@@ -102,6 +114,27 @@ func Idempotency(config IdempotencyConfig) func(next http.Handler) http.Handler
 	}
 }
 
+// coversMethod reports whether requests with the given method are deduplicated.
+func (c IdempotencyConfig) coversMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPatch:
+		return true
+	case http.MethodDelete:
+		return c.IncludeDelete
+	default:
+		return false
+	}
+}
+
+// scopedKey returns the store key for a user's idempotency key:
+// {namespace}:{userID}:{key}, or {userID}:{key} without a namespace.
+func (c IdempotencyConfig) scopedKey(userID, key string) string {
+	if c.Namespace == "" {
+		return userID + ":" + key
+	}
+	return c.Namespace + ":" + userID + ":" + key
+}
+
 // responseCapture captures the response for idempotency storage.
 type responseCapture struct {
 	http.ResponseWriter
@@ -170,6 +203,11 @@ func (s *InMemoryIdempotencyStore) Set(ctx context.Context, key string, record *
 }
 
 // cleanup periodically removes expired records.
+// HealthCheck always succeeds; the store lives in process.
+func (s *InMemoryIdempotencyStore) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
 func (s *InMemoryIdempotencyStore) cleanup() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
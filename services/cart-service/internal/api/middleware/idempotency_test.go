@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency_Delete(t *testing.T) {
+	tests := []struct {
+		name          string
+		includeDelete bool
+		retryStatus   int
+		replayed      bool
+	}{
+		{name: "delete covered", includeDelete: true, retryStatus: http.StatusNoContent, replayed: true},
+		{name: "delete not covered", includeDelete: false, retryStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed := false
+			handler := Idempotency(IdempotencyConfig{
+				Enabled:       true,
+				TTL:           time.Minute,
+				Store:         NewInMemoryIdempotencyStore(),
+				IncludeDelete: tt.includeDelete,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if removed {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				removed = true
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			send := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodDelete, "/v1/cart/user-123/items/item-1", nil)
+				req.Header.Set("Idempotency-Key", "key-1")
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			assert.Equal(t, http.StatusNoContent, send().Code)
+
+			rec := send()
+			assert.Equal(t, tt.retryStatus, rec.Code)
+			assert.Equal(t, tt.replayed, rec.Header().Get("X-Idempotent-Replayed") == "true")
+		})
+	}
+}
+
+func TestIdempotency_Namespace(t *testing.T) {
+	store := NewInMemoryIdempotencyStore()
+	newHandler := func(namespace string) http.Handler {
+		return Idempotency(IdempotencyConfig{
+			Enabled:   true,
+			TTL:       time.Minute,
+			Store:     store,
+			Namespace: namespace,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}))
+	}
+	send := func(handler http.Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", nil)
+		req.Header.Set("Idempotency-Key", "key-1")
+		req.Header.Set("X-User-ID", "user-123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	cartProd := newHandler("cart-service:prod")
+	assert.Empty(t, send(cartProd).Header().Get("X-Idempotent-Replayed"))
+	assert.Equal(t, "true", send(cartProd).Header().Get("X-Idempotent-Replayed"))
+
+	// Same user and key in another namespace is a new request
+	assert.Empty(t, send(newHandler("cart-service:staging")).Header().Get("X-Idempotent-Replayed"))
+	assert.Empty(t, send(newHandler("order-service:prod")).Header().Get("X-Idempotent-Replayed"))
+
+	_, err := store.Get(context.Background(), "cart-service:prod:user-123:key-1")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultJWKSRefreshInterval is used when neither the config nor the
+	// JWKS response's Cache-Control specify how long keys may be cached.
+	defaultJWKSRefreshInterval = 1 * time.Hour
+
+	// jwksMinRefetchInterval bounds how often an unknown kid can force a refetch,
+	// so tokens with random kids can't be used to hammer the identity provider.
+	jwksMinRefetchInterval = 30 * time.Second
+)
+
+// JWKSClient fetches and caches RSA public keys from a JWKS endpoint.
+type JWKSClient struct {
+	endpoint           string
+	refreshInterval    time.Duration
+	minRefetchInterval time.Duration
+	httpClient         *http.Client
+
+	keys      map[string]*rsa.PublicKey
+	expiresAt time.Time
+	lastFetch time.Time
+	inflight  *jwksFetch
+	mu        sync.Mutex
+}
+
+// jwksFetch is a key set fetch in flight. Requests that need keys while it
+// runs wait for it instead of starting their own.
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKSClient creates a new JWKS client. A zero refreshInterval defers to the
+// endpoint's Cache-Control max-age, falling back to one hour.
+func NewJWKSClient(endpoint string, refreshInterval time.Duration) *JWKSClient {
+	return &JWKSClient{
+		endpoint:           endpoint,
+		refreshInterval:    refreshInterval,
+		minRefetchInterval: jwksMinRefetchInterval,
+		httpClient:         &http.Client{Timeout: 5 * time.Second},
+		keys:               make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Key returns the public key for the given key ID, refreshing the key set when
+// the cache has expired or the key ID is unknown (to pick up key rotation).
+func (c *JWKSClient) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	now := time.Now()
+	key, ok := c.keys[kid]
+	fresh := now.Before(c.expiresAt)
+	// Refresh when expired, or when the kid is unknown and we haven't just refetched
+	refetch := !fresh || now.Sub(c.lastFetch) >= c.minRefetchInterval
+	c.mu.Unlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	if refetch {
+		if err := c.refresh(ctx); err != nil {
+			// Keep serving cached keys if the endpoint is temporarily unavailable
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		c.mu.Lock()
+		key, ok = c.keys[kid]
+		c.mu.Unlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	return key, nil
+}
+
+// refresh fetches the key set, or waits for a fetch already in flight. The
+// fetch runs without c.mu held, so cached keys stay available meanwhile.
+func (c *JWKSClient) refresh(ctx context.Context) error {
+	c.mu.Lock()
+	if f := c.inflight; f != nil {
+		c.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	f := &jwksFetch{done: make(chan struct{})}
+	c.inflight = f
+	c.lastFetch = time.Now()
+	c.mu.Unlock()
+
+	keys, ttl, err := c.fetch(ctx)
+
+	c.mu.Lock()
+	if err == nil {
+		c.keys = keys
+		c.expiresAt = time.Now().Add(ttl)
+	}
+	c.inflight = nil
+	c.mu.Unlock()
+
+	f.err = err
+	close(f.done)
+	return err
+}
+
+// jwkSet represents a JSON Web Key Set document.
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwk represents a single JSON Web Key.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// fetch downloads the key set and how long it may be cached.
+func (c *JWKSClient) fetch(ctx context.Context) (map[string]*rsa.PublicKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set jwkSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAPublicKey(k.N, k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, c.cacheTTL(resp.Header.Get("Cache-Control")), nil
+}
+
+// cacheTTL returns how long fetched keys may be cached.
+func (c *JWKSClient) cacheTTL(cacheControl string) time.Duration {
+	if c.refreshInterval > 0 {
+		return c.refreshInterval
+	}
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return defaultJWKSRefreshInterval
+}
+
+// parseRSAPublicKey builds an RSA public key from base64url-encoded modulus and exponent.
+func parseRSAPublicKey(n, e string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(eBytes)
+	if !exponent.IsInt64() || exponent.Int64() > int64(^uint32(0)>>1) {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(exponent.Int64()),
+	}, nil
+}
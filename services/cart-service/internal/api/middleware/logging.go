@@ -2,7 +2,9 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -10,6 +12,15 @@ import (
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
 )
 
+// Tracing headers
+const (
+	HeaderCorrelationID = "X-Correlation-ID"
+	HeaderTraceparent   = "traceparent"
+)
+
+// maxCorrelationIDLength bounds inbound correlation IDs so they can't bloat logs or events.
+const maxCorrelationIDLength = 128
+
 // Logger is a middleware that logs HTTP requests.
 func Logger(logger *logging.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -24,6 +35,9 @@ func Logger(logger *logging.Logger) func(next http.Handler) http.Handler {
 
 			// Extract trace ID if present
 			traceID := r.Header.Get("X-Amzn-Trace-Id")
+			if traceID == "" {
+				traceID, _ = ParseTraceparent(r.Header.Get(HeaderTraceparent))
+			}
 			if traceID == "" {
 				traceID = requestID
 			}
@@ -32,6 +46,7 @@ func Logger(logger *logging.Logger) func(next http.Handler) http.Handler {
 			ctx := r.Context()
 			ctx = logging.ContextWithRequestID(ctx, requestID)
 			ctx = logging.ContextWithTraceID(ctx, traceID)
+			ctx = withCorrelationID(ctx, w, r, requestID)
 			r = r.WithContext(ctx)
 
 			// Set response headers
@@ -59,7 +74,67 @@ func RequestID(next http.Handler) http.Handler {
 		}
 
 		ctx := logging.ContextWithRequestID(r.Context(), requestID)
+		ctx = withCorrelationID(ctx, w, r, requestID)
 		w.Header().Set("X-Request-ID", requestID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// withCorrelationID stores the request's correlation ID in the context and echoes
+// it in the response. The ID comes from X-Correlation-ID, then the traceparent
+// trace ID, and falls back to the request ID.
+func withCorrelationID(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) context.Context {
+	correlationID := r.Header.Get(HeaderCorrelationID)
+	if !validCorrelationID(correlationID) {
+		correlationID, _ = ParseTraceparent(r.Header.Get(HeaderTraceparent))
+	}
+	if correlationID == "" {
+		correlationID = requestID
+	}
+
+	w.Header().Set(HeaderCorrelationID, correlationID)
+	return logging.ContextWithCorrelationID(ctx, correlationID)
+}
+
+// validCorrelationID reports whether an inbound correlation ID is safe to propagate.
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTraceparent extracts the trace ID from a W3C traceparent header
+// ("version-traceid-parentid-flags"). It reports false if the header is malformed.
+func ParseTraceparent(header string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return "", false
+	}
+
+	traceID, parentID := parts[1], parts[2]
+	if len(traceID) != 32 || len(parentID) != 16 || len(parts[3]) != 2 {
+		return "", false
+	}
+	if !isLowerHex(traceID) || !isLowerHex(parentID) || !isLowerHex(parts[0]) || !isLowerHex(parts[3]) {
+		return "", false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return "", false
+	}
+	return traceID, true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
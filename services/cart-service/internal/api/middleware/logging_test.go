@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID_CorrelationID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.CorrelationIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "inbound correlation ID",
+			headers: map[string]string{HeaderCorrelationID: "corr-123", "X-Request-ID": "req-1"},
+			want:    "corr-123",
+		},
+		{
+			name:    "traceparent trace ID",
+			headers: map[string]string{HeaderTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "X-Request-ID": "req-1"},
+			want:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:    "falls back to request ID",
+			headers: map[string]string{"X-Request-ID": "req-1"},
+			want:    "req-1",
+		},
+		{
+			name:    "rejects unsafe correlation ID",
+			headers: map[string]string{HeaderCorrelationID: "bad id\twith spaces", "X-Request-ID": "req-1"},
+			want:    "req-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/cart/user-123", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, seen)
+			assert.Equal(t, tt.want, rec.Header().Get(HeaderCorrelationID))
+		})
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	traceID, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, ok := ParseTraceparent(header)
+		assert.False(t, ok, header)
+	}
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// OpenAPIValidation returns middleware that validates requests against the
+// matching operation of an OpenAPI 3 document: path, query and header
+// parameters, and JSON request bodies. Failures are answered with 400
+// VALIDATION_ERROR listing each violation and where it occurred. Requests
+// for undocumented routes, and non-JSON bodies, are passed through, since
+// handlers validate them anyway. Authentication is left to JWTAuth.
+func OpenAPIValidation(spec []byte) (func(next http.Handler) http.Handler, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document: %w", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	// Servers list deployments; match paths on whatever host serves them
+	doc.Servers = nil
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to route OpenAPI document: %w", err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					ExcludeRequestBody: !isJSONRequest(r),
+					MultiError:         true,
+					AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				},
+			})
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"code":    errors.CodeValidationError,
+					"message": "Request does not match the API schema",
+					"details": map[string]interface{}{
+						"violations": schemaViolations(err, nil),
+					},
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// isJSONRequest reports whether the request body is JSON. A missing
+// Content-Type counts as JSON, as it does for the handlers.
+func isJSONRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json"
+}
+
+// schemaViolations flattens a validation error into one entry per violation.
+// Each entry says where it occurred ("in": body, path, query or header, plus
+// the parameter name) and, for schema failures, the JSON pointer of the
+// offending value and the schema keyword it broke, e.g. "/quantity" and
+// "maximum".
+func schemaViolations(err error, reqErr *openapi3filter.RequestError) []map[string]interface{} {
+	// Both unwrap to what they hold, so match them directly rather than with
+	// errors.As, which would skip the request error's parameter
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var violations []map[string]interface{}
+		for _, inner := range e {
+			violations = append(violations, schemaViolations(inner, reqErr)...)
+		}
+		return violations
+	case *openapi3filter.RequestError:
+		if e.Err != nil {
+			return schemaViolations(e.Err, e)
+		}
+		reqErr = e
+	}
+
+	violation := map[string]interface{}{"in": "body"}
+	if reqErr != nil && reqErr.Parameter != nil {
+		violation["in"] = reqErr.Parameter.In
+		violation["parameter"] = reqErr.Parameter.Name
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if stderrors.As(err, &schemaErr) {
+		violation["path"] = "/" + strings.Join(schemaErr.JSONPointer(), "/")
+		violation["schema_field"] = schemaErr.SchemaField
+		violation["reason"] = schemaErr.Reason
+		return []map[string]interface{}{violation}
+	}
+
+	violation["reason"] = err.Error()
+	if reqErr != nil && reqErr.Reason != "" {
+		violation["reason"] = reqErr.Reason
+	}
+	return []map[string]interface{}{violation}
+}
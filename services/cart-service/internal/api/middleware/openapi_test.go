@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/docs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIValidation(t *testing.T) {
+	validate, err := OpenAPIValidation(docs.OpenAPI)
+	require.NoError(t, err)
+
+	handler := validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantIn      string
+		wantPath    string
+		wantField   string
+	}{
+		{name: "valid body", method: http.MethodPost, path: "/v1/cart/user-123/items", body: `{"product_id":"product-1","quantity":2,"unit_price":1000}`, wantStatus: http.StatusNoContent},
+		{name: "body above maximum", method: http.MethodPost, path: "/v1/cart/user-123/items", body: `{"product_id":"product-1","quantity":150}`, wantStatus: http.StatusBadRequest, wantIn: "body", wantPath: "/quantity", wantField: "maximum"},
+		{name: "missing required property", method: http.MethodPost, path: "/v1/cart/user-123/items", body: `{"quantity":1}`, wantStatus: http.StatusBadRequest, wantIn: "body", wantPath: "/product_id", wantField: "required"},
+		{name: "invalid path parameter", method: http.MethodGet, path: "/v1/cart/bad$user", wantStatus: http.StatusBadRequest, wantIn: "path", wantField: "pattern"},
+		{name: "undocumented route", method: http.MethodPost, path: "/v1/cart/user-123/restore", wantStatus: http.StatusNoContent},
+		{name: "non-JSON body", method: http.MethodPost, path: "/v1/cart/user-123/items", contentType: "application/msgpack", body: "\x80", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				contentType := tt.contentType
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				req.Header.Set("Content-Type", contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusBadRequest {
+				return
+			}
+
+			var body struct {
+				Code    string `json:"code"`
+				Details struct {
+					Violations []map[string]interface{} `json:"violations"`
+				} `json:"details"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, "VALIDATION_ERROR", body.Code)
+			require.Len(t, body.Details.Violations, 1)
+			violation := body.Details.Violations[0]
+			assert.Equal(t, tt.wantIn, violation["in"])
+			assert.Equal(t, tt.wantField, violation["schema_field"])
+			if tt.wantPath != "" {
+				assert.Equal(t, tt.wantPath, violation["path"])
+			}
+		})
+	}
+}
+
+func TestOpenAPIValidation_InvalidDocument(t *testing.T) {
+	_, err := OpenAPIValidation([]byte("openapi: 3.0.3\npaths: {}\n"))
+	assert.Error(t, err)
+}
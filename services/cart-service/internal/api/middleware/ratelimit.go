@@ -2,80 +2,230 @@ package middleware
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
 	"golang.org/x/time/rate"
 )
 
+// DefaultRateLimiterIdleTTL is how long an unused per-key limiter is kept.
+const DefaultRateLimiterIdleTTL = 10 * time.Minute
+
+// Rate limit tiers. Routes use the tier matching their cost; DefaultTier applies
+// to Middleware and to any tier without its own configuration.
+const (
+	DefaultTier = "default"
+	TierRead    = "read"
+	TierWrite   = "write"
+)
+
+// RateLimitTier configures the limits for a tier.
+type RateLimitTier struct {
+	RPS   float64
+	Burst int
+}
+
 // RateLimiter provides rate limiting middleware.
 type RateLimiter struct {
-	limiters map[string]*rate.Limiter
+	limiters map[string]*limiterEntry
 	mu       sync.RWMutex
-	rps      rate.Limit
-	burst    int
+	tiers    map[string]RateLimitTier
+	idleTTL  time.Duration
+	now      func() time.Time
+
+	stop      chan struct{}
+	closeOnce sync.Once
 }
 
-// NewRateLimiter creates a new rate limiter.
+// limiterEntry tracks a per-key limiter and when it was last used.
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen atomic.Int64 // unix nanoseconds
+}
+
+// NewRateLimiter creates a new rate limiter that evicts limiters idle for
+// DefaultRateLimiterIdleTTL.
 func NewRateLimiter(rps int, burst int) *RateLimiter {
-	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rps:      rate.Limit(rps),
-		burst:    burst,
+	return NewRateLimiterWithIdleTTL(rps, burst, DefaultRateLimiterIdleTTL)
+}
+
+// NewRateLimiterWithIdleTTL creates a new rate limiter that evicts per-key limiters
+// unused for longer than idleTTL. Call Close to stop the cleanup goroutine.
+func NewRateLimiterWithIdleTTL(rps int, burst int, idleTTL time.Duration) *RateLimiter {
+	return NewTieredRateLimiter(map[string]RateLimitTier{
+		DefaultTier: {RPS: float64(rps), Burst: burst},
+	}, idleTTL)
+}
+
+// NewTieredRateLimiter creates a rate limiter with distinct limits per tier.
+// Each client is limited independently in each tier. The DefaultTier entry, if
+// present, applies to tiers that aren't configured.
+func NewTieredRateLimiter(tiers map[string]RateLimitTier, idleTTL time.Duration) *RateLimiter {
+	if idleTTL <= 0 {
+		idleTTL = DefaultRateLimiterIdleTTL
 	}
+
+	rl := &RateLimiter{
+		limiters: make(map[string]*limiterEntry),
+		tiers:    make(map[string]RateLimitTier, len(tiers)),
+		idleTTL:  idleTTL,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+	for name, tier := range tiers {
+		rl.tiers[name] = tier
+	}
+	// Start cleanup goroutine
+	go rl.cleanup()
+	return rl
+}
+
+// tier returns the limits for the named tier, falling back to DefaultTier.
+func (rl *RateLimiter) tier(name string) (RateLimitTier, bool) {
+	if tier, ok := rl.tiers[name]; ok {
+		return tier, true
+	}
+	tier, ok := rl.tiers[DefaultTier]
+	return tier, ok
 }
 
-// getLimiter returns a rate limiter for the given key.
-func (rl *RateLimiter) getLimiter(key string) *rate.Limiter {
+// getLimiter returns a rate limiter for the given key in the given tier.
+func (rl *RateLimiter) getLimiter(tierName, clientKey string) *rate.Limiter {
+	now := rl.now().UnixNano()
+	key := tierName + "|" + clientKey
+
 	rl.mu.RLock()
-	limiter, exists := rl.limiters[key]
+	entry, exists := rl.limiters[key]
 	rl.mu.RUnlock()
 
 	if exists {
-		return limiter
+		entry.lastSeen.Store(now)
+		return entry.limiter
 	}
 
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
 	// Double-check after acquiring write lock
-	if limiter, exists = rl.limiters[key]; exists {
-		return limiter
+	if entry, exists = rl.limiters[key]; exists {
+		entry.lastSeen.Store(now)
+		return entry.limiter
 	}
 
-	limiter = rate.NewLimiter(rl.rps, rl.burst)
-	rl.limiters[key] = limiter
-	return limiter
+	tier, _ := rl.tier(tierName)
+	entry = &limiterEntry{limiter: rate.NewLimiter(rate.Limit(tier.RPS), tier.Burst)}
+	entry.lastSeen.Store(now)
+	rl.limiters[key] = entry
+	return entry.limiter
 }
 
-// Middleware returns the rate limiting middleware.
-func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get client identifier (IP address or user ID)
-		key := getClientKey(r)
-
-		limiter := rl.getLimiter(key)
-		if !limiter.Allow() {
-			w.Header().Set("Content-Type", "application/json")
-			w.Header().Set("Retry-After", "1")
-			w.WriteHeader(http.StatusTooManyRequests)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"code":    errors.CodeRateLimited,
-				"message": "Too many requests, please try again later",
-			})
+// cleanup periodically evicts idle limiters until Close is called.
+func (rl *RateLimiter) cleanup() {
+	ticker := time.NewTicker(rl.idleTTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rl.stop:
 			return
+		case <-ticker.C:
+			rl.evictIdle()
+		}
+	}
+}
+
+// evictIdle removes limiters that haven't been used within the idle TTL.
+func (rl *RateLimiter) evictIdle() {
+	cutoff := rl.now().Add(-rl.idleTTL).UnixNano()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for key, entry := range rl.limiters {
+		if entry.lastSeen.Load() < cutoff {
+			delete(rl.limiters, key)
 		}
+	}
+}
+
+// Size returns the number of tracked keys.
+func (rl *RateLimiter) Size() int {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return len(rl.limiters)
+}
+
+// Close stops the cleanup goroutine.
+func (rl *RateLimiter) Close() error {
+	rl.closeOnce.Do(func() {
+		close(rl.stop)
+	})
+	return nil
+}
+
+// Middleware returns the rate limiting middleware using the default tier.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return rl.MiddlewareFor(DefaultTier)(next)
+}
+
+// MiddlewareFor returns rate limiting middleware for the given tier. Tiers without
+// their own configuration use the default tier's limits; if neither is configured
+// requests pass through unlimited.
+func (rl *RateLimiter) MiddlewareFor(tierName string) func(next http.Handler) http.Handler {
+	if _, ok := rl.tier(tierName); !ok {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Get client identifier (IP address or user ID)
+			key := getClientKey(r)
+
+			limiter := rl.getLimiter(tierName, key)
+			now := rl.now()
+			reservation := limiter.ReserveN(now, 1)
+			if !reservation.OK() {
+				writeRateLimited(w, time.Second, now)
+				return
+			}
+
+			// A delay means no token is available yet; give it back and tell the
+			// client exactly how long to wait
+			if delay := reservation.DelayFrom(now); delay > 0 {
+				reservation.CancelAt(now)
+				writeRateLimited(w, delay, now)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-		next.ServeHTTP(w, r)
+// writeRateLimited writes a 429 response with Retry-After set to the ceiling of the
+// delay in seconds and X-RateLimit-Reset set to when the next request is allowed.
+func writeRateLimited(w http.ResponseWriter, delay time.Duration, now time.Time) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(delay).Unix(), 10))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    errors.CodeRateLimited,
+		"message": "Too many requests, please try again later",
 	})
 }
 
-// getClientKey extracts the client identifier from the request.
+// getClientKey extracts the client identifier from the request. Only the
+// claims verified by the auth middleware identify a user; a client-supplied
+// X-User-ID header would let callers pick their own bucket.
 func getClientKey(r *http.Request) string {
-	// Try to get user ID from context first (set by auth middleware)
-	if userID := r.Header.Get("X-User-ID"); userID != "" {
-		return "user:" + userID
+	if claims := GetUserFromContext(r.Context()); claims != nil && claims.UserID != "" {
+		return "user:" + claims.UserID
 	}
 
 	// Fall back to IP address
@@ -90,6 +240,8 @@ func getClientKey(r *http.Request) string {
 }
 
 // RateLimit creates a simple rate limit middleware with default settings.
+// The underlying limiter is never closed; use NewRateLimiter directly when the
+// cleanup goroutine needs to be stopped on shutdown.
 func RateLimit(rps int, burst int) func(next http.Handler) http.Handler {
 	limiter := NewRateLimiter(rps, burst)
 	return limiter.Middleware
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript implements a token bucket per key. Time is taken from the Redis
+// server so that limits are consistent regardless of clock skew between pods.
+// Returns {allowed, remaining tokens, ms until next token, server time in ms}.
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', key, math.ceil(burst * 1000 / rate) + 1000)
+
+local wait = 0
+if tokens < 1 then
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+return {allowed, math.floor(tokens), wait, now}
+`)
+
+// RedisRateLimiter provides rate limiting enforced cluster-wide via Redis.
+// When Redis is unavailable it falls back to a per-instance in-memory limiter.
+type RedisRateLimiter struct {
+	client    redis.Scripter
+	tiers     map[string]RateLimitTier
+	keyPrefix string
+	timeout   time.Duration
+	fallback  *RateLimiter
+}
+
+// NewRedisRateLimiter creates a new Redis-backed rate limiter. rps and burst
+// must be positive, since the token bucket script divides by the rate.
+func NewRedisRateLimiter(client redis.Scripter, rps int, burst int) (*RedisRateLimiter, error) {
+	return NewTieredRedisRateLimiter(client, map[string]RateLimitTier{
+		DefaultTier: {RPS: float64(rps), Burst: burst},
+	}, DefaultRateLimiterIdleTTL)
+}
+
+// NewTieredRedisRateLimiter creates a Redis-backed rate limiter with distinct
+// limits per tier, like NewTieredRateLimiter. idleTTL applies to the in-memory
+// fallback. Every tier's rps and burst must be positive.
+func NewTieredRedisRateLimiter(client redis.Scripter, tiers map[string]RateLimitTier, idleTTL time.Duration) (*RedisRateLimiter, error) {
+	for name, tier := range tiers {
+		if tier.RPS <= 0 {
+			return nil, fmt.Errorf("rate limit rps for tier %q must be positive, got %v", name, tier.RPS)
+		}
+		if tier.Burst <= 0 {
+			return nil, fmt.Errorf("rate limit burst for tier %q must be positive, got %d", name, tier.Burst)
+		}
+	}
+
+	rl := &RedisRateLimiter{
+		client:    client,
+		tiers:     make(map[string]RateLimitTier, len(tiers)),
+		keyPrefix: "ratelimit:",
+		timeout:   50 * time.Millisecond,
+		fallback:  NewTieredRateLimiter(tiers, idleTTL),
+	}
+	for name, tier := range tiers {
+		rl.tiers[name] = tier
+	}
+	return rl, nil
+}
+
+// tier returns the limits for the named tier, falling back to DefaultTier.
+func (rl *RedisRateLimiter) tier(name string) (RateLimitTier, bool) {
+	if tier, ok := rl.tiers[name]; ok {
+		return tier, true
+	}
+	tier, ok := rl.tiers[DefaultTier]
+	return tier, ok
+}
+
+// rateLimitDecision is the outcome of a rate limit check.
+type rateLimitDecision struct {
+	allowed    bool
+	remaining  int64
+	retryAfter time.Duration
+	now        time.Time
+}
+
+// allow consumes a token for the key from the tier's Redis bucket.
+func (rl *RedisRateLimiter) allow(ctx context.Context, tierName string, tier RateLimitTier, key string) (*rateLimitDecision, error) {
+	ctx, cancel := context.WithTimeout(ctx, rl.timeout)
+	defer cancel()
+
+	result, err := tokenBucketScript.Run(ctx, rl.client, []string{rl.keyPrefix + tierName + ":" + key}, tier.RPS, tier.Burst).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	return &rateLimitDecision{
+		allowed:    result[0] == 1,
+		remaining:  result[1],
+		retryAfter: time.Duration(result[2]) * time.Millisecond,
+		now:        time.UnixMilli(result[3]),
+	}, nil
+}
+
+// Close stops the fallback limiter's cleanup goroutine.
+func (rl *RedisRateLimiter) Close() error {
+	return rl.fallback.Close()
+}
+
+// Middleware returns the rate limiting middleware using the default tier.
+func (rl *RedisRateLimiter) Middleware(next http.Handler) http.Handler {
+	return rl.MiddlewareFor(DefaultTier)(next)
+}
+
+// MiddlewareFor returns rate limiting middleware for the given tier. Tiers without
+// their own configuration use the default tier's limits; if neither is configured
+// requests pass through unlimited.
+func (rl *RedisRateLimiter) MiddlewareFor(tierName string) func(next http.Handler) http.Handler {
+	tier, ok := rl.tier(tierName)
+	if !ok {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		fallback := rl.fallback.MiddlewareFor(tierName)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := getClientKey(r)
+
+			decision, err := rl.allow(r.Context(), tierName, tier, key)
+			if err != nil {
+				// Redis unavailable: enforce limits per instance rather than failing open
+				fallback.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(decision.remaining, 10))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(decision.now.Add(decision.retryAfter).Unix(), 10))
+
+			if !decision.allowed {
+				writeRateLimited(w, decision.retryAfter, decision.now)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
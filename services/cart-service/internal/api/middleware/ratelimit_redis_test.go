@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisRateLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	// Two limiters sharing Redis behave like pods sharing a cluster-wide limit
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limiterA, err := NewRedisRateLimiter(client, 1, 2)
+	require.NoError(t, err)
+	limiterB, err := NewRedisRateLimiter(client, 1, 2)
+	require.NoError(t, err)
+	podA, podB := limiterA.Middleware(okHandler), limiterB.Middleware(okHandler)
+
+	call := func(h http.Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, &UserClaims{UserID: "user-123"}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call(podA)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.NotEmpty(t, rec.Header().Get("X-RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusOK, call(podB).Code)
+
+	rec = call(podA)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+}
+
+func TestRedisRateLimiter_FallsBackWhenUnavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	mr.Close()
+
+	limiter, err := NewRedisRateLimiter(client, 1, 1)
+	require.NoError(t, err)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	codes := make([]int, 0, 2)
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	require.Len(t, codes, 2)
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestNewRedisRateLimiter_InvalidLimits(t *testing.T) {
+	for _, limits := range [][2]int{{0, 1}, {-1, 1}, {1, 0}} {
+		_, err := NewRedisRateLimiter(nil, limits[0], limits[1])
+		assert.Error(t, err, limits)
+	}
+}
+
+func TestRedisRateLimiter_Tiers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	limiter, err := NewTieredRedisRateLimiter(client, map[string]RateLimitTier{
+		TierRead:  {RPS: 10, Burst: 3},
+		TierWrite: {RPS: 1, Burst: 1},
+	}, DefaultRateLimiterIdleTTL)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	read, write := limiter.MiddlewareFor(TierRead)(okHandler), limiter.MiddlewareFor(TierWrite)(okHandler)
+
+	call := func(h http.Handler) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, &UserClaims{UserID: "user-123"}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Each tier has its own bucket per client
+	assert.Equal(t, http.StatusOK, call(write))
+	assert.Equal(t, http.StatusTooManyRequests, call(write))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, call(read))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, call(read))
+
+	_, err = NewTieredRedisRateLimiter(client, map[string]RateLimitTier{TierWrite: {RPS: 0, Burst: 1}}, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_EvictsIdleLimiters(t *testing.T) {
+	rl := NewRateLimiterWithIdleTTL(10, 10, time.Minute)
+	defer rl.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 1000; i++ {
+		rl.getLimiter(DefaultTier, fmt.Sprintf("ip:10.0.%d.%d", i/256, i%256))
+	}
+	assert.Equal(t, 1000, rl.Size())
+
+	// One key stays active while the rest go idle
+	now = now.Add(45 * time.Second)
+	rl.getLimiter(DefaultTier, "ip:10.0.0.0")
+
+	now = now.Add(30 * time.Second)
+	rl.evictIdle()
+
+	assert.Equal(t, 1, rl.Size())
+}
+
+func TestRateLimiter_RetryAfter(t *testing.T) {
+	// 1 request every 4 seconds with no burst headroom
+	rl := NewTieredRateLimiter(map[string]RateLimitTier{DefaultTier: {RPS: 0.25, Burst: 1}}, time.Minute)
+	defer rl.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rl.now = func() time.Time { return now }
+
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	call := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, call().Code)
+
+	now = now.Add(1500 * time.Millisecond)
+	rec := call()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("Retry-After"))
+	assert.Equal(t, strconv.FormatInt(now.Add(2500*time.Millisecond).Unix(), 10), rec.Header().Get("X-RateLimit-Reset"))
+
+	// The rejected request must not have consumed the pending token
+	now = now.Add(2500 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, call().Code)
+}
+
+func TestRateLimiter_MiddlewareFor(t *testing.T) {
+	rl := NewTieredRateLimiter(map[string]RateLimitTier{
+		TierRead:  {RPS: 1, Burst: 3},
+		TierWrite: {RPS: 1, Burst: 1},
+	}, time.Minute)
+	defer rl.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rl.now = func() time.Time { return now }
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	reads := rl.MiddlewareFor(TierRead)(ok)
+	writes := rl.MiddlewareFor(TierWrite)(ok)
+	call := func(h http.Handler) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	// Writes are exhausted after one request without affecting reads
+	assert.Equal(t, http.StatusOK, call(writes))
+	assert.Equal(t, http.StatusTooManyRequests, call(writes))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, call(reads))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, call(reads))
+
+	// Unconfigured tiers without a default are not limited
+	assert.Equal(t, http.StatusOK, call(rl.MiddlewareFor("unknown")(ok)))
+}
+
+func TestGetClientKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	// A client-supplied user ID doesn't choose the bucket
+	req.Header.Set("X-User-ID", "someone-else")
+	assert.Equal(t, "ip:10.0.0.1:1234", getClientKey(req))
+
+	req = req.WithContext(context.WithValue(req.Context(), userContextKey, &UserClaims{UserID: "user-123"}))
+	assert.Equal(t, "user:user-123", getClientKey(req))
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"bytes"
 	"encoding/json"
+	stderrors "errors"
 	"io"
 	"net/http"
 	"strings"
@@ -25,29 +27,148 @@ func SecurityHeaders(next http.Handler) http.Handler {
 	})
 }
 
-// RequestSizeLimit limits the size of request bodies.
+// RequestSizeLimit limits the size of request bodies. A declared
+// Content-Length over maxBytes is rejected up front; otherwise reads are
+// capped at the declared length, or at maxBytes for chunked bodies, so a body
+// longer than its header claims fails with *http.MaxBytesError rather than
+// being read past. Handlers answer that error with 413 like this middleware.
 func RequestSizeLimit(maxBytes int64) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.ContentLength > maxBytes {
+				writeRequestTooLarge(w, maxBytes)
+				return
+			}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				limit := maxBytes
+				if r.ContentLength >= 0 {
+					limit = r.ContentLength
+				}
+				r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit), maxBytes: maxBytes}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitedBody reports the configured maxBytes in the *http.MaxBytesError of a
+// body capped at its shorter declared length, so 413 responses don't echo the
+// client's Content-Length back as the limit.
+type limitedBody struct {
+	io.ReadCloser
+	maxBytes int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if stderrors.As(err, &maxErr) {
+		err = &http.MaxBytesError{Limit: b.maxBytes}
+	}
+	return n, err
+}
+
+// writeRequestTooLarge writes the 413 response for a body over maxBytes.
+func writeRequestTooLarge(w http.ResponseWriter, maxBytes int64) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    errors.CodeRequestTooLarge,
+		"message": "Request body too large",
+		"details": map[string]interface{}{
+			"max_bytes": maxBytes,
+		},
+	})
+}
+
+// JSONLimits rejects request bodies whose JSON nests deeper than maxDepth or
+// contains an array with more than maxArrayLength elements. Byte limits alone
+// don't stop small payloads that are expensive to decode. Malformed JSON is
+// passed through for the handler to report.
+func JSONLimits(maxDepth, maxArrayLength int) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || (r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if stderrors.As(err, &maxErr) {
+					writeRequestTooLarge(w, maxErr.Limit)
+					return
+				}
 				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				w.WriteHeader(http.StatusBadRequest)
 				json.NewEncoder(w).Encode(map[string]interface{}{
 					"code":    errors.CodeInvalidRequest,
-					"message": "Request body too large",
-					"details": map[string]interface{}{
-						"max_bytes": maxBytes,
-					},
+					"message": "Request body could not be read",
 				})
 				return
 			}
 
-			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			if details := checkJSONLimits(body, maxDepth, maxArrayLength); details != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"code":    errors.CodeValidationError,
+					"message": "Request body exceeds JSON limits",
+					"details": details,
+				})
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// jsonFrame tracks an open JSON array or object while scanning.
+type jsonFrame struct {
+	array  bool
+	tokens int
+}
+
+// checkJSONLimits scans body and returns error details for the first limit it
+// exceeds, or nil if the body is within limits (or isn't valid JSON).
+func checkJSONLimits(body []byte, maxDepth, maxArrayLength int) map[string]interface{} {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	var stack []*jsonFrame
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+
+		if delim, ok := token.(json.Delim); ok && (delim == ']' || delim == '}') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		// Count values in the enclosing array; object keys aren't values
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			top.tokens++
+			if top.array && top.tokens > maxArrayLength {
+				return map[string]interface{}{"max_array_length": maxArrayLength}
+			}
+		}
+
+		if delim, ok := token.(json.Delim); ok {
+			stack = append(stack, &jsonFrame{array: delim == '['})
+			if len(stack) > maxDepth {
+				return map[string]interface{}{"max_depth": maxDepth}
+			}
+		}
+	}
+}
+
 // ContentType validates the Content-Type header for requests with bodies.
 func ContentType(contentTypes ...string) func(next http.Handler) http.Handler {
 	allowedTypes := make(map[string]bool)
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLimits(t *testing.T) {
+	var received string
+	handler := JSONLimits(3, 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"within limits", `{"item_ids": ["a", "b"], "meta": {"k": "v"}}`, http.StatusOK},
+		{"too deep", `{"a": {"b": {"c": {}}}}`, http.StatusBadRequest},
+		{"array too long", `{"item_ids": ["a", "b", "c"]}`, http.StatusBadRequest},
+		{"nested values count once", `[[1, 2, 3], {"a": [1]}]`, http.StatusBadRequest},
+		{"object keys aren't array elements", `[{"a": 1, "b": 2, "c": 3}]`, http.StatusOK},
+		{"malformed json passes through", `{"a": [`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.body, received)
+			} else {
+				assert.Contains(t, rec.Body.String(), "VALIDATION_ERROR")
+			}
+		})
+	}
+}
+
+func TestRequestSizeLimit(t *testing.T) {
+	var received string
+	handler := RequestSizeLimit(16)(JSONLimits(10, 10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		wantStatus    int
+	}{
+		{"within limit", `{"a": 1}`, 8, http.StatusOK},
+		{"declared length over limit", `{"a": 1}`, 17, http.StatusRequestEntityTooLarge},
+		{"body longer than declared length", `{"a": 1, "b": 2}`, 8, http.StatusRequestEntityTooLarge},
+		{"chunked within limit", `{"a": 1}`, -1, http.StatusOK},
+		{"chunked over limit", `{"a": 1, "b": 2, "c": 3}`, -1, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			if tt.contentLength < 0 {
+				req.TransferEncoding = []string{"chunked"}
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.body, received)
+			} else {
+				assert.Contains(t, rec.Body.String(), "REQUEST_TOO_LARGE")
+				assert.Contains(t, rec.Body.String(), `"max_bytes":16`)
+				assert.Empty(t, received)
+			}
+		})
+	}
+}
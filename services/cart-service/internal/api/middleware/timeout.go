@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// Timeout bounds each request by timeout. The deadline is set on the request
+// context, so downstream calls made with it (DynamoDB included) are cancelled
+// once it passes. A handler that returns at the deadline without writing a
+// response is answered with 504 REQUEST_TIMEOUT. A zero timeout disables the
+// bound.
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{ResponseWriter: w}
+			next.ServeHTTP(tw, r.WithContext(ctx))
+
+			if !tw.wroteHeader && stderrors.Is(ctx.Err(), context.DeadlineExceeded) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"code":    errors.CodeTimeout,
+					"message": "Request timed out",
+				})
+			}
+		})
+	}
+}
+
+// timeoutWriter records whether the handler started a response.
+type timeoutWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *timeoutWriter) WriteHeader(statusCode int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
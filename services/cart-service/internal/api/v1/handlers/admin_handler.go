@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+)
+
+// AdminHandler handles administrative HTTP requests that span many carts.
+type AdminHandler struct {
+	service *cart.Service
+	logger  *logging.Logger
+}
+
+// NewAdminHandler creates a new admin handler.
+func NewAdminHandler(service *cart.Service, logger *logging.Logger) *AdminHandler {
+	return &AdminHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RepriceProduct handles POST /v1/admin/products/{productID}/reprice
+func (h *AdminHandler) RepriceProduct(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID := chi.URLParam(r, "productID")
+
+	// Validate product ID
+	if err := ValidateProductID(productID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Parse request
+	var req RepriceProductRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Reprice carts
+	result, err := h.service.RepriceProduct(ctx, productID, req.UnitPrice, req.Limit)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to reprice product")
+		writeError(w, r, err)
+		return
+	}
+
+	h.logger.WithContext(ctx).
+		WithField("product_id", productID).
+		WithField("carts_updated", result.CartsUpdated).
+		WithField("carts_failed", result.CartsFailed).
+		Info("Product repriced")
+
+	writeSuccess(w, r, result)
+}
+
+// ReplayEvents handles POST /internal/cart/{userID}/replay-events
+func (h *AdminHandler) ReplayEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := chi.URLParam(r, "userID")
+
+	// Validate user ID
+	if err := ValidateUserID(userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Replay events
+	result, err := h.service.ReplayEvents(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to replay cart events")
+		writeError(w, r, err)
+		return
+	}
+
+	h.logger.WithContext(ctx).
+		WithField("user_id", userID).
+		WithField("events_published", result.EventsPublished).
+		Info("Cart events replayed")
+
+	writeSuccess(w, r, result)
+}
+
+// GetCartHistory handles GET /internal/cart/{userID}/history
+func (h *AdminHandler) GetCartHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := chi.URLParam(r, "userID")
+
+	// Validate user ID
+	if err := ValidateUserID(userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Get history
+	snapshots, err := h.service.GetCartHistory(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to get cart history")
+		writeError(w, r, err)
+		return
+	}
+
+	if snapshots == nil {
+		snapshots = []cart.CartSnapshot{}
+	}
+	writeSuccess(w, r, &CartHistoryResponse{UserID: userID, Snapshots: snapshots})
+}
+
+// ExportCarts handles GET /internal/carts
+// Pages through all carts of the tenant_id query parameter (the default
+// tenant when absent); pass next_cursor back as the cursor query parameter
+// to get the next page.
+func (h *AdminHandler) ExportCarts(w http.ResponseWriter, r *http.Request) {
+	cursor := r.URL.Query().Get("cursor")
+
+	tenantID := r.URL.Query().Get("tenant_id")
+	if tenantID != "" {
+		if err := ValidateTenantID(tenantID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	ctx := cart.WithTenantID(r.Context(), tenantID)
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > cart.MaxExportLimit {
+			writeError(w, r, errors.ErrValidation("Invalid limit parameter", map[string]interface{}{
+				"limit": "must be between 1 and " + strconv.Itoa(cart.MaxExportLimit),
+			}))
+			return
+		}
+		limit = parsed
+	}
+
+	// Export carts
+	page, err := h.service.ExportCarts(ctx, cursor, limit)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to export carts")
+		writeError(w, r, err)
+		return
+	}
+
+	resp := NewCartListResponse(page.Carts)
+	resp.NextCursor = page.NextCursor
+	writeSuccess(w, r, resp)
+}
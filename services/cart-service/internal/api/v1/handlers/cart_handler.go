@@ -1,13 +1,24 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	apimiddleware "github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/middleware"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
 )
 
+// streamHeartbeatInterval is how often an idle change stream sends a comment so
+// proxies don't close the connection.
+const streamHeartbeatInterval = 15 * time.Second
+
 // CartHandler handles cart-related HTTP requests.
 type CartHandler struct {
 	service *cart.Service
@@ -23,183 +34,757 @@ func NewCartHandler(service *cart.Service, logger *logging.Logger) *CartHandler
 }
 
 // GetCart handles GET /v1/cart/{userID}
+// Responds with the cart version as an ETag and supports If-None-Match.
+// Items are in insertion order unless the sort query parameter is set.
 func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := chi.URLParam(r, "userID")
+	userID := pathParam(r, "userID")
+	order := r.URL.Query().Get("sort")
 
-	// Validate user ID
-	if err := ValidateUserID(userID); err != nil {
-		writeError(w, err)
+	// Validate sort order
+	if err := ValidateItemSort(order); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	// Get cart
-	c, err := h.service.GetCart(ctx, userID)
+	c, err := h.service.ReadCart(ctx, userID)
 	if err != nil {
 		h.logger.WithContext(ctx).WithError(err).Error("Failed to get cart")
-		writeError(w, err)
+		writeError(w, r, err)
+		return
+	}
+
+	etag := cartETag(c)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		writeNotModified(w, etag)
 		return
 	}
 
-	writeSuccess(w, NewCartResponse(c))
+	w.Header().Set("ETag", etag)
+	resp := NewCartResponse(c).WithItemOrder(order)
+	writeSuccess(w, r, resp.WithDeliveryEstimates(h.service.DeliveryEstimates(ctx, c)))
 }
 
-// AddItem handles POST /v1/cart/{userID}/items
-func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+// TouchCart handles POST /v1/cart/{userID}/touch
+// Extends the cart's expiration so long browsing sessions don't lose it.
+func (h *CartHandler) TouchCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	// Extend expiration
+	c, err := h.service.TouchCart(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to touch cart")
+		writeError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, r, NewCartResponse(c))
+}
+
+// GetSummary handles GET /v1/cart/{userID}/summary
+// Supports If-None-Match so frequently polling clients get 304 when nothing changed.
+func (h *CartHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	// Get summary
+	summary, err := h.service.GetCartSummary(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to get cart summary")
+		writeError(w, r, err)
+		return
+	}
+
+	etag := summaryETag(summary)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		writeNotModified(w, etag)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	writeSuccess(w, r, summary)
+}
+
+// GetCount handles GET /v1/cart/{userID}/count
+// Returns only the total quantity for header badges; a missing cart counts as 0.
+func (h *CartHandler) GetCount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	// Get count
+	count, err := h.service.GetItemCount(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to get cart count")
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	writeSuccess(w, r, &CartCountResponse{Count: count})
+}
+
+// StreamCart handles GET /v1/cart/{userID}/stream
+// Streams cart changes as Server-Sent Events until the client disconnects.
+func (h *CartHandler) StreamCart(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := chi.URLParam(r, "userID")
+	userID := pathParam(r, "userID")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errors.New(errors.CodeInvalidRequest, "Streaming is not supported"))
+		return
+	}
 
-	// Validate user ID
-	if err := ValidateUserID(userID); err != nil {
-		writeError(w, err)
+	// Subscribe; the subscription ends when the request context is canceled
+	changes, err := h.service.SubscribeChanges(ctx, userID)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(change)
+			if err != nil {
+				h.logger.WithContext(ctx).WithError(err).Error("Failed to encode cart change")
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", change.Version, change.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// AddItem handles POST /v1/cart/{userID}/items
+func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
 	// Decode request
 	var req AddItemRequest
 	if err := decodeJSON(r, &req); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
 	// Validate request
 	if err := req.Validate(); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
 	// Add item
-	c, err := h.service.AddItem(ctx, userID, cart.AddItemRequest{
-		ProductID: req.ProductID,
-		Quantity:  req.Quantity,
-		UnitPrice: req.UnitPrice,
-	})
+	addReq := cart.AddItemRequest{
+		ProductID:   req.ProductID,
+		Quantity:    req.Quantity,
+		UnitPrice:   req.UnitPrice,
+		Name:        req.Name,
+		ImageURL:    req.ImageURL,
+		SKU:         req.SKU,
+		Attributes:  req.Attributes,
+		GiftWrap:    req.GiftWrap,
+		AddMode:     cart.AddMode(req.AddMode),
+		IfNotExists: req.IfNotExists,
+
+		UnitType:        cart.UnitType(req.UnitType),
+		DecimalQuantity: req.DecimalQuantity,
+		PackSize:        req.PackSize,
+	}
+	c, err := h.service.AddItem(ctx, userID, addReq)
 	if err != nil {
 		h.logger.WithContext(ctx).WithError(err).Error("Failed to add item")
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
-	writeCreated(w, NewCartResponse(c))
+	item, _ := c.FindItemByProductID(req.ProductID)
+	writeCreated(w, r, NewCartResponse(c).
+		WithWarnings(h.service.PackSizeWarnings(addReq, item)).
+		WithWarnings(h.service.QuantityWarnings(item)).
+		WithWarnings(h.service.ItemLimitWarnings(ctx, c)))
 }
 
-// UpdateItem handles PATCH /v1/cart/{userID}/items/{itemID}
-func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+// PatchCart handles PATCH /v1/cart/{userID}
+// An array body is a JSON Patch of item additions, quantity changes and
+// removals, applied atomically. An object body updates cart attributes, e.g.
+// {"name": "Birthday list"}.
+func (h *CartHandler) PatchCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	// Decode request
+	var body json.RawMessage
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if isJSONObject(body) {
+		h.patchCartAttributes(w, r, userID, body)
+		return
+	}
+
+	var req []PatchOperationRequest
+	if err := unmarshalJSON(r, body, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Validate request
+	ops, err := ParsePatch(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Apply patch
+	c, err := h.service.PatchCart(ctx, userID, ops)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to patch cart")
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", cartETag(c))
+	writeSuccess(w, r, NewCartResponse(c))
+}
+
+// patchCartAttributes applies an object body of PatchCart.
+func (h *CartHandler) patchCartAttributes(w http.ResponseWriter, r *http.Request, userID string, body json.RawMessage) {
 	ctx := r.Context()
-	userID := chi.URLParam(r, "userID")
-	itemID := chi.URLParam(r, "itemID")
 
-	// Validate IDs
-	if err := ValidateUserID(userID); err != nil {
-		writeError(w, err)
+	var req PatchCartRequest
+	if err := unmarshalJSON(r, body, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
-	if err := ValidateItemID(itemID); err != nil {
-		writeError(w, err)
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Set name
+	c, err := h.service.SetCartName(ctx, userID, *req.Name)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to set cart name")
+		writeError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", cartETag(c))
+	writeSuccess(w, r, NewCartResponse(c))
+}
+
+// ApplyTemplate handles POST /v1/cart/{userID}/templates/{templateID}:apply
+func (h *CartHandler) ApplyTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+	templateID := chi.URLParam(r, "templateID")
+
+	// Validate template ID
+	if err := ValidateTemplateID(templateID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Apply template
+	result, err := h.service.AddTemplate(ctx, userID, templateID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to apply template")
+		writeError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, r, &ApplyTemplateResponse{
+		Cart:    NewCartResponse(result.Cart),
+		Added:   result.Added,
+		Skipped: result.Skipped,
+	})
+}
+
+// UpdateItem handles PATCH /v1/cart/{userID}/items/{itemID}
+// An If-Match header takes precedence over the version in the body.
+func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+	itemID := pathParam(r, "itemID")
+
 	// Decode request
 	var req UpdateQuantityRequest
 	if err := decodeJSON(r, &req); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
 	// Validate request
 	if err := req.Validate(); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
+		return
+	}
+
+	expectedVersion := req.Version
+	ifMatch, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
+	if ifMatch > 0 {
+		expectedVersion = ifMatch
+	}
 
-	// Update item
-	c, err := h.service.UpdateItemQuantity(ctx, userID, cart.UpdateItemRequest{
+	// Update item; the quantity is absolute, so conflicts are retried
+	// server-side unless the client pinned a version
+	c, err := h.service.UpdateItemQuantityWithAutoRetry(ctx, userID, cart.UpdateItemRequest{
 		ItemID:          itemID,
 		Quantity:        req.Quantity,
-		ExpectedVersion: req.Version,
+		DecimalQuantity: req.DecimalQuantity,
+		ExpectedVersion: expectedVersion,
+		GiftWrap:        req.GiftWrap,
 	})
 	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && ifMatch > 0 && appErr.Code == errors.CodeConflict {
+			err = errors.ErrPreconditionFailed(appErr.Details)
+		}
 		h.logger.WithContext(ctx).WithError(err).Error("Failed to update item")
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
-	writeSuccess(w, NewCartResponse(c))
+	item, _ := c.FindItem(itemID)
+	w.Header().Set("ETag", cartETag(c))
+	writeSuccess(w, r, NewCartResponse(c).WithWarnings(h.service.QuantityWarnings(item)))
 }
 
 // RemoveItem handles DELETE /v1/cart/{userID}/items/{itemID}
 func (h *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := chi.URLParam(r, "userID")
-	itemID := chi.URLParam(r, "itemID")
+	userID := pathParam(r, "userID")
+	itemID := pathParam(r, "itemID")
 
-	// Validate IDs
-	if err := ValidateUserID(userID); err != nil {
-		writeError(w, err)
+	// Remove item
+	c, err := h.service.RemoveItem(ctx, userID, itemID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to remove item")
+		writeError(w, r, err)
 		return
 	}
-	if err := ValidateItemID(itemID); err != nil {
-		writeError(w, err)
+
+	writeSuccess(w, r, NewCartResponse(c))
+}
+
+// AdjustItem handles POST /v1/cart/{userID}/items/{itemID}/adjust
+// Changes the quantity by a relative delta, removing the item at zero.
+func (h *CartHandler) AdjustItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+	itemID := pathParam(r, "itemID")
+
+	// Decode request
+	var req AdjustQuantityRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Remove item
-	c, err := h.service.RemoveItem(ctx, userID, itemID)
+	// Validate request
+	if err := req.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Adjust item
+	c, err := h.service.AdjustItemQuantity(ctx, userID, itemID, req.Delta)
 	if err != nil {
-		h.logger.WithContext(ctx).WithError(err).Error("Failed to remove item")
-		writeError(w, err)
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to adjust item")
+		writeError(w, r, err)
 		return
 	}
 
-	writeSuccess(w, NewCartResponse(c))
+	item, _ := c.FindItem(itemID)
+	w.Header().Set("ETag", cartETag(c))
+	writeSuccess(w, r, NewCartResponse(c).WithWarnings(h.service.QuantityWarnings(item)))
 }
 
-// ClearCart handles DELETE /v1/cart/{userID}
-func (h *CartHandler) ClearCart(w http.ResponseWriter, r *http.Request) {
+// ReorderItems handles PUT /v1/cart/{userID}/items/order
+func (h *CartHandler) ReorderItems(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := chi.URLParam(r, "userID")
+	userID := pathParam(r, "userID")
 
-	// Validate user ID
-	if err := ValidateUserID(userID); err != nil {
-		writeError(w, err)
+	// Decode request
+	var req ReorderItemsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	// Validate request
+	if err := req.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Reorder items
+	c, err := h.service.ReorderItems(ctx, userID, req.ItemIDs)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to reorder items")
+		writeError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, r, NewCartResponse(c))
+}
+
+// ClearCart handles DELETE /v1/cart/{userID}
+func (h *CartHandler) ClearCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
 	// Clear cart
 	if err := h.service.ClearCart(ctx, userID); err != nil {
 		h.logger.WithContext(ctx).WithError(err).Error("Failed to clear cart")
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
 	writeNoContent(w)
 }
 
-// MergeCart handles POST /v1/cart/{userID}/merge
-func (h *CartHandler) MergeCart(w http.ResponseWriter, r *http.Request) {
+// GetPriceChanges handles GET /v1/cart/{userID}/price-changes
+// Lists items whose catalog price dropped since they were added.
+func (h *CartHandler) GetPriceChanges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	// Compare prices
+	report, err := h.service.PriceDrops(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to get price changes")
+		writeError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, r, report)
+}
+
+// ValidateCart handles POST /v1/cart/{userID}/validate
+// Reports per-item price and stock status for checkout. With ?reprice=true,
+// changed prices are stored. Returns 200 even when items fail validation.
+func (h *CartHandler) ValidateCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	reprice := false
+	if raw := r.URL.Query().Get("reprice"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, r, errors.ErrValidation("Invalid reprice parameter", map[string]interface{}{
+				"reprice": "must be true or false",
+			}))
+			return
+		}
+		reprice = parsed
+	}
+
+	// Validate cart
+	report, err := h.service.ValidateCart(ctx, userID, reprice)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to validate cart")
+		writeError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, r, report)
+}
+
+// RestoreCart handles POST /v1/cart/{userID}/restore
+// Restores the items removed by a recent clear.
+func (h *CartHandler) RestoreCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	// Restore cart
+	c, err := h.service.RestoreCart(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to restore cart")
+		writeError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, r, NewCartResponse(c))
+}
+
+// LockCart handles POST /v1/cart/{userID}/lock
+func (h *CartHandler) LockCart(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := chi.URLParam(r, "userID")
+	userID := pathParam(r, "userID")
 
-	// Validate user ID
-	if err := ValidateUserID(userID); err != nil {
-		writeError(w, err)
+	c, err := h.service.LockCart(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to lock cart")
+		writeError(w, r, err)
 		return
 	}
 
+	writeSuccess(w, r, NewCartResponse(c))
+}
+
+// UnlockCart handles POST /v1/cart/{userID}/unlock
+func (h *CartHandler) UnlockCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	c, err := h.service.UnlockCart(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to unlock cart")
+		writeError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, r, NewCartResponse(c))
+}
+
+// SetGiftMessage handles PUT /v1/cart/{userID}/gift-message
+func (h *CartHandler) SetGiftMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
 	// Decode request
-	var req MergeCartRequest
+	var req SetGiftMessageRequest
 	if err := decodeJSON(r, &req); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Set gift message
+	c, err := h.service.SetGiftMessage(ctx, userID, req.GiftMessage)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to set gift message")
+		writeError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, r, NewCartResponse(c))
+}
+
+// MergeCart handles POST /v1/cart/{userID}/merge
+// The guest ID defaults to the guest cookie, which is cleared once merged.
+func (h *CartHandler) MergeCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	// Decode request; the body may be omitted when the guest cookie is set
+	var req MergeCartRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	cookieID := h.guestIDFromCookie(r)
+	if req.GuestID == "" {
+		req.GuestID = cookieID
+	}
+	if req.GuestID == "" {
+		writeError(w, r, errors.ErrValidation("guest_id is required", map[string]interface{}{
+			"field": "guest_id",
+		}))
 		return
 	}
 
 	// Merge carts
-	c, err := h.service.MergeGuestCart(ctx, userID, req.GuestID)
+	c, warnings, err := h.service.MergeGuestCartWithWarnings(ctx, userID, req.GuestID)
 	if err != nil {
 		h.logger.WithContext(ctx).WithError(err).Error("Failed to merge cart")
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
+	if cookieID != "" && cookieID == req.GuestID {
+		clearGuestCookie(w)
+	}
 
-	writeSuccess(w, NewCartResponse(c))
+	writeSuccess(w, r, NewCartResponse(c).
+		WithWarnings(warnings).
+		WithWarnings(h.service.ItemLimitWarnings(ctx, c)))
+}
+
+// CreateCart handles POST /v1/cart/{userID}/carts
+// Creates an additional named cart next to the user's default cart.
+func (h *CartHandler) CreateCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	// Decode request
+	var req CreateCartRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Create cart
+	c, err := h.service.CreateCart(ctx, userID, req.Name)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to create cart")
+		writeError(w, r, err)
+		return
+	}
+
+	writeCreated(w, r, NewCartResponse(c))
+}
+
+// ListCarts handles GET /v1/cart/{userID}/carts
+func (h *CartHandler) ListCarts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := pathParam(r, "userID")
+
+	// List carts
+	carts, err := h.service.ListCarts(ctx, userID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to list carts")
+		writeError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, r, NewCartListResponse(carts))
+}
+
+// ScopeTenant is middleware that scopes cart operations to the tenant_id claim
+// of the authenticated user. It must be mounted after JWTAuth; requests
+// without claims or a tenant claim use cart.DefaultTenantID. The tenant never
+// comes from the path, so callers can't reach another tenant's carts.
+func ScopeTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tenantID string
+		if claims := apimiddleware.GetUserFromContext(r.Context()); claims != nil {
+			tenantID = claims.TenantID
+		}
+		if tenantID != "" {
+			if err := ValidateTenantID(tenantID); err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(cart.WithTenantID(r.Context(), tenantID)))
+	})
+}
+
+// HeaderOverrideLimits asks to exceed standard cart limits; see OverrideLimits.
+const HeaderOverrideLimits = "X-Override-Limits"
+
+// OverrideLimits is middleware that lets users in one of adminGroups exceed
+// the standard cart item limit, up to cart.ServiceConfig.AdminMaxItemsPerCart,
+// by sending "X-Override-Limits: true". The header is ignored for everyone
+// else. Every override is logged. Must be mounted after JWTAuth.
+func OverrideLimits(adminGroups []string, logger *logging.Logger) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool)
+	for _, group := range adminGroups {
+		allowed[group] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if override, _ := strconv.ParseBool(r.Header.Get(HeaderOverrideLimits)); !override {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims := apimiddleware.GetUserFromContext(r.Context())
+			if claims == nil || !inAnyGroup(claims.Groups, allowed) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger.WithContext(r.Context()).
+				WithField("actor", claims.UserID).
+				WithField("method", r.Method).
+				WithField("path", r.URL.Path).
+				Warn("Cart limit override used")
+			next.ServeHTTP(w, r.WithContext(cart.WithLimitOverride(r.Context())))
+		})
+	}
+}
+
+// inAnyGroup reports whether any of groups is allowed.
+func inAnyGroup(groups []string, allowed map[string]bool) bool {
+	for _, group := range groups {
+		if allowed[group] {
+			return true
+		}
+	}
+	return false
+}
+
+// PathParamValidator is middleware that validates the named URL parameter
+// once for a route group, responding 400 before any handler runs when
+// validate fails. Handlers read the validated value with pathParam.
+func PathParamValidator(name string, validate func(string) error) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value := chi.URLParam(r, name)
+			if err := validate(value); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			ctx := context.WithValue(r.Context(), pathParamKey(name), value)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+type pathParamKey string
+
+// pathParam returns a URL parameter validated by PathParamValidator, or ""
+// if the route doesn't mount one for it.
+func pathParam(r *http.Request, name string) string {
+	value, _ := r.Context().Value(pathParamKey(name)).(string)
+	return value
+}
+
+// SelectCart is middleware for routes under /v1/cart/{userID}/carts/{cartID}.
+// It selects the cart named by the cartID URL parameter so the regular cart
+// handlers act on it instead of the user's default cart.
+func SelectCart(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cartID := chi.URLParam(r, "cartID")
+		if err := ValidateCartID(cartID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(cart.WithCartID(r.Context(), cartID)))
+	})
 }
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// cartETag returns a weak ETag derived from the cart version.
+func cartETag(c *cart.Cart) string {
+	return fmt.Sprintf(`W/"v%d"`, c.Version)
+}
+
+// parseIfMatch parses an If-Match header produced from cartETag into the cart
+// version it names. It returns 0 when the header is absent or "*", which
+// places no constraint on the version.
+func parseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseInt(strings.TrimPrefix(tag, "v"), 10, 64)
+	if err != nil || !strings.HasPrefix(tag, "v") || version <= 0 {
+		return 0, errors.New(errors.CodeInvalidRequest, "Invalid If-Match header").
+			WithDetail("if_match", header)
+	}
+	return version, nil
+}
+
+// summaryETag returns a weak ETag for a cart summary derived from its item count,
+// total and version, so it changes whenever any displayed value changes.
+func summaryETag(s *cart.CartSummary) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%d:%d", s.ItemCount, s.TotalPrice, s.Version)
+	return fmt.Sprintf(`W/"summary-%x"`, h.Sum64())
+}
+
+// etagMatches reports whether an If-None-Match header value matches the ETag.
+// Comparison is weak, as required for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified writes a 304 response with the current ETag.
+func writeNotModified(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+)
+
+// GuestCookieName is the cookie carrying the guest ID issued by
+// CreateGuestCart.
+const GuestCookieName = "cart_guest_id"
+
+// GuestCartResponse is the response of CreateGuestCart.
+type GuestCartResponse struct {
+	GuestID string        `json:"guest_id"`
+	Cart    *CartResponse `json:"cart"`
+}
+
+// CreateGuestCart handles POST /v1/cart
+// Creates a cart for a new server-issued guest ID and sets the ID in an
+// httpOnly cookie. A request that already carries the cookie of an unexpired
+// guest cart gets that cart back instead of a new one.
+func (h *CartHandler) CreateGuestCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if guestID := h.guestIDFromCookie(r); guestID != "" {
+		if c, err := h.service.GetCart(ctx, guestID); err == nil {
+			writeSuccess(w, r, GuestCartResponse{GuestID: guestID, Cart: NewCartResponse(c)})
+			return
+		}
+	}
+
+	c, err := h.service.CreateGuestCart(ctx)
+	if err != nil {
+		h.logger.WithContext(ctx).WithError(err).Error("Failed to create guest cart")
+		writeError(w, r, err)
+		return
+	}
+
+	setGuestCookie(w, c)
+	writeCreated(w, r, GuestCartResponse{GuestID: c.UserID, Cart: NewCartResponse(c)})
+}
+
+// guestIDFromCookie returns the guest ID from the request's guest cookie, or
+// "" if there is none or it doesn't name a guest cart.
+func (h *CartHandler) guestIDFromCookie(r *http.Request) string {
+	cookie, err := r.Cookie(GuestCookieName)
+	if err != nil || !h.service.IsGuestID(cookie.Value) || ValidateUserID(cookie.Value) != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// setGuestCookie associates later requests with the guest cart c until it
+// expires.
+func setGuestCookie(w http.ResponseWriter, c *cart.Cart) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     GuestCookieName,
+		Value:    c.UserID,
+		Path:     "/",
+		Expires:  c.ExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearGuestCookie removes the guest cookie once its cart has been merged.
+func clearGuestCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     GuestCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// preferredLocale returns the highest-weighted locale in an Accept-Language
+// header that has a message catalog, trying the full tag ("de-AT") before
+// its base language ("de"). It falls back to errors.DefaultLocale.
+func preferredLocale(acceptLanguage string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 {
+				continue
+			}
+			q = parsed
+		}
+		tags = append(tags, weighted{tag: strings.ToLower(tag), q: q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	for _, t := range tags {
+		if errors.HasCatalog(t.tag) {
+			return t.tag
+		}
+		if base, _, ok := strings.Cut(t.tag, "-"); ok && errors.HasCatalog(base) {
+			return base
+		}
+	}
+	return errors.DefaultLocale
+}
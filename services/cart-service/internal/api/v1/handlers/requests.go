@@ -2,57 +2,304 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	stderrors "errors"
+	"io"
 	"net/http"
+	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 var (
-	validate    = validator.New()
+	validate    = newValidator()
 	uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
 	alphanumPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	// markupPattern matches HTML tags, comments and script URLs
+	markupPattern = regexp.MustCompile(`(?i)<\s*[a-z!/?]|javascript\s*:`)
 )
 
 // AddItemRequest represents a request to add an item to the cart.
 type AddItemRequest struct {
 	ProductID string `json:"product_id" validate:"required,max=64"`
-	Quantity  int    `json:"quantity" validate:"required,min=1,max=99"`
-	UnitPrice int64  `json:"unit_price" validate:"min=0,max=999999999"`
+	Quantity  int    `json:"quantity" validate:"required_unless=UnitType weight,min=0,max=99"`
+	UnitPrice int64  `json:"unit_price"` // Checked by cart.ValidatePrice
+
+	// UnitType "weight" sells the item by decimal_quantity milli-units (e.g.
+	// 1500 for 1.5 kg) instead of quantity
+	UnitType        string `json:"unit_type,omitempty" validate:"omitempty,oneof=each weight"`
+	DecimalQuantity int64  `json:"decimal_quantity,omitempty" validate:"required_if=UnitType weight,min=0,max=99000"`
+
+	// PackSize requires quantity to be a multiple of it, e.g. 6 for a
+	// six-pack; other quantities are rejected or rounded up, per service
+	// configuration
+	PackSize int `json:"pack_size,omitempty" validate:"min=0,max=99"`
+
+	// Optional product details
+	Name       string            `json:"name,omitempty" validate:"max=256"`
+	ImageURL   string            `json:"image_url,omitempty" validate:"omitempty,url,max=2048"`
+	SKU        string            `json:"sku,omitempty" validate:"max=64"`
+	Attributes map[string]string `json:"attributes,omitempty" validate:"max=20,dive,keys,required,max=64,endkeys,max=256"`
+
+	GiftWrap bool `json:"gift_wrap,omitempty"`
+
+	// AddMode "set" replaces the quantity of a product already in the cart
+	// instead of adding to it
+	AddMode string `json:"add_mode,omitempty" validate:"omitempty,oneof=increment set"`
+
+	// IfNotExists fails with 409 ITEM_ALREADY_EXISTS when the product is
+	// already in the cart instead of merging quantities
+	IfNotExists bool `json:"if_not_exists,omitempty" validate:"excluded_with=AddMode"`
 }
 
 // UpdateQuantityRequest represents a request to update item quantity.
 type UpdateQuantityRequest struct {
-	Quantity int   `json:"quantity" validate:"required,min=1,max=99"`
+	Quantity int   `json:"quantity" validate:"required_without=DecimalQuantity,min=0,max=99"`
 	Version  int64 `json:"version" validate:"min=0"`
+
+	// DecimalQuantity sets the milli-units of a weighted item instead of quantity
+	DecimalQuantity int64 `json:"decimal_quantity,omitempty" validate:"min=0,max=99000"`
+
+	// GiftWrap turns gift wrapping on or off; omitted leaves it unchanged
+	GiftWrap *bool `json:"gift_wrap,omitempty"`
 }
 
-// MergeCartRequest represents a request to merge guest cart.
+// Item sort orders accepted by the sort query parameter
+const (
+	SortAddedAsc   = "added_asc"
+	SortAddedDesc  = "added_desc"
+	SortProductAsc = "product_asc"
+	SortPriceDesc  = "price_desc"
+)
+
+// ValidateItemSort validates the sort query parameter. Empty keeps insertion order.
+func ValidateItemSort(sort string) error {
+	switch sort {
+	case "", SortAddedAsc, SortAddedDesc, SortProductAsc, SortPriceDesc:
+		return nil
+	}
+	return errors.ErrValidation("Invalid sort", map[string]interface{}{
+		"sort": "must be one of added_asc, added_desc, product_asc, price_desc",
+	})
+}
+
+// AdjustQuantityRequest represents a request to change an item's quantity by a delta.
+type AdjustQuantityRequest struct {
+	Delta int `json:"delta" validate:"required,min=-99,max=99"`
+}
+
+// ReorderItemsRequest represents a request to reorder cart items.
+type ReorderItemsRequest struct {
+	ItemIDs []string `json:"item_ids" validate:"required,min=1,max=100,dive,required,max=64"`
+}
+
+// PatchOperationRequest is one RFC 6902 JSON Patch operation. Items are
+// addressed by ID rather than array index:
+//
+//	{"op": "add", "path": "/items/-", "value": {"product_id": "...", "quantity": 1}}
+//	{"op": "replace", "path": "/items/{itemID}/quantity", "value": 3}
+//	{"op": "remove", "path": "/items/{itemID}"}
+//	{"op": "test", "path": "/version", "value": 4}
+type PatchOperationRequest struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// maxPatchOperations caps the operations in one patch request.
+const maxPatchOperations = 100
+
+// RepriceProductRequest represents an admin request to refresh a product's price across carts.
+type RepriceProductRequest struct {
+	UnitPrice int64 `json:"unit_price" validate:"min=0,max=999999999"`
+	Limit     int   `json:"limit" validate:"omitempty,min=1,max=5000"`
+}
+
+// MergeCartRequest represents a request to merge guest cart. Without a
+// guest ID, the one in the guest cookie set by CreateGuestCart is merged.
 type MergeCartRequest struct {
-	GuestID string `json:"guest_id" validate:"required,max=64"`
+	GuestID string `json:"guest_id" validate:"omitempty,max=64"`
 }
 
-// Validate validates the request and returns an error if invalid.
+// Validate validates the request, returning every failed field at once.
 func (r *AddItemRequest) Validate() error {
-	if err := validate.Struct(r); err != nil {
-		return errors.ErrValidation("Invalid request", validationErrors(err))
+	fields := validationErrors(validate.Struct(r))
+	if cart.ValidatePrice(r.UnitPrice) != nil {
+		fields.add("unit_price", FieldError{
+			Tag:     "range",
+			Param:   strconv.FormatInt(cart.MinUnitPrice, 10) + "-" + strconv.FormatInt(cart.MaxUnitPrice, 10),
+			Value:   r.UnitPrice,
+			Message: "must be between " + strconv.FormatInt(cart.MinUnitPrice, 10) + " and " + strconv.FormatInt(cart.MaxUnitPrice, 10),
+		})
 	}
-	if !alphanumPattern.MatchString(r.ProductID) {
-		return errors.ErrValidation("Invalid product_id format", map[string]interface{}{
-			"product_id": "must be alphanumeric with underscores and hyphens only",
+	if r.ProductID != "" && !alphanumPattern.MatchString(r.ProductID) {
+		fields.add("product_id", FieldError{
+			Tag:     "format",
+			Value:   r.ProductID,
+			Message: "must be alphanumeric with underscores and hyphens only",
 		})
 	}
-	return nil
+	return fields.err()
 }
 
-// Validate validates the request and returns an error if invalid.
+// Validate validates the request, returning every failed field at once.
 func (r *UpdateQuantityRequest) Validate() error {
-	if err := validate.Struct(r); err != nil {
-		return errors.ErrValidation("Invalid request", validationErrors(err))
+	return validationErrors(validate.Struct(r)).err()
+}
+
+// Validate validates the request, returning every failed field at once.
+func (r *AdjustQuantityRequest) Validate() error {
+	return validationErrors(validate.Struct(r)).err()
+}
+
+// Validate validates the request, returning every failed field at once.
+func (r *ReorderItemsRequest) Validate() error {
+	return validationErrors(validate.Struct(r)).err()
+}
+
+// Validate validates the request, returning every failed field at once.
+func (r *RepriceProductRequest) Validate() error {
+	return validationErrors(validate.Struct(r)).err()
+}
+
+// SetGiftMessageRequest represents a request to set the cart's gift message.
+// An empty message clears it.
+type SetGiftMessageRequest struct {
+	GiftMessage string `json:"gift_message" validate:"max=500"`
+}
+
+// Validate validates the request, returning every failed field at once.
+func (r *SetGiftMessageRequest) Validate() error {
+	fields := validationErrors(validate.Struct(r))
+	if markupPattern.MatchString(r.GiftMessage) {
+		fields.add("gift_message", markupError(r.GiftMessage))
 	}
-	return nil
+	return fields.err()
+}
+
+// PatchCartRequest represents a request to update cart attributes. An empty
+// name clears it.
+type PatchCartRequest struct {
+	Name *string `json:"name" validate:"required,max=100"`
+}
+
+// Validate validates the request, returning every failed field at once.
+func (r *PatchCartRequest) Validate() error {
+	fields := validationErrors(validate.Struct(r))
+	if r.Name != nil && markupPattern.MatchString(*r.Name) {
+		fields.add("name", markupError(*r.Name))
+	}
+	return fields.err()
+}
+
+// CreateCartRequest represents a request to create an additional named cart.
+type CreateCartRequest struct {
+	Name string `json:"name" validate:"max=100"`
+}
+
+// Validate validates the request, returning every failed field at once.
+func (r *CreateCartRequest) Validate() error {
+	fields := validationErrors(validate.Struct(r))
+	if markupPattern.MatchString(r.Name) {
+		fields.add("name", markupError(r.Name))
+	}
+	return fields.err()
+}
+
+// ParsePatch validates patch operations and converts them to cart operations.
+func ParsePatch(ops []PatchOperationRequest) ([]cart.PatchOperation, error) {
+	if len(ops) == 0 || len(ops) > maxPatchOperations {
+		return nil, errors.ErrValidation("Patch must contain between 1 and 100 operations", map[string]interface{}{
+			"operations": len(ops),
+		})
+	}
+
+	parsed := make([]cart.PatchOperation, len(ops))
+	for i, op := range ops {
+		p, err := parsePatchOperation(op)
+		if err != nil {
+			details := map[string]interface{}{
+				"operation": i,
+				"op":        op.Op,
+				"path":      op.Path,
+				"error":     err.Error(),
+			}
+			if appErr, ok := errors.IsAppError(err); ok {
+				details["error"] = appErr.Message
+				details["fields"] = appErr.Details
+			}
+			return nil, errors.ErrValidation("Invalid patch operation", details)
+		}
+		parsed[i] = p
+	}
+	return parsed, nil
+}
+
+// parsePatchOperation converts a single patch operation.
+func parsePatchOperation(op PatchOperationRequest) (cart.PatchOperation, error) {
+	segments := strings.Split(strings.TrimPrefix(op.Path, "/"), "/")
+
+	switch {
+	case op.Op == cart.PatchOpAdd && op.Path == "/items/-":
+		var req AddItemRequest
+		if err := json.Unmarshal(op.Value, &req); err != nil {
+			return cart.PatchOperation{}, err
+		}
+		if err := req.Validate(); err != nil {
+			return cart.PatchOperation{}, err
+		}
+		return cart.PatchOperation{Op: op.Op, Item: cart.AddItemRequest{
+			ProductID:  req.ProductID,
+			Quantity:   req.Quantity,
+			UnitPrice:  req.UnitPrice,
+			Name:       req.Name,
+			ImageURL:   req.ImageURL,
+			SKU:        req.SKU,
+			Attributes: req.Attributes,
+			GiftWrap:   req.GiftWrap,
+
+			IfNotExists:     req.IfNotExists,
+			UnitType:        cart.UnitType(req.UnitType),
+			DecimalQuantity: req.DecimalQuantity,
+			PackSize:        req.PackSize,
+		}}, nil
+
+	case op.Op == cart.PatchOpReplace && len(segments) == 3 && segments[0] == "items" && segments[2] == "quantity":
+		if err := ValidateItemID(segments[1]); err != nil {
+			return cart.PatchOperation{}, err
+		}
+		var req UpdateQuantityRequest
+		if err := json.Unmarshal(op.Value, &req.Quantity); err != nil {
+			return cart.PatchOperation{}, err
+		}
+		if err := req.Validate(); err != nil {
+			return cart.PatchOperation{}, err
+		}
+		return cart.PatchOperation{Op: op.Op, ItemID: segments[1], Quantity: req.Quantity}, nil
+
+	case op.Op == cart.PatchOpRemove && len(segments) == 2 && segments[0] == "items":
+		if err := ValidateItemID(segments[1]); err != nil {
+			return cart.PatchOperation{}, err
+		}
+		return cart.PatchOperation{Op: op.Op, ItemID: segments[1]}, nil
+
+	case op.Op == cart.PatchOpTest && op.Path == "/version":
+		var version int64
+		if err := json.Unmarshal(op.Value, &version); err != nil {
+			return cart.PatchOperation{}, err
+		}
+		return cart.PatchOperation{Op: op.Op, Version: version}, nil
+	}
+
+	return cart.PatchOperation{}, errors.New(errors.CodeValidationError, "unsupported op or path")
 }
 
 // ValidateUserID validates a user ID.
@@ -86,16 +333,153 @@ func ValidateItemID(itemID string) error {
 	return nil
 }
 
-// decodeJSON decodes JSON from request body.
+// ValidateCartID validates a cart ID.
+func ValidateCartID(cartID string) error {
+	if cartID == "" {
+		return errors.ErrValidation("cart_id is required", nil)
+	}
+	if len(cartID) > 64 {
+		return errors.ErrValidation("cart_id too long", nil)
+	}
+	if !uuidPattern.MatchString(cartID) && !alphanumPattern.MatchString(cartID) {
+		return errors.ErrValidation("Invalid cart_id format", nil)
+	}
+	return nil
+}
+
+// ValidateTenantID validates a tenant ID.
+func ValidateTenantID(tenantID string) error {
+	if len(tenantID) > 64 {
+		return errors.ErrValidation("tenant_id too long", nil)
+	}
+	if !alphanumPattern.MatchString(tenantID) {
+		return errors.ErrValidation("Invalid tenant_id format", nil)
+	}
+	return nil
+}
+
+// ValidateTemplateID validates a template ID.
+func ValidateTemplateID(templateID string) error {
+	if templateID == "" {
+		return errors.ErrValidation("template_id is required", nil)
+	}
+	if len(templateID) > 64 {
+		return errors.ErrValidation("template_id too long", nil)
+	}
+	if !alphanumPattern.MatchString(templateID) {
+		return errors.ErrValidation("Invalid template_id format", nil)
+	}
+	return nil
+}
+
+// ValidateProductID validates a product ID.
+func ValidateProductID(productID string) error {
+	if productID == "" {
+		return errors.ErrValidation("product_id is required", nil)
+	}
+	if len(productID) > 64 {
+		return errors.ErrValidation("product_id too long", nil)
+	}
+	if !alphanumPattern.MatchString(productID) {
+		return errors.ErrValidation("Invalid product_id format", nil)
+	}
+	return nil
+}
+
+// LenientJSON makes request decoding ignore unknown fields instead of
+// rejecting them. Duplicate keys and malformed bodies are still rejected.
+func LenientJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), lenientJSONKey{}, true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type lenientJSONKey struct{}
+
+// strictJSON reports whether unknown fields in r's body are rejected.
+func strictJSON(r *http.Request) bool {
+	lenient, _ := r.Context().Value(lenientJSONKey{}).(bool)
+	return !lenient
+}
+
+// decodeJSON decodes JSON from request body, or MessagePack when the
+// Content-Type says so. A body cut off by RequestSizeLimit is reported as 413.
 func decodeJSON(r *http.Request, v interface{}) error {
 	if r.Body == nil {
 		return errors.ErrValidation("Request body is required", nil)
 	}
-	
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if stderrors.As(err, &maxErr) {
+			return errors.ErrRequestTooLarge(maxErr.Limit)
+		}
+		return errors.ErrValidation("Invalid JSON", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if isMsgpack(r.Header.Get("Content-Type")) {
+		return decodeMsgpack(bytes.NewReader(data), v, strictJSON(r))
+	}
+	return unmarshalJSON(r, data, v)
+}
+
+// decodeMsgpack decodes a MessagePack body into v using its JSON field
+// names, rejecting unknown fields when strict. A *json.RawMessage receives
+// the body converted to JSON.
+func decodeMsgpack(body io.Reader, v interface{}, strict bool) error {
+	decoder := msgpack.NewDecoder(body)
+	decoder.SetCustomStructTag("json")
+	decoder.DisallowUnknownFields(strict)
+
+	if raw, ok := v.(*json.RawMessage); ok {
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return errors.ErrValidation("Invalid MessagePack", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return errors.ErrValidation("Invalid MessagePack", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		*raw = data
+		return nil
+	}
+
 	if err := decoder.Decode(v); err != nil {
+		return errors.ErrValidation("Invalid MessagePack", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	return nil
+}
+
+// unmarshalJSON decodes an already read JSON body of r like decodeJSON.
+// Duplicate keys are always rejected; unknown fields are rejected unless
+// LenientJSON is in effect, naming the field in the error details.
+func unmarshalJSON(r *http.Request, data []byte, v interface{}) error {
+	if key, ok := duplicateKey(data); ok {
+		return errors.ErrValidation("Duplicate JSON key", map[string]interface{}{
+			"field": key,
+		})
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strictJSON(r) {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		if field, ok := unknownField(err); ok {
+			return errors.ErrValidation("Unknown field", map[string]interface{}{
+				"field": field,
+			})
+		}
 		return errors.ErrValidation("Invalid JSON", map[string]interface{}{
 			"error": err.Error(),
 		})
@@ -103,17 +487,165 @@ func decodeJSON(r *http.Request, v interface{}) error {
 	return nil
 }
 
-// validationErrors converts validator errors to a map.
-func validationErrors(err error) map[string]interface{} {
-	if err == nil {
+// unknownField returns the field named by a json.Decoder unknown field error.
+func unknownField(err error) (string, bool) {
+	quoted, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	field, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", false
+	}
+	return field, true
+}
+
+// duplicateKey returns the first key repeated within one JSON object of
+// data. Malformed JSON reports no duplicate; decoding rejects it instead.
+func duplicateKey(data []byte) (string, bool) {
+	type object struct {
+		keys      map[string]bool
+		expectKey bool
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var stack []*object // nil entries are arrays
+	valueDone := func() {
+		if n := len(stack); n > 0 && stack[n-1] != nil {
+			stack[n-1].expectKey = true
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", false
+		}
+
+		switch token {
+		case json.Delim('{'):
+			valueDone()
+			stack = append(stack, &object{keys: make(map[string]bool), expectKey: true})
+			continue
+		case json.Delim('['):
+			valueDone()
+			stack = append(stack, nil)
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if n := len(stack); n > 0 && stack[n-1] != nil && stack[n-1].expectKey {
+			key, _ := token.(string)
+			if stack[n-1].keys[key] {
+				return key, true
+			}
+			stack[n-1].keys[key] = true
+			stack[n-1].expectKey = false
+			continue
+		}
+		valueDone()
+	}
+}
+
+// isJSONObject reports whether a JSON value is an object.
+func isJSONObject(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '{'
+}
+
+// newValidator returns a validator that reports fields by their JSON name.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// FieldError describes a failed validation rule of a request field, e.g.
+// {"tag": "max", "param": "99", "value": 150, "message": "must be at most 99"}.
+type FieldError struct {
+	Tag     string      `json:"tag"`
+	Param   string      `json:"param,omitempty"`
+	Value   interface{} `json:"value"`
+	Message string      `json:"message"`
+}
+
+// fieldErrors holds the failed fields of a request by JSON field name, as
+// validation error details.
+type fieldErrors map[string]interface{}
+
+// add records a failure unless the field already failed.
+func (f fieldErrors) add(field string, e FieldError) {
+	if _, ok := f[field]; !ok {
+		f[field] = e
+	}
+}
+
+// err returns a validation error listing the failed fields, or nil.
+func (f fieldErrors) err() error {
+	if len(f) == 0 {
 		return nil
 	}
-	
-	errs := make(map[string]interface{})
+	return errors.ErrValidation("Invalid request", f)
+}
+
+// validationErrors converts validator errors to field errors.
+func validationErrors(err error) fieldErrors {
+	errs := make(fieldErrors)
 	if validationErrs, ok := err.(validator.ValidationErrors); ok {
 		for _, e := range validationErrs {
-			errs[e.Field()] = e.Tag()
+			// The namespace is "Request.field[key]"; drop the struct name
+			_, field, _ := strings.Cut(e.Namespace(), ".")
+			errs.add(field, FieldError{
+				Tag:     e.Tag(),
+				Param:   e.Param(),
+				Value:   e.Value(),
+				Message: fieldMessage(e),
+			})
 		}
 	}
 	return errs
 }
+
+// fieldMessage describes a failed validation rule.
+func fieldMessage(e validator.FieldError) string {
+	unit := ""
+	switch e.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Map:
+		unit = " entries"
+	}
+
+	switch e.Tag() {
+	case "required", "required_if", "required_unless", "required_without":
+		return "is required"
+	case "min":
+		return "must be at least " + e.Param() + unit
+	case "max":
+		return "must be at most " + e.Param() + unit
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(e.Param(), " ", ", ")
+	case "url":
+		return "must be a valid URL"
+	case "excluded_with":
+		return "must not be combined with " + e.Param()
+	}
+	return "failed the " + e.Tag() + " rule"
+}
+
+// markupError reports a free-text field containing markup.
+func markupError(value string) FieldError {
+	return FieldError{
+		Tag:     "markup",
+		Value:   value,
+		Message: "must not contain HTML or script content",
+	}
+}
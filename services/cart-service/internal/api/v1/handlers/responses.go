@@ -1,26 +1,55 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Content types for request and response bodies
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeMsgpack = "application/msgpack"
 )
 
 // CartResponse represents the API response for a cart.
 type CartResponse struct {
 	ID            string             `json:"id"`
 	UserID        string             `json:"user_id"`
+	Name          string             `json:"name,omitempty"`
+	Default       bool               `json:"default"`
 	Items         []CartItemResponse `json:"items"`
 	ItemCount     int                `json:"item_count"`
 	TotalQuantity int                `json:"total_quantity"`
 	TotalPrice    int64              `json:"total_price"`
+	TotalWithFees int64              `json:"total_with_fees"`
 	Version       int64              `json:"version"`
 	CreatedAt     time.Time          `json:"created_at"`
 	UpdatedAt     time.Time          `json:"updated_at"`
 	ExpiresAt     time.Time          `json:"expires_at"`
+	GiftMessage   string             `json:"gift_message,omitempty"`
+
+	Locked      bool       `json:"locked"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+
+	CheckoutEligible bool  `json:"checkout_eligible"`
+	AmountToMinimum  int64 `json:"amount_to_minimum"`
+
+	Warnings []cart.Warning `json:"warnings,omitempty"`
 }
 
 // CartItemResponse represents the API response for a cart item.
@@ -31,6 +60,36 @@ type CartItemResponse struct {
 	UnitPrice int64     `json:"unit_price"`
 	Subtotal  int64     `json:"subtotal"`
 	AddedAt   time.Time `json:"added_at"`
+
+	Name       string            `json:"name,omitempty"`
+	ImageURL   string            `json:"image_url,omitempty"`
+	SKU        string            `json:"sku,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	GiftWrap    bool  `json:"gift_wrap,omitempty"`
+	GiftWrapFee int64 `json:"gift_wrap_fee,omitempty"`
+
+	UnitType        cart.UnitType `json:"unit_type,omitempty"`
+	DecimalQuantity int64         `json:"decimal_quantity,omitempty"`
+	PackSize        int           `json:"pack_size,omitempty"`
+
+	// Stale marks an item kept past the maximum item age; its price may be
+	// out of date
+	Stale bool `json:"stale,omitempty"`
+
+	DeliveryEstimate *cart.DeliveryEstimate `json:"delivery_estimate,omitempty"`
+}
+
+// ApplyTemplateResponse represents the API response for applying a cart template.
+type ApplyTemplateResponse struct {
+	Cart    *CartResponse              `json:"cart"`
+	Added   []cart.TemplateLine        `json:"added"`
+	Skipped []cart.SkippedTemplateLine `json:"skipped"`
+}
+
+// CartCountResponse represents the API response for the cart item count.
+type CartCountResponse struct {
+	Count int `json:"count"`
 }
 
 // ErrorResponse represents an API error response.
@@ -45,64 +104,293 @@ func NewCartResponse(c *cart.Cart) *CartResponse {
 	items := make([]CartItemResponse, len(c.Items))
 	for i, item := range c.Items {
 		items[i] = CartItemResponse{
-			ItemID:    item.ItemID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			UnitPrice: item.UnitPrice,
-			Subtotal:  item.UnitPrice * int64(item.Quantity),
-			AddedAt:   item.AddedAt,
+			ItemID:     item.ItemID,
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.UnitPrice,
+			Subtotal:   c.LineTotal(&item),
+			AddedAt:    item.AddedAt,
+			Name:       item.Name,
+			ImageURL:   item.ImageURL,
+			SKU:        item.SKU,
+			Attributes: item.Attributes,
+
+			GiftWrap:    item.GiftWrap,
+			GiftWrapFee: item.GiftWrapFee,
+
+			UnitType:        item.UnitType,
+			DecimalQuantity: item.DecimalQuantity,
+			PackSize:        item.PackSize,
+
+			Stale: item.Stale,
 		}
 	}
 
-	return &CartResponse{
+	resp := &CartResponse{
 		ID:            c.ID,
 		UserID:        c.UserID,
+		Name:          c.Name,
+		Default:       c.IsDefault(),
 		Items:         items,
 		ItemCount:     c.ItemCount(),
 		TotalQuantity: c.TotalQuantity(),
 		TotalPrice:    c.TotalPrice(),
+		TotalWithFees: c.TotalWithFees(),
 		Version:       c.Version,
 		CreatedAt:     c.CreatedAt,
 		UpdatedAt:     c.UpdatedAt,
 		ExpiresAt:     c.ExpiresAt,
+		GiftMessage:   c.GiftMessage,
+
+		CheckoutEligible: c.CheckoutEligible(),
+		AmountToMinimum:  c.AmountToMinimum(),
+	}
+
+	// An expired lock no longer blocks edits, so it is not reported
+	if c.IsLocked() {
+		lockedUntil := c.LockedUntil
+		resp.Locked = true
+		resp.LockedUntil = &lockedUntil
+	}
+
+	return resp
+}
+
+// CartListResponse represents the API response listing carts. NextCursor is
+// set on paginated listings with more pages.
+type CartListResponse struct {
+	Carts      []*CartResponse `json:"carts"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// NewCartListResponse creates a CartListResponse from carts.
+func NewCartListResponse(carts []*cart.Cart) *CartListResponse {
+	resp := &CartListResponse{Carts: make([]*CartResponse, len(carts))}
+	for i, c := range carts {
+		resp.Carts[i] = NewCartResponse(c)
+	}
+	return resp
+}
+
+// CartHistoryResponse represents the API response listing a cart's snapshots,
+// oldest first.
+type CartHistoryResponse struct {
+	UserID    string              `json:"user_id"`
+	Snapshots []cart.CartSnapshot `json:"snapshots"`
+}
+
+// WithWarnings attaches non-blocking warnings to the response.
+func (r *CartResponse) WithWarnings(warnings []cart.Warning) *CartResponse {
+	r.Warnings = append(r.Warnings, warnings...)
+	return r
+}
+
+// WithItemOrder sorts the response items by a validated sort key; ties and
+// an empty key keep insertion order. The stored cart is not affected.
+func (r *CartResponse) WithItemOrder(order string) *CartResponse {
+	var less func(a, b CartItemResponse) bool
+	switch order {
+	case SortAddedAsc:
+		less = func(a, b CartItemResponse) bool { return a.AddedAt.Before(b.AddedAt) }
+	case SortAddedDesc:
+		less = func(a, b CartItemResponse) bool { return a.AddedAt.After(b.AddedAt) }
+	case SortProductAsc:
+		less = func(a, b CartItemResponse) bool { return a.ProductID < b.ProductID }
+	case SortPriceDesc:
+		less = func(a, b CartItemResponse) bool { return a.UnitPrice > b.UnitPrice }
+	default:
+		return r
+	}
+
+	sort.SliceStable(r.Items, func(i, j int) bool { return less(r.Items[i], r.Items[j]) })
+	return r
+}
+
+// WithDeliveryEstimates annotates response items with their delivery estimates.
+// Items without an estimate are left unannotated.
+func (r *CartResponse) WithDeliveryEstimates(estimates map[string]cart.DeliveryEstimate) *CartResponse {
+	for i := range r.Items {
+		if estimate, ok := estimates[r.Items[i].ItemID]; ok {
+			r.Items[i].DeliveryEstimate = &estimate
+		}
 	}
+	return r
+}
+
+// ResponseEnvelope wraps JSON responses written by the handlers as
+// {"data": ..., "meta": {...}}, with errors under "error" instead of "data".
+func ResponseEnvelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), envelopeKey{}, true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type envelopeKey struct{}
+
+// Envelope is the response body when ResponseEnvelope is enabled.
+type Envelope struct {
+	Data  interface{}    `json:"data,omitempty"`
+	Error *ErrorResponse `json:"error,omitempty"`
+	Meta  EnvelopeMeta   `json:"meta"`
+}
+
+// EnvelopeMeta carries request metadata in an Envelope.
+type EnvelopeMeta struct {
+	RequestID string `json:"request_id,omitempty"`
+	Version   int64  `json:"version,omitempty"`
 }
 
-// writeJSON writes a JSON response.
-func writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+// envelope wraps data for r when envelopes are enabled and returns data
+// unchanged otherwise.
+func envelope(r *http.Request, data interface{}) interface{} {
+	if enabled, _ := r.Context().Value(envelopeKey{}).(bool); !enabled {
+		return data
+	}
+
+	env := Envelope{Meta: EnvelopeMeta{RequestID: logging.RequestIDFromContext(r.Context())}}
+	switch v := data.(type) {
+	case ErrorResponse:
+		env.Error = &v
+	case *CartResponse:
+		env.Data = v
+		env.Meta.Version = v.Version
+	case *ApplyTemplateResponse:
+		env.Data = v
+		env.Meta.Version = v.Cart.Version
+	default:
+		env.Data = data
+	}
+	return env
+}
+
+// writeJSON writes a JSON response, or MessagePack when the Accept header
+// prefers it, wrapped in an Envelope when enabled.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	contentType := negotiateContentType(r.Header.Get("Accept"))
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
 	w.WriteHeader(status)
-	
-	if data != nil {
-		json.NewEncoder(w).Encode(data)
+
+	if data == nil {
+		return
+	}
+	if contentType == ContentTypeMsgpack {
+		encoder := msgpack.NewEncoder(w)
+		encoder.SetCustomStructTag("json")
+		encoder.Encode(envelope(r, data))
+		return
 	}
+	json.NewEncoder(w).Encode(envelope(r, data))
 }
 
-// writeError writes an error response.
-func writeError(w http.ResponseWriter, err error) {
+// negotiateContentType returns ContentTypeMsgpack when an Accept header
+// weighs MessagePack above JSON and ContentTypeJSON otherwise.
+func negotiateContentType(accept string) string {
+	var jsonQ, msgpackQ float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case ContentTypeMsgpack, "application/x-msgpack":
+			msgpackQ = max(msgpackQ, q)
+		case ContentTypeJSON, "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	if msgpackQ > jsonQ {
+		return ContentTypeMsgpack
+	}
+	return ContentTypeJSON
+}
+
+// isMsgpack reports whether a Content-Type header names MessagePack.
+func isMsgpack(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == ContentTypeMsgpack || mediaType == "application/x-msgpack")
+}
+
+// writeError writes an error response with the message in the locale
+// preferred by the request's Accept-Language header.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
 	appErr, ok := errors.IsAppError(err)
 	if !ok {
 		// Unknown error - return internal error
 		appErr = errors.ErrInternal(err)
 	}
+	if appErr.HTTPStatus >= http.StatusInternalServerError && stderrors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		// A failed downstream call was cut short by the request deadline
+		appErr = errors.ErrTimeout()
+	}
+
+	locale := preferredLocale(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", locale)
 
 	resp := ErrorResponse{
 		Code:    appErr.Code,
-		Message: appErr.Message,
-		Details: appErr.Details,
+		Message: errors.LocalizedMessage(appErr, locale),
+		Details: redactDetails(r, appErr.Details),
+	}
+
+	writeJSON(w, r, appErr.HTTPStatus, resp)
+}
+
+// RedactErrorDetails replaces the values of the given detail keys in error
+// responses with a hash, so clients never see them while support can still
+// match a response against the unredacted error in the logs.
+func RedactErrorDetails(keys []string) func(http.Handler) http.Handler {
+	redact := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		redact[key] = struct{}{}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), redactKeysKey{}, redact)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
 	}
+}
+
+type redactKeysKey struct{}
+
+// redactDetails returns a copy of details with the keys configured by
+// RedactErrorDetails hashed. details itself is left untouched.
+func redactDetails(r *http.Request, details map[string]interface{}) map[string]interface{} {
+	redact, _ := r.Context().Value(redactKeysKey{}).(map[string]struct{})
+	if len(redact) == 0 || len(details) == 0 {
+		return details
+	}
+	out := make(map[string]interface{}, len(details))
+	for key, value := range details {
+		if _, ok := redact[key]; ok {
+			value = hashDetail(value)
+		}
+		out[key] = value
+	}
+	return out
+}
 
-	writeJSON(w, appErr.HTTPStatus, resp)
+// hashDetail returns a short, stable digest of a detail value.
+func hashDetail(value interface{}) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+	return "sha256:" + hex.EncodeToString(sum[:8])
 }
 
 // writeSuccess writes a success response with optional data.
-func writeSuccess(w http.ResponseWriter, data interface{}) {
-	writeJSON(w, http.StatusOK, data)
+func writeSuccess(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeJSON(w, r, http.StatusOK, data)
 }
 
 // writeCreated writes a created response with optional data.
-func writeCreated(w http.ResponseWriter, data interface{}) {
-	writeJSON(w, http.StatusCreated, data)
+func writeCreated(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeJSON(w, r, http.StatusCreated, data)
 }
 
 // writeNoContent writes a no content response.
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/config"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/health"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
 )
 
 // Application is the main application container that holds all dependencies.
@@ -21,8 +24,15 @@ type Application struct {
 	Features   FeatureFlags
 	Secrets    SecretsManager
 	
+	// Stores responses to idempotent requests, when idempotency is enabled
+	IdempotencyStore IdempotencyStore
+
+	// Readiness checks
+	Health *health.Handler
+
 	// Resilience
 	CircuitBreakers map[string]CircuitBreaker
+	Bulkheads       *resilience.BulkheadManager
 	
 	// Lifecycle management
 	shutdownFuncs []func(context.Context) error
@@ -32,7 +42,9 @@ type Application struct {
 // New creates a new Application instance with the provided options.
 func New(ctx context.Context, opts ...Option) (*Application, error) {
 	app := &Application{
+		Health:          health.NewHandler(),
 		CircuitBreakers: make(map[string]CircuitBreaker),
+		Bulkheads:       resilience.NewBulkheadManager(),
 		shutdownFuncs:   make([]func(context.Context) error, 0),
 	}
 
@@ -57,6 +69,8 @@ func New(ctx context.Context, opts ...Option) (*Application, error) {
 		})
 	}
 
+	app.registerReadinessChecks()
+
 	app.Logger.Info("Application initialized successfully")
 	return app, nil
 }
@@ -110,6 +124,17 @@ func (a *Application) RegisterCircuitBreaker(name string, cb CircuitBreaker) {
 	a.CircuitBreakers[name] = cb
 }
 
+// AllCircuitBreakers returns a copy of the registered circuit breakers.
+func (a *Application) AllCircuitBreakers() map[string]CircuitBreaker {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	breakers := make(map[string]CircuitBreaker, len(a.CircuitBreakers))
+	for name, cb := range a.CircuitBreakers {
+		breakers[name] = cb
+	}
+	return breakers
+}
+
 // HealthCheck performs a health check on all dependencies.
 func (a *Application) HealthCheck(ctx context.Context) error {
 	// Check repository if available
@@ -126,29 +151,47 @@ func (a *Application) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-// ReadinessCheck performs a readiness check to verify the service can handle traffic.
-func (a *Application) ReadinessCheck(ctx context.Context) error {
-	// Check all critical dependencies
-	checks := []struct {
-		name string
-		fn   func() error
-	}{
-		{
-			name: "repository",
-			fn: func() error {
-				if a.Repository == nil {
-					return fmt.Errorf("repository not initialized")
-				}
-				return nil
-			},
-		},
+// ReadinessCheck verifies the service can handle traffic and reports the
+// status and latency of each dependency check.
+func (a *Application) ReadinessCheck(ctx context.Context) health.HealthResponse {
+	return a.Health.CheckReadiness(ctx)
+}
+
+// RegisterDeepReadinessCheck registers a dependency check that only runs when
+// deep readiness is enabled, so probes don't hit dependencies by default.
+func (a *Application) RegisterDeepReadinessCheck(name string, check func(context.Context) error) {
+	if !a.Config.ReadinessDeepCheck {
+		return
 	}
+	a.Health.RegisterChecker(health.NewRepositoryChecker(name, check))
+}
+
+// repositoryCheckTimeout bounds the repository ping (DescribeTable for DynamoDB).
+const repositoryCheckTimeout = 2 * time.Second
 
-	for _, check := range checks {
-		if err := check.fn(); err != nil {
-			return fmt.Errorf("%s check failed: %w", check.name, err)
+// idempotencyCheckTimeout bounds the idempotency store ping.
+const idempotencyCheckTimeout = time.Second
+
+// registerReadinessChecks registers the checks for core dependencies. A shallow
+// check only verifies the repository is configured; a deep check pings it.
+// The idempotency store is checked the same way when idempotency is enabled.
+func (a *Application) registerReadinessChecks() {
+	a.Health.RegisterChecker(health.NewRepositoryChecker("repository", func(ctx context.Context) error {
+		if a.Repository == nil {
+			return fmt.Errorf("repository not initialized")
 		}
-	}
+		if !a.Config.ReadinessDeepCheck {
+			return nil
+		}
+		return a.Repository.HealthCheck(ctx)
+	}).WithTimeout(repositoryCheckTimeout))
 
-	return nil
+	if a.Config.IdempotencyEnabled && a.IdempotencyStore != nil {
+		a.Health.RegisterChecker(health.NewRepositoryChecker("idempotency_store", func(ctx context.Context) error {
+			if !a.Config.ReadinessDeepCheck {
+				return nil
+			}
+			return a.IdempotencyStore.HealthCheck(ctx)
+		}).WithTimeout(idempotencyCheckTimeout))
+	}
 }
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/config"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableRepository fails its health check.
+type unreachableRepository struct {
+	*inmemory.Repository
+}
+
+func (r unreachableRepository) HealthCheck(ctx context.Context) error {
+	return fmt.Errorf("connection refused")
+}
+
+func TestApplication_ReadinessCheck(t *testing.T) {
+	newApp := func(deep bool) *Application {
+		application, err := New(context.Background(),
+			WithConfig(&config.Config{ReadinessDeepCheck: deep}),
+			WithLogger(logging.New(logging.Config{Level: "error"})),
+			WithRepository(unreachableRepository{inmemory.NewRepository()}),
+		)
+		require.NoError(t, err)
+		return application
+	}
+
+	// Shallow readiness only checks the repository is configured
+	shallow := newApp(false)
+	shallow.RegisterDeepReadinessCheck("publisher", func(context.Context) error { return fmt.Errorf("down") })
+	response := shallow.ReadinessCheck(context.Background())
+	assert.Equal(t, "ready", response.Status)
+	assert.Len(t, response.Checks, 1)
+
+	deep := newApp(true)
+	deep.RegisterDeepReadinessCheck("publisher", func(context.Context) error { return nil })
+	response = deep.ReadinessCheck(context.Background())
+	assert.Equal(t, "not ready", response.Status)
+	assert.Equal(t, "error", response.Checks["repository"].Status)
+	assert.Equal(t, "ok", response.Checks["publisher"].Status)
+	assert.NotEmpty(t, response.Checks["publisher"].Latency)
+}
+
+// downIdempotencyStore fails its health check.
+type downIdempotencyStore struct{}
+
+func (downIdempotencyStore) HealthCheck(ctx context.Context) error {
+	return fmt.Errorf("connection refused")
+}
+
+func TestApplication_IdempotencyStoreReadiness(t *testing.T) {
+	newApp := func(cfg *config.Config) *Application {
+		application, err := New(context.Background(),
+			WithConfig(cfg),
+			WithLogger(logging.New(logging.Config{Level: "error"})),
+			WithRepository(inmemory.NewRepository()),
+			WithIdempotencyStore(downIdempotencyStore{}),
+		)
+		require.NoError(t, err)
+		return application
+	}
+
+	response := newApp(&config.Config{IdempotencyEnabled: true, ReadinessDeepCheck: true}).ReadinessCheck(context.Background())
+	assert.Equal(t, "not ready", response.Status)
+	assert.Equal(t, "error", response.Checks["idempotency_store"].Status)
+
+	// Shallow readiness only checks the store is configured
+	response = newApp(&config.Config{IdempotencyEnabled: true}).ReadinessCheck(context.Background())
+	assert.Equal(t, "ready", response.Status)
+	assert.Equal(t, "ok", response.Checks["idempotency_store"].Status)
+
+	response = newApp(&config.Config{ReadinessDeepCheck: true}).ReadinessCheck(context.Background())
+	assert.NotContains(t, response.Checks, "idempotency_store")
+}
+
+func TestCartServiceConfig(t *testing.T) {
+	ctx := context.Background()
+	service := cart.NewService(inmemory.NewRepository(), nil, CartServiceConfig(&config.Config{
+		CartExpirationDuration:      48 * time.Hour,
+		GuestCartExpirationDuration: 2 * time.Hour,
+		GuestUserIDPrefix:           "anon-",
+	}))
+
+	add := cart.AddItemRequest{ProductID: "prod-1", Quantity: 1, UnitPrice: 1000}
+	user, err := service.AddItem(ctx, "user-123", add)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), user.ExpiresAt, time.Minute)
+
+	guest, err := service.AddItem(ctx, "anon-123", add)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), guest.ExpiresAt, time.Minute)
+
+	history := CartServiceConfig(&config.Config{CartSnapshotHistory: true, CartSnapshotTTL: time.Hour})
+	assert.True(t, history.SnapshotHistory)
+	assert.Equal(t, time.Hour, history.SnapshotTTL)
+
+	events := CartServiceConfig(&config.Config{EventBridgeEnabled: true, EventPublishMode: "sync"})
+	assert.True(t, events.PublishEvents)
+	assert.Equal(t, cart.EventPublishModeSync, events.EventPublishMode)
+	assert.True(t, CartServiceConfig(&config.Config{EventPublishMode: "outbox"}).PublishEvents)
+}
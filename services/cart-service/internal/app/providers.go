@@ -36,6 +36,15 @@ func WithRepository(repo CartRepository) Option {
 	}
 }
 
+// WithIdempotencyStore sets the idempotency store, checked for readiness
+// when idempotency is enabled.
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(a *Application) error {
+		a.IdempotencyStore = store
+		return nil
+	}
+}
+
 // WithEventPublisher sets the event publisher.
 func WithEventPublisher(pub EventPublisher) Option {
 	return func(a *Application) error {
@@ -68,6 +77,22 @@ func WithSecrets(s SecretsManager) Option {
 	}
 }
 
+// CartServiceConfig returns the cart service configuration set in cfg, so
+// every binary building a cart.Service applies the same settings.
+func CartServiceConfig(cfg *config.Config) cart.ServiceConfig {
+	return cart.ServiceConfig{
+		CartExpiration:       cfg.CartExpirationDuration,
+		GuestCartExpiration:  cfg.GuestCartExpirationDuration,
+		GuestUserIDPrefix:    cfg.GuestUserIDPrefix,
+		SnapshotHistory:      cfg.CartSnapshotHistory,
+		SnapshotTTL:          cfg.CartSnapshotTTL,
+		MaxItemsPerCart:      cfg.MaxItemsPerCart,
+		AdminMaxItemsPerCart: cfg.AdminMaxItemsPerCart,
+		PublishEvents:        cfg.EventBridgeEnabled || cfg.EventPublishMode == string(cart.EventPublishModeOutbox),
+		EventPublishMode:     cart.EventPublishMode(cfg.EventPublishMode),
+	}
+}
+
 // CartRepository interface for cart persistence.
 type CartRepository interface {
 	GetCart(ctx context.Context, userID string) (*cart.Cart, error)
@@ -77,6 +102,11 @@ type CartRepository interface {
 	HealthCheck(ctx context.Context) error
 }
 
+// IdempotencyStore interface for the store behind idempotent requests.
+type IdempotencyStore interface {
+	HealthCheck(ctx context.Context) error
+}
+
 // EventPublisher interface for event publishing.
 type EventPublisher interface {
 	Publish(ctx context.Context, event interface{}) error
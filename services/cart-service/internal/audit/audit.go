@@ -0,0 +1,52 @@
+// Package audit defines the audit trail of cart mutations.
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Audited operations
+const (
+	OpAddItem        = "add_item"
+	OpAddTemplate    = "add_template"
+	OpUpdateItem     = "update_item"
+	OpRemoveItem     = "remove_item"
+	OpReorderItems   = "reorder_items"
+	OpPatchCart      = "patch_cart"
+	OpClearCart      = "clear_cart"
+	OpRestoreCart    = "restore_cart"
+	OpDeleteCart     = "delete_cart"
+	OpMergeCart      = "merge_cart"
+	OpRepriceItem    = "reprice_item"
+	OpSetGiftMessage = "set_gift_message"
+	OpSetCartName    = "set_cart_name"
+	OpLockCart       = "lock_cart"
+	OpUnlockCart     = "unlock_cart"
+	OpPruneItems     = "prune_items"
+
+	// OpOverrideItemLimit records an admin taking a cart past the standard
+	// item limit.
+	OpOverrideItemLimit = "override_item_limit"
+)
+
+// AuditEntry records a single change to a cart. Entries are immutable once recorded.
+type AuditEntry struct {
+	ID            string    `json:"id"`
+	Timestamp     time.Time `json:"timestamp"`
+	TenantID      string    `json:"tenant_id"`
+	UserID        string    `json:"user_id"`         // Cart owner
+	Actor         string    `json:"actor,omitempty"` // Authenticated caller, when known
+	Operation     string    `json:"operation"`
+	CartID        string    `json:"cart_id,omitempty"`
+	VersionBefore int64     `json:"version_before"`
+	VersionAfter  int64     `json:"version_after"`
+	ItemID        string    `json:"item_id,omitempty"`
+	ProductID     string    `json:"product_id,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+}
+
+// Auditor records audit entries to an append-only store.
+type Auditor interface {
+	Record(ctx context.Context, entry AuditEntry) error
+}
@@ -26,24 +26,38 @@ type Config struct {
 	AWSRegion   string `validate:"required"`
 	XRayEnabled bool
 
+	// Tracing (spans are exported only when OTELExporterEndpoint is set)
+	OTELExporterEndpoint string
+	OTELSampleRatio      float64 `validate:"gte=0,lte=1"`
+
 	// DynamoDB Configuration
 	DynamoDBTable    string `validate:"required"`
 	DynamoDBEndpoint string // Optional, for local development
 
-	// Redis Configuration (for idempotency)
+	// Redis Configuration (for idempotency and rate limiting)
 	RedisURL     string
 	RedisEnabled bool
 
 	// Rate Limiting
-	RateLimitRPS   int `validate:"min=1,max=10000"`
-	RateLimitBurst int `validate:"min=1,max=10000"`
+	RateLimitRPS        int           `validate:"min=1,max=10000"`
+	RateLimitBurst      int           `validate:"min=1,max=10000"`
+	RateLimitIdleTTL    time.Duration `validate:"min=1m,max=24h"`
+	RateLimitWriteRPS   int           `validate:"min=1,max=10000"`
+	RateLimitWriteBurst int           `validate:"min=1,max=10000"`
 
 	// Request Limits
-	MaxRequestSize int64 `validate:"min=1024,max=10485760"`
+	MaxRequestSize     int64 `validate:"min=1024,max=10485760"`
+	MaxJSONDepth       int   `validate:"min=1,max=1000"`
+	MaxJSONArrayLength int   `validate:"min=1,max=100000"`
 
 	// Idempotency
 	IdempotencyEnabled bool
 	IdempotencyTTL     time.Duration `validate:"min=1m,max=168h"`
+	// IdempotencyIncludeDelete extends idempotency to DELETE requests
+	IdempotencyIncludeDelete bool
+	// IdempotencyNamespace prefixes idempotency store keys; defaults to
+	// "{ServiceName}:{Environment}"
+	IdempotencyNamespace string
 
 	// Circuit Breaker
 	CircuitBreakerEnabled         bool
@@ -60,13 +74,72 @@ type Config struct {
 	DynamoDBReadTimeout  time.Duration `validate:"min=50ms,max=30s"`
 	DynamoDBWriteTimeout time.Duration `validate:"min=50ms,max=30s"`
 
+	// Per-route request timeouts; at least the DynamoDB timeout so one attempt
+	// can finish, and a timed-out request cancels its DynamoDB calls
+	RequestReadTimeout  time.Duration `validate:"min=100ms,max=1m,gtefield=DynamoDBReadTimeout"`
+	RequestWriteTimeout time.Duration `validate:"min=100ms,max=1m,gtefield=DynamoDBWriteTimeout"`
+
+	// Event bus: where cart events are published
+	EventBus string `validate:"oneof=eventbridge kafka"`
+
+	// EventFormat selects the wire format: our native struct or CloudEvents 1.0
+	EventFormat string `validate:"oneof=native cloudevents"`
+
 	// EventBridge Configuration
 	EventBridgeEnabled  bool
 	EventBridgeBusName  string
 	EventBridgeSource   string
-
-	// Feature Flags
-	FeatureFlagsEnabled bool
+	EventPublishMode   string `validate:"oneof=async sync outbox"`
+
+	// Kafka Configuration (used when EventBus is kafka)
+	KafkaBrokers     []string
+	KafkaTopicPrefix string
+
+	// Event Outbox (used when EventPublishMode is outbox)
+	OutboxPollInterval time.Duration `validate:"min=100ms,max=5m"`
+	OutboxBatchSize    int           `validate:"min=1,max=100"`
+
+	// Readiness probe: deep checks ping dependencies on every probe
+	ReadinessDeepCheck bool
+
+	// ShutdownPreDrainDelay is how long to keep serving after /ready starts
+	// failing on shutdown, before draining in-flight requests
+	ShutdownPreDrainDelay time.Duration `validate:"min=0,max=1m"`
+
+	// Cart Expiration; guest carts are identified by GuestUserIDPrefix
+	CartExpirationDuration      time.Duration `validate:"min=1h,max=8760h"`
+	GuestCartExpirationDuration time.Duration `validate:"min=1h,max=8760h,ltefield=CartExpirationDuration"`
+	GuestUserIDPrefix           string
+
+	// Cart snapshot history, listed by /internal/cart/{userID}/history
+	CartSnapshotHistory bool
+	CartSnapshotTTL     time.Duration `validate:"min=1h,max=8760h"`
+
+	// Field-level encryption of gift messages and item attributes at rest
+	FieldEncryptionEnabled  bool
+	FieldEncryptionKMSKeyID string
+
+	// Product policy; an empty allowlist allows every product not denylisted
+	ProductAllowlist []string
+	ProductDenylist  []string
+
+	// Cart item limits; users in CartLimitOverrideGroups may send
+	// X-Override-Limits to add up to AdminMaxItemsPerCart items
+	MaxItemsPerCart         int `validate:"min=1,max=10000"`
+	AdminMaxItemsPerCart    int `validate:"min=0,max=100000"`
+	CartLimitOverrideGroups []string
+
+	// Cart Expiry Warnings
+	ExpiryWarningEnabled  bool
+	ExpiryWarningWindow   time.Duration `validate:"min=1m,max=168h"`
+	ExpiryWarningInterval time.Duration `validate:"min=1m,max=24h"`
+
+	// Feature Flags; read from AppConfig when AppConfigApplication is set
+	FeatureFlagsEnabled   bool
+	AppConfigApplication  string
+	AppConfigEnvironment  string
+	AppConfigProfile      string
+	AppConfigPollInterval time.Duration `validate:"min=15s,max=24h"`
 
 	// Secrets Manager
 	SecretsManagerEnabled bool
@@ -78,8 +151,36 @@ type Config struct {
 	CORSAllowedHeaders []string
 
 	// JWT Configuration
-	JWTIssuer   string
-	JWTAudience string
+	JWTIssuer           string
+	JWTAudience         string
+	JWKSEndpoint        string        // When set, RS256 tokens are verified against this JWKS
+	JWKSRefreshInterval time.Duration // Zero defers to the JWKS Cache-Control header
+	JWTClockSkewLeeway  time.Duration `validate:"min=0,max=5m"`
+
+	// CartAdminGroups are the JWT groups allowed to access other users' carts
+	CartAdminGroups []string
+
+	// Per-request debug logging (X-Debug-Log header)
+	DebugLogAPIKeys     []string
+	DebugLogAdminGroups []string
+
+	// ResponseEnvelopeEnabled wraps /v1 responses as {"data": ..., "meta": ...}
+	ResponseEnvelopeEnabled bool
+
+	// ErrorDetailRedactKeys are error detail keys hashed in prod responses;
+	// logs keep the original values
+	ErrorDetailRedactKeys []string
+
+	// StrictJSON rejects request bodies with unknown fields; when false they
+	// are ignored so older servers tolerate additive client changes
+	StrictJSON bool
+
+	// OpenAPIValidation validates /v1 requests against the embedded OpenAPI
+	// document (docs/swagger.yaml) before the handlers run
+	OpenAPIValidation bool
+
+	// InternalAPIKeys are the X-API-Key values accepted by /internal endpoints
+	InternalAPIKeys []string
 }
 
 // Load loads configuration from .env file (if present) and environment variables, then validates it.
@@ -101,6 +202,10 @@ func Load() (*Config, error) {
 		AWSRegion:   getEnvString("AWS_REGION", "us-east-1"),
 		XRayEnabled: getEnvBool("AWS_XRAY_ENABLED", false),
 
+		// Tracing defaults
+		OTELExporterEndpoint: getEnvString("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		OTELSampleRatio:      getEnvFloat("OTEL_TRACES_SAMPLE_RATIO", 1.0),
+
 		// DynamoDB defaults
 		DynamoDBTable:    getEnvString("DYNAMODB_TABLE", "cart-service-carts"),
 		DynamoDBEndpoint: getEnvString("DYNAMODB_ENDPOINT", ""),
@@ -110,16 +215,24 @@ func Load() (*Config, error) {
 		RedisEnabled: getEnvBool("REDIS_ENABLED", false),
 
 		// Rate limiting defaults
-		RateLimitRPS:   getEnvInt("RATE_LIMIT_RPS", 100),
-		RateLimitBurst: getEnvInt("RATE_LIMIT_BURST", 200),
+		RateLimitRPS:        getEnvInt("RATE_LIMIT_RPS", 100),
+		RateLimitBurst:      getEnvInt("RATE_LIMIT_BURST", 200),
+		RateLimitIdleTTL:    getEnvDuration("RATE_LIMIT_IDLE_TTL", 10*time.Minute),
+		RateLimitWriteRPS:   getEnvInt("RATE_LIMIT_WRITE_RPS", 20),
+		RateLimitWriteBurst: getEnvInt("RATE_LIMIT_WRITE_BURST", 40),
 
 		// Request limits defaults
-		MaxRequestSize: getEnvInt64("MAX_REQUEST_SIZE", 1048576), // 1MB
+		MaxRequestSize:     getEnvInt64("MAX_REQUEST_SIZE", 1048576), // 1MB
+		MaxJSONDepth:       getEnvInt("MAX_JSON_DEPTH", 20),
+		MaxJSONArrayLength: getEnvInt("MAX_JSON_ARRAY_LENGTH", 1000),
 
 		// Idempotency defaults
 		IdempotencyEnabled: getEnvBool("IDEMPOTENCY_ENABLED", true),
 		IdempotencyTTL:     getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
 
+		IdempotencyIncludeDelete: getEnvBool("IDEMPOTENCY_INCLUDE_DELETE", false),
+		IdempotencyNamespace:     getEnvString("IDEMPOTENCY_NAMESPACE", ""),
+
 		// Circuit breaker defaults
 		CircuitBreakerEnabled:         getEnvBool("CIRCUIT_BREAKER_ENABLED", true),
 		CircuitBreakerFailureThreshold: getEnvInt("CIRCUIT_BREAKER_FAILURE_THRESHOLD", 5),
@@ -134,14 +247,66 @@ func Load() (*Config, error) {
 		// Timeout defaults
 		DynamoDBReadTimeout:  getEnvDuration("DYNAMODB_READ_TIMEOUT", 500*time.Millisecond),
 		DynamoDBWriteTimeout: getEnvDuration("DYNAMODB_WRITE_TIMEOUT", 1*time.Second),
+		RequestReadTimeout:   getEnvDuration("REQUEST_READ_TIMEOUT", 2*time.Second),
+		RequestWriteTimeout:  getEnvDuration("REQUEST_WRITE_TIMEOUT", 5*time.Second),
+
+		// Event bus defaults
+		EventBus:    getEnvString("EVENT_BUS", "eventbridge"),
+		EventFormat: getEnvString("EVENT_FORMAT", "native"),
 
 		// EventBridge defaults
 		EventBridgeEnabled: getEnvBool("EVENTBRIDGE_ENABLED", true),
 		EventBridgeBusName: getEnvString("EVENTBRIDGE_BUS_NAME", "default"),
 		EventBridgeSource:  getEnvString("EVENTBRIDGE_SOURCE", "cart-service"),
+		EventPublishMode:   getEnvString("EVENT_PUBLISH_MODE", "async"),
+
+		// Kafka defaults
+		KafkaBrokers:     getEnvStringSlice("KAFKA_BROKERS", nil),
+		KafkaTopicPrefix: getEnvString("KAFKA_TOPIC_PREFIX", ""),
+
+		// Event outbox defaults
+		OutboxPollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", 5*time.Second),
+		OutboxBatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", 25),
+
+		// Readiness defaults
+		ReadinessDeepCheck: getEnvBool("READINESS_DEEP_CHECK", false),
+
+		// Shutdown defaults
+		ShutdownPreDrainDelay: getEnvDuration("SHUTDOWN_PRE_DRAIN_DELAY", 5*time.Second),
+
+		// Field encryption defaults
+		FieldEncryptionEnabled:  getEnvBool("FIELD_ENCRYPTION_ENABLED", false),
+		FieldEncryptionKMSKeyID: getEnvString("FIELD_ENCRYPTION_KMS_KEY_ID", ""),
+
+		// Cart expiration defaults
+		CartExpirationDuration:      getEnvDuration("CART_EXPIRATION", 7*24*time.Hour),
+		GuestCartExpirationDuration: getEnvDuration("GUEST_CART_EXPIRATION", 24*time.Hour),
+		GuestUserIDPrefix:           getEnvString("GUEST_USER_ID_PREFIX", "guest-"),
+
+		// Cart snapshot history defaults
+		CartSnapshotHistory: getEnvBool("CART_SNAPSHOT_HISTORY", false),
+		CartSnapshotTTL:     getEnvDuration("CART_SNAPSHOT_TTL", 30*24*time.Hour),
+
+		// Product policy defaults
+		ProductAllowlist: getEnvStringSlice("PRODUCT_ALLOWLIST", nil),
+		ProductDenylist:  getEnvStringSlice("PRODUCT_DENYLIST", nil),
+
+		// Cart item limit defaults
+		MaxItemsPerCart:         getEnvInt("MAX_ITEMS_PER_CART", 100),
+		AdminMaxItemsPerCart:    getEnvInt("ADMIN_MAX_ITEMS_PER_CART", 0),
+		CartLimitOverrideGroups: getEnvStringSlice("CART_LIMIT_OVERRIDE_GROUPS", []string{"admin"}),
+
+		// Cart expiry warning defaults
+		ExpiryWarningEnabled:  getEnvBool("EXPIRY_WARNING_ENABLED", false),
+		ExpiryWarningWindow:   getEnvDuration("EXPIRY_WARNING_WINDOW", 24*time.Hour),
+		ExpiryWarningInterval: getEnvDuration("EXPIRY_WARNING_INTERVAL", 15*time.Minute),
 
 		// Feature flags defaults
-		FeatureFlagsEnabled: getEnvBool("FEATURE_FLAGS_ENABLED", false),
+		FeatureFlagsEnabled:   getEnvBool("FEATURE_FLAGS_ENABLED", false),
+		AppConfigApplication:  getEnvString("APPCONFIG_APPLICATION", ""),
+		AppConfigEnvironment:  getEnvString("APPCONFIG_ENVIRONMENT", getEnvString("ENV_NAME", "dev")),
+		AppConfigProfile:      getEnvString("APPCONFIG_PROFILE", "feature-flags"),
+		AppConfigPollInterval: getEnvDuration("APPCONFIG_POLL_INTERVAL", 30*time.Second),
 
 		// Secrets Manager defaults
 		SecretsManagerEnabled: getEnvBool("SECRETS_MANAGER_ENABLED", false),
@@ -150,11 +315,32 @@ func Load() (*Config, error) {
 		// CORS defaults
 		CORSAllowedOrigins: getEnvStringSlice("CORS_ALLOWED_ORIGINS", []string{"*"}),
 		CORSAllowedMethods: getEnvStringSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
-		CORSAllowedHeaders: getEnvStringSlice("CORS_ALLOWED_HEADERS", []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Request-ID", "Idempotency-Key"}),
+		CORSAllowedHeaders: getEnvStringSlice("CORS_ALLOWED_HEADERS", []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Request-ID", "Idempotency-Key", "If-Match", "If-None-Match", "X-Correlation-ID", "traceparent"}),
 
 		// JWT defaults
-		JWTIssuer:   getEnvString("JWT_ISSUER", ""),
-		JWTAudience: getEnvString("JWT_AUDIENCE", ""),
+		JWTIssuer:           getEnvString("JWT_ISSUER", ""),
+		JWTAudience:         getEnvString("JWT_AUDIENCE", ""),
+		JWKSEndpoint:        getEnvString("JWT_JWKS_ENDPOINT", ""),
+		JWKSRefreshInterval: getEnvDuration("JWT_JWKS_REFRESH_INTERVAL", 0),
+		JWTClockSkewLeeway:  getEnvDuration("JWT_CLOCK_SKEW_LEEWAY", 30*time.Second),
+		CartAdminGroups:     getEnvStringSlice("CART_ADMIN_GROUPS", []string{"admin"}),
+
+		// Debug logging defaults
+		DebugLogAPIKeys:     getEnvStringSlice("DEBUG_LOG_API_KEYS", nil),
+		DebugLogAdminGroups: getEnvStringSlice("DEBUG_LOG_ADMIN_GROUPS", []string{"admin"}),
+
+		ResponseEnvelopeEnabled: getEnvBool("RESPONSE_ENVELOPE_ENABLED", false),
+
+		ErrorDetailRedactKeys: getEnvStringSlice("ERROR_DETAIL_REDACT_KEYS", []string{"user_id"}),
+
+		StrictJSON: getEnvBool("STRICT_JSON", getEnvString("ENV_NAME", "dev") != "prod"),
+
+		OpenAPIValidation: getEnvBool("OPENAPI_VALIDATION", false),
+
+		InternalAPIKeys: getEnvStringSlice("INTERNAL_API_KEYS", nil),
+	}
+	if cfg.IdempotencyNamespace == "" {
+		cfg.IdempotencyNamespace = cfg.ServiceName + ":" + cfg.Environment
 	}
 
 	// Validate configuration
@@ -212,6 +398,15 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -0,0 +1,96 @@
+package cart
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/metrics"
+)
+
+// AuditFailures returns how many audit entries could not be recorded.
+func (s *Service) AuditFailures() int64 {
+	return s.auditFailures.Load()
+}
+
+// recordAudit records a mutation of the cart, which has already been saved,
+// in the audit trail and the cart history.
+func (s *Service) recordAudit(ctx context.Context, operation string, c *Cart, pending ...pendingEvent) {
+	s.recordSnapshot(ctx, operation, c)
+	s.recordAuditEntries(ctx, operation, c, pending...)
+}
+
+// recordAuditEntries audits a mutation of the cart. Item-level changes
+// produce one entry per affected item; otherwise a single cart-level entry is
+// written. Every mutation bumps the version once, so the version before is one
+// less than the saved version. Failures are counted but never fail the
+// operation.
+func (s *Service) recordAuditEntries(ctx context.Context, operation string, c *Cart, pending ...pendingEvent) {
+	if s.auditor == nil {
+		return
+	}
+
+	base := audit.AuditEntry{
+		Timestamp:     s.now(),
+		TenantID:      NormalizeTenantID(c.TenantID),
+		UserID:        c.UserID,
+		Actor:         logging.UserIDFromContext(ctx),
+		Operation:     operation,
+		CartID:        c.ID,
+		VersionBefore: c.Version - 1,
+		VersionAfter:  c.Version,
+		CorrelationID: logging.CorrelationIDFromContext(ctx),
+	}
+
+	var entries []audit.AuditEntry
+	for _, event := range pending {
+		if change := event.change(); change.ItemID != "" {
+			entry := base
+			entry.ItemID = change.ItemID
+			entry.ProductID = change.ProductID
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		entries = append(entries, base)
+	}
+
+	for _, entry := range entries {
+		entry.ID = uuid.New().String()
+		s.writeAudit(ctx, entry)
+	}
+}
+
+// recordDeleteAudit records the deletion of a user's cart.
+func (s *Service) recordDeleteAudit(ctx context.Context, userID string) {
+	if s.auditor == nil {
+		return
+	}
+
+	s.writeAudit(ctx, audit.AuditEntry{
+		ID:            uuid.New().String(),
+		Timestamp:     s.now(),
+		TenantID:      TenantIDFromContext(ctx),
+		UserID:        userID,
+		Actor:         logging.UserIDFromContext(ctx),
+		Operation:     audit.OpDeleteCart,
+		CorrelationID: logging.CorrelationIDFromContext(ctx),
+	})
+}
+
+// writeAudit sends an entry to the auditor, counting failures.
+func (s *Service) writeAudit(ctx context.Context, entry audit.AuditEntry) {
+	status := "success"
+	if err := s.auditor.Record(ctx, entry); err != nil {
+		s.auditFailures.Add(1)
+		status = "failed"
+	}
+
+	if s.metrics != nil {
+		s.metrics.IncrementCounter(metrics.MetricAuditRecordTotal, map[string]string{
+			"operation": entry.Operation,
+			"status":    status,
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package cart
+
+import (
+	"context"
+	stderrors "errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
+)
+
+// Per-cart bulkhead defaults
+const (
+	DefaultCartMaxWaiting          = 10
+	DefaultCartBulkheadIdleTimeout = 5 * time.Minute
+)
+
+// cartBulkheads serializes mutations of the same cart within this instance so
+// concurrent writers to a popular cart queue up instead of repeatedly failing
+// with version conflicts. Bulkheads of idle carts are removed as new carts
+// are mutated.
+type cartBulkheads struct {
+	manager     *resilience.BulkheadManager
+	config      resilience.BulkheadConfig
+	idleTimeout time.Duration
+	clock       Clock
+	lastSweep   atomic.Int64
+}
+
+func newCartBulkheads(config ServiceConfig, clock Clock) *cartBulkheads {
+	maxWaiting := config.CartMaxWaiting
+	if maxWaiting <= 0 {
+		maxWaiting = DefaultCartMaxWaiting
+	}
+	idleTimeout := config.CartBulkheadIdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultCartBulkheadIdleTimeout
+	}
+
+	b := &cartBulkheads{
+		manager: resilience.NewBulkheadManagerWithClock(clock.Now),
+		config: resilience.BulkheadConfig{
+			MaxConcurrent: config.CartConcurrency,
+			MaxWaiting:    maxWaiting,
+		},
+		idleTimeout: idleTimeout,
+		clock:       clock,
+	}
+	b.lastSweep.Store(clock.Now().UnixNano())
+	return b
+}
+
+// get returns the bulkhead for the cart identified by key, first removing
+// idle bulkheads when a sweep is due.
+func (b *cartBulkheads) get(key string) *resilience.Bulkhead {
+	now := b.clock.Now().UnixNano()
+	last := b.lastSweep.Load()
+	if now-last >= int64(b.idleTimeout) && b.lastSweep.CompareAndSwap(last, now) {
+		b.manager.RemoveIdle(b.idleTimeout)
+	}
+	return b.manager.Get(key, b.config)
+}
+
+// guardCart runs fn within the bulkhead of the selected cart when per-cart
+// concurrency is limited. A full queue fails with CodeServiceUnavailable.
+func guardCart[T any](ctx context.Context, s *Service, userID string, fn func() (T, error)) (T, error) {
+	if s.bulkheads == nil {
+		return fn()
+	}
+
+	var result T
+	err := s.bulkheads.get(cartKey(ctx, userID)).Execute(ctx, func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	if stderrors.Is(err, resilience.ErrBulkheadFull) {
+		return result, errors.ErrServiceUnavailable("cart_bulkhead").WithDetail("user_id", userID)
+	}
+	return result, err
+}
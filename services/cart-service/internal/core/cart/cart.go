@@ -3,9 +3,12 @@ package cart
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/google/uuid"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
 )
 
@@ -14,18 +17,116 @@ const (
 	MaxItemsPerCart    = 100
 	MaxQuantityPerItem = 99
 	MinQuantityPerItem = 1
-	CartExpirationDays = 7
+
+	// MaxGiftMessageLength is the maximum gift message length in characters.
+	MaxGiftMessageLength = 500
+
+	// MaxCartNameLength is the maximum cart name length in characters.
+	MaxCartNameLength = 100
+)
+
+// UnitType is how a cart item's quantity is measured.
+type UnitType string
+
+// Unit types
+const (
+	// UnitTypeEach counts whole units in Quantity (default).
+	UnitTypeEach UnitType = "each"
+	// UnitTypeWeight measures DecimalQuantity in milli-units, e.g. 1500 for
+	// 1.5 kg of a product priced per kg.
+	UnitTypeWeight UnitType = "weight"
+)
+
+// Weighted quantity limits, in milli-units
+const (
+	MilliUnitsPerUnit  = 1000
+	MinDecimalQuantity = 1
+	MaxDecimalQuantity = MaxQuantityPerItem * MilliUnitsPerUnit
+)
+
+// Unit price limits, in cents per unit (per whole unit for weighted items)
+const (
+	MinUnitPrice = 0
+	MaxUnitPrice = 999999999
+)
+
+// AddMode controls how AddItem treats a product already in the cart.
+type AddMode string
+
+// Add modes
+const (
+	// AddModeIncrement adds the quantity to the existing quantity (default).
+	AddModeIncrement AddMode = "increment"
+	// AddModeSet replaces the existing quantity with the submitted one.
+	AddModeSet AddMode = "set"
+	// AddModeIfNotExists fails with CodeItemAlreadyExists instead.
+	AddModeIfNotExists AddMode = "if_not_exists"
+)
+
+// PackSizeMode controls how a quantity that isn't a multiple of the item's
+// pack size is handled when adding items.
+type PackSizeMode string
+
+const (
+	// PackSizeModeReject fails the add with a validation error (default).
+	PackSizeModeReject PackSizeMode = "reject"
+	// PackSizeModeRoundUp rounds the quantity up to the next full pack.
+	PackSizeModeRoundUp PackSizeMode = "round_up"
 )
 
+// DefaultCartExpiration is how long a cart lives without activity when no
+// other expiration is configured.
+const DefaultCartExpiration = 7 * 24 * time.Hour
+
 // Cart represents a shopping cart.
 type Cart struct {
 	ID        string     `json:"id"`
+	TenantID  string     `json:"tenant_id,omitempty"` // Empty is DefaultTenantID
 	UserID    string     `json:"user_id"`
 	Items     []CartItem `json:"items"`
 	Version   int64      `json:"version"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	ExpiresAt time.Time  `json:"expires_at"`
+
+	// Snapshot of the items removed by the last Clear, kept so it can be undone
+	LastClearedItems []CartItem `json:"last_cleared_items,omitempty"`
+	ClearedAt        time.Time  `json:"cleared_at,omitempty"`
+
+	// GiftMessage is an optional note printed with the order
+	GiftMessage string `json:"gift_message,omitempty"`
+
+	// Name labels the cart, e.g. "office"
+	Name string `json:"name,omitempty"`
+
+	// Secondary marks a cart created with Service.CreateCart, addressed by
+	// its ID. The zero value is the user's default cart, addressed by user ID.
+	Secondary bool `json:"secondary,omitempty"`
+
+	// Locked blocks edits while checkout is in progress, until LockedUntil
+	Locked      bool      `json:"locked,omitempty"`
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+
+	// MaxTotalValue caps TotalPrice in cents for AddItem and UpdateItemQuantity;
+	// zero is unlimited. It is set by the service and not persisted.
+	MaxTotalValue int64 `json:"-"`
+
+	// MaxItems caps the distinct items in the cart; zero means
+	// MaxItemsPerCart. It is set by the service and not persisted.
+	MaxItems int `json:"-"`
+
+	// MinCheckoutTotal is the TotalPrice in cents at which the cart becomes
+	// checkout eligible; zero is no minimum. It is set by the service and not
+	// persisted.
+	MinCheckoutTotal int64 `json:"-"`
+
+	// Clock is the time source for expiration and timestamps; nil is the
+	// system clock. It is set by the service and not persisted.
+	Clock Clock `json:"-"`
+
+	// Pricing computes line prices and TotalPrice; nil is LegacyPricing. It
+	// is set by the service and not persisted.
+	Pricing PricingEngine `json:"-"`
 }
 
 // CartItem represents an item in the cart.
@@ -35,36 +136,93 @@ type CartItem struct {
 	Quantity  int       `json:"quantity"`
 	UnitPrice int64     `json:"unit_price"` // In cents
 	AddedAt   time.Time `json:"added_at"`
+
+	// Optional product details, stored so the cart can be rendered without a
+	// catalog lookup per item
+	Name       string            `json:"name,omitempty"`
+	ImageURL   string            `json:"image_url,omitempty"`
+	SKU        string            `json:"sku,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// GiftWrap marks the line for gift wrapping, charged GiftWrapFee per unit
+	GiftWrap    bool  `json:"gift_wrap,omitempty"`
+	GiftWrapFee int64 `json:"gift_wrap_fee,omitempty"` // In cents
+
+	// UnitType UnitTypeWeight sells the line by DecimalQuantity milli-units
+	// instead of Quantity, which is then 1. Empty means UnitTypeEach.
+	UnitType        UnitType `json:"unit_type,omitempty"`
+	DecimalQuantity int64    `json:"decimal_quantity,omitempty"`
+
+	// PackSize, when positive, requires Quantity to be a multiple of it,
+	// e.g. 6 for a product sold in six-packs
+	PackSize int `json:"pack_size,omitempty"`
+
+	// Stale marks an item older than ServiceConfig.MaxItemAge, kept because
+	// StaleItemAction is StaleItemFlag; its price may be out of date
+	Stale bool `json:"stale,omitempty"`
 }
 
-// NewCart creates a new cart for a user.
+// NewCart creates a new cart for a user that expires after DefaultCartExpiration.
 func NewCart(userID string) *Cart {
-	now := time.Now().UTC()
+	return NewCartWithTTL(userID, DefaultCartExpiration)
+}
+
+// NewCartWithTTL creates a new cart for a user that expires after ttl
+// (DefaultCartExpiration when zero).
+func NewCartWithTTL(userID string, ttl time.Duration) *Cart {
+	return newCartAt(UUIDGenerator{}, RealClock{}, userID, ttl)
+}
+
+// newCartAt creates a new cart with an ID from ids, timestamped by clock.
+func newCartAt(ids IDGenerator, clock Clock, userID string, ttl time.Duration) *Cart {
+	if ttl <= 0 {
+		ttl = DefaultCartExpiration
+	}
+	now := clock.Now().UTC()
 	return &Cart{
-		ID:        uuid.New().String(),
+		ID:        ids.NewID(),
 		UserID:    userID,
 		Items:     make([]CartItem, 0),
 		Version:   1,
 		CreatedAt: now,
 		UpdatedAt: now,
-		ExpiresAt: now.Add(CartExpirationDays * 24 * time.Hour),
+		ExpiresAt: now.Add(ttl),
+		Clock:     clock,
 	}
 }
 
 // NewCartItem creates a new cart item.
 func NewCartItem(productID string, quantity int, unitPrice int64) *CartItem {
+	return newCartItemAt(UUIDGenerator{}, time.Now().UTC(), productID, quantity, unitPrice)
+}
+
+// newCartItemAt creates a new cart item with an ID from ids, added at addedAt.
+func newCartItemAt(ids IDGenerator, addedAt time.Time, productID string, quantity int, unitPrice int64) *CartItem {
 	return &CartItem{
-		ItemID:    uuid.New().String(),
+		ItemID:    ids.NewID(),
 		ProductID: productID,
 		Quantity:  quantity,
 		UnitPrice: unitPrice,
-		AddedAt:   time.Now().UTC(),
+		AddedAt:   addedAt,
+	}
+}
+
+// now returns the current time in UTC from the cart's clock.
+func (c *Cart) now() time.Time {
+	if c.Clock == nil {
+		return time.Now().UTC()
 	}
+	return c.Clock.Now().UTC()
+}
+
+// IsDefault reports whether this is the user's default cart.
+func (c *Cart) IsDefault() bool {
+	return !c.Secondary
 }
 
 // IsExpired checks if the cart has expired.
 func (c *Cart) IsExpired() bool {
-	return time.Now().UTC().After(c.ExpiresAt)
+	return c.now().After(c.ExpiresAt)
 }
 
 // ItemCount returns the number of items in the cart.
@@ -72,7 +230,17 @@ func (c *Cart) ItemCount() int {
 	return len(c.Items)
 }
 
-// TotalQuantity returns the total quantity of all items.
+// NewWeightedCartItem creates a cart item sold by weight. decimalQuantity is
+// in milli-units and unitPrice is per whole unit.
+func NewWeightedCartItem(productID string, decimalQuantity int64, unitPrice int64) *CartItem {
+	item := NewCartItem(productID, 1, unitPrice)
+	item.UnitType = UnitTypeWeight
+	item.DecimalQuantity = decimalQuantity
+	return item
+}
+
+// TotalQuantity returns the total quantity of all items. A weighted item
+// counts as one.
 func (c *Cart) TotalQuantity() int {
 	total := 0
 	for _, item := range c.Items {
@@ -81,13 +249,79 @@ func (c *Cart) TotalQuantity() int {
 	return total
 }
 
-// TotalPrice returns the total price in cents.
+// TotalPrice returns the total price in cents, as computed by the cart's
+// pricing engine.
 func (c *Cart) TotalPrice() int64 {
-	var total int64
+	return c.pricing().Total(c.Items)
+}
+
+// LineTotal returns the price of an item in cents, as computed by the cart's
+// pricing engine.
+func (c *Cart) LineTotal(item *CartItem) int64 {
+	return c.pricing().LineTotal(item)
+}
+
+// PricingEngineName returns the name of the engine pricing the cart.
+func (c *Cart) PricingEngineName() string {
+	return c.pricing().Name()
+}
+
+// pricing returns the cart's pricing engine.
+func (c *Cart) pricing() PricingEngine {
+	if c.Pricing != nil {
+		return c.Pricing
+	}
+	return LegacyPricing{}
+}
+
+// IsWeighted reports whether the item is sold by DecimalQuantity.
+func (i *CartItem) IsWeighted() bool {
+	return i.UnitType == UnitTypeWeight
+}
+
+// Subtotal returns the line price in cents. For weighted items it is
+// UnitPrice * DecimalQuantity / MilliUnitsPerUnit, truncated to the cent.
+func (i *CartItem) Subtotal() int64 {
+	if i.IsWeighted() {
+		return i.UnitPrice * i.DecimalQuantity / MilliUnitsPerUnit
+	}
+	return i.UnitPrice * int64(i.Quantity)
+}
+
+// AmountToMinimum returns how much more in cents must be added for TotalPrice
+// to reach MinCheckoutTotal, or 0 once it is reached.
+func (c *Cart) AmountToMinimum() int64 {
+	if remaining := c.MinCheckoutTotal - c.TotalPrice(); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// CheckoutEligible reports whether TotalPrice meets MinCheckoutTotal.
+func (c *Cart) CheckoutEligible() bool {
+	return c.AmountToMinimum() == 0
+}
+
+// GiftWrapFees returns the total gift wrap fees in cents.
+func (c *Cart) GiftWrapFees() int64 {
+	var fees int64
 	for _, item := range c.Items {
-		total += item.UnitPrice * int64(item.Quantity)
+		fees += item.GiftWrapTotal()
 	}
-	return total
+	return fees
+}
+
+// TotalWithFees returns the merchandise total plus gift wrap fees in cents.
+func (c *Cart) TotalWithFees() int64 {
+	return c.TotalPrice() + c.GiftWrapFees()
+}
+
+// GiftWrapTotal returns the gift wrap fee for the line in cents.
+func (i *CartItem) GiftWrapTotal() int64 {
+	if !i.GiftWrap {
+		return 0
+	}
+	return i.GiftWrapFee * int64(i.Quantity)
 }
 
 // FindItem finds an item by its ID.
@@ -112,32 +346,125 @@ func (c *Cart) FindItemByProductID(productID string) (*CartItem, int) {
 
 // AddItem adds an item to the cart or updates quantity if product already exists.
 func (c *Cart) AddItem(item *CartItem) error {
-	// Validate quantity
-	if err := ValidateQuantity(item.Quantity); err != nil {
+	return c.AddItemWithMode(item, AddModeIncrement)
+}
+
+// AddItemWithMode adds an item to the cart. When the product already exists,
+// its quantity is increased by the item's quantity or, with AddModeSet,
+// replaced by it; AddModeIfNotExists rejects the add. An empty mode means
+// AddModeIncrement.
+func (c *Cart) AddItemWithMode(item *CartItem, mode AddMode) error {
+	if mode != "" && mode != AddModeIncrement && mode != AddModeSet && mode != AddModeIfNotExists {
+		return errors.ErrValidation("Invalid add mode", map[string]interface{}{
+			"add_mode": string(mode),
+		})
+	}
+
+	// Validate quantity and price
+	if err := ValidateItemQuantity(item); err != nil {
+		return err
+	}
+	if err := ValidatePrice(item.UnitPrice); err != nil {
 		return err
 	}
 
 	// Check if product already exists in cart
 	if existing, idx := c.FindItemByProductID(item.ProductID); existing != nil {
+		if mode == AddModeIfNotExists {
+			return errors.ErrItemAlreadyExists(item.ProductID, existing.ItemID)
+		}
+		if existing.IsWeighted() != item.IsWeighted() {
+			return errors.ErrValidation("Unit type does not match the item in the cart", map[string]interface{}{
+				"product_id": item.ProductID,
+				"unit_type":  string(existing.UnitType),
+			})
+		}
+
 		// Update quantity
-		newQuantity := existing.Quantity + item.Quantity
-		if newQuantity > MaxQuantityPerItem {
-			return errors.ErrQuantityLimitExceeded(newQuantity, MaxQuantityPerItem)
+		next := *existing
+		next.UnitPrice = item.UnitPrice
+		if item.IsWeighted() {
+			next.DecimalQuantity = existing.DecimalQuantity + item.DecimalQuantity
+			if mode == AddModeSet {
+				next.DecimalQuantity = item.DecimalQuantity
+			}
+			if err := ValidateDecimalQuantity(next.DecimalQuantity); err != nil {
+				return err
+			}
+		} else {
+			next.Quantity = existing.Quantity + item.Quantity
+			if mode == AddModeSet {
+				next.Quantity = item.Quantity
+			}
+			if next.Quantity > MaxQuantityPerItem {
+				return errors.ErrQuantityLimitExceeded(next.Quantity, MaxQuantityPerItem)
+			}
+			if err := ValidatePackSize(next.Quantity, item.PackSize); err != nil {
+				return err
+			}
 		}
-		c.Items[idx].Quantity = newQuantity
+		projected := c.TotalPrice() - c.LineTotal(existing) + c.LineTotal(&next)
+		if err := c.checkTotalValue(projected); err != nil {
+			return err
+		}
+		c.Items[idx].Quantity = next.Quantity
+		c.Items[idx].DecimalQuantity = next.DecimalQuantity
 		c.Items[idx].UnitPrice = item.UnitPrice // Update price
-		c.UpdatedAt = time.Now().UTC()
+		c.Items[idx].PackSize = item.PackSize
+		c.Items[idx].updateDetails(item)
+		c.UpdatedAt = c.now()
 		return nil
 	}
 
 	// Check cart item limit
-	if len(c.Items) >= MaxItemsPerCart {
-		return errors.ErrCartLimitExceeded(len(c.Items), MaxItemsPerCart)
+	if limit := c.itemLimit(); len(c.Items) >= limit {
+		return errors.ErrCartLimitExceeded(len(c.Items), limit)
+	}
+	if err := c.checkTotalValue(c.TotalPrice() + c.LineTotal(item)); err != nil {
+		return err
 	}
 
 	// Add new item
 	c.Items = append(c.Items, *item)
-	c.UpdatedAt = time.Now().UTC()
+	c.UpdatedAt = c.now()
+	return nil
+}
+
+// updateDetails copies the product details that are set on from, keeping the
+// existing values for the rest.
+func (i *CartItem) updateDetails(from *CartItem) {
+	if from.Name != "" {
+		i.Name = from.Name
+	}
+	if from.ImageURL != "" {
+		i.ImageURL = from.ImageURL
+	}
+	if from.SKU != "" {
+		i.SKU = from.SKU
+	}
+	if len(from.Attributes) > 0 {
+		i.Attributes = from.Attributes
+	}
+	if from.GiftWrap {
+		i.GiftWrap = true
+		i.GiftWrapFee = from.GiftWrapFee
+	}
+}
+
+// SetGiftWrap turns gift wrapping on or off for an item. fee is the per-unit
+// charge in cents and is ignored when wrap is false.
+func (c *Cart) SetGiftWrap(itemID string, wrap bool, fee int64) error {
+	item, _ := c.FindItem(itemID)
+	if item == nil {
+		return errors.ErrItemNotFound(c.UserID, itemID)
+	}
+
+	item.GiftWrap = wrap
+	item.GiftWrapFee = 0
+	if wrap {
+		item.GiftWrapFee = fee
+	}
+	c.UpdatedAt = c.now()
 	return nil
 }
 
@@ -148,14 +475,41 @@ func (c *Cart) RemoveItem(itemID string) error {
 		return errors.ErrItemNotFound(c.UserID, itemID)
 	}
 
-	// Remove item by swapping with last and truncating
-	c.Items[idx] = c.Items[len(c.Items)-1]
-	c.Items = c.Items[:len(c.Items)-1]
-	c.UpdatedAt = time.Now().UTC()
+	// Remove item preserving the order of the remaining items
+	c.Items = append(c.Items[:idx], c.Items[idx+1:]...)
+	c.UpdatedAt = c.now()
+	return nil
+}
+
+// ReorderItems reorders the items to match the given item IDs.
+// Items not listed keep their relative order and are placed after the listed ones.
+func (c *Cart) ReorderItems(orderedItemIDs []string) error {
+	positions := make(map[string]int, len(orderedItemIDs))
+	for _, itemID := range orderedItemIDs {
+		if _, idx := c.FindItem(itemID); idx == -1 {
+			return errors.ErrItemNotFound(c.UserID, itemID)
+		}
+		if _, seen := positions[itemID]; !seen {
+			positions[itemID] = len(positions)
+		}
+	}
+
+	ordered := make([]CartItem, len(positions), len(c.Items))
+	for _, item := range c.Items {
+		if pos, ok := positions[item.ItemID]; ok {
+			ordered[pos] = item
+		} else {
+			ordered = append(ordered, item)
+		}
+	}
+
+	c.Items = ordered
+	c.UpdatedAt = c.now()
 	return nil
 }
 
-// UpdateItemQuantity updates the quantity of an item.
+// UpdateItemQuantity updates the quantity of an item. Weighted items are
+// updated with UpdateItemDecimalQuantity instead.
 func (c *Cart) UpdateItemQuantity(itemID string, quantity int) error {
 	if err := ValidateQuantity(quantity); err != nil {
 		return err
@@ -165,28 +519,197 @@ func (c *Cart) UpdateItemQuantity(itemID string, quantity int) error {
 	if item == nil {
 		return errors.ErrItemNotFound(c.UserID, itemID)
 	}
+	if item.IsWeighted() {
+		return errors.ErrValidation("Weighted items are updated by decimal quantity", map[string]interface{}{
+			"item_id": itemID,
+		})
+	}
+	if err := ValidatePackSize(quantity, item.PackSize); err != nil {
+		return err
+	}
+
+	next := *item
+	next.Quantity = quantity
+	projected := c.TotalPrice() - c.LineTotal(item) + c.LineTotal(&next)
+	if err := c.checkTotalValue(projected); err != nil {
+		return err
+	}
 
 	item.Quantity = quantity
-	c.UpdatedAt = time.Now().UTC()
+	c.UpdatedAt = c.now()
+	return nil
+}
+
+// UpdateItemDecimalQuantity updates the quantity of a weighted item in
+// milli-units.
+func (c *Cart) UpdateItemDecimalQuantity(itemID string, decimalQuantity int64) error {
+	if err := ValidateDecimalQuantity(decimalQuantity); err != nil {
+		return err
+	}
+
+	item, _ := c.FindItem(itemID)
+	if item == nil {
+		return errors.ErrItemNotFound(c.UserID, itemID)
+	}
+	if !item.IsWeighted() {
+		return errors.ErrValidation("Only weighted items have a decimal quantity", map[string]interface{}{
+			"item_id": itemID,
+		})
+	}
+
+	next := *item
+	next.DecimalQuantity = decimalQuantity
+	projected := c.TotalPrice() - c.LineTotal(item) + c.LineTotal(&next)
+	if err := c.checkTotalValue(projected); err != nil {
+		return err
+	}
+
+	item.DecimalQuantity = decimalQuantity
+	c.UpdatedAt = c.now()
 	return nil
 }
 
-// Clear removes all items from the cart.
+// itemLimit returns MaxItems, or MaxItemsPerCart when unset.
+func (c *Cart) itemLimit() int {
+	if c.MaxItems > 0 {
+		return c.MaxItems
+	}
+	return MaxItemsPerCart
+}
+
+// checkTotalValue returns an error if projected exceeds MaxTotalValue. Changes
+// that lower the total are always allowed, even on a cart already over the limit.
+func (c *Cart) checkTotalValue(projected int64) error {
+	if c.MaxTotalValue <= 0 || projected <= c.MaxTotalValue || projected <= c.TotalPrice() {
+		return nil
+	}
+	return errors.ErrCartValueLimitExceeded(projected, c.MaxTotalValue)
+}
+
+// RepriceProduct sets the unit price of the item holding the given product.
+// It returns the item and its previous price, or nil if the product isn't in the
+// cart or already has that price.
+func (c *Cart) RepriceProduct(productID string, unitPrice int64) (*CartItem, int64) {
+	item, _ := c.FindItemByProductID(productID)
+	if item == nil || item.UnitPrice == unitPrice {
+		return nil, 0
+	}
+
+	previousPrice := item.UnitPrice
+	item.UnitPrice = unitPrice
+	c.UpdatedAt = c.now()
+	return item, previousPrice
+}
+
+// Clear removes all items from the cart, keeping a snapshot for Restore.
+// Clearing an already empty cart keeps the previous snapshot.
 func (c *Cart) Clear() {
+	now := c.now()
+	if len(c.Items) > 0 {
+		c.LastClearedItems = c.Items
+		c.ClearedAt = now
+	}
 	c.Items = make([]CartItem, 0)
-	c.UpdatedAt = time.Now().UTC()
+	c.UpdatedAt = now
+}
+
+// SetGiftMessage replaces the gift message; an empty message clears it.
+// Control characters other than newlines and tabs are stripped before the
+// length is checked.
+func (c *Cart) SetGiftMessage(message string) error {
+	message = SanitizeGiftMessage(message)
+	if n := utf8.RuneCountInString(message); n > MaxGiftMessageLength {
+		return errors.ErrValidation("Gift message is too long", map[string]interface{}{
+			"max_length": MaxGiftMessageLength,
+			"length":     n,
+		})
+	}
+
+	c.GiftMessage = message
+	c.UpdatedAt = c.now()
+	return nil
+}
+
+// SetName replaces the cart's name; an empty name clears it. The name is
+// sanitized with SanitizeCartName before the length is checked.
+func (c *Cart) SetName(name string) error {
+	name = SanitizeCartName(name)
+	if n := utf8.RuneCountInString(name); n > MaxCartNameLength {
+		return errors.ErrValidation("Cart name is too long", map[string]interface{}{
+			"max_length": MaxCartNameLength,
+			"length":     n,
+		})
+	}
+
+	c.Name = name
+	c.UpdatedAt = c.now()
+	return nil
+}
+
+// SanitizeCartName strips control characters, newlines included, collapses
+// runs of whitespace and trims the result, so a name always fits on one line.
+func SanitizeCartName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// SanitizeGiftMessage strips control characters other than newlines and tabs
+// and trims surrounding whitespace.
+func SanitizeGiftMessage(message string) string {
+	message = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, message)
+	return strings.TrimSpace(message)
+}
+
+// Restore re-adds the items removed by the last Clear if it happened within window.
+// Items whose product was re-added since the clear keep their current line.
+func (c *Cart) Restore(window time.Duration) error {
+	if len(c.LastClearedItems) == 0 || c.now().Sub(c.ClearedAt) > window {
+		return errors.ErrCartNotFound(c.UserID).WithDetail("reason", "no recently cleared items to restore")
+	}
+
+	for _, item := range c.LastClearedItems {
+		if existing, _ := c.FindItemByProductID(item.ProductID); existing != nil {
+			continue
+		}
+		if len(c.Items) >= c.itemLimit() {
+			break
+		}
+		c.Items = append(c.Items, item)
+	}
+
+	c.LastClearedItems = nil
+	c.ClearedAt = time.Time{}
+	c.UpdatedAt = c.now()
+	return nil
 }
 
 // IncrementVersion increments the cart version for optimistic locking.
 func (c *Cart) IncrementVersion() {
 	c.Version++
-	c.UpdatedAt = time.Now().UTC()
+	c.UpdatedAt = c.now()
 }
 
-// ExtendExpiration extends the cart expiration time.
-func (c *Cart) ExtendExpiration() {
-	c.ExpiresAt = time.Now().UTC().Add(CartExpirationDays * 24 * time.Hour)
-	c.UpdatedAt = time.Now().UTC()
+// ExtendExpiration resets the cart to expire ttl from now (DefaultCartExpiration when zero).
+func (c *Cart) ExtendExpiration(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultCartExpiration
+	}
+	now := c.now()
+	c.ExpiresAt = now.Add(ttl)
+	c.UpdatedAt = now
 }
 
 // ValidateQuantity validates that quantity is within allowed limits.
@@ -200,36 +723,202 @@ func ValidateQuantity(quantity int) error {
 	return nil
 }
 
+// ValidatePackSize validates that quantity is a whole number of packs. A
+// pack size of zero allows any quantity.
+func ValidatePackSize(quantity, packSize int) error {
+	if packSize < 0 || packSize > MaxQuantityPerItem {
+		return errors.ErrValidation("Invalid pack size", map[string]interface{}{
+			"pack_size": packSize,
+			"max":       MaxQuantityPerItem,
+		})
+	}
+	if packSize > 0 && quantity%packSize != 0 {
+		return errors.ErrValidation("Quantity must be a multiple of the pack size", map[string]interface{}{
+			"quantity":  quantity,
+			"pack_size": packSize,
+		})
+	}
+	return nil
+}
+
+// RoundUpToPackSize rounds quantity up to a whole number of packs. A pack
+// size of zero or less leaves it unchanged.
+func RoundUpToPackSize(quantity, packSize int) int {
+	if packSize <= 0 || quantity%packSize == 0 {
+		return quantity
+	}
+	return (quantity/packSize + 1) * packSize
+}
+
+// ValidateDecimalQuantity validates that a weighted quantity in milli-units
+// is within allowed limits.
+func ValidateDecimalQuantity(decimalQuantity int64) error {
+	if decimalQuantity < MinDecimalQuantity || decimalQuantity > MaxDecimalQuantity {
+		return errors.ErrValidation("Invalid decimal quantity", map[string]interface{}{
+			"decimal_quantity": decimalQuantity,
+			"min":              MinDecimalQuantity,
+			"max":              MaxDecimalQuantity,
+		})
+	}
+	return nil
+}
+
+// ValidatePrice validates that a unit price in cents is within allowed limits.
+func ValidatePrice(price int64) error {
+	if price < MinUnitPrice || price > MaxUnitPrice {
+		return errors.ErrValidation("Invalid unit price", map[string]interface{}{
+			"unit_price": price,
+			"min":        MinUnitPrice,
+			"max":        MaxUnitPrice,
+		})
+	}
+	return nil
+}
+
+// ValidateItemQuantity validates an item's quantity according to its unit
+// type: Quantity for UnitTypeEach, DecimalQuantity for UnitTypeWeight.
+func ValidateItemQuantity(item *CartItem) error {
+	switch item.UnitType {
+	case "", UnitTypeEach:
+		if item.DecimalQuantity != 0 {
+			return errors.ErrValidation("Only weighted items have a decimal quantity", map[string]interface{}{
+				"unit_type": string(UnitTypeEach),
+			})
+		}
+		if err := ValidateQuantity(item.Quantity); err != nil {
+			return err
+		}
+		return ValidatePackSize(item.Quantity, item.PackSize)
+	case UnitTypeWeight:
+		if item.PackSize != 0 {
+			return errors.ErrValidation("Weighted items have no pack size", map[string]interface{}{
+				"unit_type": string(UnitTypeWeight),
+			})
+		}
+		return ValidateDecimalQuantity(item.DecimalQuantity)
+	}
+	return errors.ErrValidation("Invalid unit type", map[string]interface{}{
+		"unit_type": string(item.UnitType),
+	})
+}
+
+// Normalize merges items that share a product ID into the first of them,
+// summing quantities capped at MaxQuantityPerItem as MergeSum does. A correct
+// cart never holds duplicates; this repairs data written by an older bug. It
+// returns the number of rows merged away.
+func (c *Cart) Normalize() int {
+	seen := make(map[string]int, len(c.Items))
+	items := c.Items[:0]
+	merged := 0
+	for _, item := range c.Items {
+		if idx, ok := seen[item.ProductID]; ok {
+			mergeQuantities(&items[idx], &item, MergeSum)
+			merged++
+			continue
+		}
+		seen[item.ProductID] = len(items)
+		items = append(items, item)
+	}
+	c.Items = items
+	return merged
+}
+
+// MergeStrategy decides the quantity of a product found in both carts when a
+// guest cart is merged into a user cart.
+type MergeStrategy string
+
+const (
+	// MergeKeepHigher keeps the higher of the two quantities (default).
+	MergeKeepHigher MergeStrategy = "keep_higher"
+	// MergeSum adds the quantities, capped at MaxQuantityPerItem.
+	MergeSum MergeStrategy = "sum"
+	// MergePreferUser keeps the user cart's quantity.
+	MergePreferUser MergeStrategy = "prefer_user"
+	// MergePreferGuest takes the guest cart's quantity.
+	MergePreferGuest MergeStrategy = "prefer_guest"
+)
+
 // MergeCarts merges a guest cart into a user cart.
 // For duplicate products, keeps the higher quantity.
 func MergeCarts(userCart, guestCart *Cart) *Cart {
+	merged, _ := MergeCartsWithStrategy(userCart, guestCart, MergeKeepHigher)
+	return merged
+}
+
+// MergeCartsWithStrategy merges a guest cart into a user cart, resolving
+// products found in both with strategy (empty is MergeKeepHigher). Guest
+// items for other products are added while the cart has room. It returns a
+// WarningQuantityCapped warning for each product whose summed quantity was
+// capped.
+func MergeCartsWithStrategy(userCart, guestCart *Cart, strategy MergeStrategy) (*Cart, []Warning) {
 	if userCart == nil {
 		if guestCart != nil {
-			guestCart.UpdatedAt = time.Now().UTC()
+			guestCart.UpdatedAt = guestCart.now()
 		}
-		return guestCart
+		return guestCart, nil
 	}
 
 	if guestCart == nil {
-		return userCart
+		return userCart, nil
 	}
 
+	var warnings []Warning
 	for _, guestItem := range guestCart.Items {
 		if existing, _ := userCart.FindItemByProductID(guestItem.ProductID); existing != nil {
-			// Keep higher quantity
-			if guestItem.Quantity > existing.Quantity {
-				existing.Quantity = guestItem.Quantity
+			if existing.IsWeighted() != guestItem.IsWeighted() {
+				continue
+			}
+			if mergeQuantities(existing, &guestItem, strategy) {
+				warnings = append(warnings, Warning{
+					Code:      WarningQuantityCapped,
+					Message:   fmt.Sprintf("Merged quantity was capped at the maximum of %d per item", MaxQuantityPerItem),
+					ItemID:    existing.ItemID,
+					ProductID: existing.ProductID,
+				})
 			}
 		} else {
 			// Add new item if cart isn't full
-			if len(userCart.Items) < MaxItemsPerCart {
+			if len(userCart.Items) < userCart.itemLimit() {
 				userCart.Items = append(userCart.Items, guestItem)
 			}
 		}
 	}
 
-	userCart.UpdatedAt = time.Now().UTC()
-	return userCart
+	userCart.UpdatedAt = userCart.now()
+	return userCart, warnings
+}
+
+// mergeQuantities sets the quantity of existing, a user cart item, from the
+// matching guest item per strategy. It reports whether a sum was capped.
+func mergeQuantities(existing, guest *CartItem, strategy MergeStrategy) (capped bool) {
+	switch strategy {
+	case MergeSum:
+		if existing.IsWeighted() {
+			existing.DecimalQuantity += guest.DecimalQuantity
+			if existing.DecimalQuantity > MaxDecimalQuantity {
+				existing.DecimalQuantity = MaxDecimalQuantity
+				capped = true
+			}
+			return capped
+		}
+		existing.Quantity += guest.Quantity
+		if existing.Quantity > MaxQuantityPerItem {
+			existing.Quantity = MaxQuantityPerItem
+			capped = true
+		}
+	case MergePreferUser:
+	case MergePreferGuest:
+		existing.Quantity = guest.Quantity
+		existing.DecimalQuantity = guest.DecimalQuantity
+	default:
+		if guest.Quantity > existing.Quantity {
+			existing.Quantity = guest.Quantity
+		}
+		if guest.DecimalQuantity > existing.DecimalQuantity {
+			existing.DecimalQuantity = guest.DecimalQuantity
+		}
+	}
+	return capped
 }
 
 // PriceValidator interface for validating prices with product catalog.
@@ -245,14 +934,62 @@ type InventoryChecker interface {
 	ReleaseReservation(ctx context.Context, reservationID string) error
 }
 
+// ProductPolicy decides whether a product may be added to carts, e.g. to block
+// recalled or region-restricted products. reason explains a denial.
+type ProductPolicy interface {
+	IsAllowed(ctx context.Context, productID string) (allowed bool, reason string)
+}
+
+// DeliveryEstimate is a display-only delivery window for a cart line.
+// Estimates are computed at read time and never persisted.
+type DeliveryEstimate struct {
+	Earliest time.Time `json:"earliest"`
+	Latest   time.Time `json:"latest"`
+}
+
+// Warning codes
+const (
+	// WarningBulkQuantity flags a line whose quantity exceeds the soft limit.
+	WarningBulkQuantity = "BULK_QUANTITY"
+
+	// WarningQuantityRounded flags a quantity rounded up to a full pack.
+	WarningQuantityRounded = "QUANTITY_ROUNDED"
+
+	// WarningQuantityCapped flags a merged quantity cut to the item maximum.
+	WarningQuantityCapped = "QUANTITY_CAPPED"
+
+	// WarningCartNearingLimit flags a cart at or above the soft item limit.
+	WarningCartNearingLimit = "CART_NEARING_LIMIT"
+)
+
+// Warning is a non-blocking notice about a cart line, e.g. so the UI can ask
+// about a bulk order. Warnings are computed per request and never persisted.
+type Warning struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	ItemID    string `json:"item_id,omitempty"`
+	ProductID string `json:"product_id,omitempty"`
+}
+
+// EstimateProvider interface for looking up delivery estimates.
+type EstimateProvider interface {
+	EstimateFor(ctx context.Context, productID string, quantity int) (DeliveryEstimate, error)
+}
+
 // CartSummary provides a summary of the cart for API responses.
 type CartSummary struct {
 	ID            string `json:"id"`
 	UserID        string `json:"user_id"`
+	Name          string `json:"name,omitempty"`
 	ItemCount     int    `json:"item_count"`
 	TotalQuantity int    `json:"total_quantity"`
 	TotalPrice    int64  `json:"total_price"`
+	TotalWithFees int64  `json:"total_with_fees"`
+	PricingEngine string `json:"pricing_engine"`
 	Version       int64  `json:"version"`
+
+	CheckoutEligible bool  `json:"checkout_eligible"`
+	AmountToMinimum  int64 `json:"amount_to_minimum"`
 }
 
 // Summary returns a summary of the cart.
@@ -260,9 +997,15 @@ func (c *Cart) Summary() CartSummary {
 	return CartSummary{
 		ID:            c.ID,
 		UserID:        c.UserID,
+		Name:          c.Name,
 		ItemCount:     c.ItemCount(),
 		TotalQuantity: c.TotalQuantity(),
 		TotalPrice:    c.TotalPrice(),
+		TotalWithFees: c.TotalWithFees(),
+		PricingEngine: c.PricingEngineName(),
 		Version:       c.Version,
+
+		CheckoutEligible: c.CheckoutEligible(),
+		AmountToMinimum:  c.AmountToMinimum(),
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -115,6 +116,77 @@ func TestCart_AddItem_UpdatesQuantityForExistingProduct(t *testing.T) {
 	assert.Equal(t, 5, item.Quantity)
 }
 
+func TestCart_AddItemWithMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         AddMode
+		quantity     int
+		wantQuantity int
+		wantErr      string
+	}{
+		{name: "default increments", quantity: 3, wantQuantity: 5},
+		{name: "increment", mode: AddModeIncrement, quantity: 3, wantQuantity: 5},
+		{name: "set replaces", mode: AddModeSet, quantity: 3, wantQuantity: 3},
+		{name: "set can lower", mode: AddModeSet, quantity: 1, wantQuantity: 1},
+		{name: "set validates quantity", mode: AddModeSet, quantity: 0, wantErr: errors.CodeInvalidQuantity},
+		{name: "increment past max", mode: AddModeIncrement, quantity: 98, wantErr: errors.CodeQuantityLimit},
+		{name: "set up to max", mode: AddModeSet, quantity: 99, wantQuantity: 99},
+		{name: "if not exists rejects", mode: AddModeIfNotExists, quantity: 3, wantErr: errors.CodeItemAlreadyExists},
+		{name: "unknown mode", mode: "replace", quantity: 1, wantErr: errors.CodeValidationError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := NewCart("user-123")
+			require.NoError(t, cart.AddItem(NewCartItem("product-1", 2, 1000)))
+
+			err := cart.AddItemWithMode(NewCartItem("product-1", tt.quantity, 1000), tt.mode)
+			item, _ := cart.FindItemByProductID("product-1")
+			if tt.wantErr != "" {
+				assert.True(t, errors.IsCode(err, tt.wantErr), "got %v", err)
+				assert.Equal(t, 2, item.Quantity)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, cart.ItemCount())
+			assert.Equal(t, tt.wantQuantity, item.Quantity)
+		})
+	}
+}
+
+func TestCart_WeightedItems(t *testing.T) {
+	cart := NewCart("user-123")
+	cart.AddItem(NewCartItem("product-1", 2, 100))
+
+	// 1.5 units at 399 cents per unit
+	err := cart.AddItem(NewWeightedCartItem("produce-1", 1500, 399))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200+598), cart.TotalPrice())
+	assert.Equal(t, 3, cart.TotalQuantity())
+
+	// Adding more weight accumulates milli-units
+	err = cart.AddItem(NewWeightedCartItem("produce-1", 250, 399))
+	assert.NoError(t, err)
+	item, _ := cart.FindItemByProductID("produce-1")
+	assert.Equal(t, int64(1750), item.DecimalQuantity)
+	assert.Equal(t, int64(698), item.Subtotal())
+
+	// Weighted items are updated by decimal quantity only
+	assert.Error(t, cart.UpdateItemQuantity(item.ItemID, 2))
+	assert.NoError(t, cart.UpdateItemDecimalQuantity(item.ItemID, 500))
+	assert.Equal(t, int64(199), item.Subtotal())
+
+	// The same product can't switch unit types
+	assert.Error(t, cart.AddItem(NewCartItem("produce-1", 1, 399)))
+
+	// Invalid decimal quantities are rejected
+	assert.Error(t, cart.AddItem(NewWeightedCartItem("produce-2", 0, 100)))
+	assert.Error(t, cart.AddItem(NewWeightedCartItem("produce-2", MaxDecimalQuantity+1, 100)))
+	each := NewCartItem("product-2", 1, 100)
+	each.DecimalQuantity = 500
+	assert.Error(t, cart.AddItem(each))
+}
+
 func TestCart_RemoveItem(t *testing.T) {
 	cart := NewCart("user-123")
 	item := NewCartItem("product-1", 1, 1000)
@@ -220,13 +292,15 @@ func TestCart_IsExpired(t *testing.T) {
 }
 
 func TestCart_ExtendExpiration(t *testing.T) {
+	clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
 	cart := NewCart("user-123")
-	originalExpiry := cart.ExpiresAt
+	cart.Clock = clock
 
-	time.Sleep(10 * time.Millisecond)
-	cart.ExtendExpiration()
+	clock.Advance(time.Hour)
+	cart.ExtendExpiration(DefaultCartExpiration)
 
-	assert.True(t, cart.ExpiresAt.After(originalExpiry))
+	assert.Equal(t, clock.Now().Add(DefaultCartExpiration), cart.ExpiresAt)
+	assert.Equal(t, clock.Now(), cart.UpdatedAt)
 }
 
 func TestCart_IncrementVersion(t *testing.T) {
@@ -282,6 +356,21 @@ func TestCart_Summary(t *testing.T) {
 	assert.Equal(t, 5, summary.TotalQuantity)
 	assert.Equal(t, int64(3500), summary.TotalPrice)
 	assert.Equal(t, cart.Version, summary.Version)
+	assert.True(t, summary.CheckoutEligible)
+	assert.Zero(t, summary.AmountToMinimum)
+}
+
+func TestCart_CheckoutMinimum(t *testing.T) {
+	cart := NewCart("user-123")
+	cart.MinCheckoutTotal = 5000
+	cart.AddItem(NewCartItem("product-1", 2, 1000))
+
+	assert.False(t, cart.CheckoutEligible())
+	assert.Equal(t, int64(3000), cart.AmountToMinimum())
+
+	cart.AddItem(NewCartItem("product-2", 1, 3000))
+	assert.True(t, cart.CheckoutEligible())
+	assert.Zero(t, cart.AmountToMinimum())
 }
 
 func TestMergeCarts(t *testing.T) {
@@ -388,6 +477,55 @@ func TestMergeCarts(t *testing.T) {
 	}
 }
 
+func TestMergeCartsWithStrategy(t *testing.T) {
+	tests := []struct {
+		name         string
+		strategy     MergeStrategy
+		userQty      int
+		guestQty     int
+		wantQuantity int
+		wantCapped   bool
+	}{
+		{name: "default keeps higher", strategy: "", userQty: 2, guestQty: 5, wantQuantity: 5},
+		{name: "keep higher", strategy: MergeKeepHigher, userQty: 7, guestQty: 5, wantQuantity: 7},
+		{name: "sum", strategy: MergeSum, userQty: 2, guestQty: 5, wantQuantity: 7},
+		{name: "sum at max is not capped", strategy: MergeSum, userQty: 49, guestQty: 50, wantQuantity: MaxQuantityPerItem},
+		{name: "sum capped at max", strategy: MergeSum, userQty: 60, guestQty: 50, wantQuantity: MaxQuantityPerItem, wantCapped: true},
+		{name: "prefer user", strategy: MergePreferUser, userQty: 2, guestQty: 5, wantQuantity: 2},
+		{name: "prefer guest", strategy: MergePreferGuest, userQty: 7, guestQty: 5, wantQuantity: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userCart := NewCart("user-123")
+			require.NoError(t, userCart.AddItem(NewCartItem("product-1", tt.userQty, 1000)))
+			guestCart := NewCart("guest-123")
+			require.NoError(t, guestCart.AddItem(NewCartItem("product-1", tt.guestQty, 1000)))
+			require.NoError(t, guestCart.AddItem(NewCartItem("product-2", 3, 500)))
+
+			result, warnings := MergeCartsWithStrategy(userCart, guestCart, tt.strategy)
+
+			require.NotNil(t, result)
+			assert.Equal(t, 2, result.ItemCount())
+			item, _ := result.FindItemByProductID("product-1")
+			require.NotNil(t, item)
+			assert.Equal(t, tt.wantQuantity, item.Quantity)
+			added, _ := result.FindItemByProductID("product-2")
+			require.NotNil(t, added)
+			assert.Equal(t, 3, added.Quantity)
+
+			if tt.wantCapped {
+				require.Len(t, warnings, 1)
+				assert.Equal(t, WarningQuantityCapped, warnings[0].Code)
+				assert.Equal(t, "product-1", warnings[0].ProductID)
+				assert.Equal(t, item.ItemID, warnings[0].ItemID)
+			} else {
+				assert.Empty(t, warnings)
+			}
+		})
+	}
+}
+
 func TestValidateQuantity(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -413,3 +551,242 @@ func TestValidateQuantity(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		price   int64
+		wantErr bool
+	}{
+		{"free item", 0, false},
+		{"valid price", 1999, false},
+		{"valid max price", MaxUnitPrice, false},
+		{"negative price", -1, true},
+		{"exceeds max price", MaxUnitPrice + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePrice(tt.price)
+			if tt.wantErr {
+				assert.True(t, errors.IsCode(err, errors.CodeValidationError), "got %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCart_AddItemRejectsInvalidPrice(t *testing.T) {
+	cart := NewCart("user-123")
+
+	err := cart.AddItem(NewCartItem("product-1", 1, -100))
+	assert.True(t, errors.IsCode(err, errors.CodeValidationError), "got %v", err)
+	assert.Equal(t, 0, cart.ItemCount())
+}
+
+func TestValidatePackSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity int
+		packSize int
+		wantErr  bool
+	}{
+		{"no pack size", 7, 0, false},
+		{"whole packs", 12, 6, false},
+		{"partial pack", 7, 6, true},
+		{"negative pack size", 6, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePackSize(tt.quantity, tt.packSize)
+			if tt.wantErr {
+				assert.True(t, errors.IsCode(err, errors.CodeValidationError), "got %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRoundUpToPackSize(t *testing.T) {
+	assert.Equal(t, 7, RoundUpToPackSize(7, 0))
+	assert.Equal(t, 6, RoundUpToPackSize(1, 6))
+	assert.Equal(t, 6, RoundUpToPackSize(6, 6))
+	assert.Equal(t, 12, RoundUpToPackSize(7, 6))
+}
+
+func TestCart_PackSize(t *testing.T) {
+	cart := NewCart("user-123")
+	item := NewCartItem("product-1", 6, 100)
+	item.PackSize = 6
+	require.NoError(t, cart.AddItem(item))
+
+	// Adding more must keep whole packs
+	more := NewCartItem("product-1", 3, 100)
+	more.PackSize = 6
+	assert.True(t, errors.IsCode(cart.AddItem(more), errors.CodeValidationError))
+
+	assert.True(t, errors.IsCode(cart.UpdateItemQuantity(item.ItemID, 8), errors.CodeValidationError))
+	assert.NoError(t, cart.UpdateItemQuantity(item.ItemID, 18))
+}
+
+func TestCart_ReorderItems(t *testing.T) {
+	cart := NewCart("user-123")
+	item1 := NewCartItem("product-1", 1, 1000)
+	item2 := NewCartItem("product-2", 1, 1000)
+	item3 := NewCartItem("product-3", 1, 1000)
+	cart.AddItem(item1)
+	cart.AddItem(item2)
+	cart.AddItem(item3)
+
+	err := cart.ReorderItems([]string{item3.ItemID, item1.ItemID, item2.ItemID})
+	require.NoError(t, err)
+
+	assert.Equal(t, item3.ItemID, cart.Items[0].ItemID)
+	assert.Equal(t, item1.ItemID, cart.Items[1].ItemID)
+	assert.Equal(t, item2.ItemID, cart.Items[2].ItemID)
+}
+
+func TestCart_ReorderItems_PartialListAppendsUnlisted(t *testing.T) {
+	cart := NewCart("user-123")
+	item1 := NewCartItem("product-1", 1, 1000)
+	item2 := NewCartItem("product-2", 1, 1000)
+	item3 := NewCartItem("product-3", 1, 1000)
+	cart.AddItem(item1)
+	cart.AddItem(item2)
+	cart.AddItem(item3)
+
+	err := cart.ReorderItems([]string{item3.ItemID})
+	require.NoError(t, err)
+
+	require.Len(t, cart.Items, 3)
+	assert.Equal(t, item3.ItemID, cart.Items[0].ItemID)
+	assert.Equal(t, item1.ItemID, cart.Items[1].ItemID)
+	assert.Equal(t, item2.ItemID, cart.Items[2].ItemID)
+}
+
+func TestCart_ReorderItems_UnknownItem(t *testing.T) {
+	cart := NewCart("user-123")
+	item1 := NewCartItem("product-1", 1, 1000)
+	cart.AddItem(item1)
+
+	err := cart.ReorderItems([]string{"non-existent", item1.ItemID})
+	assert.Error(t, err)
+	assert.Equal(t, item1.ItemID, cart.Items[0].ItemID)
+}
+
+func TestCart_RemoveItem_PreservesOrder(t *testing.T) {
+	cart := NewCart("user-123")
+	item1 := NewCartItem("product-1", 1, 1000)
+	item2 := NewCartItem("product-2", 1, 1000)
+	item3 := NewCartItem("product-3", 1, 1000)
+	cart.AddItem(item1)
+	cart.AddItem(item2)
+	cart.AddItem(item3)
+
+	require.NoError(t, cart.RemoveItem(item1.ItemID))
+
+	assert.Equal(t, item2.ItemID, cart.Items[0].ItemID)
+	assert.Equal(t, item3.ItemID, cart.Items[1].ItemID)
+}
+
+func TestCart_ClearAndRestore(t *testing.T) {
+	cart := NewCart("user-123")
+	require.NoError(t, cart.AddItem(NewCartItem("product-1", 2, 1000)))
+	require.NoError(t, cart.AddItem(NewCartItem("product-2", 1, 500)))
+
+	cart.Clear()
+	assert.Empty(t, cart.Items)
+	assert.Len(t, cart.LastClearedItems, 2)
+
+	// Re-adding a product after the clear keeps the newer line
+	require.NoError(t, cart.AddItem(NewCartItem("product-1", 5, 900)))
+
+	require.NoError(t, cart.Restore(time.Hour))
+	require.Len(t, cart.Items, 2)
+	assert.Equal(t, 5, cart.Items[0].Quantity)
+	assert.Equal(t, "product-2", cart.Items[1].ProductID)
+	assert.Empty(t, cart.LastClearedItems)
+}
+
+func TestCart_Restore_OutsideWindow(t *testing.T) {
+	clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	cart := NewCart("user-123")
+	cart.Clock = clock
+	require.NoError(t, cart.AddItem(NewCartItem("product-1", 1, 1000)))
+	cart.Clear()
+	clock.Advance(2 * time.Hour)
+
+	err := cart.Restore(time.Hour)
+	require.Error(t, err)
+	assert.True(t, errors.IsCode(err, errors.CodeCartNotFound))
+	assert.Empty(t, cart.Items)
+}
+
+func TestCart_MaxTotalValue(t *testing.T) {
+	cart := NewCart("user-123")
+	cart.MaxTotalValue = 10000
+
+	require.NoError(t, cart.AddItem(NewCartItem("product-1", 2, 3000)))
+
+	// A new line that would exceed the limit
+	err := cart.AddItem(NewCartItem("product-2", 1, 5000))
+	require.Error(t, err)
+	assert.True(t, errors.IsCode(err, errors.CodeCartValueLimitExceeded))
+	appErr, _ := errors.IsAppError(err)
+	assert.Equal(t, int64(11000), appErr.Details["attempted_total"])
+	assert.Equal(t, int64(10000), appErr.Details["max_allowed"])
+
+	// Merging into an existing line counts the combined quantity
+	err = cart.AddItem(NewCartItem("product-1", 2, 3000))
+	assert.True(t, errors.IsCode(err, errors.CodeCartValueLimitExceeded))
+
+	itemID := cart.Items[0].ItemID
+	err = cart.UpdateItemQuantity(itemID, 4)
+	assert.True(t, errors.IsCode(err, errors.CodeCartValueLimitExceeded))
+	assert.Equal(t, 2, cart.Items[0].Quantity)
+
+	// Reductions are allowed even when the cart is already over the limit
+	cart.MaxTotalValue = 5000
+	require.NoError(t, cart.UpdateItemQuantity(itemID, 1))
+	require.NoError(t, cart.RemoveItem(itemID))
+
+	// Zero is unlimited
+	cart.MaxTotalValue = 0
+	require.NoError(t, cart.AddItem(NewCartItem("product-3", 99, 999999)))
+}
+
+// cappedLinePricing caps every line at maxLine cents.
+type cappedLinePricing struct {
+	maxLine int64
+}
+
+func (p cappedLinePricing) Name() string { return "capped" }
+
+func (p cappedLinePricing) LineTotal(item *CartItem) int64 {
+	return min(item.Subtotal(), p.maxLine)
+}
+
+func (p cappedLinePricing) Total(items []CartItem) int64 {
+	return sumLines(p, items)
+}
+
+func TestCart_MaxTotalValue_PricingEngine(t *testing.T) {
+	cart := NewCart("user-123")
+	cart.MaxTotalValue = 6000
+	cart.Pricing = cappedLinePricing{maxLine: 5000}
+
+	require.NoError(t, cart.AddItem(NewCartItem("product-1", 1, 3000)))
+	itemID := cart.Items[0].ItemID
+
+	// The projected total is priced by the engine, not by unit price
+	require.NoError(t, cart.UpdateItemQuantity(itemID, 4))
+	assert.Equal(t, int64(5000), cart.TotalPrice())
+
+	require.NoError(t, cart.AddItem(NewCartItem("product-2", 1, 1000)))
+	cart.MaxTotalValue = 5500
+	err := cart.UpdateItemQuantity(cart.Items[1].ItemID, 2)
+	assert.True(t, errors.IsCode(err, errors.CodeCartValueLimitExceeded))
+}
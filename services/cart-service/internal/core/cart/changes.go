@@ -0,0 +1,141 @@
+package cart
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// changeBufferSize is how many changes a subscriber can fall behind before
+// further changes are dropped for it.
+const changeBufferSize = 16
+
+// CartChange is a compact description of a cart mutation, sent to subscribers
+// of a ChangeFeed. Item fields are set only for item-level changes.
+type CartChange struct {
+	Type          string `json:"type"`
+	UserID        string `json:"user_id"`
+	Version       int64  `json:"version"`
+	ItemID        string `json:"item_id,omitempty"`
+	ProductID     string `json:"product_id,omitempty"`
+	Quantity      int    `json:"quantity,omitempty"`
+	ItemCount     int    `json:"item_count"`
+	TotalQuantity int    `json:"total_quantity"`
+	TotalPrice    int64  `json:"total_price"`
+}
+
+// cartChange describes the cart totals after a change of the given type.
+func cartChange(c *Cart, eventType string) func() CartChange {
+	return func() CartChange {
+		return CartChange{
+			Type:          eventType,
+			UserID:        c.UserID,
+			Version:       c.Version,
+			ItemCount:     c.ItemCount(),
+			TotalQuantity: c.TotalQuantity(),
+			TotalPrice:    c.TotalPrice(),
+		}
+	}
+}
+
+// itemChange describes an item-level change along with the cart totals.
+func itemChange(c *Cart, eventType string, item *CartItem) func() CartChange {
+	totals := cartChange(c, eventType)
+	return func() CartChange {
+		change := totals()
+		change.ItemID = item.ItemID
+		change.ProductID = item.ProductID
+		change.Quantity = item.Quantity
+		return change
+	}
+}
+
+// ChangeFeed is an in-process pub/sub of cart changes, keyed by user ID. It only
+// reaches subscribers connected to this instance.
+type ChangeFeed struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan CartChange]struct{}
+}
+
+// NewChangeFeed creates an empty change feed.
+func NewChangeFeed() *ChangeFeed {
+	return &ChangeFeed{
+		subscribers: make(map[string]map[chan CartChange]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving changes to the user's cart. The
+// subscription ends when ctx is done or the returned cancel func is called,
+// after which the channel is closed.
+func (f *ChangeFeed) Subscribe(ctx context.Context, userID string) (<-chan CartChange, func()) {
+	ch := make(chan CartChange, changeBufferSize)
+
+	f.mu.Lock()
+	if f.subscribers[userID] == nil {
+		f.subscribers[userID] = make(map[chan CartChange]struct{})
+	}
+	f.subscribers[userID][ch] = struct{}{}
+	f.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subscribers[userID], ch)
+			if len(f.subscribers[userID]) == 0 {
+				delete(f.subscribers, userID)
+			}
+			close(ch)
+			f.mu.Unlock()
+		})
+	}
+
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
+
+	return ch, cancel
+}
+
+// Notify sends a change to the user's subscribers. Subscribers that have
+// fallen behind miss the change rather than block the caller.
+func (f *ChangeFeed) Notify(change CartChange) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for ch := range f.subscribers[change.UserID] {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
+
+// Subscribers returns the number of active subscriptions for a user.
+func (f *ChangeFeed) Subscribers(userID string) int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.subscribers[userID])
+}
+
+// SubscribeChanges subscribes to changes to the user's cart until ctx is done.
+func (s *Service) SubscribeChanges(ctx context.Context, userID string) (<-chan CartChange, error) {
+	if s.changes == nil {
+		return nil, errors.ErrServiceUnavailable("change feed")
+	}
+
+	ch, _ := s.changes.Subscribe(ctx, userID)
+	return ch, nil
+}
+
+// notifyChanges sends the changes behind pending events to the change feed.
+func (s *Service) notifyChanges(pending ...pendingEvent) {
+	if s.changes == nil {
+		return
+	}
+	for _, event := range pending {
+		s.changes.Notify(event.change())
+	}
+}
@@ -0,0 +1,64 @@
+package cart
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// addDedup remembers recent AddItem calls so an identical add repeated within
+// the window, typically a client retry without a stable idempotency key, is
+// applied once. Entries are only kept for this instance and are swept lazily.
+type addDedup struct {
+	window time.Duration
+	clock  Clock
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newAddDedup(window time.Duration, clock Clock) *addDedup {
+	return &addDedup{
+		window:    window,
+		clock:     clock,
+		seen:      make(map[string]time.Time),
+		lastSweep: clock.Now(),
+	}
+}
+
+// addDedupKey identifies an add by cart, product, quantity and unit price.
+func addDedupKey(cartKey string, req AddItemRequest) string {
+	return fmt.Sprintf("%s|%s|%d|%d|%d", cartKey, req.ProductID, req.Quantity, req.DecimalQuantity, req.UnitPrice)
+}
+
+// claim records key and reports whether it was not already claimed within
+// the window. A caller that gets false should treat the add as a duplicate.
+func (d *addDedup) claim(key string) bool {
+	now := d.clock.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.Sub(d.lastSweep) >= d.window {
+		for k, at := range d.seen {
+			if now.Sub(at) >= d.window {
+				delete(d.seen, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	if at, ok := d.seen[key]; ok && now.Sub(at) < d.window {
+		return false
+	}
+	d.seen[key] = now
+	return true
+}
+
+// release forgets key so a failed add can be retried immediately.
+func (d *addDedup) release(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.seen, key)
+}
@@ -0,0 +1,54 @@
+package cart
+
+import (
+	"context"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// Export page sizes
+const (
+	DefaultExportLimit = 100
+	MaxExportLimit     = 1000
+)
+
+// CartScanner pages through every stored cart.
+type CartScanner interface {
+	// ScanCarts returns up to limit carts following cursor, and the cursor of
+	// the next page. An empty cursor starts from the beginning; an empty next
+	// cursor means there are no more carts. Cursors are opaque to callers.
+	ScanCarts(ctx context.Context, cursor string, limit int) ([]*Cart, string, error)
+}
+
+// CartPage is one page of an ExportCarts call.
+type CartPage struct {
+	Carts      []*Cart `json:"carts"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// ExportCarts returns a page of all stored carts, expired ones included, for
+// support tooling. Pass the returned NextCursor to get the next page.
+func (s *Service) ExportCarts(ctx context.Context, cursor string, limit int) (*CartPage, error) {
+	if s.scanner == nil {
+		return nil, errors.ErrServiceUnavailable("cart_export")
+	}
+	if limit <= 0 {
+		limit = DefaultExportLimit
+	}
+	if limit > MaxExportLimit {
+		limit = MaxExportLimit
+	}
+
+	carts, next, err := s.scanner.ScanCarts(ctx, cursor, limit)
+	if err != nil {
+		if errors.IsCode(err, errors.CodeValidationError) {
+			return nil, err
+		}
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to scan carts", err)
+	}
+
+	for _, cart := range carts {
+		s.attach(ctx, cart)
+	}
+	return &CartPage{Carts: carts, NextCursor: next}, nil
+}
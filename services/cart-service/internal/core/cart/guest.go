@@ -0,0 +1,47 @@
+package cart
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// DefaultGuestUserIDPrefix identifies guest carts when
+// ServiceConfig.GuestUserIDPrefix is empty.
+const DefaultGuestUserIDPrefix = "guest-"
+
+// guestUserIDPrefix returns the user ID prefix of guest carts.
+func (s *Service) guestUserIDPrefix() string {
+	if s.config.GuestUserIDPrefix != "" {
+		return s.config.GuestUserIDPrefix
+	}
+	return DefaultGuestUserIDPrefix
+}
+
+// IsGuestID reports whether userID identifies a guest cart.
+func (s *Service) IsGuestID(userID string) bool {
+	return strings.HasPrefix(userID, s.guestUserIDPrefix())
+}
+
+// CreateGuestCart creates an empty cart for a new server-issued guest ID: the
+// guest prefix followed by a generated ID. The cart gets the guest expiration
+// and can later be merged into a user's cart with MergeGuestCart. Fails with
+// SERVICE_UNAVAILABLE unless ServiceConfig.AnonymousCarts is set.
+func (s *Service) CreateGuestCart(ctx context.Context) (*Cart, error) {
+	if !s.config.AnonymousCarts {
+		return nil, errors.ErrServiceUnavailable("anonymous_carts")
+	}
+
+	cart := s.newCart(ctx, s.guestUserIDPrefix()+s.ids.NewID())
+	created := cartCreatedEvent(cart)
+	if err := s.saveCart(ctx, cart, 0, created); err != nil {
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to create cart", err)
+	}
+
+	if err := s.publishEvents(ctx, created); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
@@ -0,0 +1,108 @@
+package cart
+
+import (
+	"context"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/metrics"
+)
+
+// DefaultSnapshotTTL is used when ServiceConfig.SnapshotTTL is zero.
+const DefaultSnapshotTTL = 30 * 24 * time.Hour
+
+// CartSnapshot is a compact copy of a cart after a mutation, used to
+// reconstruct how the cart evolved.
+type CartSnapshot struct {
+	TenantID  string         `json:"tenant_id"`
+	UserID    string         `json:"user_id"`
+	CartID    string         `json:"cart_id"`
+	Version   int64          `json:"version"`
+	Items     []SnapshotItem `json:"items"`
+	Total     int64          `json:"total"`
+	Timestamp time.Time      `json:"timestamp"`
+	Operation string         `json:"operation"`
+}
+
+// SnapshotItem is an item line in a CartSnapshot.
+type SnapshotItem struct {
+	ItemID    string `json:"item_id"`
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int64  `json:"unit_price"`
+}
+
+// SnapshotStore is optionally implemented by a Repository that can keep cart
+// history. Snapshots are append-only and keyed by cart version.
+type SnapshotStore interface {
+	// SaveSnapshot stores a snapshot, expiring it after ttl.
+	SaveSnapshot(ctx context.Context, snapshot CartSnapshot, ttl time.Duration) error
+
+	// ListSnapshots returns the snapshots of a user's default cart, oldest
+	// first.
+	ListSnapshots(ctx context.Context, userID string) ([]CartSnapshot, error)
+}
+
+// newSnapshot captures the cart as saved by operation.
+func newSnapshot(c *Cart, operation string, at time.Time) CartSnapshot {
+	items := make([]SnapshotItem, len(c.Items))
+	for i, item := range c.Items {
+		items[i] = SnapshotItem{
+			ItemID:    item.ItemID,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		}
+	}
+
+	return CartSnapshot{
+		TenantID:  NormalizeTenantID(c.TenantID),
+		UserID:    c.UserID,
+		CartID:    c.ID,
+		Version:   c.Version,
+		Items:     items,
+		Total:     c.TotalPrice(),
+		Timestamp: at,
+		Operation: operation,
+	}
+}
+
+// recordSnapshot stores a snapshot of the saved cart when SnapshotHistory is
+// enabled. Only default carts are snapshotted. Failures are counted in
+// metrics but never fail the operation.
+func (s *Service) recordSnapshot(ctx context.Context, operation string, c *Cart) {
+	if !s.config.SnapshotHistory || s.snapshots == nil || c.Secondary {
+		return
+	}
+
+	ttl := s.config.SnapshotTTL
+	if ttl <= 0 {
+		ttl = DefaultSnapshotTTL
+	}
+
+	status := "success"
+	if err := s.snapshots.SaveSnapshot(ctx, newSnapshot(c, operation, s.now()), ttl); err != nil {
+		status = "failed"
+	}
+
+	if s.metrics != nil {
+		s.metrics.IncrementCounter(metrics.MetricSnapshotRecordTotal, map[string]string{
+			"operation": operation,
+			"status":    status,
+		})
+	}
+}
+
+// GetCartHistory returns the snapshots recorded for a user's default cart,
+// oldest first. Snapshots past SnapshotTTL may already be gone.
+func (s *Service) GetCartHistory(ctx context.Context, userID string) ([]CartSnapshot, error) {
+	if !s.config.SnapshotHistory || s.snapshots == nil {
+		return nil, errors.ErrServiceUnavailable("cart_history")
+	}
+
+	snapshots, err := s.snapshots.ListSnapshots(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to list cart snapshots", err)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,30 @@
+package cart
+
+import "github.com/google/uuid"
+
+// IDGenerator generates cart and item IDs.
+type IDGenerator interface {
+	NewID() string
+}
+
+// UUIDGenerator generates random (version 4) UUIDs. It is the default.
+type UUIDGenerator struct{}
+
+// NewID returns a new random UUID.
+func (UUIDGenerator) NewID() string {
+	return uuid.New().String()
+}
+
+// TimeOrderedUUIDGenerator generates version 7 UUIDs, which sort by creation
+// time like ULIDs, so IDs created close together share key prefixes.
+type TimeOrderedUUIDGenerator struct{}
+
+// NewID returns a new time-ordered UUID, falling back to a random one if the
+// system random source fails.
+func (TimeOrderedUUIDGenerator) NewID() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.New().String()
+	}
+	return id.String()
+}
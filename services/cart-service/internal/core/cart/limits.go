@@ -0,0 +1,64 @@
+package cart
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
+)
+
+// limitOverrideKey marks a context whose caller may exceed standard limits.
+type limitOverrideKey struct{}
+
+// WithLimitOverride returns a context in which cart operations may hold up
+// to ServiceConfig.AdminMaxItemsPerCart items instead of MaxItemsPerCart.
+// Callers must only set it for authorized admins.
+func WithLimitOverride(ctx context.Context) context.Context {
+	return context.WithValue(ctx, limitOverrideKey{}, true)
+}
+
+// LimitOverrideFromContext reports whether ctx was created by WithLimitOverride.
+func LimitOverrideFromContext(ctx context.Context) bool {
+	override, _ := ctx.Value(limitOverrideKey{}).(bool)
+	return override
+}
+
+// maxItemsPerCart returns the configured item limit for regular requests.
+func (s *Service) maxItemsPerCart() int {
+	if s.config.MaxItemsPerCart > 0 {
+		return s.config.MaxItemsPerCart
+	}
+	return MaxItemsPerCart
+}
+
+// itemLimit returns the item limit for a request: AdminMaxItemsPerCart when
+// ctx carries a limit override and it is higher, the standard limit otherwise.
+func (s *Service) itemLimit(ctx context.Context) int {
+	limit := s.maxItemsPerCart()
+	if LimitOverrideFromContext(ctx) && s.config.AdminMaxItemsPerCart > limit {
+		return s.config.AdminMaxItemsPerCart
+	}
+	return limit
+}
+
+// ItemLimitWarnings returns a warning when c holds at least the configured
+// SoftItemLimit items. Adding items stays possible up to the hard limit.
+func (s *Service) ItemLimitWarnings(ctx context.Context, c *Cart) []Warning {
+	if c == nil || s.config.SoftItemLimit <= 0 || len(c.Items) < s.config.SoftItemLimit {
+		return nil
+	}
+
+	return []Warning{{
+		Code:    WarningCartNearingLimit,
+		Message: fmt.Sprintf("Cart holds %d of at most %d items", len(c.Items), s.itemLimit(ctx)),
+	}}
+}
+
+// recordLimitOverride audits a change that left c above the standard item
+// limit under a limit override. The change itself is already recorded, so no
+// snapshot is taken.
+func (s *Service) recordLimitOverride(ctx context.Context, c *Cart) {
+	if LimitOverrideFromContext(ctx) && len(c.Items) > s.maxItemsPerCart() {
+		s.recordAuditEntries(ctx, audit.OpOverrideItemLimit, c)
+	}
+}
@@ -0,0 +1,129 @@
+package cart
+
+import (
+	"context"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// DefaultMaxCartsPerUser is used when ServiceConfig.MaxCartsPerUser is zero.
+// It includes the default cart.
+const DefaultMaxCartsPerUser = 10
+
+// MultiCartRepository is optionally implemented by a Repository that can
+// store more than one cart per user. The default cart stays addressed by user
+// ID through Repository; additional carts are addressed by their cart ID and
+// saved through Repository like any other cart.
+type MultiCartRepository interface {
+	GetCartByID(ctx context.Context, userID, cartID string) (*Cart, error)
+	ListCarts(ctx context.Context, userID string) ([]*Cart, error)
+	DeleteCartByID(ctx context.Context, userID, cartID string) error
+}
+
+type cartIDKey struct{}
+
+// WithCartID selects one of the user's additional carts for the service
+// operations called with the returned context. Without a cart ID, or with an
+// empty one, operations act on the user's default cart.
+func WithCartID(ctx context.Context, cartID string) context.Context {
+	return context.WithValue(ctx, cartIDKey{}, cartID)
+}
+
+// CartIDFromContext returns the cart ID selected with WithCartID, or "" for
+// the default cart.
+func CartIDFromContext(ctx context.Context) string {
+	cartID, _ := ctx.Value(cartIDKey{}).(string)
+	return cartID
+}
+
+// cartKey identifies the cart selected by ctx among all tenants' and users'
+// carts, for per-cart state kept in memory.
+func cartKey(ctx context.Context, userID string) string {
+	key := TenantIDFromContext(ctx) + "#" + userID
+	if cartID := CartIDFromContext(ctx); cartID != "" {
+		return key + "#" + cartID
+	}
+	return key
+}
+
+// maxCartsPerUser returns the configured cart limit per user.
+func (s *Service) maxCartsPerUser() int {
+	if s.config.MaxCartsPerUser > 0 {
+		return s.config.MaxCartsPerUser
+	}
+	return DefaultMaxCartsPerUser
+}
+
+// CreateCart creates an additional, named cart for a user. Operations on it
+// are selected with WithCartID. The user's default cart counts towards
+// MaxCartsPerUser once it exists; expired carts don't.
+func (s *Service) CreateCart(ctx context.Context, userID, name string) (*Cart, error) {
+	if s.multi == nil {
+		return nil, errors.ErrServiceUnavailable("multi_cart")
+	}
+
+	cart := s.newCart(ctx, userID)
+	cart.Secondary = true
+	if err := cart.SetName(name); err != nil {
+		return nil, err
+	}
+
+	carts, err := s.ListCarts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if limit := s.maxCartsPerUser(); len(carts) >= limit {
+		return nil, errors.ErrCartCountLimitExceeded(len(carts), limit)
+	}
+
+	created := cartCreatedEvent(cart)
+	if err := s.saveCart(ctx, cart, 0, created); err != nil {
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to create cart", err)
+	}
+
+	if err := s.publishEvents(ctx, created); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// ListCarts returns the user's unexpired carts, default cart included.
+func (s *Service) ListCarts(ctx context.Context, userID string) ([]*Cart, error) {
+	if s.multi == nil {
+		return nil, errors.ErrServiceUnavailable("multi_cart")
+	}
+
+	carts, err := s.multi.ListCarts(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to list carts", err)
+	}
+
+	active := make([]*Cart, 0, len(carts))
+	for _, cart := range carts {
+		if !s.attach(ctx, cart).IsExpired() {
+			active = append(active, cart)
+		}
+	}
+	return active, nil
+}
+
+// getSelectedCart loads the additional cart selected by cartID.
+func (s *Service) getSelectedCart(ctx context.Context, userID, cartID string) (*Cart, error) {
+	if s.multi == nil {
+		return nil, errors.ErrServiceUnavailable("multi_cart")
+	}
+
+	cart, err := s.multi.GetCartByID(ctx, userID, cartID)
+	if err != nil {
+		if errors.IsCode(err, errors.CodeCartNotFound) {
+			return nil, err
+		}
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to get cart", err)
+	}
+
+	if s.attach(ctx, cart).IsExpired() {
+		return nil, errors.ErrCartExpired(userID).WithDetail("cart_id", cartID)
+	}
+	return s.pruneStaleItems(ctx, cart), nil
+}
@@ -0,0 +1,135 @@
+package cart
+
+import (
+	"context"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// Patch operations, a subset of RFC 6902 JSON Patch addressed by item ID
+const (
+	PatchOpAdd     = "add"     // add an item (merged into an existing line for the product)
+	PatchOpReplace = "replace" // set an item's quantity
+	PatchOpRemove  = "remove"  // remove an item
+	PatchOpTest    = "test"    // require the cart to be at a version
+)
+
+// PatchOperation is a single change within a PatchCart call.
+type PatchOperation struct {
+	Op       string
+	ItemID   string         // replace, remove
+	Item     AddItemRequest // add
+	Quantity int            // replace
+	Version  int64          // test
+}
+
+// PatchCart applies operations to a user's cart atomically: either every
+// operation succeeds and the cart is saved once with a single version
+// increment, or the cart is left unchanged. Errors carry the failing
+// operation's index in the "operation" detail.
+func (s *Service) PatchCart(ctx context.Context, userID string, ops []PatchOperation) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		return s.patchCart(ctx, userID, ops)
+	})
+}
+
+// patchCart is PatchCart without the per-cart bulkhead.
+func (s *Service) patchCart(ctx context.Context, userID string, ops []PatchOperation) (*Cart, error) {
+	cart, _, err := s.GetOrCreateCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+	cart.MaxTotalValue = s.config.MaxCartTotalValue
+	cart.MaxItems = s.itemLimit(ctx)
+
+	pending := make([]pendingEvent, 0, len(ops))
+	for i, op := range ops {
+		event, err := s.applyPatchOperation(ctx, cart, op)
+		if err != nil {
+			if appErr, ok := errors.IsAppError(err); ok {
+				return nil, appErr.WithDetail("operation", i)
+			}
+			return nil, err
+		}
+		if event != nil {
+			pending = append(pending, *event)
+		}
+	}
+
+	// Save once with optimistic locking so concurrent changes aren't overwritten
+	expectedVersion := cart.Version
+	cart.IncrementVersion()
+
+	if err := s.saveCart(ctx, cart, expectedVersion, pending...); err != nil {
+		return nil, saveError(err, "failed to save cart")
+	}
+	s.recordAudit(ctx, audit.OpPatchCart, cart, pending...)
+	s.recordLimitOverride(ctx, cart)
+
+	// Publish events
+	if err := s.publishEvents(ctx, pending...); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// applyPatchOperation applies one operation to the cart and returns the event it produces, if any.
+func (s *Service) applyPatchOperation(ctx context.Context, cart *Cart, op PatchOperation) (*pendingEvent, error) {
+	switch op.Op {
+	case PatchOpAdd:
+		if err := s.checkProductAllowed(ctx, op.Item.ProductID); err != nil {
+			return nil, err
+		}
+		mode, err := op.Item.mode()
+		if err != nil {
+			return nil, err
+		}
+		item := s.newCartItem(op.Item)
+		if err := cart.AddItemWithMode(item, mode); err != nil {
+			return nil, err
+		}
+		event := itemAddedEvent(cart, item)
+		return &event, nil
+
+	case PatchOpReplace:
+		existing, _ := cart.FindItem(op.ItemID)
+		if existing == nil {
+			return nil, errors.ErrItemNotFound(cart.UserID, op.ItemID)
+		}
+		prevQuantity := existing.Quantity
+		if err := cart.UpdateItemQuantity(op.ItemID, op.Quantity); err != nil {
+			return nil, err
+		}
+		// Copy the item; later operations may move it within the slice
+		item, _ := cart.FindItem(op.ItemID)
+		updated := *item
+		event := itemUpdatedEvent(cart, &updated, prevQuantity)
+		return &event, nil
+
+	case PatchOpRemove:
+		existing, _ := cart.FindItem(op.ItemID)
+		if existing == nil {
+			return nil, errors.ErrItemNotFound(cart.UserID, op.ItemID)
+		}
+		productID := existing.ProductID
+		if err := cart.RemoveItem(op.ItemID); err != nil {
+			return nil, err
+		}
+		event := itemRemovedEvent(cart, op.ItemID, productID)
+		return &event, nil
+
+	case PatchOpTest:
+		if cart.Version != op.Version {
+			return nil, errors.ErrConflict(op.Version, cart.Version)
+		}
+		return nil, nil
+
+	default:
+		return nil, errors.ErrValidation("Unsupported patch operation", map[string]interface{}{"op": op.Op})
+	}
+}
@@ -0,0 +1,46 @@
+package cart
+
+import "context"
+
+// Product policy denial reasons
+const (
+	PolicyReasonDenied         = "product is blocked"
+	PolicyReasonNotAllowlisted = "product is not on the allowlist"
+)
+
+// StaticProductPolicy is a ProductPolicy backed by fixed product ID lists.
+// The denylist takes precedence; when the allowlist is non-empty, only the
+// products on it are allowed.
+type StaticProductPolicy struct {
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+// NewStaticProductPolicy creates a policy from allowlisted and denylisted product IDs.
+func NewStaticProductPolicy(allow, deny []string) *StaticProductPolicy {
+	return &StaticProductPolicy{
+		allow: toSet(allow),
+		deny:  toSet(deny),
+	}
+}
+
+// IsAllowed implements ProductPolicy.
+func (p *StaticProductPolicy) IsAllowed(ctx context.Context, productID string) (bool, string) {
+	if _, denied := p.deny[productID]; denied {
+		return false, PolicyReasonDenied
+	}
+	if len(p.allow) > 0 {
+		if _, allowed := p.allow[productID]; !allowed {
+			return false, PolicyReasonNotAllowlisted
+		}
+	}
+	return true, ""
+}
+
+func toSet(ids []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
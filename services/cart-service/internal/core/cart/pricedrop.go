@@ -0,0 +1,72 @@
+package cart
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// PriceDrop is a cart item whose catalog price is now lower than the stored price.
+type PriceDrop struct {
+	ItemID    string `json:"item_id"`
+	ProductID string `json:"product_id"`
+	OldPrice  int64  `json:"old_price"`
+	NewPrice  int64  `json:"new_price"`
+	Delta     int64  `json:"delta"` // Decrease per unit, in cents
+}
+
+// PriceDropReport lists the items that dropped in price, and the items whose
+// current price could not be looked up.
+type PriceDropReport struct {
+	Items         []PriceDrop `json:"items"`
+	FailedLookups []string    `json:"failed_lookups,omitempty"` // Item IDs
+}
+
+// PriceDrops compares each item's stored price with the current catalog price
+// and returns the items that became cheaper. Lookup failures are reported per
+// item rather than failing the call. The cart is not modified.
+func (s *Service) PriceDrops(ctx context.Context, userID string) (*PriceDropReport, error) {
+	if s.prices == nil {
+		return nil, errors.ErrServiceUnavailable("price lookup")
+	}
+
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	type lookup struct {
+		price int64
+		err   error
+	}
+	lookups := make([]lookup, len(cart.Items))
+	var wg sync.WaitGroup
+	for i, item := range cart.Items {
+		wg.Add(1)
+		go func(i int, productID string) {
+			defer wg.Done()
+			price, err := s.prices.GetCurrentPrice(ctx, productID)
+			lookups[i] = lookup{price, err}
+		}(i, item.ProductID)
+	}
+	wg.Wait()
+
+	report := &PriceDropReport{Items: make([]PriceDrop, 0)}
+	for i, item := range cart.Items {
+		if lookups[i].err != nil {
+			report.FailedLookups = append(report.FailedLookups, item.ItemID)
+			continue
+		}
+		if current := lookups[i].price; current < item.UnitPrice {
+			report.Items = append(report.Items, PriceDrop{
+				ItemID:    item.ItemID,
+				ProductID: item.ProductID,
+				OldPrice:  item.UnitPrice,
+				NewPrice:  current,
+				Delta:     item.UnitPrice - current,
+			})
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,92 @@
+package cart
+
+import (
+	"context"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/features"
+)
+
+// PricingEngine computes line prices and the merchandise total of a cart.
+// Cart.TotalPrice routes through it, and with it summaries, responses and
+// events.
+type PricingEngine interface {
+	// Name identifies the engine; it is also the FlagNewPricingEngine
+	// variant that selects it.
+	Name() string
+
+	// LineTotal returns the price of the line in cents.
+	LineTotal(item *CartItem) int64
+
+	// Total returns the merchandise total of the lines in cents, after any
+	// cart-level discounts.
+	Total(items []CartItem) int64
+}
+
+// Pricing engines
+const (
+	PricingEngineLegacy  = "legacy"
+	PricingEngineRounded = "rounded"
+)
+
+// LegacyPricing is the default engine: the line subtotals summed, with
+// weighted lines truncated to the cent.
+type LegacyPricing struct{}
+
+// Name returns PricingEngineLegacy.
+func (LegacyPricing) Name() string { return PricingEngineLegacy }
+
+// LineTotal returns item.Subtotal().
+func (LegacyPricing) LineTotal(item *CartItem) int64 {
+	return item.Subtotal()
+}
+
+// Total sums the line totals.
+func (e LegacyPricing) Total(items []CartItem) int64 {
+	return sumLines(e, items)
+}
+
+// RoundedPricing rounds weighted lines to the nearest cent, half up, instead
+// of truncating them. Other lines are priced as by LegacyPricing.
+type RoundedPricing struct{}
+
+// Name returns PricingEngineRounded.
+func (RoundedPricing) Name() string { return PricingEngineRounded }
+
+// LineTotal returns the line price, rounding weighted lines half up.
+func (RoundedPricing) LineTotal(item *CartItem) int64 {
+	if item.IsWeighted() {
+		return (item.UnitPrice*item.DecimalQuantity + MilliUnitsPerUnit/2) / MilliUnitsPerUnit
+	}
+	return item.Subtotal()
+}
+
+// Total sums the line totals.
+func (e RoundedPricing) Total(items []CartItem) int64 {
+	return sumLines(e, items)
+}
+
+// sumLines sums the line totals of items under engine.
+func sumLines(engine PricingEngine, items []CartItem) int64 {
+	var total int64
+	for i := range items {
+		total += engine.LineTotal(&items[i])
+	}
+	return total
+}
+
+// pricingEngines are the engines FlagNewPricingEngine variants select.
+var pricingEngines = map[string]PricingEngine{
+	PricingEngineLegacy:  LegacyPricing{},
+	PricingEngineRounded: RoundedPricing{},
+}
+
+// pricingEngine returns the engine for the user's FlagNewPricingEngine
+// variant, or LegacyPricing without feature flags or for an unknown variant.
+func (s *Service) pricingEngine(ctx context.Context, userID string) PricingEngine {
+	if s.flags != nil {
+		if engine, ok := pricingEngines[s.flags.GetVariant(ctx, features.FlagNewPricingEngine, userID)]; ok {
+			return engine
+		}
+	}
+	return LegacyPricing{}
+}
@@ -0,0 +1,85 @@
+package cart
+
+import (
+	"context"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+)
+
+// DefaultPruneInterval is how often a cart is checked for stale items when
+// MaxItemAge is set.
+const DefaultPruneInterval = time.Hour
+
+// StaleItemAction is what pruning does with items older than MaxItemAge.
+type StaleItemAction string
+
+const (
+	// StaleItemRemove removes stale items (default).
+	StaleItemRemove StaleItemAction = "remove"
+	// StaleItemFlag keeps stale items but marks them Stale.
+	StaleItemFlag StaleItemAction = "flag"
+)
+
+// StaleItems returns the items added before cutoff. With StaleItemFlag,
+// items already flagged are skipped.
+func (c *Cart) StaleItems(cutoff time.Time, action StaleItemAction) []CartItem {
+	var stale []CartItem
+	for _, item := range c.Items {
+		if !item.AddedAt.Before(cutoff) || (action == StaleItemFlag && item.Stale) {
+			continue
+		}
+		stale = append(stale, item)
+	}
+	return stale
+}
+
+// pruneStaleItems removes or flags the items of cart older than MaxItemAge,
+// checking each cart at most once per PruneInterval on this instance. Pruning
+// is best-effort: if the save fails, e.g. because the cart changed
+// concurrently, the cart is returned as loaded and pruned on a later check.
+func (s *Service) pruneStaleItems(ctx context.Context, cart *Cart) *Cart {
+	if s.pruneChecks == nil || !s.pruneChecks.claim(cartKey(ctx, cart.UserID)) {
+		return cart
+	}
+
+	action := s.config.StaleItemAction
+	if action != StaleItemFlag {
+		action = StaleItemRemove
+	}
+	cutoff := s.now().Add(-s.config.MaxItemAge)
+	stale := cart.StaleItems(cutoff, action)
+	if len(stale) == 0 {
+		return cart
+	}
+
+	pruned := *cart
+	pruned.Items = make([]CartItem, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		if !item.AddedAt.Before(cutoff) {
+			pruned.Items = append(pruned.Items, item)
+		} else if action == StaleItemFlag {
+			item.Stale = true
+			pruned.Items = append(pruned.Items, item)
+		}
+	}
+
+	expectedVersion := pruned.Version
+	pruned.IncrementVersion()
+	event := itemsPrunedEvent(&pruned, stale, action)
+	if err := s.saveCart(ctx, &pruned, expectedVersion, event); err != nil {
+		return cart
+	}
+	s.recordAudit(ctx, audit.OpPruneItems, &pruned, event)
+
+	// The prune is saved; a failed publish shouldn't fail the read
+	_ = s.publishEvents(ctx, event)
+	return &pruned
+}
+
+func itemsPrunedEvent(c *Cart, items []CartItem, action StaleItemAction) pendingEvent {
+	return pendingEvent{c.UserID, events.EventTypeItemsPruned, cartChange(c, events.EventTypeItemsPruned), func(ctx context.Context, p EventPublisher) error {
+		return p.PublishItemsPruned(ctx, c, items, action)
+	}}
+}
@@ -0,0 +1,246 @@
+package cart
+
+import (
+	"context"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/features"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/metrics"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EventPublishMode controls how event publishing failures affect cart operations.
+type EventPublishMode string
+
+// Event publish modes
+const (
+	// EventPublishModeAsync ignores publish failures; the operation still succeeds.
+	EventPublishModeAsync EventPublishMode = "async"
+	// EventPublishModeSync retries publishing and fails the operation if it still fails.
+	EventPublishModeSync EventPublishMode = "sync"
+	// EventPublishModeOutbox writes events in the same transaction as the cart for
+	// later dispatch. Without an OutboxRepository it behaves like async.
+	EventPublishModeOutbox EventPublishMode = "outbox"
+)
+
+// OutboxRepository saves a cart together with the events it produced in a single
+// transaction, so events aren't lost when the publisher is unavailable.
+type OutboxRepository interface {
+	SaveCartWithOutbox(ctx context.Context, cart *Cart, expectedVersion int64, events []events.Event) error
+}
+
+// pendingEvent is an event produced by a cart operation. change describes it
+// for the change feed; it is evaluated once the cart has been saved.
+type pendingEvent struct {
+	userID    string
+	eventType string
+	change    func() CartChange
+	send      func(ctx context.Context, p EventPublisher) error
+}
+
+func cartCreatedEvent(c *Cart) pendingEvent {
+	return pendingEvent{c.UserID, events.EventTypeCartCreated, cartChange(c, events.EventTypeCartCreated), func(ctx context.Context, p EventPublisher) error {
+		return p.PublishCartCreated(ctx, c)
+	}}
+}
+
+func itemAddedEvent(c *Cart, item *CartItem) pendingEvent {
+	return pendingEvent{c.UserID, events.EventTypeItemAdded, itemChange(c, events.EventTypeItemAdded, item), func(ctx context.Context, p EventPublisher) error {
+		return p.PublishItemAdded(ctx, c, item)
+	}}
+}
+
+func itemUpdatedEvent(c *Cart, item *CartItem, prevQuantity int) pendingEvent {
+	return pendingEvent{c.UserID, events.EventTypeItemUpdated, itemChange(c, events.EventTypeItemUpdated, item), func(ctx context.Context, p EventPublisher) error {
+		return p.PublishItemUpdated(ctx, c, item, prevQuantity)
+	}}
+}
+
+func itemRemovedEvent(c *Cart, itemID, productID string) pendingEvent {
+	return pendingEvent{c.UserID, events.EventTypeItemRemoved, itemChange(c, events.EventTypeItemRemoved, &CartItem{ItemID: itemID, ProductID: productID}), func(ctx context.Context, p EventPublisher) error {
+		return p.PublishItemRemoved(ctx, c, itemID, productID)
+	}}
+}
+
+func cartClearedEvent(c *Cart, itemsRemoved int, previousTotal int64) pendingEvent {
+	return pendingEvent{c.UserID, events.EventTypeCartCleared, cartChange(c, events.EventTypeCartCleared), func(ctx context.Context, p EventPublisher) error {
+		return p.PublishCartCleared(ctx, c, itemsRemoved, previousTotal)
+	}}
+}
+
+func cartMergedEvent(c *Cart, guestID string, itemsMerged int) pendingEvent {
+	return pendingEvent{c.UserID, events.EventTypeCartMerged, cartChange(c, events.EventTypeCartMerged), func(ctx context.Context, p EventPublisher) error {
+		return p.PublishCartMerged(ctx, c, guestID, itemsMerged)
+	}}
+}
+
+func priceCorrectedEvent(c *Cart, item *CartItem, previousPrice int64) pendingEvent {
+	return pendingEvent{c.UserID, events.EventTypePriceCorrected, itemChange(c, events.EventTypePriceCorrected, item), func(ctx context.Context, p EventPublisher) error {
+		return p.PublishPriceCorrected(ctx, c, item, previousPrice)
+	}}
+}
+
+func itemsReorderedEvent(c *Cart) pendingEvent {
+	return pendingEvent{c.UserID, events.EventTypeItemsReordered, cartChange(c, events.EventTypeItemsReordered), func(ctx context.Context, p EventPublisher) error {
+		return p.PublishItemsReordered(ctx, c)
+	}}
+}
+
+// publishingEnabled reports whether events are published for the user's
+// carts: FlagEventPublishing when feature flags are set, PublishEvents
+// otherwise.
+func (s *Service) publishingEnabled(ctx context.Context, userID string) bool {
+	if s.flags != nil {
+		return s.flags.IsEnabled(ctx, features.FlagEventPublishing, userID)
+	}
+	return s.config.PublishEvents
+}
+
+// optimisticLocking reports whether saves of the user's carts check the
+// expected version: FlagOptimisticLocking when feature flags are set, unless
+// DisableOptimisticLocking otherwise.
+func (s *Service) optimisticLocking(ctx context.Context, userID string) bool {
+	if s.flags != nil {
+		return s.flags.IsEnabled(ctx, features.FlagOptimisticLocking, userID)
+	}
+	return !s.config.DisableOptimisticLocking
+}
+
+// outboxEnabled reports whether the user's events are written to the outbox
+// instead of published.
+func (s *Service) outboxEnabled(ctx context.Context, userID string) bool {
+	return s.config.EventPublishMode == EventPublishModeOutbox && s.outbox != nil && s.publishingEnabled(ctx, userID)
+}
+
+// saveCart saves the cart, checking expectedVersion when it is non-zero and
+// optimistic locking applies to the user. In outbox mode the pending events
+// are written in the same transaction.
+func (s *Service) saveCart(ctx context.Context, cart *Cart, expectedVersion int64, pending ...pendingEvent) error {
+	if !s.optimisticLocking(ctx, cart.UserID) {
+		expectedVersion = 0
+	}
+
+	if len(pending) > 0 && s.outboxEnabled(ctx, cart.UserID) {
+		recorder := events.NewRecorder()
+		publisher := s.newOutboxPublisher(recorder)
+		for _, event := range pending {
+			if err := event.send(ctx, publisher); err != nil {
+				return err
+			}
+		}
+		ctx, span := startSpan(ctx, "cart.repository.SaveCartWithOutbox", attribute.Int("events.count", len(pending)))
+		err := s.outbox.SaveCartWithOutbox(ctx, cart, expectedVersion, recorder.Events())
+		endSpan(span, err)
+		return err
+	}
+
+	if expectedVersion > 0 {
+		return s.repo.SaveCartWithVersion(ctx, cart, expectedVersion)
+	}
+	return s.repo.SaveCart(ctx, cart)
+}
+
+// saveError passes through conflicts and oversized carts, which callers retry
+// or report as they are, and wraps any other save failure as a persistence
+// error.
+func saveError(err error, message string) error {
+	if errors.IsCode(err, errors.CodeConflict) || errors.IsCode(err, errors.CodeCartTooLarge) {
+		return err
+	}
+	return errors.Wrap(errors.CodePersistenceError, message, err)
+}
+
+// saveMergedCart saves a merged cart and deletes the guest cart in one
+// transaction, writing the pending events to the outbox in outbox mode.
+func (s *Service) saveMergedCart(ctx context.Context, merged *Cart, expectedVersion int64, guest *Cart, pending ...pendingEvent) error {
+	if !s.optimisticLocking(ctx, merged.UserID) {
+		expectedVersion = 0
+	}
+
+	var evts []events.Event
+	if len(pending) > 0 && s.outboxEnabled(ctx, merged.UserID) {
+		recorder := events.NewRecorder()
+		publisher := s.newOutboxPublisher(recorder)
+		for _, event := range pending {
+			if err := event.send(ctx, publisher); err != nil {
+				return err
+			}
+		}
+		evts = recorder.Events()
+	}
+
+	ctx, span := startSpan(ctx, "cart.repository.SaveMergedCart",
+		attribute.Int64("cart.version", merged.Version),
+		attribute.Int64("cart.expected_version", expectedVersion),
+		attribute.Int("events.count", len(evts)),
+	)
+	err := s.merges.SaveMergedCart(ctx, merged, expectedVersion, guest.UserID, guest.Version, evts)
+	endSpan(span, err)
+	return err
+}
+
+// publishEvents notifies the change feed and publishes events according to the
+// configured EventPublishMode.
+// In sync mode the cart has already been saved when a failure is returned, so
+// callers surface the error without rolling back. Events already written to the
+// outbox are left for the dispatcher.
+func (s *Service) publishEvents(ctx context.Context, pending ...pendingEvent) error {
+	s.notifyChanges(pending...)
+
+	if len(pending) == 0 || s.publisher == nil {
+		return nil
+	}
+	if userID := pending[0].userID; !s.publishingEnabled(ctx, userID) || s.outboxEnabled(ctx, userID) {
+		return nil
+	}
+
+	for _, event := range pending {
+		if err := s.publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish sends a single event, retrying in sync mode.
+func (s *Service) publish(ctx context.Context, event pendingEvent) (err error) {
+	ctx, span := startSpan(ctx, "cart.publisher.Publish", attribute.String("event.type", event.eventType))
+	defer func() { endSpan(span, err) }()
+
+	send := func() error {
+		return event.send(ctx, s.publisher)
+	}
+
+	switch s.config.EventPublishMode {
+	case EventPublishModeSync:
+		retry := s.config.EventPublishRetry
+		if retry.MaxAttempts <= 0 {
+			retry = resilience.DefaultRetryConfig()
+		}
+		if err := resilience.Retry(ctx, retry, send); err != nil {
+			s.recordPublish(event.eventType, "failed")
+			return errors.ErrEventPublish(event.eventType, err)
+		}
+	default:
+		if err := send(); err != nil {
+			s.recordPublish(event.eventType, "failed")
+			return nil
+		}
+	}
+
+	s.recordPublish(event.eventType, "success")
+	return nil
+}
+
+// recordPublish increments the event publish counter when metrics are configured.
+func (s *Service) recordPublish(eventType, status string) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.IncrementCounter(metrics.MetricEventPublishTotal, map[string]string{
+		"event_type": eventType,
+		"status":     status,
+	})
+}
@@ -0,0 +1,47 @@
+package cart
+
+import (
+	"context"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// EventReplayer is implemented by event publishers that can re-emit the events
+// describing a cart's current state.
+type EventReplayer interface {
+	// ReplayCart publishes the replayed events and returns how many were sent.
+	ReplayCart(ctx context.Context, cart *Cart) (int, error)
+}
+
+// ReplayResult summarizes a ReplayEvents call.
+type ReplayResult struct {
+	UserID          string `json:"user_id"`
+	CartID          string `json:"cart_id"`
+	EventsPublished int    `json:"events_published"`
+}
+
+// ReplayEvents re-publishes the events describing a cart's current state for
+// consumers that missed the originals. The replayed events are flagged so
+// consumers can deduplicate them; the cart itself is not changed.
+func (s *Service) ReplayEvents(ctx context.Context, userID string) (*ReplayResult, error) {
+	replayer, ok := s.publisher.(EventReplayer)
+	if !ok {
+		return nil, errors.ErrServiceUnavailable("event_replay")
+	}
+
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	published, err := replayer.ReplayCart(ctx, cart)
+	if err != nil {
+		return nil, errors.ErrEventPublish("replay", err)
+	}
+
+	return &ReplayResult{
+		UserID:          userID,
+		CartID:          cart.ID,
+		EventsPublished: published,
+	}, nil
+}
@@ -0,0 +1,114 @@
+package cart
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// Bulk reprice limits
+const (
+	DefaultRepriceLimit = 500
+	MaxRepriceLimit     = 5000
+	repriceBatchSize    = 25
+)
+
+// ProductCartFinder finds carts containing a product.
+type ProductCartFinder interface {
+	// FindCartsByProduct returns up to limit carts holding the product.
+	FindCartsByProduct(ctx context.Context, productID string, limit int) ([]*Cart, error)
+}
+
+// RepriceResult summarizes a bulk price refresh.
+type RepriceResult struct {
+	ProductID    string `json:"product_id"`
+	UnitPrice    int64  `json:"unit_price"`
+	CartsMatched int    `json:"carts_matched"`
+	CartsUpdated int    `json:"carts_updated"`
+	CartsFailed  int    `json:"carts_failed"`
+}
+
+// RepriceProduct updates the stored price of a product across the carts holding it.
+// Up to limit carts are processed in batches; each updated cart has its version
+// bumped and emits a price_corrected event. Carts that fail to save (e.g. due to a
+// concurrent update) are counted in the result rather than failing the operation.
+func (s *Service) RepriceProduct(ctx context.Context, productID string, unitPrice int64, limit int) (*RepriceResult, error) {
+	if s.products == nil {
+		return nil, errors.ErrServiceUnavailable("product index")
+	}
+	if limit <= 0 {
+		limit = DefaultRepriceLimit
+	}
+	if limit > MaxRepriceLimit {
+		limit = MaxRepriceLimit
+	}
+
+	carts, err := s.products.FindCartsByProduct(ctx, productID, limit)
+	if err != nil {
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to find carts for product", err)
+	}
+
+	result := &RepriceResult{
+		ProductID:    productID,
+		UnitPrice:    unitPrice,
+		CartsMatched: len(carts),
+	}
+
+	var mu sync.Mutex
+	for start := 0; start < len(carts); start += repriceBatchSize {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		end := min(start+repriceBatchSize, len(carts))
+
+		var wg sync.WaitGroup
+		for _, c := range carts[start:end] {
+			wg.Add(1)
+			go func(c *Cart) {
+				defer wg.Done()
+				updated, err := s.repriceCart(ctx, c, productID, unitPrice)
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err != nil:
+					result.CartsFailed++
+				case updated:
+					result.CartsUpdated++
+				}
+			}(c)
+		}
+		wg.Wait()
+	}
+
+	return result, nil
+}
+
+// repriceCart applies the new price to a single cart and reports whether it changed.
+func (s *Service) repriceCart(ctx context.Context, c *Cart, productID string, unitPrice int64) (bool, error) {
+	if c.IsExpired() {
+		return false, nil
+	}
+
+	item, previousPrice := c.RepriceProduct(productID, unitPrice)
+	if item == nil {
+		return false, nil
+	}
+
+	expectedVersion := c.Version
+	c.IncrementVersion()
+	corrected := priceCorrectedEvent(c, item, previousPrice)
+	if err := s.saveCart(ctx, c, expectedVersion, corrected); err != nil {
+		return false, err
+	}
+	s.recordAudit(ctx, audit.OpRepriceItem, c, corrected)
+
+	if err := s.publishEvents(ctx, corrected); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -2,9 +2,17 @@ package cart
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/features"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/metrics"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
 )
 
 // Repository defines the interface for cart persistence.
@@ -15,18 +23,167 @@ type Repository interface {
 	DeleteCart(ctx context.Context, userID string) error
 }
 
+// MergeRepository is optionally implemented by a Repository that can save a
+// merged cart and delete the guest cart it absorbed in a single transaction.
+// The guest cart is deleted only if it is still at guestVersion, so a retried
+// or concurrent merge can't apply the same guest items twice. Events, when
+// given, are written to the outbox in the same transaction.
+type MergeRepository interface {
+	SaveMergedCart(ctx context.Context, merged *Cart, expectedVersion int64, guestID string, guestVersion int64, events []events.Event) error
+}
+
 // EventPublisher defines the interface for publishing cart events.
 type EventPublisher interface {
 	PublishCartCreated(ctx context.Context, cart *Cart) error
 	PublishItemAdded(ctx context.Context, cart *Cart, item *CartItem) error
-	PublishItemRemoved(ctx context.Context, cart *Cart, itemID string) error
-	PublishItemUpdated(ctx context.Context, cart *Cart, item *CartItem) error
-	PublishCartCleared(ctx context.Context, cart *Cart) error
+	PublishItemRemoved(ctx context.Context, cart *Cart, itemID, productID string) error
+	PublishItemUpdated(ctx context.Context, cart *Cart, item *CartItem, prevQuantity int) error
+	PublishCartCleared(ctx context.Context, cart *Cart, itemsRemoved int, previousTotal int64) error
+	PublishPriceCorrected(ctx context.Context, cart *Cart, item *CartItem, previousPrice int64) error
+	PublishCartMerged(ctx context.Context, cart *Cart, guestID string, itemsMerged int) error
+	PublishItemsPruned(ctx context.Context, cart *Cart, items []CartItem, action StaleItemAction) error
+	PublishItemsReordered(ctx context.Context, cart *Cart) error
 }
 
 // ServiceConfig holds configuration for the cart service.
 type ServiceConfig struct {
+	// PublishEvents enables event publishing. With feature flags set,
+	// FlagEventPublishing decides per user instead.
 	PublishEvents bool
+
+	// DisableOptimisticLocking saves carts without checking the version they
+	// were read at, so the last write wins. With feature flags set,
+	// FlagOptimisticLocking decides per user instead.
+	DisableOptimisticLocking bool
+
+	// EventPublishMode controls whether publish failures fail the operation (default async).
+	EventPublishMode EventPublishMode
+
+	// EventPublishRetry configures retries in sync mode (default resilience.DefaultRetryConfig).
+	EventPublishRetry resilience.RetryConfig
+
+	// AutoExtendOnRead extends a cart's expiration whenever it is read via ReadCart.
+	AutoExtendOnRead bool
+
+	// RestoreWindow is how long cleared items can be restored (default 24h).
+	RestoreWindow time.Duration
+
+	// MaxCartTotalValue caps a cart's total in cents when adding or updating
+	// items (0 = unlimited).
+	MaxCartTotalValue int64
+
+	// MinCheckoutTotal is the cart total in cents that makes a cart checkout
+	// eligible, e.g. a free-shipping threshold (0 = no minimum). It is only
+	// reported on carts and summaries, never enforced.
+	MinCheckoutTotal int64
+
+	// CartExpiration is how long a cart lives without activity (default
+	// DefaultCartExpiration).
+	CartExpiration time.Duration
+
+	// GuestCartExpiration applies instead of CartExpiration to guest carts,
+	// identified by GuestUserIDPrefix (0 = same as CartExpiration; empty
+	// prefix = DefaultGuestUserIDPrefix).
+	GuestCartExpiration time.Duration
+	GuestUserIDPrefix   string
+
+	// AnonymousCarts enables CreateGuestCart, which issues guest IDs so
+	// clients don't have to generate their own.
+	AnonymousCarts bool
+
+	// SoftQuantityLimit is the quantity above which a line gets a
+	// WarningBulkQuantity warning without being rejected (0 = no warnings).
+	SoftQuantityLimit int
+
+	// SoftItemLimit is the distinct item count from which a cart gets a
+	// WarningCartNearingLimit warning, ahead of the hard MaxItemsPerCart
+	// limit (0 = no warnings).
+	SoftItemLimit int
+
+	// MaxItemAge prunes items added longer ago when their cart is loaded
+	// (0 = never). StaleItemAction chooses between removing them (default)
+	// and flagging them Stale. Each cart is checked at most once per
+	// PruneInterval (default DefaultPruneInterval) on each instance.
+	MaxItemAge      time.Duration
+	StaleItemAction StaleItemAction
+	PruneInterval   time.Duration
+
+	// GiftWrapFee is the per-unit gift wrap charge in cents, used when a
+	// request does not pass its own fee.
+	GiftWrapFee int64
+
+	// ConflictRetry configures UpdateItemQuantityWithAutoRetry (default
+	// DefaultConflictRetryConfig). RetryableFunc is ignored.
+	ConflictRetry resilience.RetryConfig
+
+	// KeepItemOnZeroAdjust makes AdjustItemQuantity stop at
+	// MinQuantityPerItem instead of removing an item decremented to zero.
+	KeepItemOnZeroAdjust bool
+
+	// CartConcurrency is how many mutations of one cart run at once within
+	// this instance (0 = unlimited). Further mutations queue, up to
+	// CartMaxWaiting in flight (default DefaultCartMaxWaiting), and fail with
+	// CodeServiceUnavailable once the queue is full.
+	CartConcurrency int
+	CartMaxWaiting  int
+
+	// CartBulkheadIdleTimeout is how long a cart's bulkhead is kept after its
+	// last mutation (default DefaultCartBulkheadIdleTimeout).
+	CartBulkheadIdleTimeout time.Duration
+
+	// AddItemDedupWindow treats an AddItem with the same user, product,
+	// quantity and unit price as one already applied within the window as a
+	// duplicate, returning the current cart unchanged (0 = disabled). This
+	// complements Idempotency-Key handling for clients that retry without a
+	// stable key.
+	AddItemDedupWindow time.Duration
+
+	// MaxItemsPerCart caps the distinct items in a cart (default
+	// MaxItemsPerCart).
+	MaxItemsPerCart int
+
+	// AdminMaxItemsPerCart is the item cap for requests carrying
+	// WithLimitOverride, e.g. bulk B2B carts built by customer service. It
+	// only applies when above MaxItemsPerCart.
+	AdminMaxItemsPerCart int
+
+	// MaxCartsPerUser caps how many carts CreateCart lets a user have,
+	// counting the default cart (default DefaultMaxCartsPerUser).
+	MaxCartsPerUser int
+
+	// LockTimeout is how long LockCart blocks edits before the lock expires
+	// on its own (default DefaultLockTimeout).
+	LockTimeout time.Duration
+
+	// PackSizeMode controls adds whose quantity isn't a multiple of the
+	// item's pack size (default PackSizeModeReject). Quantity updates are
+	// always rejected.
+	PackSizeMode PackSizeMode
+
+	// MergeStrategy resolves products found in both carts when a guest cart
+	// is merged (default MergeKeepHigher).
+	MergeStrategy MergeStrategy
+
+	// SnapshotHistory records a CartSnapshot of the default cart after every
+	// mutation, for GetCartHistory, when the repository is a SnapshotStore.
+	// Snapshots expire after SnapshotTTL (default DefaultSnapshotTTL).
+	SnapshotHistory bool
+	SnapshotTTL     time.Duration
+}
+
+// DefaultRestoreWindow is used when ServiceConfig.RestoreWindow is zero.
+const DefaultRestoreWindow = 24 * time.Hour
+
+// DefaultConflictRetryConfig is used when ServiceConfig.ConflictRetry is unset.
+// Conflicts clear as soon as the competing write lands, so delays are short.
+func DefaultConflictRetryConfig() resilience.RetryConfig {
+	return resilience.RetryConfig{
+		MaxAttempts:  3,
+		InitialDelay: 10 * time.Millisecond,
+		MaxDelay:     100 * time.Millisecond,
+		Multiplier:   2.0,
+		Jitter:       true,
+	}
 }
 
 // Service provides cart business operations.
@@ -34,19 +191,229 @@ type Service struct {
 	repo      Repository
 	publisher EventPublisher
 	config    ServiceConfig
+
+	// Optional collaborators
+	estimates EstimateProvider
+	prices    PriceValidator
+	inventory InventoryChecker
+	templates TemplateStore
+	products  ProductCartFinder
+	scanner   CartScanner
+	policy    ProductPolicy
+	metrics   metrics.Collector
+	changes   *ChangeFeed
+	auditor   audit.Auditor
+
+	// Per-user overrides of PublishEvents and DisableOptimisticLocking
+	flags features.Flags
+
+	// Cart and item IDs (default UUIDGenerator)
+	ids IDGenerator
+
+	// Time source (default RealClock)
+	clock Clock
+
+	// Audit entries that could not be recorded
+	auditFailures atomic.Int64
+
+	// Transactional merge, when the repository supports it
+	merges MergeRepository
+
+	// Additional carts per user, when the repository supports them
+	multi MultiCartRepository
+
+	// Cart history, when the repository supports it
+	snapshots SnapshotStore
+
+	// Per-cart bulkheads, when CartConcurrency is set
+	bulkheads *cartBulkheads
+
+	// Recent adds, when AddItemDedupWindow is set
+	addDedup *addDedup
+
+	// Carts recently checked for stale items, when MaxItemAge is set
+	pruneChecks *addDedup
+
+	// Outbox, used when EventPublishMode is outbox
+	outbox             OutboxRepository
+	newOutboxPublisher func(events.Publisher) EventPublisher
+}
+
+// ServiceOption is a functional option for configuring optional Service dependencies.
+type ServiceOption func(*Service)
+
+// WithEstimateProvider sets the provider used to annotate items with delivery estimates.
+func WithEstimateProvider(provider EstimateProvider) ServiceOption {
+	return func(s *Service) {
+		s.estimates = provider
+	}
+}
+
+// WithPriceValidator sets the validator used to look up current catalog prices.
+func WithPriceValidator(validator PriceValidator) ServiceOption {
+	return func(s *Service) {
+		s.prices = validator
+	}
+}
+
+// WithInventoryChecker sets the checker used to confirm stock availability.
+func WithInventoryChecker(checker InventoryChecker) ServiceOption {
+	return func(s *Service) {
+		s.inventory = checker
+	}
+}
+
+// WithTemplateStore sets the store used to look up cart templates.
+func WithTemplateStore(store TemplateStore) ServiceOption {
+	return func(s *Service) {
+		s.templates = store
+	}
+}
+
+// WithProductCartFinder sets the finder used to locate carts holding a product.
+func WithProductCartFinder(finder ProductCartFinder) ServiceOption {
+	return func(s *Service) {
+		s.products = finder
+	}
+}
+
+// WithCartScanner sets the scanner used to export all carts.
+func WithCartScanner(scanner CartScanner) ServiceOption {
+	return func(s *Service) {
+		s.scanner = scanner
+	}
+}
+
+// WithProductPolicy sets the policy consulted before products are added to carts.
+func WithProductPolicy(policy ProductPolicy) ServiceOption {
+	return func(s *Service) {
+		s.policy = policy
+	}
+}
+
+// WithMetrics sets the collector used to record event publish outcomes.
+func WithMetrics(collector metrics.Collector) ServiceOption {
+	return func(s *Service) {
+		s.metrics = collector
+	}
+}
+
+// WithChangeFeed sets the feed notified of every successful cart mutation.
+func WithChangeFeed(feed *ChangeFeed) ServiceOption {
+	return func(s *Service) {
+		s.changes = feed
+	}
+}
+
+// WithAuditor sets the auditor that records every cart mutation.
+func WithAuditor(auditor audit.Auditor) ServiceOption {
+	return func(s *Service) {
+		s.auditor = auditor
+	}
+}
+
+// WithFeatureFlags sets the feature flags that decide per user whether events
+// are published (FlagEventPublishing) and saves check versions
+// (FlagOptimisticLocking), overriding ServiceConfig.
+func WithFeatureFlags(flags features.Flags) ServiceOption {
+	return func(s *Service) {
+		s.flags = flags
+	}
+}
+
+// WithOutbox sets the repository used to write events in the cart's transaction.
+// newPublisher builds the cart events, sending them to the given Publisher.
+func WithOutbox(repo OutboxRepository, newPublisher func(events.Publisher) EventPublisher) ServiceOption {
+	return func(s *Service) {
+		s.outbox = repo
+		s.newOutboxPublisher = newPublisher
+	}
+}
+
+// WithIDGenerator sets the generator of new cart and item IDs, e.g.
+// TimeOrderedUUIDGenerator for better key locality or a deterministic
+// generator in tests.
+func WithIDGenerator(ids IDGenerator) ServiceOption {
+	return func(s *Service) {
+		s.ids = ids
+	}
+}
+
+// WithClock sets the time source for expiration and timestamps, e.g. a
+// FakeClock in tests.
+func WithClock(clock Clock) ServiceOption {
+	return func(s *Service) {
+		s.clock = clock
+	}
 }
 
 // NewService creates a new cart service.
-func NewService(repo Repository, publisher EventPublisher, config ServiceConfig) *Service {
-	return &Service{
+func NewService(repo Repository, publisher EventPublisher, config ServiceConfig, opts ...ServiceOption) *Service {
+	s := &Service{
 		repo:      repo,
 		publisher: publisher,
 		config:    config,
+		ids:       UUIDGenerator{},
+		clock:     RealClock{},
+	}
+	if repo != nil {
+		s.repo = tracedRepository{repo}
+		if merges, ok := repo.(MergeRepository); ok {
+			s.merges = merges
+		}
+		if multi, ok := repo.(MultiCartRepository); ok {
+			s.multi = multi
+		}
+		if snapshots, ok := repo.(SnapshotStore); ok {
+			s.snapshots = snapshots
+		}
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	if config.CartConcurrency > 0 {
+		s.bulkheads = newCartBulkheads(config, s.clock)
+	}
+	if config.AddItemDedupWindow > 0 {
+		s.addDedup = newAddDedup(config.AddItemDedupWindow, s.clock)
+	}
+	if config.MaxItemAge > 0 {
+		interval := config.PruneInterval
+		if interval <= 0 {
+			interval = DefaultPruneInterval
+		}
+		s.pruneChecks = newAddDedup(interval, s.clock)
+	}
+	return s
+}
+
+// now returns the current time in UTC from the service clock.
+func (s *Service) now() time.Time {
+	return s.clock.Now().UTC()
+}
+
+// attach sets the service-owned fields of a cart that are not persisted.
+func (s *Service) attach(ctx context.Context, cart *Cart) *Cart {
+	cart.MinCheckoutTotal = s.config.MinCheckoutTotal
+	cart.Clock = s.clock
+	cart.Pricing = s.pricingEngine(ctx, cart.UserID)
+	return cart
+}
+
+// newCart creates an empty cart for userID in the tenant of ctx.
+func (s *Service) newCart(ctx context.Context, userID string) *Cart {
+	cart := s.attach(ctx, newCartAt(s.ids, s.clock, userID, s.expirationFor(userID)))
+	cart.TenantID = TenantIDFromContext(ctx)
+	return cart
 }
 
-// GetCart retrieves a cart for a user.
+// GetCart retrieves a cart for a user: the default cart, or the additional
+// cart selected with WithCartID.
 func (s *Service) GetCart(ctx context.Context, userID string) (*Cart, error) {
+	if cartID := CartIDFromContext(ctx); cartID != "" {
+		return s.getSelectedCart(ctx, userID, cartID)
+	}
+
 	cart, err := s.repo.GetCart(ctx, userID)
 	if err != nil {
 		if errors.IsCode(err, errors.CodeCartNotFound) {
@@ -55,27 +422,35 @@ func (s *Service) GetCart(ctx context.Context, userID string) (*Cart, error) {
 		return nil, errors.Wrap(errors.CodePersistenceError, "failed to get cart", err)
 	}
 
-	if cart.IsExpired() {
+	if s.attach(ctx, cart).IsExpired() {
 		return nil, errors.ErrCartExpired(userID)
 	}
 
-	return cart, nil
+	return s.pruneStaleItems(ctx, cart), nil
 }
 
 // GetOrCreateCart retrieves a cart or creates a new one if it doesn't exist.
+// Only the default cart is created; an additional cart selected with
+// WithCartID must exist.
 func (s *Service) GetOrCreateCart(ctx context.Context, userID string) (*Cart, bool, error) {
+	if cartID := CartIDFromContext(ctx); cartID != "" {
+		cart, err := s.getSelectedCart(ctx, userID, cartID)
+		return cart, false, err
+	}
+
 	cart, err := s.repo.GetCart(ctx, userID)
 	if err != nil {
 		if errors.IsCode(err, errors.CodeCartNotFound) {
 			// Create new cart
-			newCart := NewCart(userID)
-			if err := s.repo.SaveCart(ctx, newCart); err != nil {
+			newCart := s.newCart(ctx, userID)
+			created := cartCreatedEvent(newCart)
+			if err := s.saveCart(ctx, newCart, 0, created); err != nil {
 				return nil, false, errors.Wrap(errors.CodePersistenceError, "failed to create cart", err)
 			}
 
 			// Publish event
-			if s.config.PublishEvents && s.publisher != nil {
-				_ = s.publisher.PublishCartCreated(ctx, newCart)
+			if err := s.publishEvents(ctx, created); err != nil {
+				return nil, false, err
 			}
 
 			return newCart, true, nil
@@ -83,21 +458,22 @@ func (s *Service) GetOrCreateCart(ctx context.Context, userID string) (*Cart, bo
 		return nil, false, errors.Wrap(errors.CodePersistenceError, "failed to get cart", err)
 	}
 
-	if cart.IsExpired() {
+	if s.attach(ctx, cart).IsExpired() {
 		// Create new cart for expired cart
-		newCart := NewCart(userID)
-		if err := s.repo.SaveCart(ctx, newCart); err != nil {
+		newCart := s.newCart(ctx, userID)
+		created := cartCreatedEvent(newCart)
+		if err := s.saveCart(ctx, newCart, 0, created); err != nil {
 			return nil, false, errors.Wrap(errors.CodePersistenceError, "failed to create cart", err)
 		}
 
-		if s.config.PublishEvents && s.publisher != nil {
-			_ = s.publisher.PublishCartCreated(ctx, newCart)
+		if err := s.publishEvents(ctx, created); err != nil {
+			return nil, false, err
 		}
 
 		return newCart, true, nil
 	}
 
-	return cart, false, nil
+	return s.pruneStaleItems(ctx, cart), false, nil
 }
 
 // AddItemRequest represents a request to add an item to the cart.
@@ -105,90 +481,418 @@ type AddItemRequest struct {
 	ProductID string
 	Quantity  int
 	UnitPrice int64
+
+	// Optional product details
+	Name       string
+	ImageURL   string
+	SKU        string
+	Attributes map[string]string
+
+	// GiftWrap requests gift wrapping; GiftWrapFee overrides
+	// ServiceConfig.GiftWrapFee when positive.
+	GiftWrap    bool
+	GiftWrapFee int64
+
+	// AddMode controls whether the quantity is added to or replaces the
+	// quantity of a product already in the cart (default AddModeIncrement).
+	AddMode AddMode
+
+	// IfNotExists fails the add with CodeItemAlreadyExists when the product
+	// is already in the cart. It can't be combined with another AddMode.
+	IfNotExists bool
+
+	// UnitType UnitTypeWeight adds DecimalQuantity milli-units instead of
+	// Quantity (default UnitTypeEach).
+	UnitType        UnitType
+	DecimalQuantity int64
+
+	// PackSize requires Quantity to be a multiple of it; how other
+	// quantities are handled depends on ServiceConfig.PackSizeMode.
+	PackSize int
+}
+
+// newCartItem builds a cart item from an add request.
+func (s *Service) newCartItem(req AddItemRequest) *CartItem {
+	item := newCartItemAt(s.ids, s.now(), req.ProductID, s.packQuantity(req), req.UnitPrice)
+	item.PackSize = req.PackSize
+	item.UnitType = req.UnitType
+	item.DecimalQuantity = req.DecimalQuantity
+	if item.IsWeighted() {
+		item.Quantity = 1
+	}
+	item.Name = req.Name
+	item.ImageURL = req.ImageURL
+	item.SKU = req.SKU
+	item.Attributes = req.Attributes
+	if req.GiftWrap {
+		item.GiftWrap = true
+		item.GiftWrapFee = s.giftWrapFee(req.GiftWrapFee)
+	}
+	return item
 }
 
-// AddItem adds an item to a user's cart.
+// packQuantity returns the quantity to add for req, rounded up to a full pack
+// in PackSizeModeRoundUp.
+func (s *Service) packQuantity(req AddItemRequest) int {
+	if s.config.PackSizeMode != PackSizeModeRoundUp || req.UnitType == UnitTypeWeight {
+		return req.Quantity
+	}
+	return RoundUpToPackSize(req.Quantity, req.PackSize)
+}
+
+// checkProductAllowed returns CodeProductNotAllowed when the configured
+// ProductPolicy denies productID.
+func (s *Service) checkProductAllowed(ctx context.Context, productID string) error {
+	if s.policy == nil {
+		return nil
+	}
+	if allowed, reason := s.policy.IsAllowed(ctx, productID); !allowed {
+		return errors.ErrProductNotAllowed(productID, reason)
+	}
+	return nil
+}
+
+// giftWrapFee returns fee when positive, otherwise the configured fee.
+func (s *Service) giftWrapFee(fee int64) int64 {
+	if fee > 0 {
+		return fee
+	}
+	return s.config.GiftWrapFee
+}
+
+// AddItem adds an item to a user's cart. When AddItemDedupWindow is set, an
+// identical add repeated within the window returns the current cart instead.
 func (s *Service) AddItem(ctx context.Context, userID string, req AddItemRequest) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		if s.addDedup == nil {
+			return s.addItem(ctx, userID, req)
+		}
+
+		key := addDedupKey(cartKey(ctx, userID), req)
+		if !s.addDedup.claim(key) {
+			return s.GetCart(ctx, userID)
+		}
+		cart, err := s.addItem(ctx, userID, req)
+		if err != nil {
+			s.addDedup.release(key)
+		}
+		return cart, err
+	})
+}
+
+// mode resolves the add mode, folding IfNotExists into AddModeIfNotExists.
+func (r AddItemRequest) mode() (AddMode, error) {
+	if !r.IfNotExists {
+		return r.AddMode, nil
+	}
+	if r.AddMode != "" && r.AddMode != AddModeIfNotExists {
+		return "", errors.ErrValidation("if_not_exists can't be combined with add_mode", map[string]interface{}{
+			"add_mode": string(r.AddMode),
+		})
+	}
+	return AddModeIfNotExists, nil
+}
+
+// addItem is AddItem without the per-cart bulkhead.
+func (s *Service) addItem(ctx context.Context, userID string, req AddItemRequest) (*Cart, error) {
+	mode, err := req.mode()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkProductAllowed(ctx, req.ProductID); err != nil {
+		return nil, err
+	}
+
 	// Get or create cart
 	cart, _, err := s.GetOrCreateCart(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+
+	cart.MaxTotalValue = s.config.MaxCartTotalValue
+	cart.MaxItems = s.itemLimit(ctx)
 
 	// Create cart item
-	item := NewCartItem(req.ProductID, req.Quantity, req.UnitPrice)
+	item := s.newCartItem(req)
 
 	// Add item to cart (domain logic handles validation)
-	if err := cart.AddItem(item); err != nil {
+	if err := cart.AddItemWithMode(item, mode); err != nil {
 		return nil, err
 	}
 
 	// Increment version and save
 	cart.IncrementVersion()
-	if err := s.repo.SaveCart(ctx, cart); err != nil {
-		return nil, errors.Wrap(errors.CodePersistenceError, "failed to save cart", err)
+	added := itemAddedEvent(cart, item)
+	if err := s.saveCart(ctx, cart, 0, added); err != nil {
+		return nil, saveError(err, "failed to save cart")
 	}
+	s.recordAudit(ctx, audit.OpAddItem, cart, added)
+	s.recordLimitOverride(ctx, cart)
 
 	// Publish event
-	if s.config.PublishEvents && s.publisher != nil {
-		_ = s.publisher.PublishItemAdded(ctx, cart, item)
+	if err := s.publishEvents(ctx, added); err != nil {
+		return nil, err
 	}
 
 	return cart, nil
 }
 
+// AddTemplate adds all lines of a template to a user's cart.
+// Lines are merged into existing items and priced at the current catalog price when a
+// PriceValidator is configured. Lines that would violate cart limits or cannot be priced
+// are skipped and reported in the result rather than failing the whole operation.
+func (s *Service) AddTemplate(ctx context.Context, userID, templateID string) (*TemplateResult, error) {
+	return guardCart(ctx, s, userID, func() (*TemplateResult, error) {
+		return s.addTemplate(ctx, userID, templateID)
+	})
+}
+
+// addTemplate is AddTemplate without the per-cart bulkhead.
+func (s *Service) addTemplate(ctx context.Context, userID, templateID string) (*TemplateResult, error) {
+	if s.templates == nil {
+		return nil, errors.ErrServiceUnavailable("templates")
+	}
+
+	template, err := s.templates.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		if errors.IsCode(err, errors.CodeTemplateNotFound) {
+			return nil, err
+		}
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to get template", err)
+	}
+
+	cart, _, err := s.GetOrCreateCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+
+	cart.MaxTotalValue = s.config.MaxCartTotalValue
+	cart.MaxItems = s.itemLimit(ctx)
+
+	result := &TemplateResult{
+		Cart:    cart,
+		Added:   make([]TemplateLine, 0, len(template.Lines)),
+		Skipped: make([]SkippedTemplateLine, 0),
+	}
+	addedItems := make([]*CartItem, 0, len(template.Lines))
+
+	for _, line := range template.Lines {
+		if err := s.checkProductAllowed(ctx, line.ProductID); err != nil {
+			appErr, _ := errors.IsAppError(err)
+			result.Skipped = append(result.Skipped, SkippedTemplateLine{
+				TemplateLine: line,
+				Reason:       appErr.Code,
+				Message:      appErr.Message,
+			})
+			continue
+		}
+
+		price := line.UnitPrice
+		if s.prices != nil {
+			current, err := s.prices.GetCurrentPrice(ctx, line.ProductID)
+			if err != nil {
+				result.Skipped = append(result.Skipped, SkippedTemplateLine{
+					TemplateLine: line,
+					Reason:       "PRICE_UNAVAILABLE",
+					Message:      "Current price could not be determined",
+				})
+				continue
+			}
+			price = current
+		}
+
+		item := newCartItemAt(s.ids, s.now(), line.ProductID, line.Quantity, price)
+		if err := cart.AddItem(item); err != nil {
+			skipped := SkippedTemplateLine{TemplateLine: line, Reason: errors.CodeInternalError, Message: err.Error()}
+			if appErr, ok := errors.IsAppError(err); ok {
+				skipped.Reason = appErr.Code
+				skipped.Message = appErr.Message
+			}
+			result.Skipped = append(result.Skipped, skipped)
+			continue
+		}
+
+		line.UnitPrice = price
+		result.Added = append(result.Added, line)
+		addedItems = append(addedItems, item)
+	}
+
+	if len(addedItems) == 0 {
+		return result, nil
+	}
+
+	added := make([]pendingEvent, len(addedItems))
+	for i, item := range addedItems {
+		added[i] = itemAddedEvent(cart, item)
+	}
+
+	cart.IncrementVersion()
+	if err := s.saveCart(ctx, cart, 0, added...); err != nil {
+		return nil, saveError(err, "failed to save cart")
+	}
+	s.recordAudit(ctx, audit.OpAddTemplate, cart, added...)
+	s.recordLimitOverride(ctx, cart)
+
+	// Publish events
+	if err := s.publishEvents(ctx, added...); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 // UpdateItemRequest represents a request to update an item quantity.
 type UpdateItemRequest struct {
 	ItemID          string
 	Quantity        int
 	ExpectedVersion int64
+
+	// DecimalQuantity replaces Quantity for weighted items, in milli-units
+	DecimalQuantity int64
+
+	// GiftWrap turns gift wrapping on or off when set; GiftWrapFee overrides
+	// ServiceConfig.GiftWrapFee when positive.
+	GiftWrap    *bool
+	GiftWrapFee int64
 }
 
 // UpdateItemQuantity updates the quantity of an item in the cart.
 func (s *Service) UpdateItemQuantity(ctx context.Context, userID string, req UpdateItemRequest) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		return s.updateItemQuantity(ctx, userID, req)
+	})
+}
+
+// updateItemQuantity is UpdateItemQuantity without the per-cart bulkhead.
+func (s *Service) updateItemQuantity(ctx context.Context, userID string, req UpdateItemRequest) (*Cart, error) {
 	cart, err := s.GetCart(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
 
 	// Check version for optimistic locking
 	if req.ExpectedVersion > 0 && cart.Version != req.ExpectedVersion {
 		return nil, errors.ErrConflict(req.ExpectedVersion, cart.Version)
 	}
 
+	// Capture the previous quantity for the event before mutating
+	prevQuantity := 0
+	if existing, _ := cart.FindItem(req.ItemID); existing != nil {
+		prevQuantity = existing.Quantity
+	}
+
 	// Update quantity (domain logic handles validation)
-	if err := cart.UpdateItemQuantity(req.ItemID, req.Quantity); err != nil {
+	cart.MaxTotalValue = s.config.MaxCartTotalValue
+	if req.DecimalQuantity > 0 {
+		err = cart.UpdateItemDecimalQuantity(req.ItemID, req.DecimalQuantity)
+	} else {
+		err = cart.UpdateItemQuantity(req.ItemID, req.Quantity)
+	}
+	if err != nil {
 		return nil, err
 	}
+	if req.GiftWrap != nil {
+		if err := cart.SetGiftWrap(req.ItemID, *req.GiftWrap, s.giftWrapFee(req.GiftWrapFee)); err != nil {
+			return nil, err
+		}
+	}
 
 	// Get the updated item for event
 	item, _ := cart.FindItem(req.ItemID)
 
+	var updated []pendingEvent
+	if item != nil {
+		updated = append(updated, itemUpdatedEvent(cart, item, prevQuantity))
+	}
+
 	// Increment version and save with optimistic locking
 	expectedVersion := cart.Version
 	cart.IncrementVersion()
 
-	if err := s.repo.SaveCartWithVersion(ctx, cart, expectedVersion); err != nil {
-		if errors.IsCode(err, errors.CodeConflict) {
-			return nil, err
-		}
-		return nil, errors.Wrap(errors.CodePersistenceError, "failed to save cart", err)
+	if err := s.saveCart(ctx, cart, expectedVersion, updated...); err != nil {
+		return nil, saveError(err, "failed to save cart")
 	}
+	s.recordAudit(ctx, audit.OpUpdateItem, cart, updated...)
 
 	// Publish event
-	if s.config.PublishEvents && s.publisher != nil && item != nil {
-		_ = s.publisher.PublishItemUpdated(ctx, cart, item)
+	if err := s.publishEvents(ctx, updated...); err != nil {
+		return nil, err
 	}
 
 	return cart, nil
 }
 
+// UpdateItemQuantityWithAutoRetry sets an item's quantity like
+// UpdateItemQuantity, but on a version conflict reloads the cart and sets the
+// same absolute quantity again, up to ServiceConfig.ConflictRetry.MaxAttempts
+// times. Only absolute updates are safe to retry this way; relative changes
+// such as AddItem would be applied twice. A request with an ExpectedVersion is
+// not retried, since the caller asked to fail on concurrent changes.
+func (s *Service) UpdateItemQuantityWithAutoRetry(ctx context.Context, userID string, req UpdateItemRequest) (*Cart, error) {
+	if req.ExpectedVersion > 0 {
+		return s.UpdateItemQuantity(ctx, userID, req)
+	}
+
+	retry := s.config.ConflictRetry
+	if retry.MaxAttempts <= 0 {
+		retry = DefaultConflictRetryConfig()
+	}
+	retry.RetryableFunc = func(err error) bool {
+		return errors.IsCode(err, errors.CodeConflict)
+	}
+
+	attempt := 0
+	return resilience.RetryWithResult(ctx, retry, func() (*Cart, error) {
+		if attempt > 0 {
+			s.recordConflictRetry()
+		}
+		attempt++
+		return s.UpdateItemQuantity(ctx, userID, req)
+	})
+}
+
+// recordConflictRetry increments the conflict retry counter when metrics are configured.
+func (s *Service) recordConflictRetry() {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.IncrementCounter(metrics.MetricConflictRetryTotal, map[string]string{
+		"operation": "update_item",
+	})
+}
+
 // RemoveItem removes an item from the cart.
 func (s *Service) RemoveItem(ctx context.Context, userID, itemID string) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		return s.removeItem(ctx, userID, itemID)
+	})
+}
+
+// removeItem is RemoveItem without the per-cart bulkhead.
+func (s *Service) removeItem(ctx context.Context, userID, itemID string) (*Cart, error) {
 	cart, err := s.GetCart(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+
+	// Look up the product for the event before the item is gone
+	var productID string
+	if existing, _ := cart.FindItem(itemID); existing != nil {
+		productID = existing.ProductID
+	}
 
 	// Remove item (domain logic handles validation)
 	if err := cart.RemoveItem(itemID); err != nil {
@@ -197,13 +901,130 @@ func (s *Service) RemoveItem(ctx context.Context, userID, itemID string) (*Cart,
 
 	// Save cart
 	cart.IncrementVersion()
-	if err := s.repo.SaveCart(ctx, cart); err != nil {
-		return nil, errors.Wrap(errors.CodePersistenceError, "failed to save cart", err)
+	removed := itemRemovedEvent(cart, itemID, productID)
+	if err := s.saveCart(ctx, cart, 0, removed); err != nil {
+		return nil, saveError(err, "failed to save cart")
+	}
+	s.recordAudit(ctx, audit.OpRemoveItem, cart, removed)
+
+	// Publish event
+	if err := s.publishEvents(ctx, removed); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// AdjustItemQuantity changes an item's quantity by delta relative to the
+// stored quantity, clamped to MaxQuantityPerItem. An item decremented to zero
+// or below is removed unless ServiceConfig.KeepItemOnZeroAdjust is set, in
+// which case it stops at MinQuantityPerItem. The save is version checked, so a
+// concurrent change fails with a conflict instead of being overwritten.
+func (s *Service) AdjustItemQuantity(ctx context.Context, userID, itemID string, delta int) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		return s.adjustItemQuantity(ctx, userID, itemID, delta)
+	})
+}
+
+// adjustItemQuantity is AdjustItemQuantity without the per-cart bulkhead.
+func (s *Service) adjustItemQuantity(ctx context.Context, userID, itemID string, delta int) (*Cart, error) {
+	if delta == 0 {
+		return nil, errors.ErrValidation("Invalid delta", map[string]interface{}{
+			"delta": "must not be zero",
+		})
+	}
+
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+
+	item, _ := cart.FindItem(itemID)
+	if item == nil {
+		return nil, errors.ErrItemNotFound(userID, itemID)
+	}
+	prevQuantity, productID := item.Quantity, item.ProductID
+
+	quantity := prevQuantity + delta
+	if quantity > MaxQuantityPerItem {
+		quantity = MaxQuantityPerItem
+	}
+	if quantity < MinQuantityPerItem && s.config.KeepItemOnZeroAdjust {
+		quantity = MinQuantityPerItem
+	}
+	if quantity == prevQuantity {
+		return cart, nil
+	}
+
+	var (
+		event pendingEvent
+		op    string
+	)
+	if quantity < MinQuantityPerItem {
+		if err := cart.RemoveItem(itemID); err != nil {
+			return nil, err
+		}
+		event, op = itemRemovedEvent(cart, itemID, productID), audit.OpRemoveItem
+	} else {
+		cart.MaxTotalValue = s.config.MaxCartTotalValue
+		if err := cart.UpdateItemQuantity(itemID, quantity); err != nil {
+			return nil, err
+		}
+		item, _ = cart.FindItem(itemID)
+		event, op = itemUpdatedEvent(cart, item, prevQuantity), audit.OpUpdateItem
+	}
+
+	expectedVersion := cart.Version
+	cart.IncrementVersion()
+	if err := s.saveCart(ctx, cart, expectedVersion, event); err != nil {
+		return nil, saveError(err, "failed to save cart")
 	}
+	s.recordAudit(ctx, op, cart, event)
 
 	// Publish event
-	if s.config.PublishEvents && s.publisher != nil {
-		_ = s.publisher.PublishItemRemoved(ctx, cart, itemID)
+	if err := s.publishEvents(ctx, event); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// ReorderItems reorders the items in a cart to match the given item IDs.
+// Items not listed keep their relative order at the end of the cart.
+func (s *Service) ReorderItems(ctx context.Context, userID string, orderedItemIDs []string) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		return s.reorderItems(ctx, userID, orderedItemIDs)
+	})
+}
+
+// reorderItems is ReorderItems without the per-cart bulkhead.
+func (s *Service) reorderItems(ctx context.Context, userID string, orderedItemIDs []string) (*Cart, error) {
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+
+	// Reorder items (domain logic handles validation)
+	if err := cart.ReorderItems(orderedItemIDs); err != nil {
+		return nil, err
+	}
+
+	expectedVersion := cart.Version
+	cart.IncrementVersion()
+	reordered := itemsReorderedEvent(cart)
+	if err := s.saveCart(ctx, cart, expectedVersion, reordered); err != nil {
+		return nil, saveError(err, "failed to save cart")
+	}
+	s.recordAudit(ctx, audit.OpReorderItems, cart, reordered)
+
+	if err := s.publishEvents(ctx, reordered); err != nil {
+		return nil, err
 	}
 
 	return cart, nil
@@ -211,6 +1032,14 @@ func (s *Service) RemoveItem(ctx context.Context, userID, itemID string) (*Cart,
 
 // ClearCart removes all items from the cart.
 func (s *Service) ClearCart(ctx context.Context, userID string) error {
+	_, err := guardCart(ctx, s, userID, func() (struct{}, error) {
+		return struct{}{}, s.clearCart(ctx, userID)
+	})
+	return err
+}
+
+// clearCart is ClearCart without the per-cart bulkhead.
+func (s *Service) clearCart(ctx context.Context, userID string) error {
 	cart, err := s.GetCart(ctx, userID)
 	if err != nil {
 		if errors.IsCode(err, errors.CodeCartNotFound) {
@@ -218,39 +1047,204 @@ func (s *Service) ClearCart(ctx context.Context, userID string) error {
 		}
 		return err
 	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return err
+	}
+
+	// Capture what's being removed for the event before clearing
+	itemsRemoved, previousTotal := cart.ItemCount(), cart.TotalPrice()
 
 	cart.Clear()
 	cart.IncrementVersion()
 
-	if err := s.repo.SaveCart(ctx, cart); err != nil {
-		return errors.Wrap(errors.CodePersistenceError, "failed to save cart", err)
+	cleared := cartClearedEvent(cart, itemsRemoved, previousTotal)
+	if err := s.saveCart(ctx, cart, 0, cleared); err != nil {
+		return saveError(err, "failed to save cart")
 	}
+	s.recordAudit(ctx, audit.OpClearCart, cart)
 
 	// Publish event
-	if s.config.PublishEvents && s.publisher != nil {
-		_ = s.publisher.PublishCartCleared(ctx, cart)
+	return s.publishEvents(ctx, cleared)
+}
+
+// RestoreCart restores the items removed by the most recent ClearCart, provided
+// it happened within the configured restore window.
+func (s *Service) RestoreCart(ctx context.Context, userID string) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		return s.restoreCart(ctx, userID)
+	})
+}
+
+// restoreCart is RestoreCart without the per-cart bulkhead.
+func (s *Service) restoreCart(ctx context.Context, userID string) (*Cart, error) {
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	window := s.config.RestoreWindow
+	if window <= 0 {
+		window = DefaultRestoreWindow
+	}
+
+	// Restore items (domain logic handles the window check)
+	cart.MaxItems = s.itemLimit(ctx)
+	if err := cart.Restore(window); err != nil {
+		return nil, err
+	}
+
+	expectedVersion := cart.Version
+	cart.IncrementVersion()
+	if err := s.saveCart(ctx, cart, expectedVersion); err != nil {
+		return nil, saveError(err, "failed to save cart")
+	}
+	s.recordAudit(ctx, audit.OpRestoreCart, cart)
+	s.recordLimitOverride(ctx, cart)
+
+	return cart, nil
+}
+
+// SetGiftMessage sets or, when message is empty, clears the cart's gift message.
+func (s *Service) SetGiftMessage(ctx context.Context, userID, message string) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		return s.setGiftMessage(ctx, userID, message)
+	})
+}
+
+// setGiftMessage is SetGiftMessage without the per-cart bulkhead.
+func (s *Service) setGiftMessage(ctx context.Context, userID, message string) (*Cart, error) {
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+
+	if err := cart.SetGiftMessage(message); err != nil {
+		return nil, err
+	}
+
+	expectedVersion := cart.Version
+	cart.IncrementVersion()
+	if err := s.saveCart(ctx, cart, expectedVersion); err != nil {
+		return nil, saveError(err, "failed to save cart")
+	}
+	s.recordAudit(ctx, audit.OpSetGiftMessage, cart)
+
+	return cart, nil
 }
 
-// DeleteCart deletes a cart entirely.
+// SetCartName sets or, when name is empty, clears the cart's name.
+func (s *Service) SetCartName(ctx context.Context, userID, name string) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		return s.setCartName(ctx, userID, name)
+	})
+}
+
+// setCartName is SetCartName without the per-cart bulkhead.
+func (s *Service) setCartName(ctx context.Context, userID, name string) (*Cart, error) {
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+
+	if err := cart.SetName(name); err != nil {
+		return nil, err
+	}
+
+	expectedVersion := cart.Version
+	cart.IncrementVersion()
+	if err := s.saveCart(ctx, cart, expectedVersion); err != nil {
+		return nil, saveError(err, "failed to save cart")
+	}
+	s.recordAudit(ctx, audit.OpSetCartName, cart)
+
+	return cart, nil
+}
+
+// DeleteCart deletes a cart entirely: the default cart, or the additional
+// cart selected with WithCartID. Locked carts are not deleted.
 func (s *Service) DeleteCart(ctx context.Context, userID string) error {
-	if err := s.repo.DeleteCart(ctx, userID); err != nil {
+	_, err := guardCart(ctx, s, userID, func() (struct{}, error) {
+		return struct{}{}, s.deleteCart(ctx, userID)
+	})
+	return err
+}
+
+// deleteCart is DeleteCart without the per-cart bulkhead.
+func (s *Service) deleteCart(ctx context.Context, userID string) error {
+	getCart, deleteCart := s.repo.GetCart, s.repo.DeleteCart
+	if cartID := CartIDFromContext(ctx); cartID != "" {
+		if s.multi == nil {
+			return errors.ErrServiceUnavailable("multi_cart")
+		}
+		getCart = func(ctx context.Context, userID string) (*Cart, error) {
+			return s.multi.GetCartByID(ctx, userID, cartID)
+		}
+		deleteCart = func(ctx context.Context, userID string) error {
+			return s.multi.DeleteCartByID(ctx, userID, cartID)
+		}
+	}
+
+	// Read the stored cart directly, so expired carts can still be deleted
+	cart, err := getCart(ctx, userID)
+	if err != nil {
+		if errors.IsCode(err, errors.CodeCartNotFound) {
+			return nil
+		}
+		return errors.Wrap(errors.CodePersistenceError, "failed to get cart", err)
+	}
+	if err := s.attach(ctx, cart).CheckUnlocked(); err != nil {
+		return err
+	}
+
+	if err := deleteCart(ctx, userID); err != nil {
 		if errors.IsCode(err, errors.CodeCartNotFound) {
 			return nil
 		}
 		return errors.Wrap(errors.CodePersistenceError, "failed to delete cart", err)
 	}
+	s.recordDeleteAudit(ctx, userID)
 	return nil
 }
 
-// MergeGuestCart merges a guest cart into a user's cart.
+// MergeGuestCart merges a guest cart into a user's cart using the configured
+// MergeStrategy.
 func (s *Service) MergeGuestCart(ctx context.Context, userID, guestID string) (*Cart, error) {
+	cart, _, err := s.MergeGuestCartWithWarnings(ctx, userID, guestID)
+	return cart, err
+}
+
+// MergeGuestCartWithWarnings is MergeGuestCart, also returning a
+// WarningQuantityCapped warning for each product whose merged quantity was
+// capped.
+func (s *Service) MergeGuestCartWithWarnings(ctx context.Context, userID, guestID string) (*Cart, []Warning, error) {
+	var warnings []Warning
+	cart, err := guardCart(ctx, s, userID, func() (*Cart, error) {
+		var err error
+		var cart *Cart
+		cart, warnings, err = s.mergeGuestCart(ctx, userID, guestID)
+		return cart, err
+	})
+	return cart, warnings, err
+}
+
+// mergeGuestCart is MergeGuestCartWithWarnings without the per-cart bulkhead.
+func (s *Service) mergeGuestCart(ctx context.Context, userID, guestID string) (*Cart, []Warning, error) {
 	// Get user cart (or create new one)
 	userCart, _, err := s.GetOrCreateCart(ctx, userID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if err := userCart.CheckUnlocked(); err != nil {
+		return nil, nil, err
 	}
 
 	// Get guest cart
@@ -258,35 +1252,127 @@ func (s *Service) MergeGuestCart(ctx context.Context, userID, guestID string) (*
 	if err != nil {
 		if errors.IsCode(err, errors.CodeCartNotFound) {
 			// No guest cart to merge
-			return userCart, nil
+			return userCart, nil, nil
 		}
-		return nil, errors.Wrap(errors.CodePersistenceError, "failed to get guest cart", err)
+		return nil, nil, errors.Wrap(errors.CodePersistenceError, "failed to get guest cart", err)
 	}
 
 	// Merge carts
-	mergedCart := MergeCarts(userCart, guestCart)
+	expectedVersion := userCart.Version
+	userCart.MaxItems = s.itemLimit(ctx)
+	mergedCart, warnings := MergeCartsWithStrategy(userCart, guestCart, s.config.MergeStrategy)
 	mergedCart.IncrementVersion()
 
-	// Save merged cart
-	if err := s.repo.SaveCart(ctx, mergedCart); err != nil {
-		return nil, errors.Wrap(errors.CodePersistenceError, "failed to save merged cart", err)
+	merged := cartMergedEvent(mergedCart, guestID, countMergedItems(mergedCart, guestCart))
+	if s.merges != nil {
+		// Save merged cart and delete guest cart atomically
+		if err := s.saveMergedCart(ctx, mergedCart, expectedVersion, guestCart, merged); err != nil {
+			return nil, nil, saveError(err, "failed to save merged cart")
+		}
+		s.recordAudit(ctx, audit.OpMergeCart, mergedCart)
+		s.recordLimitOverride(ctx, mergedCart)
+	} else {
+		// Save merged cart
+		if err := s.saveCart(ctx, mergedCart, 0, merged); err != nil {
+			return nil, nil, saveError(err, "failed to save merged cart")
+		}
+		s.recordAudit(ctx, audit.OpMergeCart, mergedCart)
+		s.recordLimitOverride(ctx, mergedCart)
+
+		// Delete guest cart
+		_ = s.repo.DeleteCart(ctx, guestID)
 	}
 
-	// Delete guest cart
-	_ = s.repo.DeleteCart(ctx, guestID)
+	// Publish event
+	if err := s.publishEvents(ctx, merged); err != nil {
+		return nil, nil, err
+	}
+
+	return mergedCart, warnings, nil
+}
 
-	return mergedCart, nil
+// countMergedItems returns how many guest items ended up in the merged cart.
+// Items dropped because the cart was full are not counted.
+func countMergedItems(merged, guest *Cart) int {
+	count := 0
+	for _, item := range guest.Items {
+		if existing, _ := merged.FindItemByProductID(item.ProductID); existing != nil {
+			count++
+		}
+	}
+	return count
 }
 
 // TouchCart extends the expiration of a cart.
-func (s *Service) TouchCart(ctx context.Context, userID string) error {
+func (s *Service) TouchCart(ctx context.Context, userID string) (*Cart, error) {
+	return guardCart(ctx, s, userID, func() (*Cart, error) {
+		return s.touchCart(ctx, userID)
+	})
+}
+
+// touchCart is TouchCart without the per-cart bulkhead.
+func (s *Service) touchCart(ctx context.Context, userID string) (*Cart, error) {
 	cart, err := s.GetCart(ctx, userID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := cart.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+
+	expectedVersion := cart.Version
+	cart.ExtendExpiration(s.expirationFor(userID))
+	cart.IncrementVersion()
+	if err := s.saveCart(ctx, cart, expectedVersion); err != nil {
+		return nil, saveError(err, "failed to save cart")
 	}
 
-	cart.ExtendExpiration()
-	return s.repo.SaveCart(ctx, cart)
+	return cart, nil
+}
+
+// expirationFor returns how long the user's cart lives without activity.
+func (s *Service) expirationFor(userID string) time.Duration {
+	if s.config.GuestCartExpiration > 0 && s.IsGuestID(userID) {
+		return s.config.GuestCartExpiration
+	}
+	if s.config.CartExpiration > 0 {
+		return s.config.CartExpiration
+	}
+	return DefaultCartExpiration
+}
+
+// autoExtendThreshold is how much of the expiration window must have elapsed
+// before a read extends it, so rapid reads don't each cost a write.
+const autoExtendThreshold = time.Hour
+
+// ReadCart retrieves a cart for display. When AutoExtendOnRead is enabled, the
+// cart's expiration is extended so active browsing keeps it alive.
+func (s *Service) ReadCart(ctx context.Context, userID string) (*Cart, error) {
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.config.AutoExtendOnRead {
+		return cart, nil
+	}
+
+	fullWindow := s.expirationFor(userID)
+	if cart.ExpiresAt.Sub(s.now()) > fullWindow-autoExtendThreshold {
+		return cart, nil
+	}
+
+	// Extending is best-effort; a failed write shouldn't fail the read. The
+	// write is conditional on the version read, so it can't overwrite a
+	// concurrent edit; that edit extends the expiration anyway.
+	extended := *cart
+	extended.ExtendExpiration(fullWindow)
+	extended.IncrementVersion()
+	if err := s.saveCart(ctx, &extended, cart.Version); err != nil {
+		return cart, nil
+	}
+
+	return &extended, nil
 }
 
 // GetCartSummary returns a summary of the cart.
@@ -300,6 +1386,94 @@ func (s *Service) GetCartSummary(ctx context.Context, userID string) (*CartSumma
 	return &summary, nil
 }
 
+// GetItemCount returns the total quantity of items in the cart. A missing or
+// expired cart counts as empty.
+func (s *Service) GetItemCount(ctx context.Context, userID string) (int, error) {
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		if errors.IsCode(err, errors.CodeCartNotFound) || errors.IsCode(err, errors.CodeCartExpired) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return cart.TotalQuantity(), nil
+}
+
+// QuantityWarnings returns warnings for an item whose quantity exceeds the
+// configured SoftQuantityLimit. The item is never rejected.
+func (s *Service) QuantityWarnings(item *CartItem) []Warning {
+	if item == nil || s.config.SoftQuantityLimit <= 0 || item.Quantity <= s.config.SoftQuantityLimit {
+		return nil
+	}
+
+	return []Warning{{
+		Code:      WarningBulkQuantity,
+		Message:   fmt.Sprintf("Quantity %d is above the usual limit of %d; is this a bulk order?", item.Quantity, s.config.SoftQuantityLimit),
+		ItemID:    item.ItemID,
+		ProductID: item.ProductID,
+	}}
+}
+
+// PackSizeWarnings returns a warning when the quantity of req was rounded up
+// to a full pack of item.
+func (s *Service) PackSizeWarnings(req AddItemRequest, item *CartItem) []Warning {
+	quantity := s.packQuantity(req)
+	if item == nil || quantity == req.Quantity {
+		return nil
+	}
+
+	return []Warning{{
+		Code:      WarningQuantityRounded,
+		Message:   fmt.Sprintf("Quantity %d was rounded up to %d, a multiple of the pack size %d", req.Quantity, quantity, req.PackSize),
+		ItemID:    item.ItemID,
+		ProductID: item.ProductID,
+	}}
+}
+
+// maxEstimateLookups bounds the concurrent EstimateProvider lookups of one
+// DeliveryEstimates call, so large carts don't fan out unbounded.
+const maxEstimateLookups = 8
+
+// DeliveryEstimates returns delivery estimates for the items in a cart, keyed by item ID.
+// Up to maxEstimateLookups lookups run concurrently; items whose lookup fails are
+// omitted. Returns nil when no EstimateProvider is configured.
+func (s *Service) DeliveryEstimates(ctx context.Context, c *Cart) map[string]DeliveryEstimate {
+	if s.estimates == nil || c == nil || len(c.Items) == 0 {
+		return nil
+	}
+
+	estimates := make(map[string]DeliveryEstimate, len(c.Items))
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	items := make(chan CartItem)
+	for i := 0; i < min(maxEstimateLookups, len(c.Items)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				estimate, err := s.estimates.EstimateFor(ctx, item.ProductID, item.Quantity)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				estimates[item.ItemID] = estimate
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, item := range c.Items {
+		items <- item
+	}
+	close(items)
+	wg.Wait()
+
+	return estimates
+}
+
 // AbandonedCartCriteria defines criteria for finding abandoned carts.
 type AbandonedCartCriteria struct {
 	InactiveSince time.Time
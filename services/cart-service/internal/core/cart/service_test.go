@@ -0,0 +1,1394 @@
+package cart
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/features"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/metrics"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEstimateProvider returns a fixed window per product, or an error for listed products.
+type fakeEstimateProvider struct {
+	windows map[string]time.Duration
+	failing map[string]bool
+}
+
+func (p *fakeEstimateProvider) EstimateFor(ctx context.Context, productID string, quantity int) (DeliveryEstimate, error) {
+	if p.failing[productID] {
+		return DeliveryEstimate{}, fmt.Errorf("no estimate for %s", productID)
+	}
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return DeliveryEstimate{
+		Earliest: base,
+		Latest:   base.Add(p.windows[productID]),
+	}, nil
+}
+
+func TestService_DeliveryEstimates(t *testing.T) {
+	provider := &fakeEstimateProvider{
+		windows: map[string]time.Duration{
+			"product-1": 48 * time.Hour,
+			"product-2": 72 * time.Hour,
+		},
+	}
+	service := NewService(nil, nil, ServiceConfig{}, WithEstimateProvider(provider))
+
+	cart := NewCart("user-123")
+	item1 := NewCartItem("product-1", 1, 1000)
+	item2 := NewCartItem("product-2", 2, 500)
+	cart.AddItem(item1)
+	cart.AddItem(item2)
+
+	estimates := service.DeliveryEstimates(context.Background(), cart)
+
+	assert.Len(t, estimates, 2)
+	assert.Equal(t, 48*time.Hour, estimates[item1.ItemID].Latest.Sub(estimates[item1.ItemID].Earliest))
+	assert.Equal(t, 72*time.Hour, estimates[item2.ItemID].Latest.Sub(estimates[item2.ItemID].Earliest))
+}
+
+func TestService_DeliveryEstimates_OmitsFailedLookups(t *testing.T) {
+	provider := &fakeEstimateProvider{
+		windows: map[string]time.Duration{"product-1": 24 * time.Hour},
+		failing: map[string]bool{"product-2": true},
+	}
+	service := NewService(nil, nil, ServiceConfig{}, WithEstimateProvider(provider))
+
+	cart := NewCart("user-123")
+	item1 := NewCartItem("product-1", 1, 1000)
+	item2 := NewCartItem("product-2", 1, 500)
+	cart.AddItem(item1)
+	cart.AddItem(item2)
+
+	estimates := service.DeliveryEstimates(context.Background(), cart)
+
+	assert.Len(t, estimates, 1)
+	assert.Contains(t, estimates, item1.ItemID)
+	assert.NotContains(t, estimates, item2.ItemID)
+}
+
+// concurrencyEstimateProvider records the most lookups it saw in flight at once.
+type concurrencyEstimateProvider struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (p *concurrencyEstimateProvider) EstimateFor(ctx context.Context, productID string, quantity int) (DeliveryEstimate, error) {
+	p.mu.Lock()
+	p.inFlight++
+	p.peak = max(p.peak, p.inFlight)
+	p.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	return DeliveryEstimate{}, nil
+}
+
+func TestService_DeliveryEstimates_BoundsConcurrency(t *testing.T) {
+	provider := &concurrencyEstimateProvider{}
+	service := NewService(nil, nil, ServiceConfig{}, WithEstimateProvider(provider))
+
+	cart := NewCart("user-123")
+	for i := 0; i < 3*maxEstimateLookups; i++ {
+		cart.AddItem(NewCartItem(fmt.Sprintf("product-%d", i), 1, 1000))
+	}
+
+	estimates := service.DeliveryEstimates(context.Background(), cart)
+
+	assert.Len(t, estimates, 3*maxEstimateLookups)
+	assert.LessOrEqual(t, provider.peak, maxEstimateLookups)
+}
+
+func TestService_DeliveryEstimates_NoProvider(t *testing.T) {
+	service := NewService(nil, nil, ServiceConfig{})
+
+	cart := NewCart("user-123")
+	cart.AddItem(NewCartItem("product-1", 1, 1000))
+
+	assert.Nil(t, service.DeliveryEstimates(context.Background(), cart))
+}
+
+// fakeRepository is a minimal map-backed Repository for service tests.
+type fakeRepository struct {
+	carts map[string]*Cart
+	saves int
+}
+
+func newFakeRepository(carts ...*Cart) *fakeRepository {
+	repo := &fakeRepository{carts: make(map[string]*Cart)}
+	for _, c := range carts {
+		stored := *c
+		repo.carts[c.UserID] = &stored
+	}
+	return repo
+}
+
+func (r *fakeRepository) GetCart(ctx context.Context, userID string) (*Cart, error) {
+	c, ok := r.carts[userID]
+	if !ok {
+		return nil, errors.ErrCartNotFound(userID)
+	}
+	copied := *c
+	copied.Items = append([]CartItem(nil), c.Items...)
+	return &copied, nil
+}
+
+func (r *fakeRepository) SaveCart(ctx context.Context, c *Cart) error {
+	stored := *c
+	r.carts[c.UserID] = &stored
+	r.saves++
+	return nil
+}
+
+func (r *fakeRepository) SaveCartWithVersion(ctx context.Context, c *Cart, expectedVersion int64) error {
+	if existing, ok := r.carts[c.UserID]; ok && existing.Version != expectedVersion {
+		return errors.ErrConflict(expectedVersion, existing.Version)
+	}
+	return r.SaveCart(ctx, c)
+}
+
+func (r *fakeRepository) DeleteCart(ctx context.Context, userID string) error {
+	delete(r.carts, userID)
+	return nil
+}
+
+// conflictingRepository simulates a concurrent writer by bumping the stored
+// version before the next conflicts versioned saves.
+type conflictingRepository struct {
+	*fakeRepository
+	conflicts int
+}
+
+func (r *conflictingRepository) SaveCartWithVersion(ctx context.Context, c *Cart, expectedVersion int64) error {
+	if r.conflicts > 0 {
+		r.conflicts--
+		r.carts[c.UserID].Version++
+	}
+	return r.fakeRepository.SaveCartWithVersion(ctx, c, expectedVersion)
+}
+
+func TestService_ReadCart_AutoExtendOnRead(t *testing.T) {
+	aging := NewCart("user-123")
+	aging.ExpiresAt = time.Now().UTC().Add(24 * time.Hour)
+
+	t.Run("extends when enabled", func(t *testing.T) {
+		repo := newFakeRepository(aging)
+		service := NewService(repo, nil, ServiceConfig{AutoExtendOnRead: true})
+
+		c, err := service.ReadCart(context.Background(), "user-123")
+		assert.NoError(t, err)
+		assert.True(t, c.ExpiresAt.After(aging.ExpiresAt.Add(5*24*time.Hour)))
+		assert.Equal(t, 1, repo.saves)
+
+		// A second read right away doesn't write again
+		_, err = service.ReadCart(context.Background(), "user-123")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, repo.saves)
+	})
+
+	t.Run("doesn't overwrite a concurrent edit", func(t *testing.T) {
+		repo := &conflictingRepository{fakeRepository: newFakeRepository(aging), conflicts: 1}
+		service := NewService(repo, nil, ServiceConfig{AutoExtendOnRead: true})
+
+		c, err := service.ReadCart(context.Background(), "user-123")
+		assert.NoError(t, err)
+		assert.Equal(t, aging.ExpiresAt, c.ExpiresAt)
+		assert.Equal(t, 0, repo.saves)
+		assert.Equal(t, aging.Version+1, repo.carts["user-123"].Version)
+	})
+
+	t.Run("leaves expiration alone when disabled", func(t *testing.T) {
+		repo := newFakeRepository(aging)
+		service := NewService(repo, nil, ServiceConfig{})
+
+		c, err := service.ReadCart(context.Background(), "user-123")
+		assert.NoError(t, err)
+		assert.Equal(t, aging.ExpiresAt, c.ExpiresAt)
+		assert.Equal(t, 0, repo.saves)
+	})
+}
+
+// recordingPublisher records cart.merged events, item_added attempts and
+// reorders; other events are ignored.
+type recordingPublisher struct {
+	merged    []string
+	pruned    []string
+	reordered int
+	added     int
+	addedErr  error
+}
+
+func (p *recordingPublisher) PublishCartCreated(ctx context.Context, c *Cart) error { return nil }
+func (p *recordingPublisher) PublishItemAdded(ctx context.Context, c *Cart, item *CartItem) error {
+	p.added++
+	return p.addedErr
+}
+func (p *recordingPublisher) PublishItemRemoved(ctx context.Context, c *Cart, itemID, productID string) error {
+	return nil
+}
+func (p *recordingPublisher) PublishItemUpdated(ctx context.Context, c *Cart, item *CartItem, prevQuantity int) error {
+	return nil
+}
+func (p *recordingPublisher) PublishCartCleared(ctx context.Context, c *Cart, itemsRemoved int, previousTotal int64) error {
+	return nil
+}
+func (p *recordingPublisher) PublishPriceCorrected(ctx context.Context, c *Cart, item *CartItem, previousPrice int64) error {
+	return nil
+}
+func (p *recordingPublisher) PublishCartMerged(ctx context.Context, c *Cart, guestID string, itemsMerged int) error {
+	p.merged = append(p.merged, fmt.Sprintf("%s<-%s:%d:%d", c.UserID, guestID, itemsMerged, c.TotalPrice()))
+	return nil
+}
+
+func (p *recordingPublisher) PublishItemsPruned(ctx context.Context, c *Cart, items []CartItem, action StaleItemAction) error {
+	for _, item := range items {
+		p.pruned = append(p.pruned, fmt.Sprintf("%s:%s", action, item.ProductID))
+	}
+	return nil
+}
+
+func (p *recordingPublisher) PublishItemsReordered(ctx context.Context, c *Cart) error {
+	p.reordered++
+	return nil
+}
+
+// countingCollector records counter increments with their full label sets,
+// so counters with several labels can be asserted regardless of map order.
+type countingCollector struct {
+	mu       sync.Mutex
+	counters []recordedCounter
+}
+
+type recordedCounter struct {
+	name   string
+	labels map[string]string
+}
+
+func (c *countingCollector) IncrementCounter(name string, labels map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counters = append(c.counters, recordedCounter{name: name, labels: maps.Clone(labels)})
+}
+
+func (c *countingCollector) ObserveHistogram(name string, value float64, labels map[string]string) {}
+
+func (c *countingCollector) SetGauge(name string, value float64, labels map[string]string) {}
+
+// count returns how many times the counter was incremented with exactly these labels.
+func (c *countingCollector) count(name string, labels map[string]string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, counter := range c.counters {
+		if counter.name == name && maps.Equal(counter.labels, labels) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestService_ReorderItems(t *testing.T) {
+	ctx := context.Background()
+	c := NewCart("user-123")
+	c.Items = []CartItem{{ItemID: "item-1", ProductID: "product-1", Quantity: 1}, {ItemID: "item-2", ProductID: "product-2", Quantity: 1}}
+	repo := &versionCountingRepository{fakeRepository: newFakeRepository(c)}
+	publisher := &recordingPublisher{}
+	service := NewService(repo, publisher, ServiceConfig{PublishEvents: true})
+
+	reordered, err := service.ReorderItems(ctx, "user-123", []string{"item-2", "item-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "item-2", reordered.Items[0].ItemID)
+	assert.Equal(t, 1, repo.versioned)
+	assert.Equal(t, 1, publisher.reordered)
+
+	// A concurrent edit fails the reorder instead of being overwritten
+	conflicting := &conflictingRepository{fakeRepository: newFakeRepository(c), conflicts: 1}
+	_, err = NewService(conflicting, nil, ServiceConfig{}).ReorderItems(ctx, "user-123", []string{"item-2", "item-1"})
+	assert.True(t, errors.IsCode(err, errors.CodeConflict))
+	assert.Equal(t, "item-1", conflicting.carts["user-123"].Items[0].ItemID)
+}
+
+func TestService_MergeGuestCart_PublishesEvent(t *testing.T) {
+	userCart := NewCart("user-123")
+	assert.NoError(t, userCart.AddItem(NewCartItem("product-1", 1, 1000)))
+	guestCart := NewCart("guest-456")
+	assert.NoError(t, guestCart.AddItem(NewCartItem("product-1", 3, 1000)))
+	assert.NoError(t, guestCart.AddItem(NewCartItem("product-2", 1, 500)))
+
+	publisher := &recordingPublisher{}
+	service := NewService(newFakeRepository(userCart, guestCart), publisher, ServiceConfig{PublishEvents: true})
+
+	_, err := service.MergeGuestCart(context.Background(), "user-123", "guest-456")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user-123<-guest-456:2:3500"}, publisher.merged)
+
+	// No guest cart means nothing was merged and no event
+	_, err = service.MergeGuestCart(context.Background(), "user-123", "guest-456")
+	assert.NoError(t, err)
+	assert.Len(t, publisher.merged, 1)
+}
+
+// transactionalRepository implements MergeRepository over a fakeRepository.
+// guestChanged bumps the guest cart's version before the next merge, as a
+// concurrent merge or update would.
+type transactionalRepository struct {
+	*fakeRepository
+	merges       int
+	guestChanged bool
+}
+
+func (r *transactionalRepository) SaveMergedCart(ctx context.Context, merged *Cart, expectedVersion int64, guestID string, guestVersion int64, evts []events.Event) error {
+	if r.guestChanged {
+		r.carts[guestID].Version++
+	}
+	if existing, ok := r.carts[merged.UserID]; ok && existing.Version != expectedVersion {
+		return errors.ErrConflict(expectedVersion, existing.Version)
+	}
+	if guest, ok := r.carts[guestID]; !ok || guest.Version != guestVersion {
+		return errors.ErrConflict(guestVersion, 0)
+	}
+	stored := *merged
+	r.carts[merged.UserID] = &stored
+	delete(r.carts, guestID)
+	r.merges++
+	return nil
+}
+
+func TestService_MergeGuestCart_Transactional(t *testing.T) {
+	userCart := NewCart("user-123")
+	userCart.Version = 1
+	guestCart := NewCart("guest-456")
+	guestCart.Version = 1
+	assert.NoError(t, guestCart.AddItem(NewCartItem("product-1", 2, 1000)))
+
+	t.Run("saves and deletes together", func(t *testing.T) {
+		repo := &transactionalRepository{fakeRepository: newFakeRepository(userCart, guestCart)}
+		service := NewService(repo, nil, ServiceConfig{})
+
+		merged, err := service.MergeGuestCart(context.Background(), "user-123", "guest-456")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, merged.TotalQuantity())
+		assert.Equal(t, 1, repo.merges)
+		assert.Equal(t, 0, repo.saves)
+		assert.NotContains(t, repo.carts, "guest-456")
+	})
+
+	t.Run("guest cart changed", func(t *testing.T) {
+		repo := &transactionalRepository{fakeRepository: newFakeRepository(userCart, guestCart), guestChanged: true}
+		service := NewService(repo, nil, ServiceConfig{})
+
+		_, err := service.MergeGuestCart(context.Background(), "user-123", "guest-456")
+		assert.True(t, errors.IsCode(err, errors.CodeConflict))
+		assert.Equal(t, int64(1), repo.carts["user-123"].Version)
+		assert.Contains(t, repo.carts, "guest-456")
+	})
+}
+
+func TestService_EventPublishMode(t *testing.T) {
+	req := AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 1000}
+	failed := map[string]string{"event_type": "cart.item_added", "status": "failed"}
+
+	t.Run("async ignores publish failures", func(t *testing.T) {
+		publisher := &recordingPublisher{addedErr: fmt.Errorf("bus unavailable")}
+		collector := &countingCollector{}
+		service := NewService(newFakeRepository(), publisher, ServiceConfig{
+			PublishEvents:    true,
+			EventPublishMode: EventPublishModeAsync,
+		}, WithMetrics(collector))
+
+		c, err := service.AddItem(context.Background(), "user-123", req)
+		assert.NoError(t, err)
+		assert.Len(t, c.Items, 1)
+		assert.Equal(t, 1, publisher.added)
+		assert.Equal(t, 1, collector.count(metrics.MetricEventPublishTotal, failed))
+	})
+
+	t.Run("sync fails the operation after retries", func(t *testing.T) {
+		publisher := &recordingPublisher{addedErr: fmt.Errorf("bus unavailable")}
+		collector := &countingCollector{}
+		service := NewService(newFakeRepository(), publisher, ServiceConfig{
+			PublishEvents:     true,
+			EventPublishMode:  EventPublishModeSync,
+			EventPublishRetry: resilience.RetryConfig{MaxAttempts: 3, Multiplier: 1},
+		}, WithMetrics(collector))
+
+		_, err := service.AddItem(context.Background(), "user-123", req)
+		assert.True(t, errors.IsCode(err, errors.CodeEventPublishError))
+		assert.Equal(t, 3, publisher.added)
+		assert.Equal(t, 1, collector.count(metrics.MetricEventPublishTotal, failed))
+	})
+
+	t.Run("sync succeeds when publishing does", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		service := NewService(newFakeRepository(), publisher, ServiceConfig{
+			PublishEvents:    true,
+			EventPublishMode: EventPublishModeSync,
+		})
+
+		_, err := service.AddItem(context.Background(), "user-123", req)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, publisher.added)
+	})
+}
+
+func TestService_ChangeFeed(t *testing.T) {
+	existing := NewCart("user-123")
+	assert.NoError(t, existing.AddItem(NewCartItem("product-1", 1, 1000)))
+	item := existing.Items[0]
+
+	feed := NewChangeFeed()
+	service := NewService(newFakeRepository(existing), nil, ServiceConfig{}, WithChangeFeed(feed))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, err := service.SubscribeChanges(ctx, "user-123")
+	assert.NoError(t, err)
+
+	updated, err := service.UpdateItemQuantity(context.Background(), "user-123", UpdateItemRequest{ItemID: item.ItemID, Quantity: 3})
+	assert.NoError(t, err)
+
+	// The change reflects the saved cart, not the cart as it was before saving
+	change := <-changes
+	assert.Equal(t, CartChange{
+		Type:          "cart.item_updated",
+		UserID:        "user-123",
+		Version:       updated.Version,
+		ItemID:        item.ItemID,
+		ProductID:     "product-1",
+		Quantity:      3,
+		ItemCount:     1,
+		TotalQuantity: 3,
+		TotalPrice:    3000,
+	}, change)
+
+	// Canceling the context ends the subscription
+	cancel()
+	_, open := <-changes
+	assert.False(t, open)
+	assert.Equal(t, 0, feed.Subscribers("user-123"))
+}
+
+func TestService_QuantityWarnings(t *testing.T) {
+	service := NewService(newFakeRepository(), nil, ServiceConfig{SoftQuantityLimit: 10})
+
+	c, err := service.AddItem(context.Background(), "user-123", AddItemRequest{ProductID: "product-1", Quantity: 12, UnitPrice: 100})
+	assert.NoError(t, err)
+
+	// The item is added despite the warning
+	item, _ := c.FindItemByProductID("product-1")
+	assert.Equal(t, 12, item.Quantity)
+
+	warnings := service.QuantityWarnings(item)
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, WarningBulkQuantity, warnings[0].Code)
+		assert.Equal(t, item.ItemID, warnings[0].ItemID)
+	}
+
+	item.Quantity = 10
+	assert.Empty(t, service.QuantityWarnings(item))
+
+	// Disabled by default
+	assert.Empty(t, NewService(newFakeRepository(), nil, ServiceConfig{}).QuantityWarnings(&CartItem{Quantity: 99}))
+}
+
+func TestService_ItemLimitWarnings(t *testing.T) {
+	service := NewService(newFakeRepository(), nil, ServiceConfig{SoftItemLimit: 3, MaxItemsPerCart: 4})
+	ctx := context.Background()
+
+	// Warned from the soft limit on, while items can still be added
+	var c *Cart
+	for i := 1; i <= 4; i++ {
+		var err error
+		c, err = service.AddItem(ctx, "user-123", AddItemRequest{ProductID: fmt.Sprintf("product-%d", i), Quantity: 1, UnitPrice: 100})
+		assert.NoError(t, err)
+
+		warnings := service.ItemLimitWarnings(ctx, c)
+		if i < 3 {
+			assert.Empty(t, warnings, "%d items", i)
+		} else if assert.Len(t, warnings, 1, "%d items", i) {
+			assert.Equal(t, WarningCartNearingLimit, warnings[0].Code)
+		}
+	}
+
+	// At the hard limit the existing error applies
+	_, err := service.AddItem(ctx, "user-123", AddItemRequest{ProductID: "product-5", Quantity: 1, UnitPrice: 100})
+	assert.True(t, errors.IsCode(err, errors.CodeCartLimitExceeded))
+
+	// Disabled by default
+	assert.Empty(t, NewService(newFakeRepository(), nil, ServiceConfig{}).ItemLimitWarnings(ctx, c))
+}
+
+// fakeCatalog serves current prices and stock levels per product.
+type fakeCatalog struct {
+	prices map[string]int64
+	stock  map[string]int
+}
+
+func (f *fakeCatalog) ValidatePrice(ctx context.Context, productID string, price int64) (bool, error) {
+	return f.prices[productID] == price, nil
+}
+
+func (f *fakeCatalog) GetCurrentPrice(ctx context.Context, productID string) (int64, error) {
+	price, ok := f.prices[productID]
+	if !ok {
+		return 0, fmt.Errorf("no price for %s", productID)
+	}
+	return price, nil
+}
+
+func (f *fakeCatalog) CheckAvailability(ctx context.Context, productID string, quantity int) (bool, error) {
+	return f.stock[productID] >= quantity, nil
+}
+
+func (f *fakeCatalog) AvailableQuantity(ctx context.Context, productID string) (int, error) {
+	return f.stock[productID], nil
+}
+
+func (f *fakeCatalog) ReserveStock(ctx context.Context, productID string, quantity int) (string, error) {
+	return "", nil
+}
+
+func (f *fakeCatalog) ReleaseReservation(ctx context.Context, reservationID string) error {
+	return nil
+}
+
+func TestService_ValidateCart(t *testing.T) {
+	c := NewCart("user-123")
+	item1 := NewCartItem("product-1", 2, 1000)
+	item2 := NewCartItem("product-2", 5, 500)
+	c.AddItem(item1)
+	c.AddItem(item2)
+
+	catalog := &fakeCatalog{
+		prices: map[string]int64{"product-1": 1200, "product-2": 500},
+		stock:  map[string]int{"product-1": 10, "product-2": 3},
+	}
+	repo := newFakeRepository(c)
+	service := NewService(repo, nil, ServiceConfig{}, WithPriceValidator(catalog), WithInventoryChecker(catalog))
+
+	report, err := service.ValidateCart(context.Background(), "user-123", false)
+	assert.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.False(t, report.Repriced)
+	if assert.Len(t, report.Items, 2) {
+		assert.True(t, report.Items[0].PriceChanged)
+		assert.Equal(t, int64(1000), report.Items[0].OldPrice)
+		assert.Equal(t, int64(1200), report.Items[0].NewPrice)
+		assert.True(t, report.Items[0].InStock)
+		assert.False(t, report.Items[1].PriceChanged)
+		assert.False(t, report.Items[1].InStock)
+		assert.Equal(t, 3, *report.Items[1].AvailableQty)
+	}
+	assert.Equal(t, 0, repo.saves)
+
+	// Repricing stores the current price and bumps the version once
+	report, err = service.ValidateCart(context.Background(), "user-123", true)
+	assert.NoError(t, err)
+	assert.True(t, report.Repriced)
+	assert.Equal(t, c.Version+1, report.Version)
+	assert.Equal(t, 1, repo.saves)
+
+	stored, _ := repo.GetCart(context.Background(), "user-123")
+	updated, _ := stored.FindItemByProductID("product-1")
+	assert.Equal(t, int64(1200), updated.UnitPrice)
+}
+
+func TestService_ValidateCart_NoCollaborators(t *testing.T) {
+	service := NewService(newFakeRepository(NewCart("user-123")), nil, ServiceConfig{})
+
+	_, err := service.ValidateCart(context.Background(), "user-123", false)
+	assert.True(t, errors.IsCode(err, errors.CodeServiceUnavailable))
+}
+
+func TestService_ProductPolicy(t *testing.T) {
+	c := NewCart("user-123")
+	recalled := NewCartItem("product-recalled", 1, 1000)
+	c.AddItem(recalled)
+	c.AddItem(NewCartItem("product-1", 1, 500))
+
+	policy := NewStaticProductPolicy(nil, []string{"product-recalled"})
+	repo := newFakeRepository(c)
+	service := NewService(repo, nil, ServiceConfig{}, WithProductPolicy(policy))
+
+	_, err := service.AddItem(context.Background(), "user-123", AddItemRequest{ProductID: "product-recalled", Quantity: 1, UnitPrice: 1000})
+	assert.True(t, errors.IsCode(err, errors.CodeProductNotAllowed))
+
+	_, err = service.AddItem(context.Background(), "user-123", AddItemRequest{ProductID: "product-2", Quantity: 1, UnitPrice: 700})
+	assert.NoError(t, err)
+
+	// Items already in the cart are flagged, not removed
+	report, err := service.ValidateCart(context.Background(), "user-123", false)
+	assert.NoError(t, err)
+	assert.False(t, report.Valid)
+	if assert.Len(t, report.Items, 3) {
+		assert.True(t, report.Items[0].NotAllowed)
+		assert.Equal(t, PolicyReasonDenied, report.Items[0].PolicyReason)
+		assert.False(t, report.Items[1].NotAllowed)
+	}
+}
+
+func TestStaticProductPolicy(t *testing.T) {
+	policy := NewStaticProductPolicy([]string{"product-1", "product-2"}, []string{"product-2"})
+
+	allowed, _ := policy.IsAllowed(context.Background(), "product-1")
+	assert.True(t, allowed)
+
+	allowed, reason := policy.IsAllowed(context.Background(), "product-2")
+	assert.False(t, allowed)
+	assert.Equal(t, PolicyReasonDenied, reason)
+
+	allowed, reason = policy.IsAllowed(context.Background(), "product-3")
+	assert.False(t, allowed)
+	assert.Equal(t, PolicyReasonNotAllowlisted, reason)
+}
+
+// fakeAuditor records entries, or fails every write when err is set.
+type fakeAuditor struct {
+	entries []audit.AuditEntry
+	err     error
+}
+
+func (a *fakeAuditor) Record(ctx context.Context, entry audit.AuditEntry) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func TestService_Audit(t *testing.T) {
+	ctx := context.Background()
+	auditor := &fakeAuditor{}
+	service := NewService(newFakeRepository(), nil, ServiceConfig{}, WithAuditor(auditor))
+
+	c, err := service.AddItem(ctx, "user-123", AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 100})
+	assert.NoError(t, err)
+	item, _ := c.FindItemByProductID("product-1")
+
+	_, err = service.UpdateItemQuantity(ctx, "user-123", UpdateItemRequest{ItemID: item.ItemID, Quantity: 3})
+	assert.NoError(t, err)
+	assert.NoError(t, service.ClearCart(ctx, "user-123"))
+
+	if assert.Len(t, auditor.entries, 3) {
+		assert.Equal(t, audit.OpAddItem, auditor.entries[0].Operation)
+		assert.Equal(t, item.ItemID, auditor.entries[0].ItemID)
+		assert.Equal(t, c.Version-1, auditor.entries[0].VersionBefore)
+		assert.Equal(t, c.Version, auditor.entries[0].VersionAfter)
+
+		assert.Equal(t, audit.OpUpdateItem, auditor.entries[1].Operation)
+		assert.Equal(t, c.Version, auditor.entries[1].VersionBefore)
+		assert.Equal(t, c.Version+1, auditor.entries[1].VersionAfter)
+
+		assert.Equal(t, audit.OpClearCart, auditor.entries[2].Operation)
+		assert.Empty(t, auditor.entries[2].ItemID)
+	}
+}
+
+func TestService_Audit_FailuresDontFailOperations(t *testing.T) {
+	collector := &countingCollector{}
+	service := NewService(newFakeRepository(), nil, ServiceConfig{},
+		WithAuditor(&fakeAuditor{err: fmt.Errorf("audit store unavailable")}), WithMetrics(collector))
+
+	_, err := service.AddItem(context.Background(), "user-123", AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), service.AuditFailures())
+	assert.Equal(t, 1, collector.count(metrics.MetricAuditRecordTotal, map[string]string{
+		"operation": audit.OpAddItem,
+		"status":    "failed",
+	}))
+}
+
+func TestService_CartExpiration(t *testing.T) {
+	clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	service := NewService(newFakeRepository(), nil, ServiceConfig{
+		CartExpiration:      30 * 24 * time.Hour,
+		GuestCartExpiration: 24 * time.Hour,
+		GuestUserIDPrefix:   "guest-",
+	}, WithClock(clock))
+
+	user, _, err := service.GetOrCreateCart(context.Background(), "user-123")
+	assert.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(30*24*time.Hour), user.ExpiresAt)
+
+	guest, _, err := service.GetOrCreateCart(context.Background(), "guest-abc")
+	assert.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(24*time.Hour), guest.ExpiresAt)
+
+	// Touching keeps the guest TTL
+	clock.Advance(time.Hour)
+	guest, err = service.TouchCart(context.Background(), "guest-abc")
+	assert.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(24*time.Hour), guest.ExpiresAt)
+
+	// Carts expire once the clock passes their expiration
+	clock.Advance(25 * time.Hour)
+	_, err = service.GetCart(context.Background(), "guest-abc")
+	assert.True(t, errors.IsCode(err, errors.CodeCartExpired))
+}
+
+func TestService_CreateGuestCart(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewService(newFakeRepository(), nil, ServiceConfig{}).CreateGuestCart(ctx)
+	assert.True(t, errors.IsCode(err, errors.CodeServiceUnavailable))
+
+	clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	service := NewService(newFakeRepository(), nil, ServiceConfig{
+		CartExpiration:      30 * 24 * time.Hour,
+		GuestCartExpiration: 24 * time.Hour,
+		AnonymousCarts:      true,
+	}, WithClock(clock))
+
+	first, err := service.CreateGuestCart(ctx)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(first.UserID, DefaultGuestUserIDPrefix))
+	assert.True(t, service.IsGuestID(first.UserID))
+	assert.Equal(t, clock.Now().Add(24*time.Hour), first.ExpiresAt)
+
+	second, err := service.CreateGuestCart(ctx)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.UserID, second.UserID)
+
+	stored, err := service.GetCart(ctx, first.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, stored.ID)
+}
+
+func TestService_GiftWrap(t *testing.T) {
+	service := NewService(newFakeRepository(), nil, ServiceConfig{GiftWrapFee: 300})
+	ctx := context.Background()
+
+	c, err := service.AddItem(ctx, "user-123", AddItemRequest{ProductID: "product-1", Quantity: 2, UnitPrice: 1000, GiftWrap: true})
+	assert.NoError(t, err)
+	c, err = service.AddItem(ctx, "user-123", AddItemRequest{ProductID: "product-2", Quantity: 1, UnitPrice: 500})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), c.Items[0].GiftWrapFee)
+	assert.Equal(t, int64(2500), c.TotalPrice())
+	assert.Equal(t, int64(3100), c.TotalWithFees())
+
+	// A passed fee overrides the configured one
+	wrap := true
+	c, err = service.UpdateItemQuantity(ctx, "user-123", UpdateItemRequest{ItemID: c.Items[1].ItemID, Quantity: 1, GiftWrap: &wrap, GiftWrapFee: 150})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3250), c.Summary().TotalWithFees)
+
+	// Omitting the flag leaves wrapping unchanged; false removes it
+	c, err = service.UpdateItemQuantity(ctx, "user-123", UpdateItemRequest{ItemID: c.Items[0].ItemID, Quantity: 1})
+	assert.NoError(t, err)
+	assert.True(t, c.Items[0].GiftWrap)
+	wrap = false
+	c, err = service.UpdateItemQuantity(ctx, "user-123", UpdateItemRequest{ItemID: c.Items[0].ItemID, Quantity: 1, GiftWrap: &wrap})
+	assert.NoError(t, err)
+	assert.False(t, c.Items[0].GiftWrap)
+	assert.Equal(t, int64(1650), c.TotalWithFees())
+}
+
+func TestService_UpdateItemQuantityWithAutoRetry(t *testing.T) {
+	c := NewCart("user-123")
+	item := NewCartItem("product-1", 1, 1000)
+	c.AddItem(item)
+	c.Version = 1
+
+	retry := resilience.RetryConfig{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1}
+
+	t.Run("retries conflicts", func(t *testing.T) {
+		repo := &conflictingRepository{fakeRepository: newFakeRepository(c), conflicts: 2}
+		collector := metrics.NewInMemoryCollector()
+		service := NewService(repo, nil, ServiceConfig{ConflictRetry: retry}, WithMetrics(collector))
+
+		updated, err := service.UpdateItemQuantityWithAutoRetry(context.Background(), "user-123", UpdateItemRequest{ItemID: item.ItemID, Quantity: 5})
+		assert.NoError(t, err)
+		assert.Equal(t, 5, updated.Items[0].Quantity)
+		assert.Equal(t, float64(2), collector.GetCounter(metrics.MetricConflictRetryTotal, map[string]string{"operation": "update_item"}))
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		repo := &conflictingRepository{fakeRepository: newFakeRepository(c), conflicts: 3}
+		service := NewService(repo, nil, ServiceConfig{ConflictRetry: retry})
+
+		_, err := service.UpdateItemQuantityWithAutoRetry(context.Background(), "user-123", UpdateItemRequest{ItemID: item.ItemID, Quantity: 5})
+		assert.True(t, errors.IsCode(err, errors.CodeConflict))
+	})
+
+	t.Run("does not retry a pinned version", func(t *testing.T) {
+		repo := &conflictingRepository{fakeRepository: newFakeRepository(c), conflicts: 1}
+		service := NewService(repo, nil, ServiceConfig{ConflictRetry: retry})
+
+		_, err := service.UpdateItemQuantityWithAutoRetry(context.Background(), "user-123", UpdateItemRequest{ItemID: item.ItemID, Quantity: 5, ExpectedVersion: 1})
+		assert.True(t, errors.IsCode(err, errors.CodeConflict))
+	})
+}
+
+func TestService_AdjustItemQuantity(t *testing.T) {
+	tests := []struct {
+		name         string
+		quantity     int
+		delta        int
+		keepOnZero   bool
+		wantQuantity int // 0 means the item was removed
+	}{
+		{name: "increments", quantity: 2, delta: 3, wantQuantity: 5},
+		{name: "decrements", quantity: 2, delta: -1, wantQuantity: 1},
+		{name: "clamps to max", quantity: 98, delta: 5, wantQuantity: MaxQuantityPerItem},
+		{name: "removes at zero", quantity: 1, delta: -1},
+		{name: "removes below zero", quantity: 2, delta: -5},
+		{name: "keeps at min when configured", quantity: 2, delta: -5, keepOnZero: true, wantQuantity: MinQuantityPerItem},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCart("user-123")
+			item := NewCartItem("product-1", tt.quantity, 100)
+			c.AddItem(item)
+			c.Version = 1
+			service := NewService(newFakeRepository(c), nil, ServiceConfig{KeepItemOnZeroAdjust: tt.keepOnZero})
+
+			updated, err := service.AdjustItemQuantity(context.Background(), "user-123", item.ItemID, tt.delta)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), updated.Version)
+
+			adjusted, _ := updated.FindItem(item.ItemID)
+			if tt.wantQuantity == 0 {
+				assert.Nil(t, adjusted)
+				return
+			}
+			if assert.NotNil(t, adjusted) {
+				assert.Equal(t, tt.wantQuantity, adjusted.Quantity)
+			}
+		})
+	}
+
+	t.Run("conflicts with a concurrent change", func(t *testing.T) {
+		c := NewCart("user-123")
+		item := NewCartItem("product-1", 1, 100)
+		c.AddItem(item)
+		c.Version = 1
+		repo := &conflictingRepository{fakeRepository: newFakeRepository(c), conflicts: 1}
+		service := NewService(repo, nil, ServiceConfig{})
+
+		_, err := service.AdjustItemQuantity(context.Background(), "user-123", item.ItemID, 1)
+		assert.True(t, errors.IsCode(err, errors.CodeConflict))
+	})
+}
+
+// blockingRepository holds versioned saves until release is closed, signalling
+// entered as each one starts.
+type blockingRepository struct {
+	*fakeRepository
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRepository) SaveCartWithVersion(ctx context.Context, c *Cart, expectedVersion int64) error {
+	r.entered <- struct{}{}
+	<-r.release
+	return r.fakeRepository.SaveCartWithVersion(ctx, c, expectedVersion)
+}
+
+func TestService_CartConcurrency(t *testing.T) {
+	c := NewCart("user-123")
+	item := NewCartItem("product-1", 1, 100)
+	c.AddItem(item)
+	c.Version = 1
+	repo := &blockingRepository{
+		fakeRepository: newFakeRepository(c),
+		entered:        make(chan struct{}),
+		release:        make(chan struct{}),
+	}
+	service := NewService(repo, nil, ServiceConfig{CartConcurrency: 1, CartMaxWaiting: 1})
+
+	done := make(chan error)
+	go func() {
+		_, err := service.UpdateItemQuantity(context.Background(), "user-123", UpdateItemRequest{ItemID: item.ItemID, Quantity: 2})
+		done <- err
+	}()
+	<-repo.entered
+
+	// The cart's queue is full while the first update is in flight
+	_, err := service.RemoveItem(context.Background(), "user-123", item.ItemID)
+	assert.True(t, errors.IsCode(err, errors.CodeServiceUnavailable))
+
+	// Other carts are unaffected
+	_, err = service.AddItem(context.Background(), "user-456", AddItemRequest{ProductID: "product-2", Quantity: 1, UnitPrice: 100})
+	assert.NoError(t, err)
+
+	close(repo.release)
+	assert.NoError(t, <-done)
+
+	updated, err := service.RemoveItem(context.Background(), "user-123", item.ItemID)
+	assert.NoError(t, err)
+	assert.Empty(t, updated.Items)
+}
+
+func TestService_AddItemDedupWindow(t *testing.T) {
+	ctx := context.Background()
+	req := AddItemRequest{ProductID: "product-1", Quantity: 2, UnitPrice: 100}
+
+	t.Run("identical adds within the window apply once", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil, ServiceConfig{AddItemDedupWindow: time.Minute})
+
+		_, err := service.AddItem(ctx, "user-123", req)
+		assert.NoError(t, err)
+		c, err := service.AddItem(ctx, "user-123", req)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, c.Items[0].Quantity)
+
+		// A different quantity is a separate add
+		c, err = service.AddItem(ctx, "user-123", AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 100})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, c.Items[0].Quantity)
+	})
+
+	t.Run("failed adds are not remembered", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil, ServiceConfig{AddItemDedupWindow: time.Minute},
+			WithProductPolicy(NewStaticProductPolicy(nil, []string{"product-1"})))
+
+		_, err := service.AddItem(ctx, "user-123", req)
+		assert.Error(t, err)
+		assert.NotContains(t, service.addDedup.seen, addDedupKey("user-123", req))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil, ServiceConfig{})
+
+		_, err := service.AddItem(ctx, "user-123", req)
+		assert.NoError(t, err)
+		c, err := service.AddItem(ctx, "user-123", req)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, c.Items[0].Quantity)
+	})
+}
+
+// sequentialIDs is an IDGenerator returning id-1, id-2, ...
+type sequentialIDs struct{ n int }
+
+func (g *sequentialIDs) NewID() string {
+	g.n++
+	return fmt.Sprintf("id-%d", g.n)
+}
+
+func TestService_IDGenerator(t *testing.T) {
+	service := NewService(newFakeRepository(), nil, ServiceConfig{}, WithIDGenerator(&sequentialIDs{}))
+
+	c, err := service.AddItem(context.Background(), "user-123", AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, "id-1", c.ID)
+	assert.Equal(t, "id-2", c.Items[0].ItemID)
+}
+
+func TestTimeOrderedUUIDGenerator(t *testing.T) {
+	var ids TimeOrderedUUIDGenerator
+	first := ids.NewID()
+	assert.Less(t, first, ids.NewID())
+}
+
+func TestService_PriceDrops(t *testing.T) {
+	c := NewCart("user-123")
+	cheaper := NewCartItem("product-1", 1, 1000)
+	pricier := NewCartItem("product-2", 1, 500)
+	unknown := NewCartItem("product-3", 1, 700)
+	c.AddItem(cheaper)
+	c.AddItem(pricier)
+	c.AddItem(unknown)
+
+	catalog := &fakeCatalog{prices: map[string]int64{"product-1": 800, "product-2": 600}}
+	service := NewService(newFakeRepository(c), nil, ServiceConfig{}, WithPriceValidator(catalog))
+
+	report, err := service.PriceDrops(context.Background(), "user-123")
+	assert.NoError(t, err)
+	if assert.Len(t, report.Items, 1) {
+		assert.Equal(t, cheaper.ItemID, report.Items[0].ItemID)
+		assert.Equal(t, int64(200), report.Items[0].Delta)
+	}
+	assert.Equal(t, []string{unknown.ItemID}, report.FailedLookups)
+}
+
+func TestService_AddItem_LimitOverride(t *testing.T) {
+	auditor := &fakeAuditor{}
+	service := NewService(newFakeRepository(), nil, ServiceConfig{
+		MaxItemsPerCart:      2,
+		AdminMaxItemsPerCart: 3,
+	}, WithAuditor(auditor))
+	ctx := context.Background()
+	adminCtx := WithLimitOverride(ctx)
+
+	for _, productID := range []string{"product-1", "product-2"} {
+		_, err := service.AddItem(ctx, "user-123", AddItemRequest{ProductID: productID, Quantity: 1, UnitPrice: 100})
+		assert.NoError(t, err)
+	}
+
+	// Regular requests stop at MaxItemsPerCart
+	_, err := service.AddItem(ctx, "user-123", AddItemRequest{ProductID: "product-3", Quantity: 1, UnitPrice: 100})
+	assert.True(t, errors.IsCode(err, errors.CodeCartLimitExceeded), "got %v", err)
+
+	// Overrides go up to AdminMaxItemsPerCart and are audited
+	c, err := service.AddItem(adminCtx, "user-123", AddItemRequest{ProductID: "product-3", Quantity: 1, UnitPrice: 100})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, c.ItemCount())
+	}
+	if assert.NotEmpty(t, auditor.entries) {
+		assert.Equal(t, audit.OpOverrideItemLimit, auditor.entries[len(auditor.entries)-1].Operation)
+	}
+
+	_, err = service.AddItem(adminCtx, "user-123", AddItemRequest{ProductID: "product-4", Quantity: 1, UnitPrice: 100})
+	assert.True(t, errors.IsCode(err, errors.CodeCartLimitExceeded), "got %v", err)
+}
+
+func TestService_LockCart(t *testing.T) {
+	ctx := context.Background()
+	clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	service := NewService(newFakeRepository(), nil, ServiceConfig{LockTimeout: time.Minute}, WithClock(clock))
+	req := AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 100}
+
+	_, err := service.AddItem(ctx, "user-123", req)
+	assert.NoError(t, err)
+
+	c, err := service.LockCart(ctx, "user-123")
+	assert.NoError(t, err)
+	assert.True(t, c.IsLocked())
+	assert.Equal(t, clock.Now().Add(time.Minute), c.LockedUntil)
+
+	_, err = service.AddItem(ctx, "user-123", req)
+	assert.True(t, errors.IsCode(err, errors.CodeCartLocked))
+	assert.True(t, errors.IsCode(service.ClearCart(ctx, "user-123"), errors.CodeCartLocked))
+	assert.True(t, errors.IsCode(service.DeleteCart(ctx, "user-123"), errors.CodeCartLocked))
+	_, err = service.TouchCart(ctx, "user-123")
+	assert.True(t, errors.IsCode(err, errors.CodeCartLocked))
+
+	// Unlocking allows edits again
+	_, err = service.UnlockCart(ctx, "user-123")
+	assert.NoError(t, err)
+	c, err = service.AddItem(ctx, "user-123", req)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.Items[0].Quantity)
+
+	// An abandoned lock expires on its own
+	_, err = service.LockCart(ctx, "user-123")
+	assert.NoError(t, err)
+	clock.Advance(time.Minute)
+	_, err = service.AddItem(ctx, "user-123", req)
+	assert.NoError(t, err)
+}
+
+// snapshotRepository is a fakeRepository that keeps cart snapshots.
+type snapshotRepository struct {
+	*fakeRepository
+	snapshots []CartSnapshot
+}
+
+func (r *snapshotRepository) SaveSnapshot(ctx context.Context, snapshot CartSnapshot, ttl time.Duration) error {
+	r.snapshots = append(r.snapshots, snapshot)
+	return nil
+}
+
+func (r *snapshotRepository) ListSnapshots(ctx context.Context, userID string) ([]CartSnapshot, error) {
+	return r.snapshots, nil
+}
+
+func TestService_SnapshotHistory(t *testing.T) {
+	ctx := context.Background()
+	req := AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 100}
+
+	t.Run("records each mutation", func(t *testing.T) {
+		repo := &snapshotRepository{fakeRepository: newFakeRepository()}
+		service := NewService(repo, nil, ServiceConfig{SnapshotHistory: true})
+
+		_, err := service.AddItem(ctx, "user-123", req)
+		assert.NoError(t, err)
+		c, err := service.AddItem(ctx, "user-123", req)
+		assert.NoError(t, err)
+
+		history, err := service.GetCartHistory(ctx, "user-123")
+		assert.NoError(t, err)
+		if assert.Len(t, history, 2) {
+			assert.Equal(t, c.Version, history[1].Version)
+			assert.Equal(t, int64(200), history[1].Total)
+			assert.Equal(t, audit.OpAddItem, history[1].Operation)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		repo := &snapshotRepository{fakeRepository: newFakeRepository()}
+		service := NewService(repo, nil, ServiceConfig{})
+
+		_, err := service.AddItem(ctx, "user-123", req)
+		assert.NoError(t, err)
+		assert.Empty(t, repo.snapshots)
+		_, err = service.GetCartHistory(ctx, "user-123")
+		assert.True(t, errors.IsCode(err, errors.CodeServiceUnavailable))
+	})
+
+	t.Run("requires a snapshot store", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil, ServiceConfig{SnapshotHistory: true})
+
+		_, err := service.GetCartHistory(ctx, "user-123")
+		assert.True(t, errors.IsCode(err, errors.CodeServiceUnavailable))
+	})
+}
+
+func TestService_AddItem_PackSize(t *testing.T) {
+	ctx := context.Background()
+	req := AddItemRequest{ProductID: "product-1", Quantity: 4, UnitPrice: 100, PackSize: 6}
+
+	t.Run("rejects partial packs by default", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil, ServiceConfig{})
+
+		_, err := service.AddItem(ctx, "user-123", req)
+		assert.True(t, errors.IsCode(err, errors.CodeValidationError))
+	})
+
+	t.Run("rounds up to full packs", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil, ServiceConfig{PackSizeMode: PackSizeModeRoundUp})
+
+		c, err := service.AddItem(ctx, "user-123", req)
+		assert.NoError(t, err)
+		assert.Equal(t, 6, c.Items[0].Quantity)
+
+		warnings := service.PackSizeWarnings(req, &c.Items[0])
+		if assert.Len(t, warnings, 1) {
+			assert.Equal(t, WarningQuantityRounded, warnings[0].Code)
+		}
+	})
+}
+
+// oversizedRepository rejects every save as too large to store.
+type oversizedRepository struct {
+	*fakeRepository
+}
+
+func (r *oversizedRepository) SaveCart(ctx context.Context, c *Cart) error {
+	return errors.ErrCartTooLarge(len(c.Items))
+}
+
+func (r *oversizedRepository) SaveCartWithVersion(ctx context.Context, c *Cart, expectedVersion int64) error {
+	return errors.ErrCartTooLarge(len(c.Items))
+}
+
+func TestService_CartTooLarge(t *testing.T) {
+	existing := NewCart("user-123")
+	existing.AddItem(NewCartItem("product-1", 1, 1000))
+	service := NewService(&oversizedRepository{newFakeRepository(existing)}, nil, ServiceConfig{})
+
+	_, err := service.AddItem(context.Background(), "user-123", AddItemRequest{ProductID: "product-2", Quantity: 1, UnitPrice: 500})
+	assert.True(t, errors.IsCode(err, errors.CodeCartTooLarge))
+
+	_, err = service.UpdateItemQuantity(context.Background(), "user-123", UpdateItemRequest{ItemID: existing.Items[0].ItemID, Quantity: 2})
+	assert.True(t, errors.IsCode(err, errors.CodeCartTooLarge))
+}
+
+// versionCountingRepository counts saves that check the expected version.
+type versionCountingRepository struct {
+	*fakeRepository
+	versioned int
+}
+
+func (r *versionCountingRepository) SaveCartWithVersion(ctx context.Context, c *Cart, expectedVersion int64) error {
+	r.versioned++
+	return r.fakeRepository.SaveCartWithVersion(ctx, c, expectedVersion)
+}
+
+// userFlags enables flags for listed users only.
+type userFlags map[string][]string
+
+func (f userFlags) IsEnabled(ctx context.Context, flag string, userID string) bool {
+	return slices.Contains(f[flag], userID)
+}
+func (f userFlags) GetVariant(ctx context.Context, flag string, userID string) string { return "" }
+func (f userFlags) Close() error                                                      { return nil }
+
+func TestService_FeatureFlags(t *testing.T) {
+	ctx := context.Background()
+	add := AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 1000}
+
+	// check sets a gift message, which saves with the version it read, and
+	// adds an item, which publishes an event
+	check := func(service *Service, userID string) {
+		_, err := service.SetGiftMessage(ctx, userID, "Happy birthday")
+		assert.NoError(t, err)
+		_, err = service.AddItem(ctx, userID, add)
+		assert.NoError(t, err)
+	}
+
+	// Without flags the config decides
+	repo := &versionCountingRepository{fakeRepository: newFakeRepository(NewCart("user-a"))}
+	publisher := &recordingPublisher{}
+	check(NewService(repo, publisher, ServiceConfig{PublishEvents: true}), "user-a")
+	assert.Equal(t, 1, repo.versioned)
+	assert.Equal(t, 1, publisher.added)
+
+	repo = &versionCountingRepository{fakeRepository: newFakeRepository(NewCart("user-a"))}
+	publisher = &recordingPublisher{}
+	check(NewService(repo, publisher, ServiceConfig{DisableOptimisticLocking: true}), "user-a")
+	assert.Equal(t, 0, repo.versioned)
+	assert.Equal(t, 0, publisher.added)
+
+	// With flags they decide per user, overriding the config
+	flags := userFlags{
+		features.FlagOptimisticLocking: {"user-a"},
+		features.FlagEventPublishing:   {"user-b"},
+	}
+	repo = &versionCountingRepository{fakeRepository: newFakeRepository(NewCart("user-a"), NewCart("user-b"))}
+	publisher = &recordingPublisher{}
+	service := NewService(repo, publisher, ServiceConfig{PublishEvents: true}, WithFeatureFlags(flags))
+
+	check(service, "user-a")
+	assert.Equal(t, 1, repo.versioned)
+	assert.Equal(t, 0, publisher.added)
+
+	check(service, "user-b")
+	assert.Equal(t, 1, repo.versioned)
+	assert.Equal(t, 1, publisher.added)
+}
+
+// pricingFlags assigns users FlagNewPricingEngine variants.
+type pricingFlags map[string]string
+
+func (f pricingFlags) IsEnabled(ctx context.Context, flag string, userID string) bool {
+	return flag == features.FlagNewPricingEngine && f[userID] != ""
+}
+func (f pricingFlags) GetVariant(ctx context.Context, flag string, userID string) string {
+	if flag != features.FlagNewPricingEngine {
+		return ""
+	}
+	return f[userID]
+}
+func (f pricingFlags) Close() error { return nil }
+
+func TestService_PricingEngine(t *testing.T) {
+	ctx := context.Background()
+	// 1.5 kg at 3.33 per kg is 4.995
+	add := AddItemRequest{ProductID: "product-1", UnitPrice: 333, UnitType: UnitTypeWeight, DecimalQuantity: 1500}
+
+	flags := pricingFlags{"user-b": PricingEngineRounded, "user-c": "unknown"}
+	service := NewService(newFakeRepository(), nil, ServiceConfig{}, WithFeatureFlags(flags))
+
+	for userID, want := range map[string]struct {
+		engine string
+		total  int64
+	}{
+		"user-a": {PricingEngineLegacy, 499},
+		"user-b": {PricingEngineRounded, 500},
+		"user-c": {PricingEngineLegacy, 499},
+	} {
+		cart, err := service.AddItem(ctx, userID, add)
+		assert.NoError(t, err)
+		assert.Equal(t, want.total, cart.TotalPrice(), userID)
+		assert.Equal(t, want.total, cart.LineTotal(&cart.Items[0]), userID)
+		assert.Equal(t, want.engine, cart.Summary().PricingEngine, userID)
+
+		// Reads are priced by the same engine
+		cart, err = service.GetCart(ctx, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, want.total, cart.Summary().TotalPrice, userID)
+	}
+
+	// Without flags every cart is priced by the legacy engine
+	cart, err := NewService(newFakeRepository(), nil, ServiceConfig{}).AddItem(ctx, "user-b", add)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(499), cart.TotalPrice())
+	assert.Equal(t, PricingEngineLegacy, cart.PricingEngineName())
+}
+
+func TestService_PruneStaleItems(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newStaleCart := func() *Cart {
+		c := NewCart("user-123")
+		old := NewCartItem("product-old", 1, 1000)
+		old.AddedAt = now.Add(-40 * 24 * time.Hour)
+		recent := NewCartItem("product-new", 1, 500)
+		recent.AddedAt = now.Add(-24 * time.Hour)
+		c.Items = []CartItem{*old, *recent}
+		c.Version = 3
+		return c
+	}
+
+	tests := []struct {
+		name         string
+		action       StaleItemAction
+		wantProducts []string
+		wantStale    []bool
+	}{
+		{name: "remove by default", action: "", wantProducts: []string{"product-new"}, wantStale: []bool{false}},
+		{name: "flag", action: StaleItemFlag, wantProducts: []string{"product-old", "product-new"}, wantStale: []bool{true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(now)
+			repo := newFakeRepository(newStaleCart())
+			publisher := &recordingPublisher{}
+			service := NewService(repo, publisher, ServiceConfig{
+				PublishEvents:   true,
+				MaxItemAge:      30 * 24 * time.Hour,
+				StaleItemAction: tt.action,
+			}, WithClock(clock))
+
+			c, err := service.GetCart(context.Background(), "user-123")
+			assert.NoError(t, err)
+			assert.Equal(t, int64(4), c.Version)
+			var products []string
+			var stale []bool
+			for _, item := range c.Items {
+				products = append(products, item.ProductID)
+				stale = append(stale, item.Stale)
+			}
+			assert.Equal(t, tt.wantProducts, products)
+			assert.Equal(t, tt.wantStale, stale)
+			assert.Len(t, repo.carts["user-123"].Items, len(tt.wantProducts))
+
+			wantAction := tt.action
+			if wantAction == "" {
+				wantAction = StaleItemRemove
+			}
+			assert.Equal(t, []string{string(wantAction) + ":product-old"}, publisher.pruned)
+		})
+	}
+
+	t.Run("at most once per interval", func(t *testing.T) {
+		clock := NewFakeClock(now)
+		repo := newFakeRepository(newStaleCart())
+		service := NewService(repo, nil, ServiceConfig{
+			MaxItemAge:    30 * 24 * time.Hour,
+			PruneInterval: time.Hour,
+		}, WithClock(clock))
+
+		c, err := service.GetCart(context.Background(), "user-123")
+		assert.NoError(t, err)
+		assert.Len(t, c.Items, 1)
+
+		// An item going stale within the interval is kept until the next check
+		clock.Advance(30 * time.Minute)
+		repo.carts["user-123"].Items[0].AddedAt = now.Add(-31 * 24 * time.Hour)
+		c, err = service.GetCart(context.Background(), "user-123")
+		assert.NoError(t, err)
+		assert.Len(t, c.Items, 1)
+
+		clock.Advance(30 * time.Minute)
+		c, err = service.GetCart(context.Background(), "user-123")
+		assert.NoError(t, err)
+		assert.Empty(t, c.Items)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		service := NewService(newFakeRepository(newStaleCart()), nil, ServiceConfig{}, WithClock(NewFakeClock(now)))
+
+		c, err := service.GetCart(context.Background(), "user-123")
+		assert.NoError(t, err)
+		assert.Len(t, c.Items, 2)
+		assert.Equal(t, int64(3), c.Version)
+	})
+}
@@ -0,0 +1,42 @@
+package cart
+
+import (
+	"context"
+)
+
+// Template is a named list of products that can be added to a cart in one step,
+// such as a B2B quick-order list. Templates with an empty UserID are global.
+type Template struct {
+	ID     string         `json:"id"`
+	UserID string         `json:"user_id,omitempty"`
+	Name   string         `json:"name"`
+	Lines  []TemplateLine `json:"lines"`
+}
+
+// TemplateLine is a single product/quantity pair in a template.
+type TemplateLine struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int64  `json:"unit_price"` // Fallback price when no PriceValidator is configured
+}
+
+// SkippedTemplateLine is a template line that could not be added to the cart.
+type SkippedTemplateLine struct {
+	TemplateLine
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// TemplateResult reports the outcome of applying a template to a cart.
+type TemplateResult struct {
+	Cart    *Cart                 `json:"cart"`
+	Added   []TemplateLine        `json:"added"`
+	Skipped []SkippedTemplateLine `json:"skipped"`
+}
+
+// TemplateStore defines the interface for template lookup.
+type TemplateStore interface {
+	// GetTemplate returns the user's template with the given ID, falling back to
+	// a global template. Returns a CodeTemplateNotFound error if neither exists.
+	GetTemplate(ctx context.Context, userID, templateID string) (*Template, error)
+}
@@ -0,0 +1,31 @@
+package cart
+
+import "context"
+
+// DefaultTenantID scopes carts when no tenant is given, so single-tenant
+// deployments need no configuration.
+const DefaultTenantID = "default"
+
+type tenantIDKey struct{}
+
+// WithTenantID scopes the service and repository operations called with the
+// returned context to a tenant. Carts of different tenants are stored apart,
+// so equal user IDs in two tenants never share a cart.
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey{}, tenantID)
+}
+
+// TenantIDFromContext returns the tenant selected with WithTenantID, or
+// DefaultTenantID.
+func TenantIDFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantIDKey{}).(string)
+	return NormalizeTenantID(tenantID)
+}
+
+// NormalizeTenantID maps an empty tenant ID to DefaultTenantID.
+func NormalizeTenantID(tenantID string) string {
+	if tenantID == "" {
+		return DefaultTenantID
+	}
+	return tenantID
+}
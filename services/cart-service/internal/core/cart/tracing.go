@@ -0,0 +1,62 @@
+package cart
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+
+// startSpan starts a child span for a call to a cart collaborator.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on the span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedRepository wraps a Repository with a span per call.
+type tracedRepository struct {
+	Repository
+}
+
+func (r tracedRepository) GetCart(ctx context.Context, userID string) (*Cart, error) {
+	ctx, span := startSpan(ctx, "cart.repository.GetCart")
+	cart, err := r.Repository.GetCart(ctx, userID)
+	endSpan(span, err)
+	return cart, err
+}
+
+func (r tracedRepository) SaveCart(ctx context.Context, cart *Cart) error {
+	ctx, span := startSpan(ctx, "cart.repository.SaveCart", attribute.Int64("cart.version", cart.Version))
+	err := r.Repository.SaveCart(ctx, cart)
+	endSpan(span, err)
+	return err
+}
+
+func (r tracedRepository) SaveCartWithVersion(ctx context.Context, cart *Cart, expectedVersion int64) error {
+	ctx, span := startSpan(ctx, "cart.repository.SaveCartWithVersion",
+		attribute.Int64("cart.version", cart.Version),
+		attribute.Int64("cart.expected_version", expectedVersion),
+	)
+	err := r.Repository.SaveCartWithVersion(ctx, cart, expectedVersion)
+	endSpan(span, err)
+	return err
+}
+
+func (r tracedRepository) DeleteCart(ctx context.Context, userID string) error {
+	ctx, span := startSpan(ctx, "cart.repository.DeleteCart")
+	err := r.Repository.DeleteCart(ctx, userID)
+	endSpan(span, err)
+	return err
+}
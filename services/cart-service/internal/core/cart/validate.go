@@ -0,0 +1,179 @@
+package cart
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// StockLevelChecker is optionally implemented by an InventoryChecker that can
+// report the quantity on hand, not just whether a quantity is available.
+type StockLevelChecker interface {
+	AvailableQuantity(ctx context.Context, productID string) (int, error)
+}
+
+// Item validation failure reasons
+const (
+	ValidationPriceUnavailable = "PRICE_UNAVAILABLE"
+	ValidationStockUnavailable = "STOCK_UNAVAILABLE"
+)
+
+// ItemValidation reports whether a cart line is still valid for checkout.
+type ItemValidation struct {
+	ItemID       string `json:"item_id"`
+	ProductID    string `json:"product_id"`
+	PriceChanged bool   `json:"price_changed"`
+	OldPrice     int64  `json:"old_price"`
+	NewPrice     int64  `json:"new_price"`
+	InStock      bool   `json:"in_stock"`
+	AvailableQty *int   `json:"available_qty,omitempty"`
+	Error        string `json:"error,omitempty"` // Set when a lookup failed
+
+	// NotAllowed flags a product the ProductPolicy no longer allows. The item
+	// is left in the cart for the customer to remove.
+	NotAllowed   bool   `json:"not_allowed,omitempty"`
+	PolicyReason string `json:"policy_reason,omitempty"`
+}
+
+// ValidationReport is the result of ValidateCart.
+type ValidationReport struct {
+	CartID   string           `json:"cart_id"`
+	UserID   string           `json:"user_id"`
+	Version  int64            `json:"version"`
+	Valid    bool             `json:"valid"`
+	Repriced bool             `json:"repriced"`
+	Items    []ItemValidation `json:"items"`
+}
+
+// ValidateCart checks every item against the current catalog price, stock and
+// product policy. A check is skipped when its collaborator (PriceValidator,
+// InventoryChecker or ProductPolicy) isn't configured. The cart is left unchanged unless reprice is set, in which
+// case changed prices are stored and the version is bumped once. Lookup failures
+// are reported per item rather than failing the call.
+func (s *Service) ValidateCart(ctx context.Context, userID string, reprice bool) (*ValidationReport, error) {
+	if s.prices == nil && s.inventory == nil && s.policy == nil {
+		return nil, errors.ErrServiceUnavailable("cart validation")
+	}
+
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]ItemValidation, len(cart.Items))
+	var wg sync.WaitGroup
+	for i, item := range cart.Items {
+		wg.Add(1)
+		go func(i int, item CartItem) {
+			defer wg.Done()
+			items[i] = s.validateItem(ctx, item)
+		}(i, item)
+	}
+	wg.Wait()
+
+	report := &ValidationReport{
+		CartID: cart.ID,
+		UserID: cart.UserID,
+		Items:  items,
+	}
+
+	if reprice {
+		repriced, err := s.applyValidatedPrices(ctx, cart, items)
+		if err != nil {
+			return nil, err
+		}
+		report.Repriced = repriced
+	}
+
+	report.Version = cart.Version
+	report.Valid = true
+	for _, item := range items {
+		if item.Error != "" || !item.InStock || item.NotAllowed || (item.PriceChanged && !report.Repriced) {
+			report.Valid = false
+		}
+	}
+	return report, nil
+}
+
+// validateItem looks up the policy, current price and stock for a single item.
+func (s *Service) validateItem(ctx context.Context, item CartItem) ItemValidation {
+	result := ItemValidation{
+		ItemID:    item.ItemID,
+		ProductID: item.ProductID,
+		OldPrice:  item.UnitPrice,
+		NewPrice:  item.UnitPrice,
+		InStock:   true,
+	}
+
+	if s.policy != nil {
+		if allowed, reason := s.policy.IsAllowed(ctx, item.ProductID); !allowed {
+			result.NotAllowed = true
+			result.PolicyReason = reason
+		}
+	}
+
+	if s.prices != nil {
+		current, err := s.prices.GetCurrentPrice(ctx, item.ProductID)
+		if err != nil {
+			result.Error = ValidationPriceUnavailable
+		} else {
+			result.NewPrice = current
+			result.PriceChanged = current != item.UnitPrice
+		}
+	}
+
+	if s.inventory != nil {
+		if levels, ok := s.inventory.(StockLevelChecker); ok {
+			available, err := levels.AvailableQuantity(ctx, item.ProductID)
+			if err != nil {
+				result.InStock = false
+				result.Error = ValidationStockUnavailable
+				return result
+			}
+			result.AvailableQty = &available
+			result.InStock = available >= item.Quantity
+			return result
+		}
+
+		inStock, err := s.inventory.CheckAvailability(ctx, item.ProductID, item.Quantity)
+		if err != nil {
+			result.InStock = false
+			result.Error = ValidationStockUnavailable
+			return result
+		}
+		result.InStock = inStock
+	}
+
+	return result
+}
+
+// applyValidatedPrices stores changed prices from a validation and reports whether any changed.
+func (s *Service) applyValidatedPrices(ctx context.Context, cart *Cart, items []ItemValidation) (bool, error) {
+	var corrected []pendingEvent
+	for _, item := range items {
+		if !item.PriceChanged {
+			continue
+		}
+		if updated, previousPrice := cart.RepriceProduct(item.ProductID, item.NewPrice); updated != nil {
+			corrected = append(corrected, priceCorrectedEvent(cart, updated, previousPrice))
+		}
+	}
+	if len(corrected) == 0 {
+		return false, nil
+	}
+
+	expectedVersion := cart.Version
+	cart.IncrementVersion()
+	if err := s.saveCart(ctx, cart, expectedVersion, corrected...); err != nil {
+		return false, saveError(err, "failed to save cart")
+	}
+	s.recordAudit(ctx, audit.OpRepriceItem, cart, corrected...)
+
+	if err := s.publishEvents(ctx, corrected...); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,10 @@
+// Package encryption provides field-level encryption for data stored at rest.
+package encryption
+
+import "context"
+
+// Encryptor encrypts and decrypts small payloads such as free-text fields.
+type Encryptor interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
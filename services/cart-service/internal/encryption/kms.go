@@ -0,0 +1,140 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// envelopeVersion is the first byte of every ciphertext produced by KMSEncryptor.
+const envelopeVersion byte = 1
+
+// errMalformedCiphertext is returned for ciphertexts not produced by KMSEncryptor.
+var errMalformedCiphertext = errors.New("malformed ciphertext")
+
+// KMSConfig holds configuration for the KMS encryptor.
+type KMSConfig struct {
+	Region   string
+	Endpoint string // Optional, for local testing
+	KeyID    string // Key ID, ARN or alias of the KMS key
+}
+
+// kmsAPI is the subset of the KMS client used by KMSEncryptor.
+type kmsAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KMSEncryptor implements Encryptor with envelope encryption: each payload is
+// sealed with AES-256-GCM under a fresh data key, and the data key, encrypted
+// by the KMS key, is stored alongside it. Payload size is not limited by KMS.
+type KMSEncryptor struct {
+	client kmsAPI
+	keyID  string
+}
+
+// NewKMSEncryptor creates an encryptor using the given KMS key.
+func NewKMSEncryptor(ctx context.Context, cfg KMSConfig) (*KMSEncryptor, error) {
+	if cfg.KeyID == "" {
+		return nil, errors.New("KMS key ID is required")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	var client *kms.Client
+	if cfg.Endpoint != "" {
+		client = kms.NewFromConfig(awsCfg, func(o *kms.Options) {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		})
+	} else {
+		client = kms.NewFromConfig(awsCfg)
+	}
+
+	return &KMSEncryptor{client: client, keyID: cfg.KeyID}, nil
+}
+
+// Encrypt seals plaintext under a new data key. The result is laid out as
+// version | encrypted key length (2 bytes) | encrypted key | nonce | sealed data.
+func (e *KMSEncryptor) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	dataKey, err := e.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(e.keyID),
+		KeySpec: types.DataKeySpecAes256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	defer clear(dataKey.Plaintext)
+
+	gcm, err := newGCM(dataKey.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	encryptedKey := dataKey.CiphertextBlob
+	out := make([]byte, 0, 3+len(encryptedKey)+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, envelopeVersion)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(encryptedKey)))
+	out = append(out, encryptedKey...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, out[:3+len(encryptedKey)]), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt.
+func (e *KMSEncryptor) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 3 || ciphertext[0] != envelopeVersion {
+		return nil, errMalformedCiphertext
+	}
+	keyLen := int(binary.BigEndian.Uint16(ciphertext[1:3]))
+	if len(ciphertext) < 3+keyLen {
+		return nil, errMalformedCiphertext
+	}
+	header, rest := ciphertext[:3+keyLen], ciphertext[3+keyLen:]
+
+	dataKey, err := e.client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: header[3:],
+		KeyId:          aws.String(e.keyID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	defer clear(dataKey.Plaintext)
+
+	gcm, err := newGCM(dataKey.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < gcm.NonceSize() {
+		return nil, errMalformedCiphertext
+	}
+	nonce, sealed := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,71 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS "encrypts" data keys by prefixing them with the key ID.
+type fakeKMS struct {
+	generated int
+}
+
+func (f *fakeKMS) GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	f.generated++
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return &kms.GenerateDataKeyOutput{
+		Plaintext:      append([]byte(nil), key...),
+		CiphertextBlob: append([]byte(*params.KeyId+":"), key...),
+	}, nil
+}
+
+func (f *fakeKMS) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	key, ok := bytes.CutPrefix(params.CiphertextBlob, []byte(*params.KeyId+":"))
+	if !ok {
+		return nil, assert.AnError
+	}
+	return &kms.DecryptOutput{Plaintext: append([]byte(nil), key...)}, nil
+}
+
+func TestKMSEncryptor_RoundTrip(t *testing.T) {
+	client := &fakeKMS{}
+	encryptor := &KMSEncryptor{client: client, keyID: "alias/cart"}
+	ctx := context.Background()
+
+	plaintext := []byte("Happy birthday, love from Ana")
+	ciphertext, err := encryptor.Encrypt(ctx, plaintext)
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), string(plaintext))
+
+	decrypted, err := encryptor.Decrypt(ctx, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	// Each payload gets its own data key
+	_, err = encryptor.Encrypt(ctx, plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, 2, client.generated)
+}
+
+func TestKMSEncryptor_RejectsTamperedCiphertext(t *testing.T) {
+	encryptor := &KMSEncryptor{client: &fakeKMS{}, keyID: "alias/cart"}
+	ctx := context.Background()
+
+	ciphertext, err := encryptor.Encrypt(ctx, []byte("secret"))
+	require.NoError(t, err)
+
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[len(tampered)-1] ^= 0xff
+	_, err = encryptor.Decrypt(ctx, tampered)
+	assert.Error(t, err)
+
+	_, err = encryptor.Decrypt(ctx, []byte{envelopeVersion, 0xff})
+	assert.ErrorIs(t, err, errMalformedCiphertext)
+}
@@ -4,50 +4,70 @@ package errors
 // Error codes for cart service operations.
 const (
 	// Client errors (4xx)
-	CodeCartNotFound        = "CART_NOT_FOUND"
-	CodeItemNotFound        = "ITEM_NOT_FOUND"
-	CodeCartLimitExceeded   = "CART_LIMIT_EXCEEDED"
-	CodeQuantityLimit       = "QUANTITY_LIMIT_EXCEEDED"
-	CodeInvalidQuantity     = "INVALID_QUANTITY"
-	CodeCartExpired         = "CART_EXPIRED"
-	CodeValidationError     = "VALIDATION_ERROR"
-	CodeConflict            = "CONFLICT"
-	CodeRateLimited         = "RATE_LIMITED"
-	CodeUnauthorized        = "UNAUTHORIZED"
-	CodeForbidden           = "FORBIDDEN"
-	CodeInvalidRequest      = "INVALID_REQUEST"
-	CodeIdempotencyConflict = "IDEMPOTENCY_CONFLICT"
+	CodeCartNotFound           = "CART_NOT_FOUND"
+	CodeItemNotFound           = "ITEM_NOT_FOUND"
+	CodeItemAlreadyExists      = "ITEM_ALREADY_EXISTS"
+	CodeCartLimitExceeded      = "CART_LIMIT_EXCEEDED"
+	CodeCartCountLimit         = "CART_COUNT_LIMIT_EXCEEDED"
+	CodeCartValueLimitExceeded = "CART_VALUE_LIMIT_EXCEEDED"
+	CodeCartTooLarge           = "CART_TOO_LARGE"
+	CodeRequestTooLarge        = "REQUEST_TOO_LARGE"
+	CodeQuantityLimit          = "QUANTITY_LIMIT_EXCEEDED"
+	CodeInvalidQuantity        = "INVALID_QUANTITY"
+	CodeCartExpired            = "CART_EXPIRED"
+	CodeCartLocked             = "CART_LOCKED"
+	CodeValidationError        = "VALIDATION_ERROR"
+	CodeConflict               = "CONFLICT"
+	CodeRateLimited            = "RATE_LIMITED"
+	CodeUnauthorized           = "UNAUTHORIZED"
+	CodeForbidden              = "FORBIDDEN"
+	CodeInvalidRequest         = "INVALID_REQUEST"
+	CodeIdempotencyConflict    = "IDEMPOTENCY_CONFLICT"
+	CodeTemplateNotFound       = "TEMPLATE_NOT_FOUND"
+	CodePreconditionFailed     = "PRECONDITION_FAILED"
+	CodeProductNotAllowed      = "PRODUCT_NOT_ALLOWED"
 
 	// Server errors (5xx)
-	CodeInternalError       = "INTERNAL_ERROR"
-	CodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
-	CodePersistenceError    = "PERSISTENCE_ERROR"
-	CodeEventPublishError   = "EVENT_PUBLISH_ERROR"
-	CodeInventoryError      = "INVENTORY_ERROR"
+	CodeInternalError         = "INTERNAL_ERROR"
+	CodeServiceUnavailable    = "SERVICE_UNAVAILABLE"
+	CodeTimeout               = "REQUEST_TIMEOUT"
+	CodePersistenceError      = "PERSISTENCE_ERROR"
+	CodeEventPublishError     = "EVENT_PUBLISH_ERROR"
+	CodeInventoryError        = "INVENTORY_ERROR"
 	CodeInventoryInsufficient = "INVENTORY_INSUFFICIENT"
 )
 
 // HTTP status codes mapped to error codes.
 var httpStatusCodes = map[string]int{
-	CodeCartNotFound:          404,
-	CodeItemNotFound:          404,
-	CodeCartLimitExceeded:     400,
-	CodeQuantityLimit:         400,
-	CodeInvalidQuantity:       400,
-	CodeCartExpired:           410,
-	CodeValidationError:       400,
-	CodeConflict:              409,
-	CodeRateLimited:           429,
-	CodeUnauthorized:          401,
-	CodeForbidden:             403,
-	CodeInvalidRequest:        400,
-	CodeIdempotencyConflict:   409,
-	CodeInternalError:         500,
-	CodeServiceUnavailable:    503,
-	CodePersistenceError:      500,
-	CodeEventPublishError:     500,
-	CodeInventoryError:        500,
-	CodeInventoryInsufficient: 409,
+	CodeCartNotFound:           404,
+	CodeItemNotFound:           404,
+	CodeItemAlreadyExists:      409,
+	CodeCartLimitExceeded:      400,
+	CodeCartCountLimit:         400,
+	CodeCartValueLimitExceeded: 400,
+	CodeCartTooLarge:           413,
+	CodeRequestTooLarge:        413,
+	CodeQuantityLimit:          400,
+	CodeInvalidQuantity:        400,
+	CodeCartExpired:            410,
+	CodeCartLocked:             409,
+	CodeValidationError:        400,
+	CodeConflict:               409,
+	CodeRateLimited:            429,
+	CodeUnauthorized:           401,
+	CodeForbidden:              403,
+	CodeInvalidRequest:         400,
+	CodeIdempotencyConflict:    409,
+	CodeTemplateNotFound:       404,
+	CodePreconditionFailed:     412,
+	CodeProductNotAllowed:      400,
+	CodeInternalError:          500,
+	CodeServiceUnavailable:     503,
+	CodeTimeout:                504,
+	CodePersistenceError:       500,
+	CodeEventPublishError:      500,
+	CodeInventoryError:         500,
+	CodeInventoryInsufficient:  409,
 }
 
 // HTTPStatusForCode returns the HTTP status code for a given error code.
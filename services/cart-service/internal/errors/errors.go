@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 )
 
 // AppError represents a structured application error.
@@ -119,6 +120,22 @@ func ErrItemNotFound(userID, itemID string) *AppError {
 		})
 }
 
+// ErrItemAlreadyExists creates an error for adding a product that is already
+// in the cart when merging was not requested.
+func ErrItemAlreadyExists(productID, itemID string) *AppError {
+	return New(CodeItemAlreadyExists, "Product is already in the cart").
+		WithDetails(map[string]interface{}{
+			"product_id": productID,
+			"item_id":    itemID,
+		})
+}
+
+// ErrTemplateNotFound creates a template not found error.
+func ErrTemplateNotFound(templateID string) *AppError {
+	return New(CodeTemplateNotFound, "Template not found").
+		WithDetail("template_id", templateID)
+}
+
 // ErrCartLimitExceeded creates a cart limit exceeded error.
 func ErrCartLimitExceeded(currentCount, maxAllowed int) *AppError {
 	return New(CodeCartLimitExceeded, "Cart cannot contain more items").
@@ -128,6 +145,40 @@ func ErrCartLimitExceeded(currentCount, maxAllowed int) *AppError {
 		})
 }
 
+// ErrCartCountLimitExceeded creates an error for a user who already has the
+// maximum number of carts.
+func ErrCartCountLimitExceeded(count, maxAllowed int) *AppError {
+	return New(CodeCartCountLimit, "User has reached the maximum number of carts").
+		WithDetails(map[string]interface{}{
+			"cart_count":  count,
+			"max_allowed": maxAllowed,
+		})
+}
+
+// ErrCartValueLimitExceeded creates an error for a change that would take the
+// cart total over the allowed value. Amounts are in cents.
+func ErrCartValueLimitExceeded(attemptedTotal, maxAllowed int64) *AppError {
+	return New(CodeCartValueLimitExceeded, "Cart total exceeds maximum allowed value").
+		WithDetails(map[string]interface{}{
+			"attempted_total": attemptedTotal,
+			"max_allowed":     maxAllowed,
+		})
+}
+
+// ErrCartTooLarge creates an error for a cart too large to store. The item
+// count tells the user how much to remove.
+func ErrCartTooLarge(itemCount int) *AppError {
+	return New(CodeCartTooLarge, "Cart is too large to save; remove some items and try again").
+		WithDetail("item_count", itemCount)
+}
+
+// ErrRequestTooLarge creates an error for a request body longer than
+// maxBytes.
+func ErrRequestTooLarge(maxBytes int64) *AppError {
+	return New(CodeRequestTooLarge, "Request body too large").
+		WithDetail("max_bytes", maxBytes)
+}
+
 // ErrQuantityLimitExceeded creates a quantity limit exceeded error.
 func ErrQuantityLimitExceeded(quantity, maxAllowed int) *AppError {
 	return New(CodeQuantityLimit, "Quantity exceeds maximum allowed").
@@ -149,6 +200,24 @@ func ErrCartExpired(userID string) *AppError {
 		WithDetail("user_id", userID)
 }
 
+// ErrCartLocked creates an error for changing a cart locked for checkout.
+func ErrCartLocked(userID string, lockedUntil time.Time) *AppError {
+	return New(CodeCartLocked, "Cart is locked for checkout").
+		WithDetails(map[string]interface{}{
+			"user_id":      userID,
+			"locked_until": lockedUntil.UTC().Format(time.RFC3339),
+		})
+}
+
+// ErrProductNotAllowed creates an error for a product that may not be added to carts.
+func ErrProductNotAllowed(productID, reason string) *AppError {
+	return New(CodeProductNotAllowed, "Product cannot be added to the cart").
+		WithDetails(map[string]interface{}{
+			"product_id": productID,
+			"reason":     reason,
+		})
+}
+
 // ErrValidation creates a validation error.
 func ErrValidation(message string, details map[string]interface{}) *AppError {
 	return New(CodeValidationError, message).WithDetails(details)
@@ -163,6 +232,13 @@ func ErrConflict(expectedVersion, currentVersion int64) *AppError {
 		})
 }
 
+// ErrPreconditionFailed creates an error for an If-Match header that doesn't
+// match the current cart version.
+func ErrPreconditionFailed(details map[string]interface{}) *AppError {
+	return New(CodePreconditionFailed, "Cart version does not match If-Match").
+		WithDetails(details)
+}
+
 // ErrRateLimited creates a rate limited error.
 func ErrRateLimited() *AppError {
 	return New(CodeRateLimited, "Too many requests, please try again later")
@@ -189,11 +265,22 @@ func ErrServiceUnavailable(service string) *AppError {
 		WithDetail("service", service)
 }
 
+// ErrTimeout creates an error for a request that ran past its deadline.
+func ErrTimeout() *AppError {
+	return New(CodeTimeout, "Request timed out")
+}
+
 // ErrPersistence creates a persistence error.
 func ErrPersistence(operation string, cause error) *AppError {
 	return Wrap(CodePersistenceError, fmt.Sprintf("Persistence operation failed: %s", operation), cause)
 }
 
+// ErrEventPublish creates an event publishing error.
+func ErrEventPublish(eventType string, cause error) *AppError {
+	return Wrap(CodeEventPublishError, "Failed to publish event", cause).
+		WithDetail("event_type", eventType)
+}
+
 // ErrInventoryInsufficient creates an insufficient inventory error.
 func ErrInventoryInsufficient(productID string, requested, available int) *AppError {
 	return New(CodeInventoryInsufficient, "Insufficient inventory").
@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the locale of the messages set on AppError.
+const DefaultLocale = "en"
+
+// MessageCatalog maps error codes to human-readable messages for one locale.
+type MessageCatalog map[string]string
+
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = map[string]MessageCatalog{
+		"de": germanMessages,
+	}
+)
+
+// RegisterCatalog adds or replaces the message catalog for a locale such as
+// "fr" or "pt-br". Locales are matched case-insensitively.
+func RegisterCatalog(locale string, catalog MessageCatalog) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	catalogs[strings.ToLower(locale)] = catalog
+}
+
+// HasCatalog reports whether messages are available for locale. The default
+// locale always has messages.
+func HasCatalog(locale string) bool {
+	locale = strings.ToLower(locale)
+	if locale == DefaultLocale {
+		return true
+	}
+
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// LocalizedMessage returns the message for err's code in locale, or
+// err.Message when the locale has no translation for the code.
+func LocalizedMessage(err *AppError, locale string) string {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+
+	if message, ok := catalogs[strings.ToLower(locale)][err.Code]; ok {
+		return message
+	}
+	return err.Message
+}
+
+var germanMessages = MessageCatalog{
+	CodeCartNotFound:           "Warenkorb nicht gefunden",
+	CodeItemNotFound:           "Artikel nicht im Warenkorb gefunden",
+	CodeItemAlreadyExists:      "Der Artikel befindet sich bereits im Warenkorb",
+	CodeCartLimitExceeded:      "Der Warenkorb enthält bereits die maximale Anzahl an Artikeln",
+	CodeCartCountLimit:         "Die maximale Anzahl an Warenkörben ist erreicht",
+	CodeCartValueLimitExceeded: "Der Warenkorb überschreitet den maximalen Gesamtwert",
+	CodeCartTooLarge:           "Der Warenkorb ist zu groß zum Speichern, bitte entfernen Sie Artikel",
+	CodeRequestTooLarge:        "Der Anfrageinhalt ist zu groß",
+	CodeQuantityLimit:          "Die Menge überschreitet das erlaubte Maximum",
+	CodeInvalidQuantity:        "Die Menge muss mindestens 1 betragen",
+	CodeCartExpired:            "Der Warenkorb ist abgelaufen",
+	CodeCartLocked:             "Der Warenkorb ist während des Bezahlvorgangs gesperrt",
+	CodeValidationError:        "Ungültige Anfrage",
+	CodeConflict:               "Der Warenkorb wurde von einer anderen Anfrage geändert",
+	CodeRateLimited:            "Zu viele Anfragen, bitte versuchen Sie es später erneut",
+	CodeUnauthorized:           "Anmeldung erforderlich",
+	CodeForbidden:              "Zugriff verweigert",
+	CodeInvalidRequest:         "Ungültige Anfrage",
+	CodeIdempotencyConflict:    "Der Idempotenzschlüssel wurde bereits für eine andere Anfrage verwendet",
+	CodeTemplateNotFound:       "Vorlage nicht gefunden",
+	CodePreconditionFailed:     "Die Warenkorbversion stimmt nicht mit If-Match überein",
+	CodeProductNotAllowed:      "Das Produkt kann nicht in den Warenkorb gelegt werden",
+	CodeInternalError:          "Ein interner Fehler ist aufgetreten",
+	CodeServiceUnavailable:     "Der Dienst ist vorübergehend nicht verfügbar",
+	CodeTimeout:                "Die Anfrage hat zu lange gedauert",
+	CodePersistenceError:       "Der Warenkorb konnte nicht gespeichert werden",
+	CodeEventPublishError:      "Das Ereignis konnte nicht veröffentlicht werden",
+	CodeInventoryError:         "Der Lagerbestand konnte nicht geprüft werden",
+	CodeInventoryInsufficient:  "Nicht genügend Lagerbestand",
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPublisherClosed is returned when publishing to a closed publisher.
+var ErrPublisherClosed = errors.New("publisher is closed")
+
+// BufferConfig holds configuration for the buffered publisher.
+type BufferConfig struct {
+	// QueueSize bounds the number of events waiting to be published.
+	QueueSize int
+
+	// Workers is the number of goroutines draining the queue.
+	Workers int
+
+	// DropWhenFull drops events when the queue is full instead of blocking the
+	// caller until space frees up (backpressure).
+	DropWhenFull bool
+
+	// PublishTimeout bounds each publish to the inner publisher.
+	PublishTimeout time.Duration
+
+	// OnError is called when the inner publisher fails. Optional.
+	OnError func(event Event, err error)
+}
+
+// DefaultBufferConfig returns sensible defaults.
+func DefaultBufferConfig() BufferConfig {
+	return BufferConfig{
+		QueueSize:      1000,
+		Workers:        4,
+		PublishTimeout: 5 * time.Second,
+	}
+}
+
+// BufferedPublisher queues events in memory and publishes them to an inner
+// Publisher from a pool of background workers, keeping publisher latency off the
+// request path. Queued events are lost if the process dies before they drain.
+type BufferedPublisher struct {
+	inner  Publisher
+	config BufferConfig
+	queue  chan Event
+
+	dropped atomic.Int64
+	failed  atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewBufferedPublisher creates a buffered publisher in front of inner and starts its workers.
+func NewBufferedPublisher(inner Publisher, cfg BufferConfig) *BufferedPublisher {
+	defaults := DefaultBufferConfig()
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaults.QueueSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaults.Workers
+	}
+	if cfg.PublishTimeout <= 0 {
+		cfg.PublishTimeout = defaults.PublishTimeout
+	}
+
+	p := &BufferedPublisher{
+		inner:  inner,
+		config: cfg,
+		queue:  make(chan Event, cfg.QueueSize),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+// Publish enqueues an event. When the queue is full it either blocks until space
+// is available or the context ends, or drops the event if DropWhenFull is set.
+func (p *BufferedPublisher) Publish(ctx context.Context, event Event) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrPublisherClosed
+	}
+
+	if p.config.DropWhenFull {
+		select {
+		case p.queue <- event:
+		default:
+			p.dropped.Add(1)
+		}
+		return nil
+	}
+
+	select {
+	case p.queue <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PublishBatch enqueues multiple events.
+func (p *BufferedPublisher) PublishBatch(ctx context.Context, events []Event) error {
+	for _, event := range events {
+		if err := p.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops accepting events, flushes the queue to the inner publisher and
+// closes it.
+func (p *BufferedPublisher) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return p.inner.Close()
+}
+
+// HealthCheck checks the inner publisher when it supports health checks.
+func (p *BufferedPublisher) HealthCheck(ctx context.Context) error {
+	if checker, ok := p.inner.(HealthChecker); ok {
+		return checker.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (p *BufferedPublisher) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Failed returns the number of events the inner publisher failed to publish.
+func (p *BufferedPublisher) Failed() int64 {
+	return p.failed.Load()
+}
+
+// QueueLength returns the number of events waiting to be published.
+func (p *BufferedPublisher) QueueLength() int {
+	return len(p.queue)
+}
+
+func (p *BufferedPublisher) worker() {
+	defer p.wg.Done()
+
+	for event := range p.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.PublishTimeout)
+		err := p.inner.Publish(ctx, event)
+		cancel()
+
+		if err != nil {
+			p.failed.Add(1)
+			if p.config.OnError != nil {
+				p.config.OnError(event, err)
+			}
+		}
+	}
+}
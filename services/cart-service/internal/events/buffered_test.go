@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blockingPublisher struct {
+	mu      sync.Mutex
+	release chan struct{}
+	events  []Event
+	closed  bool
+}
+
+func (p *blockingPublisher) Publish(ctx context.Context, event Event) error {
+	if p.release != nil {
+		<-p.release
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *blockingPublisher) PublishBatch(ctx context.Context, events []Event) error {
+	for _, event := range events {
+		if err := p.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *blockingPublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestBufferedPublisher_FlushesOnClose(t *testing.T) {
+	inner := &blockingPublisher{}
+	publisher := NewBufferedPublisher(inner, BufferConfig{QueueSize: 10, Workers: 2})
+
+	for _, id := range []string{"evt-1", "evt-2", "evt-3"} {
+		require.NoError(t, publisher.Publish(context.Background(), Event{ID: id}))
+	}
+
+	require.NoError(t, publisher.Close())
+	assert.Len(t, inner.events, 3)
+	assert.True(t, inner.closed)
+	assert.ErrorIs(t, publisher.Publish(context.Background(), Event{ID: "evt-4"}), ErrPublisherClosed)
+}
+
+func TestBufferedPublisher_DropsWhenFull(t *testing.T) {
+	inner := &blockingPublisher{release: make(chan struct{})}
+	publisher := NewBufferedPublisher(inner, BufferConfig{QueueSize: 1, Workers: 1, DropWhenFull: true})
+
+	// One event is held by the worker, one fills the queue, the rest are dropped.
+	require.NoError(t, publisher.Publish(context.Background(), Event{ID: "evt-1"}))
+	require.Eventually(t, func() bool { return publisher.QueueLength() == 0 }, time.Second, time.Millisecond)
+	for _, id := range []string{"evt-2", "evt-3", "evt-4"} {
+		require.NoError(t, publisher.Publish(context.Background(), Event{ID: id}))
+	}
+	assert.Equal(t, int64(2), publisher.Dropped())
+
+	close(inner.release)
+	require.NoError(t, publisher.Close())
+	assert.Len(t, inner.events, 2)
+}
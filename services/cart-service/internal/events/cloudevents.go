@@ -0,0 +1,63 @@
+package events
+
+import (
+	"encoding/json"
+)
+
+// Format selects how events are serialized on the wire.
+type Format string
+
+// Serialization formats
+const (
+	// FormatNative serializes the Event struct as-is.
+	FormatNative Format = "native"
+	// FormatCloudEvents serializes events as CloudEvents 1.0 structured JSON.
+	FormatCloudEvents Format = "cloudevents"
+)
+
+// CloudEventsSpecVersion is the CloudEvents specification version produced.
+const CloudEventsSpecVersion = "1.0"
+
+// CloudEventsContentType is the media type of a structured-mode CloudEvent.
+const CloudEventsContentType = "application/cloudevents+json"
+
+// CloudEvent is the CloudEvents 1.0 envelope for an Event. Metadata is carried
+// in extension attributes.
+type CloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Time            string      `json:"time,omitempty"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+
+	// Extension attributes
+	TraceID       string `json:"traceid,omitempty"`
+	CorrelationID string `json:"correlationid,omitempty"`
+	Replayed      bool   `json:"replayed,omitempty"`
+}
+
+// ToCloudEvent converts an event to its CloudEvents envelope.
+func ToCloudEvent(event Event) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              event.ID,
+		Source:          event.Source,
+		Type:            event.Type,
+		Time:            event.Time,
+		DataContentType: "application/json",
+		Data:            event.Data,
+		TraceID:         event.Metadata.TraceID,
+		CorrelationID:   event.Metadata.CorrelationID,
+		Replayed:        event.Metadata.Replayed,
+	}
+}
+
+// Marshal serializes an event in the given format. An empty format is native.
+func Marshal(event Event, format Format) ([]byte, error) {
+	if format == FormatCloudEvents {
+		return json.Marshal(ToCloudEvent(event))
+	}
+	return json.Marshal(event)
+}
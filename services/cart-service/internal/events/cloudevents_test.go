@@ -0,0 +1,50 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal_CloudEvents(t *testing.T) {
+	event := Event{
+		ID:          "evt-1",
+		Source:      "cart-service",
+		Type:        EventTypeCartCreated,
+		Time:        "2025-01-01T00:00:00Z",
+		Data:        map[string]string{"cart_id": "cart-1"},
+		DataVersion: "1.0",
+		Metadata: EventMetadata{
+			TraceID:       "trace-1",
+			CorrelationID: "req-1",
+			UserID:        "user-123",
+		},
+	}
+
+	body, err := Marshal(event, FormatCloudEvents)
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, map[string]interface{}{
+		"specversion":     "1.0",
+		"id":              "evt-1",
+		"source":          "cart-service",
+		"type":            "cart.created",
+		"time":            "2025-01-01T00:00:00Z",
+		"datacontenttype": "application/json",
+		"data":            map[string]interface{}{"cart_id": "cart-1"},
+		"traceid":         "trace-1",
+		"correlationid":   "req-1",
+	}, decoded)
+
+	// Native stays the default
+	body, err = Marshal(event, "")
+	require.NoError(t, err)
+	var native map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &native))
+	assert.Contains(t, native, "metadata")
+	assert.NotContains(t, native, "specversion")
+}
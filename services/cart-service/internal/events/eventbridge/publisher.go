@@ -3,7 +3,6 @@ package eventbridge
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -16,6 +15,7 @@ import (
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events/models"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
 )
 
 // PublisherConfig holds configuration for the EventBridge publisher.
@@ -23,15 +23,33 @@ type PublisherConfig struct {
 	Region   string
 	BusName  string
 	Source   string
-	Endpoint string // Optional, for local testing
+	Endpoint string        // Optional, for local testing
+	Format   events.Format // Event serialization; defaults to native
+
+	// DeadLetter receives batch entries that still fail after individual retries.
+	// Optional; without it PublishBatch returns an error for the lost events.
+	DeadLetter events.DeadLetterSink
+}
+
+// putEventsAPI is the subset of the EventBridge client used by the publisher.
+type putEventsAPI interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+// describeEventBusAPI is implemented by the EventBridge client and used for health checks.
+type describeEventBusAPI interface {
+	DescribeEventBus(ctx context.Context, params *eventbridge.DescribeEventBusInput, optFns ...func(*eventbridge.Options)) (*eventbridge.DescribeEventBusOutput, error)
 }
 
 // Publisher is an EventBridge implementation of the event publisher.
 type Publisher struct {
-	client  *eventbridge.Client
-	busName string
-	source  string
-	logger  *logging.Logger
+	client     putEventsAPI
+	busName    string
+	source     string
+	format     events.Format
+	deadLetter events.DeadLetterSink
+	retry      resilience.RetryConfig
+	logger     *logging.Logger
 }
 
 // NewPublisher creates a new EventBridge publisher.
@@ -51,31 +69,21 @@ func NewPublisher(ctx context.Context, cfg PublisherConfig, logger *logging.Logg
 	}
 
 	return &Publisher{
-		client:  client,
-		busName: cfg.BusName,
-		source:  cfg.Source,
-		logger:  logger,
+		client:     client,
+		busName:    cfg.BusName,
+		source:     cfg.Source,
+		format:     cfg.Format,
+		deadLetter: cfg.DeadLetter,
+		retry:      defaultEntryRetryConfig(),
+		logger:     logger,
 	}, nil
 }
 
 // Publish publishes a single event to EventBridge.
 func (p *Publisher) Publish(ctx context.Context, event events.Event) error {
-	detail, err := json.Marshal(event)
+	entry, err := p.toEntry(event)
 	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
-	}
-
-	entry := types.PutEventsRequestEntry{
-		EventBusName: aws.String(p.busName),
-		Source:       aws.String(p.source),
-		DetailType:   aws.String(event.Type),
-		Detail:       aws.String(string(detail)),
-		Time:         aws.Time(time.Now().UTC()),
-	}
-
-	// Add trace ID if present
-	if event.Metadata.TraceID != "" {
-		entry.TraceHeader = aws.String(event.Metadata.TraceID)
+		return err
 	}
 
 	_, err = p.client.PutEvents(ctx, &eventbridge.PutEventsInput{
@@ -95,35 +103,29 @@ func (p *Publisher) Publish(ctx context.Context, event events.Event) error {
 }
 
 // PublishBatch publishes multiple events to EventBridge.
+// Entries EventBridge rejects are retried individually with backoff; those that
+// still fail are handed to the dead-letter sink. An error is returned only when
+// failed events can't be dead-lettered.
 func (p *Publisher) PublishBatch(ctx context.Context, eventList []events.Event) error {
 	if len(eventList) == 0 {
 		return nil
 	}
 
 	entries := make([]types.PutEventsRequestEntry, 0, len(eventList))
+	sources := make([]events.Event, 0, len(eventList))
 
 	for _, event := range eventList {
-		detail, err := json.Marshal(event)
+		entry, err := p.toEntry(event)
 		if err != nil {
 			p.logger.WithContext(ctx).WithError(err).Error("Failed to marshal event")
 			continue
 		}
-
-		entry := types.PutEventsRequestEntry{
-			EventBusName: aws.String(p.busName),
-			Source:       aws.String(p.source),
-			DetailType:   aws.String(event.Type),
-			Detail:       aws.String(string(detail)),
-			Time:         aws.Time(time.Now().UTC()),
-		}
-
-		if event.Metadata.TraceID != "" {
-			entry.TraceHeader = aws.String(event.Metadata.TraceID)
-		}
-
 		entries = append(entries, entry)
+		sources = append(sources, event)
 	}
 
+	var failed []events.Event
+
 	// EventBridge allows max 10 entries per batch
 	for i := 0; i < len(entries); i += 10 {
 		end := i + 10
@@ -144,9 +146,103 @@ func (p *Publisher) PublishBatch(ctx context.Context, eventList []events.Event)
 			p.logger.WithContext(ctx).
 				WithField("failed_count", result.FailedEntryCount).
 				Warn("Some events failed to publish")
+
+			// Result entries are in the same order as the request entries
+			for j, resultEntry := range result.Entries {
+				if resultEntry.ErrorCode != nil && j < len(batch) {
+					failed = append(failed, p.retryEntry(ctx, batch[j], sources[i+j])...)
+				}
+			}
 		}
 	}
 
+	return p.deadLetterEvents(ctx, failed)
+}
+
+// retryEntry retries a rejected entry on its own, returning the event if it still fails.
+func (p *Publisher) retryEntry(ctx context.Context, entry types.PutEventsRequestEntry, event events.Event) []events.Event {
+	err := resilience.Retry(ctx, p.retry, func() error {
+		result, err := p.client.PutEvents(ctx, &eventbridge.PutEventsInput{
+			Entries: []types.PutEventsRequestEntry{entry},
+		})
+		if err != nil {
+			return err
+		}
+		if result.FailedEntryCount > 0 && len(result.Entries) > 0 {
+			return fmt.Errorf("entry rejected: %s", aws.ToString(result.Entries[0].ErrorCode))
+		}
+		return nil
+	})
+	if err != nil {
+		p.logger.WithContext(ctx).WithError(err).
+			WithField("event_type", event.Type).
+			WithField("event_id", event.ID).
+			Error("Event failed to publish after retries")
+		return []events.Event{event}
+	}
+	return nil
+}
+
+// deadLetterEvents hands events that couldn't be published to the dead-letter sink.
+func (p *Publisher) deadLetterEvents(ctx context.Context, failed []events.Event) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	if p.deadLetter == nil {
+		return fmt.Errorf("failed to publish %d events and no dead-letter sink is configured", len(failed))
+	}
+	if err := p.deadLetter.Store(ctx, failed); err != nil {
+		p.logger.WithContext(ctx).WithError(err).
+			WithField("failed_count", len(failed)).
+			Error("Failed to dead-letter events")
+		return fmt.Errorf("failed to dead-letter %d events: %w", len(failed), err)
+	}
+	return nil
+}
+
+// toEntry converts an event to an EventBridge request entry.
+func (p *Publisher) toEntry(event events.Event) (types.PutEventsRequestEntry, error) {
+	detail, err := events.Marshal(event, p.format)
+	if err != nil {
+		return types.PutEventsRequestEntry{}, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	entry := types.PutEventsRequestEntry{
+		EventBusName: aws.String(p.busName),
+		Source:       aws.String(p.source),
+		DetailType:   aws.String(event.Type),
+		Detail:       aws.String(string(detail)),
+		Time:         aws.Time(time.Now().UTC()),
+	}
+
+	// Add trace ID if present
+	if event.Metadata.TraceID != "" {
+		entry.TraceHeader = aws.String(event.Metadata.TraceID)
+	}
+
+	return entry, nil
+}
+
+// defaultEntryRetryConfig returns the backoff used when retrying rejected batch entries.
+func defaultEntryRetryConfig() resilience.RetryConfig {
+	return resilience.RetryConfig{
+		MaxAttempts:  3,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   2.0,
+		Jitter:       true,
+	}
+}
+
+// HealthCheck verifies the event bus exists and is reachable.
+func (p *Publisher) HealthCheck(ctx context.Context) error {
+	client, ok := p.client.(describeEventBusAPI)
+	if !ok {
+		return nil
+	}
+	if _, err := client.DescribeEventBus(ctx, &eventbridge.DescribeEventBusInput{Name: aws.String(p.busName)}); err != nil {
+		return fmt.Errorf("EventBridge health check failed: %w", err)
+	}
 	return nil
 }
 
@@ -157,23 +253,30 @@ func (p *Publisher) Close() error {
 
 // CartEventPublisher wraps the publisher with cart-specific methods.
 type CartEventPublisher struct {
-	publisher *Publisher
+	publisher events.Publisher
 	source    string
 }
 
 // NewCartEventPublisher creates a new cart event publisher.
 func NewCartEventPublisher(publisher *Publisher) *CartEventPublisher {
+	return NewCartEventPublisherFor(publisher, publisher.source)
+}
+
+// NewCartEventPublisherFor creates a cart event publisher that sends events built
+// with the given source to any Publisher, such as an outbox Recorder.
+func NewCartEventPublisherFor(publisher events.Publisher, source string) *CartEventPublisher {
 	return &CartEventPublisher{
 		publisher: publisher,
-		source:    publisher.source,
+		source:    source,
 	}
 }
 
 // PublishCartCreated publishes a cart.created event.
 func (p *CartEventPublisher) PublishCartCreated(ctx context.Context, c *cart.Cart) error {
-	event := p.createEvent(ctx, events.EventTypeCartCreated, models.CartCreatedData{
+	event := p.createEvent(ctx, c, events.EventTypeCartCreated, models.CartCreatedData{
 		CartID:    c.ID,
 		UserID:    c.UserID,
+		Name:      c.Name,
 		CreatedAt: c.CreatedAt,
 		ExpiresAt: c.ExpiresAt,
 	})
@@ -182,17 +285,10 @@ func (p *CartEventPublisher) PublishCartCreated(ctx context.Context, c *cart.Car
 
 // PublishItemAdded publishes a cart.item_added event.
 func (p *CartEventPublisher) PublishItemAdded(ctx context.Context, c *cart.Cart, item *cart.CartItem) error {
-	event := p.createEvent(ctx, events.EventTypeItemAdded, models.ItemAddedData{
-		CartID: c.ID,
-		UserID: c.UserID,
-		Item: models.CartItemDTO{
-			ItemID:    item.ItemID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			UnitPrice: item.UnitPrice,
-			Subtotal:  item.UnitPrice * int64(item.Quantity),
-			AddedAt:   item.AddedAt,
-		},
+	event := p.createEvent(ctx, c, events.EventTypeItemAdded, models.ItemAddedData{
+		CartID:    c.ID,
+		UserID:    c.UserID,
+		Item:      toItemDTO(c, item),
 		CartTotal: c.TotalPrice(),
 		ItemCount: c.ItemCount(),
 	})
@@ -200,11 +296,12 @@ func (p *CartEventPublisher) PublishItemAdded(ctx context.Context, c *cart.Cart,
 }
 
 // PublishItemRemoved publishes a cart.item_removed event.
-func (p *CartEventPublisher) PublishItemRemoved(ctx context.Context, c *cart.Cart, itemID string) error {
-	event := p.createEvent(ctx, events.EventTypeItemRemoved, models.ItemRemovedData{
+func (p *CartEventPublisher) PublishItemRemoved(ctx context.Context, c *cart.Cart, itemID, productID string) error {
+	event := p.createEvent(ctx, c, events.EventTypeItemRemoved, models.ItemRemovedData{
 		CartID:    c.ID,
 		UserID:    c.UserID,
 		ItemID:    itemID,
+		ProductID: productID,
 		CartTotal: c.TotalPrice(),
 		ItemCount: c.ItemCount(),
 	})
@@ -212,33 +309,132 @@ func (p *CartEventPublisher) PublishItemRemoved(ctx context.Context, c *cart.Car
 }
 
 // PublishItemUpdated publishes a cart.item_updated event.
-func (p *CartEventPublisher) PublishItemUpdated(ctx context.Context, c *cart.Cart, item *cart.CartItem) error {
-	event := p.createEvent(ctx, events.EventTypeItemUpdated, models.ItemUpdatedData{
-		CartID: c.ID,
-		UserID: c.UserID,
-		Item: models.CartItemDTO{
-			ItemID:    item.ItemID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			UnitPrice: item.UnitPrice,
-			Subtotal:  item.UnitPrice * int64(item.Quantity),
-			AddedAt:   item.AddedAt,
-		},
-		CartTotal: c.TotalPrice(),
+func (p *CartEventPublisher) PublishItemUpdated(ctx context.Context, c *cart.Cart, item *cart.CartItem, prevQuantity int) error {
+	event := p.createEvent(ctx, c, events.EventTypeItemUpdated, models.ItemUpdatedData{
+		CartID:       c.ID,
+		UserID:       c.UserID,
+		Item:         toItemDTO(c, item),
+		PrevQuantity: prevQuantity,
+		CartTotal:    c.TotalPrice(),
 	})
 	return p.publisher.Publish(ctx, event)
 }
 
 // PublishCartCleared publishes a cart.cleared event.
-func (p *CartEventPublisher) PublishCartCleared(ctx context.Context, c *cart.Cart) error {
-	event := p.createEvent(ctx, events.EventTypeCartCleared, models.CartClearedData{
-		CartID: c.ID,
-		UserID: c.UserID,
+func (p *CartEventPublisher) PublishCartCleared(ctx context.Context, c *cart.Cart, itemsRemoved int, previousTotal int64) error {
+	event := p.createEvent(ctx, c, events.EventTypeCartCleared, models.CartClearedData{
+		CartID:        c.ID,
+		UserID:        c.UserID,
+		ItemsRemoved:  itemsRemoved,
+		PreviousTotal: previousTotal,
 	})
 	return p.publisher.Publish(ctx, event)
 }
 
-func (p *CartEventPublisher) createEvent(ctx context.Context, eventType string, data interface{}) events.Event {
+// PublishCartMerged publishes a cart.merged event.
+func (p *CartEventPublisher) PublishCartMerged(ctx context.Context, c *cart.Cart, guestID string, itemsMerged int) error {
+	event := p.createEvent(ctx, c, events.EventTypeCartMerged, models.CartMergedData{
+		CartID:         c.ID,
+		UserID:         c.UserID,
+		GuestID:        guestID,
+		ItemsMerged:    itemsMerged,
+		ResultingTotal: c.TotalPrice(),
+	})
+	return p.publisher.Publish(ctx, event)
+}
+
+// PublishPriceCorrected publishes a cart.price_corrected event.
+func (p *CartEventPublisher) PublishPriceCorrected(ctx context.Context, c *cart.Cart, item *cart.CartItem, previousPrice int64) error {
+	event := p.createEvent(ctx, c, events.EventTypePriceCorrected, models.PriceCorrectedData{
+		CartID:        c.ID,
+		UserID:        c.UserID,
+		Item:          toItemDTO(c, item),
+		PreviousPrice: previousPrice,
+		CartTotal:     c.TotalPrice(),
+	})
+	return p.publisher.Publish(ctx, event)
+}
+
+// PublishItemsPruned publishes a cart.items_pruned event.
+func (p *CartEventPublisher) PublishItemsPruned(ctx context.Context, c *cart.Cart, items []cart.CartItem, action cart.StaleItemAction) error {
+	dtos := make([]models.CartItemDTO, len(items))
+	for i := range items {
+		dtos[i] = toItemDTO(c, &items[i])
+	}
+	event := p.createEvent(ctx, c, events.EventTypeItemsPruned, models.ItemsPrunedData{
+		CartID:    c.ID,
+		UserID:    c.UserID,
+		Action:    string(action),
+		Items:     dtos,
+		CartTotal: c.TotalPrice(),
+		ItemCount: c.ItemCount(),
+	})
+	return p.publisher.Publish(ctx, event)
+}
+
+// PublishItemsReordered publishes a cart.items_reordered event.
+func (p *CartEventPublisher) PublishItemsReordered(ctx context.Context, c *cart.Cart) error {
+	itemIDs := make([]string, len(c.Items))
+	for i := range c.Items {
+		itemIDs[i] = c.Items[i].ItemID
+	}
+	event := p.createEvent(ctx, c, events.EventTypeItemsReordered, models.ItemsReorderedData{
+		CartID:    c.ID,
+		UserID:    c.UserID,
+		ItemIDs:   itemIDs,
+		ItemCount: c.ItemCount(),
+	})
+	return p.publisher.Publish(ctx, event)
+}
+
+// PublishCartExpiringSoon publishes a cart.expiring_soon event.
+func (p *CartEventPublisher) PublishCartExpiringSoon(ctx context.Context, c *cart.Cart, hoursRemaining int) error {
+	event := p.createEvent(ctx, c, events.EventTypeCartExpiringSoon, models.CartExpiringSoonData{
+		CartID:         c.ID,
+		UserID:         c.UserID,
+		ItemCount:      c.ItemCount(),
+		CartTotal:      c.TotalPrice(),
+		ExpiresAt:      c.ExpiresAt,
+		HoursRemaining: hoursRemaining,
+	})
+	return p.publisher.Publish(ctx, event)
+}
+
+// ReplayCart re-publishes the events describing the cart's current state: a
+// cart.created event followed by one cart.item_added per item, all marked as
+// replayed. It returns the number of events published.
+func (p *CartEventPublisher) ReplayCart(ctx context.Context, c *cart.Cart) (int, error) {
+	replay := make([]events.Event, 0, len(c.Items)+1)
+	replay = append(replay, p.createEvent(ctx, c, events.EventTypeCartCreated, models.CartCreatedData{
+		CartID:    c.ID,
+		UserID:    c.UserID,
+		Name:      c.Name,
+		CreatedAt: c.CreatedAt,
+		ExpiresAt: c.ExpiresAt,
+	}))
+	for i := range c.Items {
+		replay = append(replay, p.createEvent(ctx, c, events.EventTypeItemAdded, models.ItemAddedData{
+			CartID:    c.ID,
+			UserID:    c.UserID,
+			Item:      toItemDTO(c, &c.Items[i]),
+			CartTotal: c.TotalPrice(),
+			ItemCount: c.ItemCount(),
+		}))
+	}
+	for i := range replay {
+		replay[i].Metadata.Replayed = true
+	}
+
+	if err := p.publisher.PublishBatch(ctx, replay); err != nil {
+		return 0, err
+	}
+	return len(replay), nil
+}
+
+// createEvent builds an event for cart c. The owner is recorded in the metadata so
+// publishers can key a user's events consistently, even when the change was made
+// by an admin or a background job, together with the engine that priced the cart.
+func (p *CartEventPublisher) createEvent(ctx context.Context, c *cart.Cart, eventType string, data interface{}) events.Event {
 	return events.Event{
 		ID:          uuid.New().String(),
 		Source:      p.source,
@@ -248,8 +444,39 @@ func (p *CartEventPublisher) createEvent(ctx context.Context, eventType string,
 		DataVersion: "1.0",
 		Metadata: events.EventMetadata{
 			TraceID:       logging.TraceIDFromContext(ctx),
-			CorrelationID: logging.RequestIDFromContext(ctx),
-			UserID:        logging.UserIDFromContext(ctx),
+			CorrelationID: correlationID(ctx),
+			UserID:        c.UserID,
+			PricingEngine: c.PricingEngineName(),
 		},
 	}
 }
+
+// correlationID returns the inbound correlation ID, falling back to the request ID.
+func correlationID(ctx context.Context) string {
+	if id := logging.CorrelationIDFromContext(ctx); id != "" {
+		return id
+	}
+	return logging.RequestIDFromContext(ctx)
+}
+
+// toItemDTO converts an item of cart c to its event representation.
+func toItemDTO(c *cart.Cart, item *cart.CartItem) models.CartItemDTO {
+	return models.CartItemDTO{
+		ItemID:     item.ItemID,
+		ProductID:  item.ProductID,
+		Quantity:   item.Quantity,
+		UnitPrice:  item.UnitPrice,
+		Subtotal:   c.LineTotal(item),
+		AddedAt:    item.AddedAt,
+		Name:       item.Name,
+		ImageURL:   item.ImageURL,
+		SKU:        item.SKU,
+		Attributes: item.Attributes,
+
+		GiftWrap:    item.GiftWrap,
+		GiftWrapFee: item.GiftWrapFee,
+
+		UnitType:        string(item.UnitType),
+		DecimalQuantity: item.DecimalQuantity,
+	}
+}
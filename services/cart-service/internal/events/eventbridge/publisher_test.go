@@ -0,0 +1,199 @@
+package eventbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events/models"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/features"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/inmemory"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCartEventPublisher_CartClearedData(t *testing.T) {
+	ctx := context.Background()
+	recorder := events.NewRecorder()
+	service := cart.NewService(inmemory.NewRepository(), NewCartEventPublisherFor(recorder, "cart-service"),
+		cart.ServiceConfig{PublishEvents: true})
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "prod-1", Quantity: 2, UnitPrice: 1000})
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "prod-2", Quantity: 1, UnitPrice: 500})
+	require.NoError(t, err)
+	require.NoError(t, service.ClearCart(ctx, "user-123"))
+
+	var cleared *events.Event
+	for _, event := range recorder.Events() {
+		if event.Type == events.EventTypeCartCleared {
+			cleared = &event
+		}
+	}
+	require.NotNil(t, cleared)
+
+	body, err := json.Marshal(cleared.Data)
+	require.NoError(t, err)
+
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &data))
+	assert.Equal(t, float64(2), data["items_removed"])
+	assert.Equal(t, float64(2500), data["previous_total"])
+}
+
+// fakePutEvents rejects entries by detail type: always for rejected types, and
+// only the first time for flaky ones.
+type fakePutEvents struct {
+	rejected map[string]bool
+	flaky    map[string]bool
+}
+
+func (f *fakePutEvents) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	out := &eventbridge.PutEventsOutput{}
+	for _, entry := range params.Entries {
+		detailType := aws.ToString(entry.DetailType)
+		if f.rejected[detailType] || f.flaky[detailType] {
+			delete(f.flaky, detailType)
+			out.FailedEntryCount++
+			out.Entries = append(out.Entries, types.PutEventsResultEntry{ErrorCode: aws.String("InternalFailure")})
+			continue
+		}
+		out.Entries = append(out.Entries, types.PutEventsResultEntry{EventId: aws.String("ok")})
+	}
+	return out, nil
+}
+
+type fakeDeadLetterSink struct {
+	stored []string
+	err    error
+}
+
+func (s *fakeDeadLetterSink) Store(ctx context.Context, evts []events.Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, event := range evts {
+		s.stored = append(s.stored, event.Type)
+	}
+	return nil
+}
+
+func newTestPublisher(client putEventsAPI, sink events.DeadLetterSink) *Publisher {
+	return &Publisher{
+		client:     client,
+		busName:    "default",
+		source:     "cart-service",
+		deadLetter: sink,
+		retry:      resilience.RetryConfig{MaxAttempts: 2, Multiplier: 1},
+		logger:     logging.New(logging.Config{Level: "error"}),
+	}
+}
+
+func TestPublisher_PublishBatch_DeadLettersFailedEntries(t *testing.T) {
+	batch := []events.Event{
+		{ID: "1", Type: events.EventTypeItemAdded},
+		{ID: "2", Type: events.EventTypeItemRemoved},
+		{ID: "3", Type: events.EventTypeCartCleared},
+	}
+	newClient := func() *fakePutEvents {
+		return &fakePutEvents{
+			rejected: map[string]bool{events.EventTypeItemRemoved: true},
+			flaky:    map[string]bool{events.EventTypeCartCleared: true},
+		}
+	}
+
+	t.Run("stores entries that fail after retries", func(t *testing.T) {
+		sink := &fakeDeadLetterSink{}
+		err := newTestPublisher(newClient(), sink).PublishBatch(context.Background(), batch)
+		require.NoError(t, err)
+		assert.Equal(t, []string{events.EventTypeItemRemoved}, sink.stored)
+	})
+
+	t.Run("errors when the sink fails", func(t *testing.T) {
+		sink := &fakeDeadLetterSink{err: errors.New("queue unavailable")}
+		err := newTestPublisher(newClient(), sink).PublishBatch(context.Background(), batch)
+		assert.Error(t, err)
+	})
+
+	t.Run("errors without a sink", func(t *testing.T) {
+		err := newTestPublisher(newClient(), nil).PublishBatch(context.Background(), batch)
+		assert.Error(t, err)
+	})
+}
+
+func TestCartEventPublisher_CorrelationID(t *testing.T) {
+	recorder := events.NewRecorder()
+	publisher := NewCartEventPublisherFor(recorder, "cart-service")
+	c := cart.NewCart("user-123")
+
+	ctx := logging.ContextWithRequestID(context.Background(), "req-1")
+	require.NoError(t, publisher.PublishCartCreated(ctx, c))
+
+	ctx = logging.ContextWithCorrelationID(ctx, "corr-123")
+	require.NoError(t, publisher.PublishCartCreated(ctx, c))
+
+	recorded := recorder.Events()
+	require.Len(t, recorded, 2)
+	assert.Equal(t, "req-1", recorded[0].Metadata.CorrelationID)
+	assert.Equal(t, "corr-123", recorded[1].Metadata.CorrelationID)
+}
+
+func TestCartEventPublisher_PricingEngine(t *testing.T) {
+	ctx := context.Background()
+	recorder := events.NewRecorder()
+	flags := features.NewInMemoryFlags()
+	flags.SetFlag(features.FlagEventPublishing, true)
+	flags.SetVariant(features.FlagNewPricingEngine, cart.PricingEngineRounded)
+	service := cart.NewService(inmemory.NewRepository(), NewCartEventPublisherFor(recorder, "cart-service"),
+		cart.ServiceConfig{}, cart.WithFeatureFlags(flags))
+
+	// 1.5 kg at 3.33 per kg is 4.995, rounded up instead of truncated
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "prod-1", UnitPrice: 333, UnitType: cart.UnitTypeWeight, DecimalQuantity: 1500})
+	require.NoError(t, err)
+
+	recorded := recorder.Events()
+	require.Len(t, recorded, 2)
+	assert.Equal(t, cart.PricingEngineRounded, recorded[1].Metadata.PricingEngine)
+	added, ok := recorded[1].Data.(models.ItemAddedData)
+	require.True(t, ok)
+	assert.Equal(t, int64(500), added.CartTotal)
+	assert.Equal(t, int64(500), added.Item.Subtotal)
+}
+
+func TestCartEventPublisher_ReplayCart(t *testing.T) {
+	ctx := context.Background()
+	recorder := events.NewRecorder()
+	service := cart.NewService(inmemory.NewRepository(), NewCartEventPublisherFor(recorder, "cart-service"),
+		cart.ServiceConfig{PublishEvents: true})
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "prod-1", Quantity: 2, UnitPrice: 1000})
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "prod-2", Quantity: 1, UnitPrice: 500})
+	require.NoError(t, err)
+	published := len(recorder.Events())
+
+	result, err := service.ReplayEvents(ctx, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.EventsPublished)
+
+	replayed := recorder.Events()[published:]
+	require.Len(t, replayed, 3)
+	assert.Equal(t, events.EventTypeCartCreated, replayed[0].Type)
+	assert.Equal(t, events.EventTypeItemAdded, replayed[1].Type)
+	assert.Equal(t, events.EventTypeItemAdded, replayed[2].Type)
+	for _, event := range replayed {
+		assert.True(t, event.Metadata.Replayed)
+		assert.Equal(t, "user-123", event.Metadata.UserID)
+	}
+
+	_, err = service.ReplayEvents(ctx, "user-404")
+	assert.Error(t, err)
+}
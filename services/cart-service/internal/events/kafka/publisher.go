@@ -0,0 +1,139 @@
+// Package kafka provides a Kafka implementation of the event publisher.
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events/eventbridge"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+)
+
+// PublisherConfig holds configuration for the Kafka publisher.
+type PublisherConfig struct {
+	Brokers     []string
+	TopicPrefix string // Prepended to the event type to form the topic, e.g. "prod." + "cart.item_added"
+	Source      string
+	Format      events.Format // Event serialization; defaults to native
+}
+
+// Publisher is a Kafka implementation of the event publisher.
+// Messages are keyed by the event's user ID so a user's events stay ordered on
+// one partition.
+type Publisher struct {
+	writer      *kafka.Writer
+	brokers     []string
+	topicPrefix string
+	source      string
+	format      events.Format
+	logger      *logging.Logger
+}
+
+// NewPublisher creates a new Kafka publisher.
+func NewPublisher(cfg PublisherConfig, logger *logging.Logger) (*Publisher, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("at least one Kafka broker is required")
+	}
+
+	return &Publisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+		brokers:     cfg.Brokers,
+		topicPrefix: cfg.TopicPrefix,
+		source:      cfg.Source,
+		format:      cfg.Format,
+		logger:      logger,
+	}, nil
+}
+
+// Publish publishes a single event to Kafka.
+func (p *Publisher) Publish(ctx context.Context, event events.Event) error {
+	msg, err := p.toMessage(event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.writer.WriteMessages(ctx, msg); err != nil {
+		p.logger.WithContext(ctx).WithError(err).Error("Failed to publish event")
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	p.logger.WithContext(ctx).
+		WithField("event_type", event.Type).
+		WithField("event_id", event.ID).
+		Debug("Event published")
+
+	return nil
+}
+
+// PublishBatch publishes multiple events to Kafka.
+func (p *Publisher) PublishBatch(ctx context.Context, eventList []events.Event) error {
+	if len(eventList) == 0 {
+		return nil
+	}
+
+	msgs := make([]kafka.Message, 0, len(eventList))
+	for _, event := range eventList {
+		msg, err := p.toMessage(event)
+		if err != nil {
+			p.logger.WithContext(ctx).WithError(err).Error("Failed to marshal event")
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if err := p.writer.WriteMessages(ctx, msgs...); err != nil {
+		p.logger.WithContext(ctx).WithError(err).Error("Failed to publish event batch")
+		return fmt.Errorf("failed to publish event batch: %w", err)
+	}
+
+	return nil
+}
+
+// HealthCheck verifies at least one broker accepts connections.
+func (p *Publisher) HealthCheck(ctx context.Context) error {
+	var lastErr error
+	for _, broker := range p.brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("Kafka health check failed: %w", lastErr)
+}
+
+// Close flushes pending messages and closes the writer.
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
+
+// toMessage converts an event to a Kafka message.
+func (p *Publisher) toMessage(event events.Event) (kafka.Message, error) {
+	value, err := events.Marshal(event, p.format)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	msg := kafka.Message{
+		Topic: p.topicPrefix + event.Type,
+		Key:   []byte(event.Metadata.UserID),
+		Value: value,
+		Time:  time.Now().UTC(),
+	}
+	if p.format == events.FormatCloudEvents {
+		msg.Headers = []kafka.Header{{Key: "content-type", Value: []byte(events.CloudEventsContentType)}}
+	}
+	return msg, nil
+}
+
+// NewCartEventPublisher creates a cart event publisher that sends to Kafka.
+func NewCartEventPublisher(publisher *Publisher) *eventbridge.CartEventPublisher {
+	return eventbridge.NewCartEventPublisherFor(publisher, publisher.source)
+}
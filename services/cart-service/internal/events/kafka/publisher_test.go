@@ -0,0 +1,47 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events/eventbridge"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisher_ToMessage(t *testing.T) {
+	publisher, err := NewPublisher(PublisherConfig{
+		Brokers:     []string{"localhost:9092"},
+		TopicPrefix: "prod.",
+		Source:      "cart-service",
+	}, logging.New(logging.Config{Level: "error"}))
+	require.NoError(t, err)
+
+	// Build an event the way the cart wrapper does
+	recorder := events.NewRecorder()
+	c := cart.NewCart("user-123")
+	require.NoError(t, eventbridge.NewCartEventPublisherFor(recorder, "cart-service").
+		PublishCartCreated(context.Background(), c))
+	require.Len(t, recorder.Events(), 1)
+
+	msg, err := publisher.toMessage(recorder.Events()[0])
+	require.NoError(t, err)
+
+	assert.Equal(t, "prod.cart.created", msg.Topic)
+	assert.Equal(t, "user-123", string(msg.Key))
+
+	var decoded events.Event
+	require.NoError(t, json.Unmarshal(msg.Value, &decoded))
+	assert.Equal(t, events.EventTypeCartCreated, decoded.Type)
+	assert.Equal(t, "cart-service", decoded.Source)
+	assert.NotNil(t, NewCartEventPublisher(publisher))
+}
+
+func TestNewPublisher_RequiresBrokers(t *testing.T) {
+	_, err := NewPublisher(PublisherConfig{}, logging.New(logging.Config{Level: "error"}))
+	assert.Error(t, err)
+}
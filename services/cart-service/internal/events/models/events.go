@@ -7,6 +7,7 @@ import "time"
 type CartCreatedData struct {
 	CartID    string    `json:"cart_id"`
 	UserID    string    `json:"user_id"`
+	Name      string    `json:"name,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
@@ -47,6 +48,44 @@ type CartClearedData struct {
 	PreviousTotal  int64  `json:"previous_total"`
 }
 
+// CartMergedData represents data for cart.merged event.
+type CartMergedData struct {
+	CartID         string `json:"cart_id"`
+	UserID         string `json:"user_id"`
+	GuestID        string `json:"guest_id"`
+	ItemsMerged    int    `json:"items_merged"`
+	ResultingTotal int64  `json:"resulting_total"`
+}
+
+// PriceCorrectedData represents data for cart.price_corrected event.
+type PriceCorrectedData struct {
+	CartID        string      `json:"cart_id"`
+	UserID        string      `json:"user_id"`
+	Item          CartItemDTO `json:"item"`
+	PreviousPrice int64       `json:"previous_price"`
+	CartTotal     int64       `json:"cart_total"`
+}
+
+// ItemsPrunedData represents data for cart.items_pruned event. Action is
+// "remove" or "flag".
+type ItemsPrunedData struct {
+	CartID    string        `json:"cart_id"`
+	UserID    string        `json:"user_id"`
+	Action    string        `json:"action"`
+	Items     []CartItemDTO `json:"items"`
+	CartTotal int64         `json:"cart_total"`
+	ItemCount int           `json:"item_count"`
+}
+
+// ItemsReorderedData represents data for cart.items_reordered event. ItemIDs
+// lists every item in its new order.
+type ItemsReorderedData struct {
+	CartID    string   `json:"cart_id"`
+	UserID    string   `json:"user_id"`
+	ItemIDs   []string `json:"item_ids"`
+	ItemCount int      `json:"item_count"`
+}
+
 // CartAbandonedData represents data for cart.abandoned event.
 type CartAbandonedData struct {
 	CartID      string    `json:"cart_id"`
@@ -57,6 +96,16 @@ type CartAbandonedData struct {
 	ExpiresAt   time.Time `json:"expires_at"`
 }
 
+// CartExpiringSoonData represents data for cart.expiring_soon event.
+type CartExpiringSoonData struct {
+	CartID         string    `json:"cart_id"`
+	UserID         string    `json:"user_id"`
+	ItemCount      int       `json:"item_count"`
+	CartTotal      int64     `json:"cart_total"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	HoursRemaining int       `json:"hours_remaining"`
+}
+
 // CartItemDTO represents a cart item in events.
 type CartItemDTO struct {
 	ItemID    string    `json:"item_id"`
@@ -65,4 +114,15 @@ type CartItemDTO struct {
 	UnitPrice int64     `json:"unit_price"`
 	Subtotal  int64     `json:"subtotal"`
 	AddedAt   time.Time `json:"added_at"`
+
+	Name       string            `json:"name,omitempty"`
+	ImageURL   string            `json:"image_url,omitempty"`
+	SKU        string            `json:"sku,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	GiftWrap    bool  `json:"gift_wrap,omitempty"`
+	GiftWrapFee int64 `json:"gift_wrap_fee,omitempty"`
+
+	UnitType        string `json:"unit_type,omitempty"`
+	DecimalQuantity int64  `json:"decimal_quantity,omitempty"`
 }
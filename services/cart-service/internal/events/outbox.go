@@ -0,0 +1,55 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OutboxRecord is an event written alongside a cart update, awaiting dispatch.
+type OutboxRecord struct {
+	TenantID  string
+	UserID    string
+	Event     Event
+	CreatedAt time.Time
+}
+
+// Recorder is a Publisher that keeps events in memory instead of sending them.
+// It is used to build events that are written to the outbox.
+type Recorder struct {
+	events []Event
+	mu     sync.Mutex
+}
+
+// NewRecorder creates a new event recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Publish records a single event.
+func (r *Recorder) Publish(ctx context.Context, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// PublishBatch records multiple events.
+func (r *Recorder) PublishBatch(ctx context.Context, events []Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, events...)
+	return nil
+}
+
+// Close is a no-op.
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// Events returns the recorded events.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
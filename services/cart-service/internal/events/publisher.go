@@ -17,6 +17,18 @@ type Publisher interface {
 	Close() error
 }
 
+// HealthChecker is implemented by publishers that can cheaply verify their
+// connection to the event bus.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// DeadLetterSink stores events that could not be published so they can be
+// inspected or replayed later.
+type DeadLetterSink interface {
+	Store(ctx context.Context, events []Event) error
+}
+
 // Event represents a domain event.
 type Event struct {
 	ID            string                 `json:"id"`
@@ -33,6 +45,13 @@ type EventMetadata struct {
 	TraceID       string `json:"trace_id,omitempty"`
 	CorrelationID string `json:"correlation_id,omitempty"`
 	UserID        string `json:"user_id,omitempty"`
+
+	// PricingEngine names the engine that computed the totals in the data
+	PricingEngine string `json:"pricing_engine,omitempty"`
+
+	// Replayed marks a re-emitted event describing existing state, so
+	// consumers that already processed the original can skip it
+	Replayed bool `json:"replayed,omitempty"`
 }
 
 // Event types
@@ -43,4 +62,9 @@ const (
 	EventTypeItemUpdated    = "cart.item_updated"
 	EventTypeCartCleared    = "cart.cleared"
 	EventTypeCartAbandoned  = "cart.abandoned"
+	EventTypeCartExpiringSoon = "cart.expiring_soon"
+	EventTypePriceCorrected   = "cart.price_corrected"
+	EventTypeCartMerged       = "cart.merged"
+	EventTypeItemsPruned      = "cart.items_pruned"
+	EventTypeItemsReordered   = "cart.items_reordered"
 )
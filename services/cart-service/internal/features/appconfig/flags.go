@@ -0,0 +1,210 @@
+// Package appconfig provides feature flags backed by AWS AppConfig.
+package appconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/appconfigdata"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/features"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+)
+
+// DefaultPollInterval is how often flags are refreshed when Config.PollInterval
+// is zero. AppConfig doesn't allow polling more often than every 15 seconds.
+const DefaultPollInterval = 30 * time.Second
+
+// fetchTimeout bounds a single AppConfig call.
+const fetchTimeout = 10 * time.Second
+
+// Config holds configuration for AppConfig feature flags.
+type Config struct {
+	Region   string
+	Endpoint string // Optional, for local testing
+
+	// Application, Environment and Profile identify the configuration
+	// profile (name or ID) holding the flags
+	Application string
+	Environment string
+	Profile     string
+
+	PollInterval time.Duration
+}
+
+// appConfigAPI is the subset of the AppConfig Data client used by Flags.
+type appConfigAPI interface {
+	StartConfigurationSession(ctx context.Context, params *appconfigdata.StartConfigurationSessionInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.StartConfigurationSessionOutput, error)
+	GetLatestConfiguration(ctx context.Context, params *appconfigdata.GetLatestConfigurationInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.GetLatestConfigurationOutput, error)
+}
+
+// flag is one entry of an AppConfig feature flag configuration, e.g.
+// {"cart.express_checkout": {"enabled": true, "variant": "b"}}.
+type flag struct {
+	Enabled bool   `json:"enabled"`
+	Variant string `json:"variant"`
+}
+
+// Flags implements features.Flags with flags polled from an AppConfig
+// configuration profile. Lookups are served from the last configuration
+// fetched successfully, so an AppConfig outage keeps the last-known-good flags;
+// until the first fetch succeeds every flag is off.
+type Flags struct {
+	client appConfigAPI
+	cfg    Config
+	logger *logging.Logger
+
+	mu    sync.RWMutex
+	flags map[string]flag
+
+	// token is the session's next poll token; only the poll loop uses it
+	token *string
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ features.Flags = (*Flags)(nil)
+
+// New creates AppConfig feature flags, fetches the configuration once and
+// starts polling for changes. A failed first fetch is logged, not returned.
+func New(ctx context.Context, cfg Config, logger *logging.Logger) (*Flags, error) {
+	if cfg.Application == "" || cfg.Environment == "" || cfg.Profile == "" {
+		return nil, errors.New("AppConfig application, environment and profile are required")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	var client *appconfigdata.Client
+	if cfg.Endpoint != "" {
+		client = appconfigdata.NewFromConfig(awsCfg, func(o *appconfigdata.Options) {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		})
+	} else {
+		client = appconfigdata.NewFromConfig(awsCfg)
+	}
+
+	return newFlags(ctx, client, cfg, logger), nil
+}
+
+// newFlags creates Flags on client and starts polling.
+func newFlags(ctx context.Context, client appConfigAPI, cfg Config, logger *logging.Logger) *Flags {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	f := &Flags{
+		client: client,
+		cfg:    cfg,
+		logger: logger,
+		flags:  make(map[string]flag),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if err := f.refresh(ctx); err != nil {
+		f.logger.WithError(err).Warn("Failed to fetch feature flags from AppConfig; all flags are off until a fetch succeeds")
+	}
+	go f.poll()
+	return f
+}
+
+// IsEnabled checks if a feature flag is enabled.
+func (f *Flags) IsEnabled(ctx context.Context, flag string, userID string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.flags[flag].Enabled
+}
+
+// GetVariant returns the variant for a feature flag, or "" when the flag is
+// off.
+func (f *Flags) GetVariant(ctx context.Context, flag string, userID string) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if !f.flags[flag].Enabled {
+		return ""
+	}
+	return f.flags[flag].Variant
+}
+
+// Close stops polling.
+func (f *Flags) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.stop)
+		<-f.done
+	})
+	return nil
+}
+
+// poll refreshes the flags every poll interval until Close.
+func (f *Flags) poll() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+			if err := f.refresh(ctx); err != nil {
+				f.logger.WithError(err).Warn("Failed to refresh feature flags from AppConfig; keeping last known flags")
+			}
+			cancel()
+		}
+	}
+}
+
+// refresh fetches the latest configuration and replaces the flags if it
+// changed. AppConfig returns an empty configuration when nothing changed
+// since the last poll. A failed poll drops the session so the next one starts
+// afresh, e.g. after the token expired.
+func (f *Flags) refresh(ctx context.Context) error {
+	if f.token == nil {
+		session, err := f.client.StartConfigurationSession(ctx, &appconfigdata.StartConfigurationSessionInput{
+			ApplicationIdentifier:                aws.String(f.cfg.Application),
+			EnvironmentIdentifier:                aws.String(f.cfg.Environment),
+			ConfigurationProfileIdentifier:       aws.String(f.cfg.Profile),
+			RequiredMinimumPollIntervalInSeconds: aws.Int32(int32(f.cfg.PollInterval / time.Second)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start configuration session: %w", err)
+		}
+		f.token = session.InitialConfigurationToken
+	}
+
+	out, err := f.client.GetLatestConfiguration(ctx, &appconfigdata.GetLatestConfigurationInput{
+		ConfigurationToken: f.token,
+	})
+	if err != nil {
+		f.token = nil
+		return fmt.Errorf("failed to get latest configuration: %w", err)
+	}
+	f.token = out.NextPollConfigurationToken
+
+	if len(out.Configuration) == 0 {
+		return nil
+	}
+
+	flags := make(map[string]flag)
+	if err := json.Unmarshal(out.Configuration, &flags); err != nil {
+		return fmt.Errorf("invalid feature flag configuration: %w", err)
+	}
+
+	f.mu.Lock()
+	f.flags = flags
+	f.mu.Unlock()
+
+	f.logger.WithField("version", aws.ToString(out.VersionLabel)).Infof("Loaded %d feature flags from AppConfig", len(flags))
+	return nil
+}
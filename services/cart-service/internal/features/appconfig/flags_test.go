@@ -0,0 +1,104 @@
+package appconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/appconfigdata"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/features"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAppConfig serves queued configurations; an empty one means unchanged.
+type fakeAppConfig struct {
+	configs      [][]byte
+	fail         bool
+	sessions     int
+	pollInterval int32
+}
+
+func (f *fakeAppConfig) StartConfigurationSession(ctx context.Context, params *appconfigdata.StartConfigurationSessionInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.StartConfigurationSessionOutput, error) {
+	if f.fail {
+		return nil, assert.AnError
+	}
+	f.sessions++
+	f.pollInterval = aws.ToInt32(params.RequiredMinimumPollIntervalInSeconds)
+	return &appconfigdata.StartConfigurationSessionOutput{InitialConfigurationToken: aws.String("token-0")}, nil
+}
+
+func (f *fakeAppConfig) GetLatestConfiguration(ctx context.Context, params *appconfigdata.GetLatestConfigurationInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.GetLatestConfigurationOutput, error) {
+	if f.fail {
+		return nil, assert.AnError
+	}
+	var config []byte
+	if len(f.configs) > 0 {
+		config, f.configs = f.configs[0], f.configs[1:]
+	}
+	return &appconfigdata.GetLatestConfigurationOutput{
+		Configuration:              config,
+		NextPollConfigurationToken: aws.String("token-next"),
+	}, nil
+}
+
+func newTestFlags(t *testing.T, client *fakeAppConfig) *Flags {
+	logger := logging.New(logging.Config{Level: "error", ServiceName: "cart-service-test", Environment: "test"})
+	f := newFlags(context.Background(), client, Config{
+		Application:  "cart-service",
+		Environment:  "test",
+		Profile:      "flags",
+		PollInterval: time.Hour,
+	}, logger)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestFlags_LastKnownGood(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeAppConfig{configs: [][]byte{
+		[]byte(`{"cart.express_checkout": {"enabled": true, "variant": "b"}, "cart.new_pricing_engine": {"enabled": false, "variant": "v2"}}`),
+	}}
+	f := newTestFlags(t, client)
+
+	assert.Equal(t, int32(3600), client.pollInterval)
+	assert.True(t, f.IsEnabled(ctx, features.FlagExpressCheckout, "user-123"))
+	assert.Equal(t, "b", f.GetVariant(ctx, features.FlagExpressCheckout, "user-123"))
+	assert.False(t, f.IsEnabled(ctx, features.FlagNewPricingEngine, "user-123"))
+	assert.Empty(t, f.GetVariant(ctx, features.FlagNewPricingEngine, "user-123"))
+
+	// An unchanged configuration keeps the flags
+	require.NoError(t, f.refresh(ctx))
+	assert.True(t, f.IsEnabled(ctx, features.FlagExpressCheckout, "user-123"))
+
+	// A failed fetch keeps the last-known-good flags and starts a new session
+	client.fail = true
+	assert.Error(t, f.refresh(ctx))
+	assert.True(t, f.IsEnabled(ctx, features.FlagExpressCheckout, "user-123"))
+
+	client.fail = false
+	client.configs = [][]byte{[]byte(`{"cart.express_checkout": {"enabled": false}}`)}
+	require.NoError(t, f.refresh(ctx))
+	assert.Equal(t, 2, client.sessions)
+	assert.False(t, f.IsEnabled(ctx, features.FlagExpressCheckout, "user-123"))
+
+	// An invalid configuration is rejected
+	client.configs = [][]byte{[]byte(`not json`)}
+	assert.Error(t, f.refresh(ctx))
+	assert.False(t, f.IsEnabled(ctx, features.FlagExpressCheckout, "user-123"))
+}
+
+func TestFlags_FirstFetchFails(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeAppConfig{fail: true}
+	f := newTestFlags(t, client)
+
+	assert.False(t, f.IsEnabled(ctx, features.FlagExpressCheckout, "user-123"))
+
+	client.fail = false
+	client.configs = [][]byte{[]byte(`{"cart.express_checkout": {"enabled": true}}`)}
+	require.NoError(t, f.refresh(ctx))
+	assert.True(t, f.IsEnabled(ctx, features.FlagExpressCheckout, "user-123"))
+}
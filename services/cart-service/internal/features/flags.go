@@ -105,36 +105,102 @@ func (f *StaticFlags) Close() error {
 	return nil
 }
 
-// PercentageFlags provides percentage-based rollout.
+// ExposureFunc records that a user was evaluated for a flag, for attributing
+// experiment outcomes to the bucket the user landed in.
+type ExposureFunc func(ctx context.Context, userID, flag string, enabled bool)
+
+// maxStickyBuckets caps the users*flags PercentageFlags remembers; when it's
+// reached the cache starts over, so exposures may be recorded again.
+const maxStickyBuckets = 100000
+
+// PercentageFlags provides percentage-based rollout. A user's bucket is
+// derived from a hash of the user, the flag and the salt, so it's stable per
+// flag; changing the salt re-randomizes every rollout.
 type PercentageFlags struct {
 	percentages map[string]int // 0-100
-	mu          sync.RWMutex
+	salt        string
+	exposure    ExposureFunc
+
+	// buckets caches each user's bucket per flag; a user is exposed when
+	// first cached
+	buckets map[bucketKey]int
+
+	mu sync.RWMutex
+}
+
+// bucketKey identifies a user's bucket for a flag.
+type bucketKey struct {
+	flag   string
+	userID string
+}
+
+// PercentageOption configures PercentageFlags.
+type PercentageOption func(*PercentageFlags)
+
+// WithSalt sets the salt mixed into the bucketing hash.
+func WithSalt(salt string) PercentageOption {
+	return func(f *PercentageFlags) {
+		f.salt = salt
+	}
+}
+
+// WithExposureLog sets a callback run the first time a user is evaluated for
+// a flag.
+func WithExposureLog(fn ExposureFunc) PercentageOption {
+	return func(f *PercentageFlags) {
+		f.exposure = fn
+	}
 }
 
 // NewPercentageFlags creates a new percentage-based feature flags instance.
-func NewPercentageFlags(percentages map[string]int) *PercentageFlags {
+func NewPercentageFlags(percentages map[string]int, opts ...PercentageOption) *PercentageFlags {
 	if percentages == nil {
 		percentages = make(map[string]int)
 	}
-	return &PercentageFlags{
+	f := &PercentageFlags{
 		percentages: percentages,
+		buckets:     make(map[bucketKey]int),
+	}
+	for _, opt := range opts {
+		opt(f)
 	}
+	return f
 }
 
 // IsEnabled checks if a feature flag is enabled for a user.
 func (f *PercentageFlags) IsEnabled(ctx context.Context, flag string, userID string) bool {
+	key := bucketKey{flag: flag, userID: userID}
+
 	f.mu.RLock()
 	percentage, ok := f.percentages[flag]
+	bucket, cached := f.buckets[key]
 	f.mu.RUnlock()
 
 	if !ok {
 		return false
 	}
+	if cached {
+		return bucket < percentage
+	}
+
+	f.mu.Lock()
+	if bucket, cached = f.buckets[key]; !cached {
+		// Use hash of userID for consistent bucketing
+		bucket = int(hashString(userID+flag+f.salt) % 100)
+		if len(f.buckets) >= maxStickyBuckets {
+			f.buckets = make(map[bucketKey]int)
+		}
+		f.buckets[key] = bucket
+	}
+	percentage = f.percentages[flag]
+	exposure := f.exposure
+	f.mu.Unlock()
 
-	// Use hash of userID for consistent bucketing
-	hash := hashString(userID + flag)
-	bucket := int(hash % 100)
-	return bucket < percentage
+	enabled := bucket < percentage
+	if !cached && exposure != nil {
+		exposure(ctx, userID, flag, enabled)
+	}
+	return enabled
 }
 
 // GetVariant returns empty string (percentage flags don't support variants).
@@ -155,6 +221,15 @@ func (f *PercentageFlags) SetPercentage(flag string, percentage int) {
 	f.percentages[flag] = percentage
 }
 
+// SetSalt changes the bucketing salt, re-randomizing every rollout. Users are
+// exposed again on their next evaluation.
+func (f *PercentageFlags) SetSalt(salt string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.salt = salt
+	f.buckets = make(map[bucketKey]int)
+}
+
 // Close closes the feature flags instance.
 func (f *PercentageFlags) Close() error {
 	return nil
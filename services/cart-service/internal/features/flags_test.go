@@ -0,0 +1,60 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type exposure struct {
+	userID  string
+	flag    string
+	enabled bool
+}
+
+func TestPercentageFlags_Exposure(t *testing.T) {
+	ctx := context.Background()
+	var exposures []exposure
+	f := NewPercentageFlags(map[string]int{FlagNewPricingEngine: 100}, WithExposureLog(func(ctx context.Context, userID, flag string, enabled bool) {
+		exposures = append(exposures, exposure{userID, flag, enabled})
+	}))
+
+	assert.True(t, f.IsEnabled(ctx, FlagNewPricingEngine, "user-a"))
+	assert.True(t, f.IsEnabled(ctx, FlagNewPricingEngine, "user-a"))
+	assert.False(t, f.IsEnabled(ctx, FlagExpressCheckout, "user-a"))
+
+	// Only the first evaluation of a configured flag is recorded
+	assert.Equal(t, []exposure{{"user-a", FlagNewPricingEngine, true}}, exposures)
+
+	// The bucket sticks when the rollout changes, without a new exposure
+	f.SetPercentage(FlagNewPricingEngine, 0)
+	assert.False(t, f.IsEnabled(ctx, FlagNewPricingEngine, "user-a"))
+	assert.Len(t, exposures, 1)
+
+	// A new salt exposes users again
+	f.SetPercentage(FlagNewPricingEngine, 100)
+	f.SetSalt("rollout-2")
+	assert.True(t, f.IsEnabled(ctx, FlagNewPricingEngine, "user-a"))
+	assert.Len(t, exposures, 2)
+}
+
+func TestPercentageFlags_Salt(t *testing.T) {
+	ctx := context.Background()
+	percentages := map[string]int{FlagNewPricingEngine: 50}
+	unsalted := NewPercentageFlags(percentages)
+	same := NewPercentageFlags(percentages)
+	salted := NewPercentageFlags(percentages, WithSalt("rollout-2"))
+
+	moved := 0
+	for i := 0; i < 100; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		enabled := unsalted.IsEnabled(ctx, FlagNewPricingEngine, userID)
+		assert.Equal(t, enabled, same.IsEnabled(ctx, FlagNewPricingEngine, userID), userID)
+		if enabled != salted.IsEnabled(ctx, FlagNewPricingEngine, userID) {
+			moved++
+		}
+	}
+	assert.Positive(t, moved)
+}
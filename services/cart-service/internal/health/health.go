@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,6 +20,9 @@ type Checker interface {
 type Handler struct {
 	checkers []Checker
 	mu       sync.RWMutex
+
+	// Set once shutdown starts; readiness fails without running checks
+	draining atomic.Bool
 }
 
 // NewHandler creates a new health handler.
@@ -59,11 +63,34 @@ func (h *Handler) LivenessHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ReadinessHandler handles GET /ready - checks all dependencies.
+// StartDraining makes readiness fail from now on so load balancers stop
+// routing new requests to this instance. Liveness is unaffected.
+func (h *Handler) StartDraining() {
+	h.draining.Store(true)
+}
+
+// IsDraining reports whether StartDraining has been called.
+func (h *Handler) IsDraining() bool {
+	return h.draining.Load()
+}
+
+// ReadinessHandler handles GET /ready - checks all dependencies. It returns
+// 503 immediately once the handler is draining.
 func (h *Handler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	if h.IsDraining() {
+		writeReadiness(w, HealthResponse{Status: "draining", Timestamp: time.Now().UTC()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	writeReadiness(w, h.CheckReadiness(ctx))
+}
+
+// CheckReadiness runs all registered checks. The response status is "ready"
+// only when every check passes.
+func (h *Handler) CheckReadiness(ctx context.Context) HealthResponse {
 	h.mu.RLock()
 	checkers := make([]Checker, len(h.checkers))
 	copy(checkers, h.checkers)
@@ -93,20 +120,24 @@ func (h *Handler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := HealthResponse{
+		Status:    "ready",
 		Timestamp: time.Now().UTC(),
 		Checks:    checks,
 	}
+	if !allHealthy {
+		response.Status = "not ready"
+	}
+	return response
+}
 
+// writeReadiness writes a readiness response, with 503 when not ready.
+func writeReadiness(w http.ResponseWriter, response HealthResponse) {
 	w.Header().Set("Content-Type", "application/json")
-
-	if allHealthy {
-		response.Status = "ready"
+	if response.Status == "ready" {
 		w.WriteHeader(http.StatusOK)
 	} else {
-		response.Status = "not ready"
 		w.WriteHeader(http.StatusServiceUnavailable)
 	}
-
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -114,6 +145,7 @@ func (h *Handler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
 type RepositoryChecker struct {
 	name      string
 	checkFunc func(ctx context.Context) error
+	timeout   time.Duration
 }
 
 // NewRepositoryChecker creates a new repository checker.
@@ -124,6 +156,13 @@ func NewRepositoryChecker(name string, checkFunc func(ctx context.Context) error
 	}
 }
 
+// WithTimeout bounds each check so a slow dependency fails fast instead of
+// holding up the probe.
+func (c *RepositoryChecker) WithTimeout(timeout time.Duration) *RepositoryChecker {
+	c.timeout = timeout
+	return c
+}
+
 // Name returns the checker name.
 func (c *RepositoryChecker) Name() string {
 	return c.name
@@ -131,5 +170,10 @@ func (c *RepositoryChecker) Name() string {
 
 // Check performs the health check.
 func (c *RepositoryChecker) Check(ctx context.Context) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
 	return c.checkFunc(ctx)
 }
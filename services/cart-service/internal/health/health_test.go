@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	handler := NewHandler()
+	handler.RegisterChecker(NewRepositoryChecker("repository", func(ctx context.Context) error {
+		return nil
+	}))
+	handler.RegisterChecker(NewRepositoryChecker("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}).WithTimeout(10 * time.Millisecond))
+
+	rec := httptest.NewRecorder()
+	handler.ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var response HealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "not ready", response.Status)
+	assert.Equal(t, "ok", response.Checks["repository"].Status)
+	assert.NotEmpty(t, response.Checks["repository"].Latency)
+	assert.Equal(t, "error", response.Checks["slow"].Status)
+	assert.Contains(t, response.Checks["slow"].Message, "deadline exceeded")
+}
+
+func TestReadinessHandler_Draining(t *testing.T) {
+	checked := false
+	handler := NewHandler()
+	handler.RegisterChecker(NewRepositoryChecker("repository", func(ctx context.Context) error {
+		checked = true
+		return nil
+	}))
+	handler.StartDraining()
+
+	rec := httptest.NewRecorder()
+	handler.ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.False(t, checked)
+
+	var response HealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "draining", response.Status)
+
+	rec = httptest.NewRecorder()
+	handler.LivenessHandler(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,147 @@
+// Package jobs provides background jobs run alongside the cart service.
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+)
+
+// ExpiringCartFinder finds carts whose expiry falls within a time range.
+type ExpiringCartFinder interface {
+	// FindExpiringCarts returns carts expiring after from and no later than to.
+	FindExpiringCarts(ctx context.Context, from, to time.Time) ([]*cart.Cart, error)
+}
+
+// ExpiryMarkerStore records which carts have already been warned about an upcoming expiry.
+// Markers are keyed by cart ID and expiry time, so a cart whose expiry is extended
+// becomes eligible for a new warning.
+type ExpiryMarkerStore interface {
+	// MarkExpiryNotified claims the marker, returning false if it already exists.
+	MarkExpiryNotified(ctx context.Context, cartID string, expiresAt time.Time) (bool, error)
+
+	// UnmarkExpiryNotified releases a marker so the warning can be retried.
+	UnmarkExpiryNotified(ctx context.Context, cartID string, expiresAt time.Time) error
+}
+
+// ExpiringSoonPublisher publishes cart.expiring_soon events.
+type ExpiringSoonPublisher interface {
+	PublishCartExpiringSoon(ctx context.Context, c *cart.Cart, hoursRemaining int) error
+}
+
+// ExpiryNotifierConfig holds configuration for the expiry notifier.
+type ExpiryNotifierConfig struct {
+	// Window is how far ahead of expiry carts are warned about.
+	Window time.Duration
+
+	// Interval is how often the job scans for expiring carts.
+	Interval time.Duration
+}
+
+// DefaultExpiryNotifierConfig returns sensible defaults.
+func DefaultExpiryNotifierConfig() ExpiryNotifierConfig {
+	return ExpiryNotifierConfig{
+		Window:   24 * time.Hour,
+		Interval: 15 * time.Minute,
+	}
+}
+
+// ExpiryNotifier periodically emits cart.expiring_soon events for carts nearing expiry.
+type ExpiryNotifier struct {
+	finder    ExpiringCartFinder
+	markers   ExpiryMarkerStore
+	publisher ExpiringSoonPublisher
+	config    ExpiryNotifierConfig
+	logger    *logging.Logger
+	now       func() time.Time
+}
+
+// NewExpiryNotifier creates a new expiry notifier.
+func NewExpiryNotifier(
+	finder ExpiringCartFinder,
+	markers ExpiryMarkerStore,
+	publisher ExpiringSoonPublisher,
+	config ExpiryNotifierConfig,
+	logger *logging.Logger,
+) *ExpiryNotifier {
+	defaults := DefaultExpiryNotifierConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+
+	return &ExpiryNotifier{
+		finder:    finder,
+		markers:   markers,
+		publisher: publisher,
+		config:    config,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Run scans for expiring carts every interval until the context is cancelled.
+func (n *ExpiryNotifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := n.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			n.logger.WithContext(ctx).WithError(err).Error("Expiry notifier run failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs a single scan and returns the number of events emitted.
+func (n *ExpiryNotifier) RunOnce(ctx context.Context) (int, error) {
+	now := n.now().UTC()
+
+	carts, err := n.finder.FindExpiringCarts(ctx, now, now.Add(n.config.Window))
+	if err != nil {
+		return 0, err
+	}
+
+	emitted := 0
+	for _, c := range carts {
+		// Nobody needs reminding about an empty cart
+		if len(c.Items) == 0 {
+			continue
+		}
+
+		claimed, err := n.markers.MarkExpiryNotified(ctx, c.ID, c.ExpiresAt)
+		if err != nil {
+			n.logger.WithContext(ctx).WithError(err).WithField("cart_id", c.ID).Error("Failed to mark cart expiry notified")
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		hoursRemaining := int(c.ExpiresAt.Sub(now).Hours())
+		if err := n.publisher.PublishCartExpiringSoon(ctx, c, hoursRemaining); err != nil {
+			n.logger.WithContext(ctx).WithError(err).WithField("cart_id", c.ID).Error("Failed to publish cart expiring soon event")
+			// Release the marker so the next run retries
+			if err := n.markers.UnmarkExpiryNotified(ctx, c.ID, c.ExpiresAt); err != nil {
+				n.logger.WithContext(ctx).WithError(err).WithField("cart_id", c.ID).Error("Failed to release cart expiry marker")
+			}
+			continue
+		}
+		emitted++
+	}
+
+	if emitted > 0 {
+		n.logger.WithContext(ctx).WithField("count", emitted).Info("Emitted cart expiring soon events")
+	}
+
+	return emitted, nil
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeExpiringSoonPublisher struct {
+	hoursRemaining map[string]int
+	err            error
+}
+
+func (p *fakeExpiringSoonPublisher) PublishCartExpiringSoon(ctx context.Context, c *cart.Cart, hoursRemaining int) error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.hoursRemaining == nil {
+		p.hoursRemaining = make(map[string]int)
+	}
+	p.hoursRemaining[c.UserID] = hoursRemaining
+	return nil
+}
+
+func newTestNotifier(t *testing.T, now time.Time, publisher ExpiringSoonPublisher, carts ...*cart.Cart) *ExpiryNotifier {
+	t.Helper()
+	repo := inmemory.NewRepository()
+	for _, c := range carts {
+		require.NoError(t, repo.SaveCart(context.Background(), c))
+	}
+
+	notifier := NewExpiryNotifier(repo, inmemory.NewExpiryMarkerStore(), publisher,
+		ExpiryNotifierConfig{Window: 24 * time.Hour}, logging.New(logging.Config{Level: "error"}))
+	notifier.now = func() time.Time { return now }
+	return notifier
+}
+
+func newCartExpiringAt(t *testing.T, userID string, expiresAt time.Time) *cart.Cart {
+	t.Helper()
+	c := cart.NewCart(userID)
+	require.NoError(t, c.AddItem(cart.NewCartItem("prod-1", 1, 1000)))
+	c.ExpiresAt = expiresAt
+	return c
+}
+
+func TestExpiryNotifier_EmitsWithinWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	publisher := &fakeExpiringSoonPublisher{}
+
+	notifier := newTestNotifier(t, now, publisher,
+		newCartExpiringAt(t, "soon", now.Add(5*time.Hour+30*time.Minute)),
+		newCartExpiringAt(t, "later", now.Add(48*time.Hour)),
+		newCartExpiringAt(t, "expired", now.Add(-time.Hour)),
+	)
+
+	emitted, err := notifier.RunOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, emitted)
+	assert.Equal(t, map[string]int{"soon": 5}, publisher.hoursRemaining)
+}
+
+func TestExpiryNotifier_SuppressesDuplicates(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	publisher := &fakeExpiringSoonPublisher{}
+	notifier := newTestNotifier(t, now, publisher, newCartExpiringAt(t, "soon", now.Add(2*time.Hour)))
+
+	emitted, err := notifier.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, emitted)
+
+	emitted, err = notifier.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, emitted)
+}
+
+func TestExpiryNotifier_RetriesAfterPublishFailure(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	publisher := &fakeExpiringSoonPublisher{err: errors.New("unavailable")}
+	notifier := newTestNotifier(t, now, publisher, newCartExpiringAt(t, "soon", now.Add(2*time.Hour)))
+
+	emitted, err := notifier.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, emitted)
+
+	publisher.err = nil
+	emitted, err = notifier.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, emitted)
+}
@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+)
+
+// OutboxStore reads and acknowledges events written to the outbox.
+type OutboxStore interface {
+	// FindPendingOutbox returns up to limit undispatched records, oldest first.
+	FindPendingOutbox(ctx context.Context, limit int) ([]events.OutboxRecord, error)
+
+	// MarkOutboxDispatched records that the event was published.
+	MarkOutboxDispatched(ctx context.Context, record events.OutboxRecord) error
+}
+
+// OutboxDispatcherConfig holds configuration for the outbox dispatcher.
+type OutboxDispatcherConfig struct {
+	// Interval is how often the outbox is polled for pending records.
+	Interval time.Duration
+
+	// BatchSize is the maximum number of records fetched per poll.
+	BatchSize int
+}
+
+// DefaultOutboxDispatcherConfig returns sensible defaults.
+func DefaultOutboxDispatcherConfig() OutboxDispatcherConfig {
+	return OutboxDispatcherConfig{
+		Interval:  5 * time.Second,
+		BatchSize: 25,
+	}
+}
+
+// OutboxDispatcher publishes events written to the outbox and marks them dispatched.
+// Delivery is at-least-once: an event published just before a failed mark is sent
+// again on the next poll.
+type OutboxDispatcher struct {
+	store     OutboxStore
+	publisher events.Publisher
+	config    OutboxDispatcherConfig
+	logger    *logging.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+// NewOutboxDispatcher creates a new outbox dispatcher.
+func NewOutboxDispatcher(
+	store OutboxStore,
+	publisher events.Publisher,
+	config OutboxDispatcherConfig,
+	logger *logging.Logger,
+) *OutboxDispatcher {
+	defaults := DefaultOutboxDispatcherConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+
+	return &OutboxDispatcher{
+		store:     store,
+		publisher: publisher,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// Start begins polling the outbox in the background. Calling Start on a running
+// dispatcher is a no-op.
+func (d *OutboxDispatcher) Start(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cancel != nil {
+		return
+	}
+
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.done = make(chan struct{})
+	go d.run(ctx, d.done)
+}
+
+// Stop stops polling and waits for an in-flight poll to finish.
+func (d *OutboxDispatcher) Stop() {
+	d.mu.Lock()
+	cancel, done := d.cancel, d.done
+	d.cancel, d.done = nil, nil
+	d.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (d *OutboxDispatcher) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			d.logger.WithContext(ctx).WithError(err).Error("Outbox dispatch failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce dispatches pending records until the outbox is drained or a publish
+// fails, and returns the number of records dispatched. Dispatch stops at the first
+// failure so events are never sent out of order.
+func (d *OutboxDispatcher) RunOnce(ctx context.Context) (int, error) {
+	dispatched := 0
+	for {
+		records, err := d.store.FindPendingOutbox(ctx, d.config.BatchSize)
+		if err != nil {
+			return dispatched, err
+		}
+
+		for _, record := range records {
+			if err := d.publisher.Publish(ctx, record.Event); err != nil {
+				return dispatched, err
+			}
+			if err := d.store.MarkOutboxDispatched(ctx, record); err != nil {
+				return dispatched, err
+			}
+			dispatched++
+		}
+
+		if len(records) < d.config.BatchSize {
+			break
+		}
+	}
+
+	if dispatched > 0 {
+		d.logger.WithContext(ctx).WithField("count", dispatched).Info("Dispatched outbox events")
+	}
+
+	return dispatched, nil
+}
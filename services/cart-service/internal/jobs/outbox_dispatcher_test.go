@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events/eventbridge"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEventPublisher records published event types, failing while err is set.
+type fakeEventPublisher struct {
+	published []string
+	err       error
+}
+
+func (p *fakeEventPublisher) Publish(ctx context.Context, event events.Event) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, event.Type)
+	return nil
+}
+
+func (p *fakeEventPublisher) PublishBatch(ctx context.Context, evts []events.Event) error {
+	for _, event := range evts {
+		if err := p.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *fakeEventPublisher) Close() error { return nil }
+
+func newOutboxService(repo *inmemory.Repository) *cart.Service {
+	return cart.NewService(repo, nil, cart.ServiceConfig{
+		PublishEvents:    true,
+		EventPublishMode: cart.EventPublishModeOutbox,
+	}, cart.WithOutbox(repo, func(p events.Publisher) cart.EventPublisher {
+		return eventbridge.NewCartEventPublisherFor(p, "cart-service")
+	}))
+}
+
+func TestOutboxDispatcher_DispatchesInOrder(t *testing.T) {
+	ctx := context.Background()
+	repo := inmemory.NewRepository()
+	service := newOutboxService(repo)
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "prod-1", Quantity: 1, UnitPrice: 1000})
+	require.NoError(t, err)
+
+	pending, err := repo.FindPendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+
+	publisher := &fakeEventPublisher{err: errors.New("bus unavailable")}
+	dispatcher := NewOutboxDispatcher(repo, publisher, OutboxDispatcherConfig{BatchSize: 1}, logging.New(logging.Config{Level: "error"}))
+
+	// A failed publish leaves the records for the next poll
+	dispatched, err := dispatcher.RunOnce(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 0, dispatched)
+
+	publisher.err = nil
+	dispatched, err = dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, dispatched)
+	assert.Equal(t, []string{events.EventTypeCartCreated, events.EventTypeItemAdded}, publisher.published)
+
+	pending, err = repo.FindPendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestOutboxDispatcher_StartStop(t *testing.T) {
+	ctx := context.Background()
+	repo := inmemory.NewRepository()
+	service := newOutboxService(repo)
+	publisher := &fakeEventPublisher{}
+
+	dispatcher := NewOutboxDispatcher(repo, publisher, OutboxDispatcherConfig{Interval: 10 * time.Millisecond}, logging.New(logging.Config{Level: "error"}))
+	dispatcher.Start(ctx)
+	defer dispatcher.Stop()
+
+	require.NoError(t, service.ClearCart(ctx, "user-123"))
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "prod-1", Quantity: 1, UnitPrice: 1000})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		pending, err := repo.FindPendingOutbox(ctx, 10)
+		return err == nil && len(pending) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	dispatcher.Stop()
+	dispatcher.Stop() // Stopping twice is safe
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Logger wraps zerolog.Logger with additional functionality.
@@ -24,6 +25,7 @@ const (
 	requestIDKey   contextKey = "request_id"
 	userIDKey      contextKey = "user_id"
 	correlationKey contextKey = "correlation_id"
+	logLevelKey    contextKey = "log_level"
 )
 
 // Config holds logger configuration.
@@ -65,10 +67,15 @@ func New(cfg Config) *Logger {
 }
 
 // WithContext returns a new logger with context values.
+// A level stored with ContextWithLogLevel overrides the logger's level.
 func (l *Logger) WithContext(ctx context.Context) *Logger {
 	zl := l.zl.With().Logger()
 
-	if traceID, ok := ctx.Value(traceIDKey).(string); ok && traceID != "" {
+	if level, ok := ctx.Value(logLevelKey).(zerolog.Level); ok {
+		zl = zl.Level(level)
+	}
+
+	if traceID := TraceIDFromContext(ctx); traceID != "" {
 		zl = zl.With().Str("trace_id", traceID).Logger()
 	}
 
@@ -199,8 +206,22 @@ func ContextWithCorrelationID(ctx context.Context, correlationID string) context
 	return context.WithValue(ctx, correlationKey, correlationID)
 }
 
-// TraceIDFromContext extracts the trace ID from context.
+// ContextWithLogLevel returns a new context that overrides the log level for
+// loggers derived with WithContext. Invalid levels are ignored.
+func ContextWithLogLevel(ctx context.Context, level string) context.Context {
+	parsed, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, logLevelKey, parsed)
+}
+
+// TraceIDFromContext returns the active span's trace ID, falling back to a
+// trace ID stored with ContextWithTraceID.
 func TraceIDFromContext(ctx context.Context) string {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
 	if traceID, ok := ctx.Value(traceIDKey).(string); ok {
 		return traceID
 	}
@@ -215,6 +236,14 @@ func RequestIDFromContext(ctx context.Context) string {
 	return ""
 }
 
+// CorrelationIDFromContext extracts the correlation ID from context.
+func CorrelationIDFromContext(ctx context.Context) string {
+	if correlationID, ok := ctx.Value(correlationKey).(string); ok {
+		return correlationID
+	}
+	return ""
+}
+
 // UserIDFromContext extracts the user ID from context.
 func UserIDFromContext(ctx context.Context) string {
 	if userID, ok := ctx.Value(userIDKey).(string); ok {
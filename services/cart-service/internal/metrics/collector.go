@@ -30,6 +30,9 @@ const (
 	MetricPersistenceDuration        = "persistence_operation_duration_seconds"
 	MetricEventPublishTotal          = "event_publish_total"
 	MetricCircuitBreakerState        = "circuit_breaker_state"
+	MetricAuditRecordTotal           = "audit_record_total"
+	MetricConflictRetryTotal         = "cart_conflict_retry_total"
+	MetricSnapshotRecordTotal        = "cart_snapshot_record_total"
 )
 
 // InMemoryCollector is an in-memory implementation of Collector for testing.
@@ -0,0 +1,83 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// AuditKeyPrefix prefixes audit records, stored in the user's partition and
+// sorted by time.
+const AuditKeyPrefix = "AUDIT#"
+
+// auditRecord represents an audit entry stored in DynamoDB.
+type auditRecord struct {
+	PK            string `dynamodbav:"PK"`
+	SK            string `dynamodbav:"SK"`
+	Type          string `dynamodbav:"type"`
+	EntryID       string `dynamodbav:"entry_id"`
+	Timestamp     string `dynamodbav:"timestamp"`
+	TenantID      string `dynamodbav:"tenant_id"`
+	UserID        string `dynamodbav:"user_id"`
+	Actor         string `dynamodbav:"actor,omitempty"`
+	Operation     string `dynamodbav:"operation"`
+	CartID        string `dynamodbav:"cart_id,omitempty"`
+	VersionBefore int64  `dynamodbav:"version_before"`
+	VersionAfter  int64  `dynamodbav:"version_after"`
+	ItemID        string `dynamodbav:"item_id,omitempty"`
+	ProductID     string `dynamodbav:"product_id,omitempty"`
+	CorrelationID string `dynamodbav:"correlation_id,omitempty"`
+}
+
+// AuditLog is a DynamoDB implementation of audit.Auditor. Records are
+// append-only: they have no TTL and are never overwritten.
+type AuditLog struct {
+	client *Client
+}
+
+// NewAuditLog creates a new DynamoDB audit log.
+func NewAuditLog(client *Client) *AuditLog {
+	return &AuditLog{client: client}
+}
+
+// Record writes an audit entry.
+func (l *AuditLog) Record(ctx context.Context, entry audit.AuditEntry) error {
+	timestamp := entry.Timestamp.UTC().Format(time.RFC3339Nano)
+	item, err := attributevalue.MarshalMap(auditRecord{
+		PK:            userPK(entry.TenantID, entry.UserID),
+		SK:            AuditKeyPrefix + timestamp + "#" + entry.ID,
+		Type:          "AUDIT",
+		EntryID:       entry.ID,
+		Timestamp:     timestamp,
+		TenantID:      cart.NormalizeTenantID(entry.TenantID),
+		UserID:        entry.UserID,
+		Actor:         entry.Actor,
+		Operation:     entry.Operation,
+		CartID:        entry.CartID,
+		VersionBefore: entry.VersionBefore,
+		VersionAfter:  entry.VersionAfter,
+		ItemID:        entry.ItemID,
+		ProductID:     entry.ProductID,
+		CorrelationID: entry.CorrelationID,
+	})
+	if err != nil {
+		return errors.Wrap(errors.CodePersistenceError, "failed to marshal audit entry", err)
+	}
+
+	_, err = l.client.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(l.client.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(PK)"),
+	})
+	if err != nil {
+		return errors.Wrap(errors.CodePersistenceError, "failed to save audit entry", err)
+	}
+
+	return nil
+}
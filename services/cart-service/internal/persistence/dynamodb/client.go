@@ -17,9 +17,22 @@ type ClientConfig struct {
 	TableName string
 }
 
+// API is the subset of the DynamoDB client used by this package.
+type API interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+}
+
 // Client wraps the DynamoDB client with configuration.
 type Client struct {
-	db        *dynamodb.Client
+	db        API
 	tableName string
 }
 
@@ -47,8 +60,17 @@ func NewClient(ctx context.Context, cfg ClientConfig) (*Client, error) {
 	}, nil
 }
 
+// NewClientWithAPI creates a client around an existing DynamoDB API, e.g. a
+// test double.
+func NewClientWithAPI(db API, tableName string) *Client {
+	return &Client{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
 // DB returns the underlying DynamoDB client.
-func (c *Client) DB() *dynamodb.Client {
+func (c *Client) DB() API {
 	return c.db
 }
 
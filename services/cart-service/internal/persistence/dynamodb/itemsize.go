@@ -0,0 +1,93 @@
+package dynamodb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// MaxItemSize is DynamoDB's limit on the size of a single item, attribute
+// names included.
+const MaxItemSize = 400 * 1024
+
+// marshalCart converts a cart to a DynamoDB item, rejecting carts whose
+// estimated size exceeds MaxItemSize before they are sent.
+func (r *Repository) marshalCart(ctx context.Context, c *cart.Cart) (map[string]types.AttributeValue, error) {
+	record, err := r.cartToRecord(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to marshal cart", err)
+	}
+	if size := itemSize(item); size > MaxItemSize {
+		return nil, errors.ErrCartTooLarge(len(c.Items)).
+			WithDetail("size_bytes", size).
+			WithDetail("max_bytes", MaxItemSize)
+	}
+	return item, nil
+}
+
+// itemSize estimates the stored size of an item the way DynamoDB counts it:
+// attribute names and values, with a few bytes of overhead per nested value.
+// Numbers are counted by their string length, which over-estimates slightly.
+func itemSize(item map[string]types.AttributeValue) int {
+	size := 0
+	for name, value := range item {
+		size += len(name) + attributeSize(value)
+	}
+	return size
+}
+
+func attributeSize(value types.AttributeValue) int {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value)
+	case *types.AttributeValueMemberN:
+		return len(v.Value)
+	case *types.AttributeValueMemberB:
+		return len(v.Value)
+	case *types.AttributeValueMemberBOOL, *types.AttributeValueMemberNULL:
+		return 1
+	case *types.AttributeValueMemberSS:
+		size := 0
+		for _, s := range v.Value {
+			size += len(s)
+		}
+		return size
+	case *types.AttributeValueMemberNS:
+		size := 0
+		for _, n := range v.Value {
+			size += len(n)
+		}
+		return size
+	case *types.AttributeValueMemberBS:
+		size := 0
+		for _, b := range v.Value {
+			size += len(b)
+		}
+		return size
+	case *types.AttributeValueMemberL:
+		size := 3
+		for _, elem := range v.Value {
+			size += 1 + attributeSize(elem)
+		}
+		return size
+	case *types.AttributeValueMemberM:
+		return 3 + len(v.Value) + itemSize(v.Value)
+	default:
+		return 0
+	}
+}
+
+// isItemSizeError reports whether DynamoDB rejected a write because the item
+// is larger than MaxItemSize. PutItem reports it as a ValidationException and
+// transactions as a cancellation reason; both carry the same message.
+func isItemSizeError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "has exceeded the maximum allowed size")
+}
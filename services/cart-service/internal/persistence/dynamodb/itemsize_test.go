@@ -0,0 +1,53 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_MarshalCartRejectsOversizedCart(t *testing.T) {
+	repo := &Repository{}
+
+	c := cart.NewCart("user-123")
+	item, err := repo.marshalCart(context.Background(), c)
+	require.NoError(t, err)
+	assert.Less(t, itemSize(item), 1024)
+
+	for i := 0; i < 100; i++ {
+		item := cart.NewCartItem(fmt.Sprintf("product-%d", i), 1, 1000)
+		item.Attributes = map[string]string{"engraving": strings.Repeat("x", 5000)}
+		require.NoError(t, c.AddItem(item))
+	}
+
+	_, err = repo.marshalCart(context.Background(), c)
+	require.Error(t, err)
+	assert.True(t, errors.IsCode(err, errors.CodeCartTooLarge))
+	appErr, _ := errors.IsAppError(err)
+	assert.Equal(t, 413, appErr.HTTPStatus)
+	assert.Equal(t, 100, appErr.Details["item_count"])
+	assert.Greater(t, appErr.Details["size_bytes"], MaxItemSize)
+}
+
+func TestIsItemSizeError(t *testing.T) {
+	assert.True(t, isItemSizeError(&smithy.GenericAPIError{
+		Code:    "ValidationException",
+		Message: "Item size has exceeded the maximum allowed size",
+	}))
+	assert.True(t, isItemSizeError(fmt.Errorf("transaction cancelled: %w", &smithy.GenericAPIError{
+		Code:    "TransactionCanceledException",
+		Message: "Transaction cancelled, please refer cancellation reasons for specific reasons [ValidationError: Item size to update has exceeded the maximum allowed size]",
+	})))
+	assert.False(t, isItemSizeError(&smithy.GenericAPIError{
+		Code:    "ValidationException",
+		Message: "One or more parameter values were invalid",
+	}))
+	assert.False(t, isItemSizeError(nil))
+}
@@ -0,0 +1,270 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+)
+
+// Outbox keys. Pending records carry GSI1 attributes under a single partition so
+// the dispatcher can query them in creation order; dispatching removes them.
+const (
+	OutboxKeyPrefix  = "OUTBOX#"
+	OutboxPendingKey = "OUTBOX#PENDING"
+
+	// TransactWriteItems allows max 100 items per call, one of which is the cart
+	maxOutboxEvents = 99
+
+	// Dispatched records are kept briefly for troubleshooting
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// outboxRecord represents an outbox event stored in DynamoDB.
+type outboxRecord struct {
+	PK           string `dynamodbav:"PK"`
+	SK           string `dynamodbav:"SK"`
+	GSI1PK       string `dynamodbav:"GSI1PK,omitempty"`
+	GSI1SK       string `dynamodbav:"GSI1SK,omitempty"`
+	Type         string `dynamodbav:"type"`
+	TenantID     string `dynamodbav:"tenant_id"`
+	UserID       string `dynamodbav:"user_id"`
+	EventID      string `dynamodbav:"event_id"`
+	EventType    string `dynamodbav:"event_type"`
+	Payload      string `dynamodbav:"payload"`
+	CreatedAt    string `dynamodbav:"created_at"`
+	DispatchedAt string `dynamodbav:"dispatched_at,omitempty"`
+	TTL          int64  `dynamodbav:"ttl,omitempty"`
+}
+
+// SaveCartWithOutbox saves a cart and its outbox records in a single transaction.
+// A non-zero expectedVersion applies the same optimistic locking as SaveCartWithVersion.
+func (r *Repository) SaveCartWithOutbox(ctx context.Context, c *cart.Cart, expectedVersion int64, evts []events.Event) error {
+	if len(evts) > maxOutboxEvents {
+		return errors.New(errors.CodePersistenceError, "Too many events for one transaction").
+			WithDetail("events", len(evts))
+	}
+
+	item, err := r.marshalCart(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	put := &types.Put{
+		TableName: aws.String(r.client.tableName),
+		Item:      item,
+	}
+	if expectedVersion > 0 {
+		put.ConditionExpression = aws.String("attribute_not_exists(PK) OR version = :expected_version")
+		put.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":expected_version": &types.AttributeValueMemberN{Value: strconv.FormatInt(expectedVersion, 10)},
+		}
+	}
+
+	outboxItems, err := r.outboxPuts(c.TenantID, c.UserID, evts)
+	if err != nil {
+		return err
+	}
+	items := append([]types.TransactWriteItem{{Put: put}}, outboxItems...)
+
+	_, err = execute(ctx, r, r.writeTimeout, r.client.db.TransactWriteItems, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if expectedVersion > 0 && isConditionalCheckFailedException(err, &condErr) {
+			currentCart, getErr := r.getCart(ctx, c.TenantID, c.UserID, cartSortKey(c))
+			if getErr != nil {
+				return errors.ErrConflict(expectedVersion, 0)
+			}
+			return errors.ErrConflict(expectedVersion, currentCart.Version)
+		}
+		if isItemSizeError(err) {
+			return errors.ErrCartTooLarge(len(c.Items))
+		}
+		return errors.Wrap(errors.CodePersistenceError, "failed to save cart with outbox", err)
+	}
+
+	r.indexProducts(ctx, c)
+	return nil
+}
+
+// SaveMergedCart saves a merged cart, deletes the guest cart and writes the
+// outbox records in a single transaction. The guest cart is deleted only if
+// it is still at guestVersion, so a retried merge can't apply it twice.
+func (r *Repository) SaveMergedCart(ctx context.Context, merged *cart.Cart, expectedVersion int64, guestID string, guestVersion int64, evts []events.Event) error {
+	// One slot each for the merged cart and the guest cart
+	if len(evts) > maxOutboxEvents-1 {
+		return errors.New(errors.CodePersistenceError, "Too many events for one transaction").
+			WithDetail("events", len(evts))
+	}
+
+	item, err := r.marshalCart(ctx, merged)
+	if err != nil {
+		return err
+	}
+
+	put := &types.Put{
+		TableName: aws.String(r.client.tableName),
+		Item:      item,
+	}
+	if expectedVersion > 0 {
+		put.ConditionExpression = aws.String("attribute_not_exists(PK) OR version = :expected_version")
+		put.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":expected_version": &types.AttributeValueMemberN{Value: strconv.FormatInt(expectedVersion, 10)},
+		}
+	}
+
+	del := &types.Delete{
+		TableName: aws.String(r.client.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: userPK(merged.TenantID, guestID)},
+			"SK": &types.AttributeValueMemberS{Value: CartKeyPrefix + guestID},
+		},
+		ConditionExpression: aws.String("version = :guest_version"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":guest_version": &types.AttributeValueMemberN{Value: strconv.FormatInt(guestVersion, 10)},
+		},
+	}
+
+	outboxItems, err := r.outboxPuts(merged.TenantID, merged.UserID, evts)
+	if err != nil {
+		return err
+	}
+	items := append([]types.TransactWriteItem{{Put: put}, {Delete: del}}, outboxItems...)
+
+	_, err = execute(ctx, r, r.writeTimeout, r.client.db.TransactWriteItems, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if isConditionalCheckFailedException(err, &condErr) {
+			// Either the merged cart or the guest cart changed
+			var currentVersion int64
+			if currentCart, getErr := r.getCart(ctx, merged.TenantID, merged.UserID, cartSortKey(merged)); getErr == nil {
+				currentVersion = currentCart.Version
+			}
+			return errors.ErrConflict(expectedVersion, currentVersion).WithDetail("guest_id", guestID)
+		}
+		if isItemSizeError(err) {
+			return errors.ErrCartTooLarge(len(merged.Items))
+		}
+		return errors.Wrap(errors.CodePersistenceError, "failed to save merged cart", err)
+	}
+
+	r.indexProducts(ctx, merged)
+	return nil
+}
+
+// outboxPuts builds the transaction items writing evts to userID's outbox.
+func (r *Repository) outboxPuts(tenantID, userID string, evts []events.Event) ([]types.TransactWriteItem, error) {
+	items := make([]types.TransactWriteItem, 0, len(evts))
+
+	now := time.Now().UTC()
+	for _, event := range evts {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to marshal event", err)
+		}
+
+		record, err := attributevalue.MarshalMap(outboxRecord{
+			PK:        userPK(tenantID, userID),
+			SK:        OutboxKeyPrefix + event.ID,
+			GSI1PK:    OutboxPendingKey,
+			GSI1SK:    now.Format(time.RFC3339Nano) + "#" + event.ID,
+			Type:      "OUTBOX",
+			TenantID:  cart.NormalizeTenantID(tenantID),
+			UserID:    userID,
+			EventID:   event.ID,
+			EventType: event.Type,
+			Payload:   string(payload),
+			CreatedAt: now.Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to marshal outbox record", err)
+		}
+
+		items = append(items, types.TransactWriteItem{Put: &types.Put{
+			TableName: aws.String(r.client.tableName),
+			Item:      record,
+		}})
+	}
+	return items, nil
+}
+
+// FindPendingOutbox returns up to limit undispatched outbox records, oldest first.
+func (r *Repository) FindPendingOutbox(ctx context.Context, limit int) ([]events.OutboxRecord, error) {
+	result, err := execute(ctx, r, r.readTimeout, r.client.db.Query, &dynamodb.QueryInput{
+		TableName:              aws.String(r.client.tableName),
+		IndexName:              aws.String(GSI1IndexName),
+		KeyConditionExpression: aws.String("GSI1PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: OutboxPendingKey},
+		},
+		Limit: aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, errors.Wrap(errors.CodePersistenceError, "failed to query outbox", err)
+	}
+
+	records := make([]events.OutboxRecord, 0, len(result.Items))
+	for _, item := range result.Items {
+		var record outboxRecord
+		if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to unmarshal outbox record", err)
+		}
+
+		var event events.Event
+		if err := json.Unmarshal([]byte(record.Payload), &event); err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to unmarshal outbox event", err)
+		}
+
+		createdAt, err := time.Parse(time.RFC3339Nano, record.CreatedAt)
+		if err != nil {
+			createdAt = time.Now().UTC()
+		}
+
+		records = append(records, events.OutboxRecord{
+			TenantID:  record.TenantID,
+			UserID:    record.UserID,
+			Event:     event,
+			CreatedAt: createdAt,
+		})
+	}
+
+	return records, nil
+}
+
+// MarkOutboxDispatched removes a record from the pending index and schedules it for expiry.
+func (r *Repository) MarkOutboxDispatched(ctx context.Context, record events.OutboxRecord) error {
+	now := time.Now().UTC()
+
+	_, err := execute(ctx, r, r.writeTimeout, r.client.db.UpdateItem, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.client.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: userPK(record.TenantID, record.UserID)},
+			"SK": &types.AttributeValueMemberS{Value: OutboxKeyPrefix + record.Event.ID},
+		},
+		UpdateExpression: aws.String("REMOVE GSI1PK, GSI1SK SET dispatched_at = :now, #ttl = :ttl"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+			":ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(outboxRetention).Unix(), 10)},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(errors.CodePersistenceError, "failed to mark outbox record dispatched", err)
+	}
+
+	return nil
+}
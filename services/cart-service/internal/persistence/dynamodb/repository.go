@@ -2,6 +2,8 @@ package dynamodb
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
@@ -11,25 +13,84 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/encryption"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
 )
 
-// Key prefixes for single-table design
+// Key prefixes for single-table design. Items owned by a user are
+// partitioned by USER#{userID} in the default tenant and by
+// TENANT#{tenantID}#USER#{userID} in any other.
 const (
-	UserKeyPrefix = "USER#"
-	CartKeyPrefix = "CART#"
+	TenantKeyPrefix       = "TENANT#"
+	UserKeyPrefix         = "USER#"
+	CartKeyPrefix         = "CART#"
+	ExpiryNoticeKeyPrefix = "EXPIRY_NOTICE#"
+	ProductKeyPrefix      = "PRODUCT#"
+
+	// GSI1 is the overloaded secondary index; cart product entries are keyed by product.
+	GSI1IndexName = "GSI1"
 )
 
 // Repository is a DynamoDB implementation of the cart repository.
 type Repository struct {
 	client *Client
+
+	// Encrypts free-text fields at rest when set
+	encryptor encryption.Encryptor
+
+	// How failed operations are retried; see IsRetryableError
+	retry resilience.RetryConfig
+
+	// Per-attempt timeouts for reads and writes
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+
+	// Reports data repairs on load when set
+	logger *logging.Logger
+}
+
+// RepositoryOption configures optional Repository behavior.
+type RepositoryOption func(*Repository)
+
+// WithEncryptor stores gift messages and item attributes encrypted with the
+// given encryptor. Keys, quantities and prices stay plaintext for querying.
+func WithEncryptor(encryptor encryption.Encryptor) RepositoryOption {
+	return func(r *Repository) {
+		r.encryptor = encryptor
+	}
+}
+
+// WithTimeouts bounds each attempt of a read (GetItem, Query, Scan) or write
+// operation.
+func WithTimeouts(read, write time.Duration) RepositoryOption {
+	return func(r *Repository) {
+		r.readTimeout = read
+		r.writeTimeout = write
+	}
+}
+
+// WithLogger logs repairs made to stored carts when they are loaded.
+func WithLogger(logger *logging.Logger) RepositoryOption {
+	return func(r *Repository) {
+		r.logger = logger
+	}
 }
 
 // NewRepository creates a new DynamoDB repository.
-func NewRepository(client *Client) *Repository {
-	return &Repository{
+func NewRepository(client *Client, opts ...RepositoryOption) *Repository {
+	r := &Repository{
 		client: client,
+		retry:  DefaultRetryConfig(),
+	}
+	timeouts := resilience.DefaultTimeoutConfig()
+	r.readTimeout = timeouts.Read
+	r.writeTimeout = timeouts.Write
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 // cartRecord represents a cart stored in DynamoDB.
@@ -38,6 +99,7 @@ type cartRecord struct {
 	SK        string          `dynamodbav:"SK"`
 	Type      string          `dynamodbav:"type"`
 	ID        string          `dynamodbav:"id"`
+	TenantID  string          `dynamodbav:"tenant_id"`
 	UserID    string          `dynamodbav:"user_id"`
 	Items     []cartItemRecord `dynamodbav:"items"`
 	Version   int64           `dynamodbav:"version"`
@@ -45,6 +107,28 @@ type cartRecord struct {
 	UpdatedAt string          `dynamodbav:"updated_at"`
 	ExpiresAt string          `dynamodbav:"expires_at"`
 	TTL       int64           `dynamodbav:"ttl"`
+
+	LastClearedItems []cartItemRecord `dynamodbav:"last_cleared_items,omitempty"`
+	ClearedAt        string           `dynamodbav:"cleared_at,omitempty"`
+
+	GiftMessage string `dynamodbav:"gift_message,omitempty"`
+
+	Name      string `dynamodbav:"name,omitempty"`
+	Secondary bool   `dynamodbav:"secondary,omitempty"`
+
+	Locked      bool   `dynamodbav:"locked,omitempty"`
+	LockedUntil string `dynamodbav:"locked_until,omitempty"`
+
+	// Encrypted holds the encryptedFields ciphertext when an encryptor is configured
+	Encrypted []byte `dynamodbav:"encrypted,omitempty"`
+}
+
+// encryptedFields are the free-text cart fields that may contain PII. They are
+// encrypted together so each save or read makes a single encryptor call.
+type encryptedFields struct {
+	GiftMessage       string                       `json:"gift_message,omitempty"`
+	Attributes        map[string]map[string]string `json:"attributes,omitempty"`         // By item ID
+	ClearedAttributes map[string]map[string]string `json:"cleared_attributes,omitempty"` // By item ID
 }
 
 // cartItemRecord represents a cart item stored in DynamoDB.
@@ -54,17 +138,42 @@ type cartItemRecord struct {
 	Quantity  int    `dynamodbav:"quantity"`
 	UnitPrice int64  `dynamodbav:"unit_price"`
 	AddedAt   string `dynamodbav:"added_at"`
+
+	Name       string            `dynamodbav:"name,omitempty"`
+	ImageURL   string            `dynamodbav:"image_url,omitempty"`
+	SKU        string            `dynamodbav:"sku,omitempty"`
+	Attributes map[string]string `dynamodbav:"attributes,omitempty"`
+
+	GiftWrap    bool  `dynamodbav:"gift_wrap,omitempty"`
+	GiftWrapFee int64 `dynamodbav:"gift_wrap_fee,omitempty"`
+
+	UnitType        string `dynamodbav:"unit_type,omitempty"`
+	DecimalQuantity int64  `dynamodbav:"decimal_quantity,omitempty"`
+
+	PackSize int  `dynamodbav:"pack_size,omitempty"`
+	Stale    bool `dynamodbav:"stale,omitempty"`
 }
 
-// GetCart retrieves a cart by user ID.
+// GetCart retrieves a user's default cart.
 func (r *Repository) GetCart(ctx context.Context, userID string) (*cart.Cart, error) {
-	pk := UserKeyPrefix + userID
-	sk := CartKeyPrefix + userID
+	return r.getCart(ctx, cart.TenantIDFromContext(ctx), userID, CartKeyPrefix+userID)
+}
 
-	result, err := r.client.db.GetItem(ctx, &dynamodb.GetItemInput{
+// GetCartByID retrieves one of a user's additional carts.
+func (r *Repository) GetCartByID(ctx context.Context, userID, cartID string) (*cart.Cart, error) {
+	c, err := r.getCart(ctx, cart.TenantIDFromContext(ctx), userID, additionalCartSK(userID, cartID))
+	if errors.IsCode(err, errors.CodeCartNotFound) {
+		return nil, errors.ErrCartNotFound(userID).WithDetail("cart_id", cartID)
+	}
+	return c, err
+}
+
+// getCart retrieves the cart stored under userID's partition at sk.
+func (r *Repository) getCart(ctx context.Context, tenantID, userID, sk string) (*cart.Cart, error) {
+	result, err := execute(ctx, r, r.readTimeout, r.client.db.GetItem, &dynamodb.GetItemInput{
 		TableName: aws.String(r.client.tableName),
 		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: pk},
+			"PK": &types.AttributeValueMemberS{Value: userPK(tenantID, userID)},
 			"SK": &types.AttributeValueMemberS{Value: sk},
 		},
 	})
@@ -81,40 +190,40 @@ func (r *Repository) GetCart(ctx context.Context, userID string) (*cart.Cart, er
 		return nil, errors.Wrap(errors.CodePersistenceError, "failed to unmarshal cart", err)
 	}
 
-	return recordToCart(&record)
+	return r.recordToCart(ctx, &record)
 }
 
 // SaveCart saves a cart.
 func (r *Repository) SaveCart(ctx context.Context, c *cart.Cart) error {
-	record := cartToRecord(c)
-
-	item, err := attributevalue.MarshalMap(record)
+	item, err := r.marshalCart(ctx, c)
 	if err != nil {
-		return errors.Wrap(errors.CodePersistenceError, "failed to marshal cart", err)
+		return err
 	}
 
-	_, err = r.client.db.PutItem(ctx, &dynamodb.PutItemInput{
+	_, err = execute(ctx, r, r.writeTimeout, r.client.db.PutItem, &dynamodb.PutItemInput{
 		TableName: aws.String(r.client.tableName),
 		Item:      item,
 	})
 	if err != nil {
+		if isItemSizeError(err) {
+			return errors.ErrCartTooLarge(len(c.Items))
+		}
 		return errors.Wrap(errors.CodePersistenceError, "failed to save cart", err)
 	}
 
+	r.indexProducts(ctx, c)
 	return nil
 }
 
 // SaveCartWithVersion saves a cart with optimistic locking.
 func (r *Repository) SaveCartWithVersion(ctx context.Context, c *cart.Cart, expectedVersion int64) error {
-	record := cartToRecord(c)
-
-	item, err := attributevalue.MarshalMap(record)
+	item, err := r.marshalCart(ctx, c)
 	if err != nil {
-		return errors.Wrap(errors.CodePersistenceError, "failed to marshal cart", err)
+		return err
 	}
 
 	// Use conditional expression for optimistic locking
-	_, err = r.client.db.PutItem(ctx, &dynamodb.PutItemInput{
+	_, err = execute(ctx, r, r.writeTimeout, r.client.db.PutItem, &dynamodb.PutItemInput{
 		TableName:           aws.String(r.client.tableName),
 		Item:                item,
 		ConditionExpression: aws.String("attribute_not_exists(PK) OR version = :expected_version"),
@@ -127,27 +236,42 @@ func (r *Repository) SaveCartWithVersion(ctx context.Context, c *cart.Cart, expe
 		var condErr *types.ConditionalCheckFailedException
 		if ok := isConditionalCheckFailedException(err, &condErr); ok {
 			// Get current version for error reporting
-			currentCart, getErr := r.GetCart(ctx, c.UserID)
+			currentCart, getErr := r.getCart(ctx, c.TenantID, c.UserID, cartSortKey(c))
 			if getErr != nil {
 				return errors.ErrConflict(expectedVersion, 0)
 			}
 			return errors.ErrConflict(expectedVersion, currentCart.Version)
 		}
+		if isItemSizeError(err) {
+			return errors.ErrCartTooLarge(len(c.Items))
+		}
 		return errors.Wrap(errors.CodePersistenceError, "failed to save cart", err)
 	}
 
+	r.indexProducts(ctx, c)
 	return nil
 }
 
-// DeleteCart deletes a cart by user ID.
+// DeleteCart deletes a user's default cart.
 func (r *Repository) DeleteCart(ctx context.Context, userID string) error {
-	pk := UserKeyPrefix + userID
-	sk := CartKeyPrefix + userID
+	return r.deleteCart(ctx, cart.TenantIDFromContext(ctx), userID, CartKeyPrefix+userID)
+}
+
+// DeleteCartByID deletes one of a user's additional carts.
+func (r *Repository) DeleteCartByID(ctx context.Context, userID, cartID string) error {
+	err := r.deleteCart(ctx, cart.TenantIDFromContext(ctx), userID, additionalCartSK(userID, cartID))
+	if errors.IsCode(err, errors.CodeCartNotFound) {
+		return errors.ErrCartNotFound(userID).WithDetail("cart_id", cartID)
+	}
+	return err
+}
 
-	_, err := r.client.db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+// deleteCart deletes the cart stored under userID's partition at sk.
+func (r *Repository) deleteCart(ctx context.Context, tenantID, userID, sk string) error {
+	_, err := execute(ctx, r, r.writeTimeout, r.client.db.DeleteItem, &dynamodb.DeleteItemInput{
 		TableName: aws.String(r.client.tableName),
 		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: pk},
+			"PK": &types.AttributeValueMemberS{Value: userPK(tenantID, userID)},
 			"SK": &types.AttributeValueMemberS{Value: sk},
 		},
 		ConditionExpression: aws.String("attribute_exists(PK)"),
@@ -163,6 +287,276 @@ func (r *Repository) DeleteCart(ctx context.Context, userID string) error {
 	return nil
 }
 
+// ListCarts returns all of a user's carts, which share the user's partition
+// under CART# sort keys: the default cart first, then additional carts in
+// cart ID order.
+func (r *Repository) ListCarts(ctx context.Context, userID string) ([]*cart.Cart, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.client.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: userPK(cart.TenantIDFromContext(ctx), userID)},
+			":sk": &types.AttributeValueMemberS{Value: CartKeyPrefix + userID},
+		},
+	}
+
+	var carts []*cart.Cart
+	paginator := dynamodb.NewQueryPaginator(r.client.db, input)
+	for paginator.HasMorePages() {
+		page, err := nextPage(ctx, r, r.readTimeout, paginator.NextPage)
+		if err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to query user carts", err)
+		}
+
+		for _, item := range page.Items {
+			var record cartRecord
+			if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+				return nil, errors.Wrap(errors.CodePersistenceError, "failed to unmarshal cart", err)
+			}
+			c, err := r.recordToCart(ctx, &record)
+			if err != nil {
+				return nil, err
+			}
+			carts = append(carts, c)
+		}
+	}
+
+	return carts, nil
+}
+
+// FindCartsByProduct returns up to limit carts of the tenant holding the
+// product, using the product entries on GSI1. Entries left behind by removed
+// items are skipped.
+func (r *Repository) FindCartsByProduct(ctx context.Context, productID string, limit int) ([]*cart.Cart, error) {
+	tenantID := cart.TenantIDFromContext(ctx)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.client.tableName),
+		IndexName:              aws.String(GSI1IndexName),
+		KeyConditionExpression: aws.String("GSI1PK = :pk AND begins_with(GSI1SK, :tenant)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":     &types.AttributeValueMemberS{Value: ProductKeyPrefix + productID},
+			":tenant": &types.AttributeValueMemberS{Value: userPK(tenantID, "")},
+		},
+	}
+
+	var carts []*cart.Cart
+	paginator := dynamodb.NewQueryPaginator(r.client.db, input)
+	for paginator.HasMorePages() && len(carts) < limit {
+		page, err := nextPage(ctx, r, r.readTimeout, paginator.NextPage)
+		if err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to query carts by product", err)
+		}
+
+		for _, item := range page.Items {
+			if len(carts) >= limit {
+				break
+			}
+
+			var entry productEntryRecord
+			if err := attributevalue.UnmarshalMap(item, &entry); err != nil {
+				return nil, errors.Wrap(errors.CodePersistenceError, "failed to unmarshal product entry", err)
+			}
+
+			c, err := r.getCart(ctx, tenantID, entry.UserID, CartKeyPrefix+entry.UserID)
+			if err != nil {
+				if errors.IsCode(err, errors.CodeCartNotFound) {
+					continue
+				}
+				return nil, err
+			}
+			if existing, _ := c.FindItemByProductID(productID); existing == nil {
+				continue
+			}
+			carts = append(carts, c)
+		}
+	}
+
+	return carts, nil
+}
+
+// FindExpiringCarts returns carts of all tenants expiring after from and no
+// later than to.
+// It scans the table filtering on the ttl attribute, so it is intended for
+// periodic background jobs rather than request paths.
+func (r *Repository) FindExpiringCarts(ctx context.Context, from, to time.Time) ([]*cart.Cart, error) {
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(r.client.tableName),
+		FilterExpression: aws.String("#type = :cart AND #ttl > :from AND #ttl <= :to"),
+		ExpressionAttributeNames: map[string]string{
+			"#type": "type",
+			"#ttl":  "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cart": &types.AttributeValueMemberS{Value: "CART"},
+			":from": &types.AttributeValueMemberN{Value: strconv.FormatInt(from.Unix(), 10)},
+			":to":   &types.AttributeValueMemberN{Value: strconv.FormatInt(to.Unix(), 10)},
+		},
+	}
+
+	var carts []*cart.Cart
+	paginator := dynamodb.NewScanPaginator(r.client.db, input)
+	for paginator.HasMorePages() {
+		page, err := nextPage(ctx, r, r.readTimeout, paginator.NextPage)
+		if err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to scan expiring carts", err)
+		}
+
+		for _, item := range page.Items {
+			var record cartRecord
+			if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+				return nil, errors.Wrap(errors.CodePersistenceError, "failed to unmarshal cart", err)
+			}
+			c, err := r.recordToCart(ctx, &record)
+			if err != nil {
+				return nil, err
+			}
+			carts = append(carts, c)
+		}
+	}
+
+	return carts, nil
+}
+
+// ScanCarts returns up to limit carts of the tenant following cursor, scanning
+// the table filtered on the CART type and the tenant's key prefix. Each request's Limit is the number of carts still
+// wanted, so a page stops exactly after its last cart and the next cursor is
+// the LastEvaluatedKey of the final request. A next cursor can lead to an
+// empty last page.
+func (r *Repository) ScanCarts(ctx context.Context, cursor string, limit int) ([]*cart.Cart, string, error) {
+	startKey, err := decodeScanCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(r.client.tableName),
+		FilterExpression: aws.String("#type = :cart AND begins_with(PK, :tenant)"),
+		ExpressionAttributeNames: map[string]string{
+			"#type": "type",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cart":   &types.AttributeValueMemberS{Value: "CART"},
+			":tenant": &types.AttributeValueMemberS{Value: userPK(cart.TenantIDFromContext(ctx), "")},
+		},
+		ExclusiveStartKey: startKey,
+	}
+
+	carts := make([]*cart.Cart, 0, limit)
+	for {
+		input.Limit = aws.Int32(int32(limit - len(carts)))
+		page, err := execute(ctx, r, r.readTimeout, r.client.db.Scan, input)
+		if err != nil {
+			return nil, "", errors.Wrap(errors.CodePersistenceError, "failed to scan carts", err)
+		}
+
+		for _, item := range page.Items {
+			var record cartRecord
+			if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+				return nil, "", errors.Wrap(errors.CodePersistenceError, "failed to unmarshal cart", err)
+			}
+			c, err := r.recordToCart(ctx, &record)
+			if err != nil {
+				return nil, "", err
+			}
+			carts = append(carts, c)
+		}
+
+		if len(page.LastEvaluatedKey) == 0 {
+			return carts, "", nil
+		}
+		if len(carts) >= limit {
+			next, err := encodeScanCursor(page.LastEvaluatedKey)
+			if err != nil {
+				return nil, "", err
+			}
+			return carts, next, nil
+		}
+		input.ExclusiveStartKey = page.LastEvaluatedKey
+	}
+}
+
+// encodeScanCursor encodes a LastEvaluatedKey as an opaque cursor. Table keys
+// are strings, so only string attributes are kept.
+func encodeScanCursor(key map[string]types.AttributeValue) (string, error) {
+	values := make(map[string]string, len(key))
+	for name, av := range key {
+		s, ok := av.(*types.AttributeValueMemberS)
+		if !ok {
+			return "", errors.New(errors.CodePersistenceError, "unexpected scan key attribute "+name)
+		}
+		values[name] = s.Value
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", errors.Wrap(errors.CodePersistenceError, "failed to encode scan cursor", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeScanCursor reverses encodeScanCursor; an empty cursor is the start
+// of the table.
+func decodeScanCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	var values map[string]string
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &values)
+	}
+	if err != nil || values["PK"] == "" || values["SK"] == "" {
+		return nil, errors.ErrValidation("Invalid cursor", nil)
+	}
+
+	key := make(map[string]types.AttributeValue, len(values))
+	for name, value := range values {
+		key[name] = &types.AttributeValueMemberS{Value: value}
+	}
+	return key, nil
+}
+
+// MarkExpiryNotified records that an expiry warning was sent for the cart's current
+// expiry. It returns false if the marker already exists.
+func (r *Repository) MarkExpiryNotified(ctx context.Context, cartID string, expiresAt time.Time) (bool, error) {
+	_, err := execute(ctx, r, r.writeTimeout, r.client.db.PutItem, &dynamodb.PutItemInput{
+		TableName: aws.String(r.client.tableName),
+		Item: map[string]types.AttributeValue{
+			"PK":   &types.AttributeValueMemberS{Value: CartKeyPrefix + cartID},
+			"SK":   &types.AttributeValueMemberS{Value: expiryNoticeSK(expiresAt)},
+			"type": &types.AttributeValueMemberS{Value: "EXPIRY_NOTICE"},
+			// Keep the marker a day past expiry so late scans can't re-emit
+			"ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Add(24*time.Hour).Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(PK)"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if ok := isConditionalCheckFailedException(err, &condErr); ok {
+			return false, nil
+		}
+		return false, errors.Wrap(errors.CodePersistenceError, "failed to save expiry marker", err)
+	}
+
+	return true, nil
+}
+
+// UnmarkExpiryNotified removes an expiry marker.
+func (r *Repository) UnmarkExpiryNotified(ctx context.Context, cartID string, expiresAt time.Time) error {
+	_, err := execute(ctx, r, r.writeTimeout, r.client.db.DeleteItem, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.client.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: CartKeyPrefix + cartID},
+			"SK": &types.AttributeValueMemberS{Value: expiryNoticeSK(expiresAt)},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(errors.CodePersistenceError, "failed to delete expiry marker", err)
+	}
+
+	return nil
+}
+
 // HealthCheck verifies repository connectivity.
 func (r *Repository) HealthCheck(ctx context.Context) error {
 	return r.client.HealthCheck(ctx)
@@ -170,73 +564,318 @@ func (r *Repository) HealthCheck(ctx context.Context) error {
 
 // Helper functions
 
-func cartToRecord(c *cart.Cart) *cartRecord {
-	items := make([]cartItemRecord, len(c.Items))
-	for i, item := range c.Items {
-		items[i] = cartItemRecord{
-			ItemID:    item.ItemID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			UnitPrice: item.UnitPrice,
-			AddedAt:   item.AddedAt.Format(time.RFC3339),
-		}
-	}
-
-	return &cartRecord{
-		PK:        UserKeyPrefix + c.UserID,
-		SK:        CartKeyPrefix + c.UserID,
+// cartToRecord converts a cart to its stored form, encrypting free-text fields
+// when an encryptor is configured.
+func (r *Repository) cartToRecord(ctx context.Context, c *cart.Cart) (*cartRecord, error) {
+	record := &cartRecord{
+		PK:        userPK(c.TenantID, c.UserID),
+		SK:        cartSortKey(c),
 		Type:      "CART",
 		ID:        c.ID,
+		TenantID:  cart.NormalizeTenantID(c.TenantID),
 		UserID:    c.UserID,
-		Items:     items,
+		Items:     itemsToRecords(c.Items),
 		Version:   c.Version,
 		CreatedAt: c.CreatedAt.Format(time.RFC3339),
 		UpdatedAt: c.UpdatedAt.Format(time.RFC3339),
 		ExpiresAt: c.ExpiresAt.Format(time.RFC3339),
 		TTL:       c.ExpiresAt.Unix(),
+
+		GiftMessage: c.GiftMessage,
+
+		Name:      c.Name,
+		Secondary: c.Secondary,
+	}
+
+	if len(c.LastClearedItems) > 0 {
+		record.LastClearedItems = itemsToRecords(c.LastClearedItems)
+		record.ClearedAt = c.ClearedAt.Format(time.RFC3339)
+	}
+
+	if c.Locked {
+		record.Locked = true
+		record.LockedUntil = c.LockedUntil.Format(time.RFC3339)
+	}
+
+	if r.encryptor != nil {
+		if err := r.encryptFields(ctx, record); err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to encrypt cart", err)
+		}
+	}
+
+	return record, nil
+}
+
+// encryptFields moves the record's free-text fields into Encrypted.
+func (r *Repository) encryptFields(ctx context.Context, record *cartRecord) error {
+	fields := encryptedFields{
+		GiftMessage:       record.GiftMessage,
+		Attributes:        takeAttributes(record.Items),
+		ClearedAttributes: takeAttributes(record.LastClearedItems),
+	}
+	if fields.GiftMessage == "" && fields.Attributes == nil && fields.ClearedAttributes == nil {
+		return nil
+	}
+
+	plaintext, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	record.Encrypted, err = r.encryptor.Encrypt(ctx, plaintext)
+	if err != nil {
+		return err
+	}
+	record.GiftMessage = ""
+	return nil
+}
+
+// decryptFields restores the record's free-text fields from Encrypted.
+func (r *Repository) decryptFields(ctx context.Context, record *cartRecord) error {
+	if r.encryptor == nil {
+		return fmt.Errorf("cart has encrypted fields but no encryptor is configured")
+	}
+
+	plaintext, err := r.encryptor.Decrypt(ctx, record.Encrypted)
+	if err != nil {
+		return err
+	}
+	var fields encryptedFields
+	if err := json.Unmarshal(plaintext, &fields); err != nil {
+		return err
+	}
+
+	record.GiftMessage = fields.GiftMessage
+	restoreAttributes(record.Items, fields.Attributes)
+	restoreAttributes(record.LastClearedItems, fields.ClearedAttributes)
+	return nil
+}
+
+// takeAttributes removes the attributes from records, returning them by item ID.
+func takeAttributes(records []cartItemRecord) map[string]map[string]string {
+	var attributes map[string]map[string]string
+	for i := range records {
+		if len(records[i].Attributes) == 0 {
+			continue
+		}
+		if attributes == nil {
+			attributes = make(map[string]map[string]string)
+		}
+		attributes[records[i].ItemID] = records[i].Attributes
+		records[i].Attributes = nil
+	}
+	return attributes
+}
+
+func restoreAttributes(records []cartItemRecord, attributes map[string]map[string]string) {
+	for i := range records {
+		if attrs, ok := attributes[records[i].ItemID]; ok {
+			records[i].Attributes = attrs
+		}
 	}
 }
 
-func recordToCart(r *cartRecord) (*cart.Cart, error) {
-	items := make([]cart.CartItem, len(r.Items))
-	for i, item := range r.Items {
+func itemsToRecords(items []cart.CartItem) []cartItemRecord {
+	records := make([]cartItemRecord, len(items))
+	for i, item := range items {
+		records[i] = cartItemRecord{
+			ItemID:     item.ItemID,
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.UnitPrice,
+			AddedAt:    item.AddedAt.Format(time.RFC3339),
+			Name:       item.Name,
+			ImageURL:   item.ImageURL,
+			SKU:        item.SKU,
+			Attributes: item.Attributes,
+
+			GiftWrap:    item.GiftWrap,
+			GiftWrapFee: item.GiftWrapFee,
+
+			UnitType:        string(item.UnitType),
+			DecimalQuantity: item.DecimalQuantity,
+
+			PackSize: item.PackSize,
+			Stale:    item.Stale,
+		}
+	}
+	return records
+}
+
+func recordsToItems(records []cartItemRecord) []cart.CartItem {
+	items := make([]cart.CartItem, len(records))
+	for i, item := range records {
 		addedAt, err := time.Parse(time.RFC3339, item.AddedAt)
 		if err != nil {
 			addedAt = time.Now().UTC()
 		}
 		items[i] = cart.CartItem{
-			ItemID:    item.ItemID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			UnitPrice: item.UnitPrice,
-			AddedAt:   addedAt,
+			ItemID:     item.ItemID,
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.UnitPrice,
+			AddedAt:    addedAt,
+			Name:       item.Name,
+			ImageURL:   item.ImageURL,
+			SKU:        item.SKU,
+			Attributes: item.Attributes,
+
+			GiftWrap:    item.GiftWrap,
+			GiftWrapFee: item.GiftWrapFee,
+
+			UnitType:        cart.UnitType(item.UnitType),
+			DecimalQuantity: item.DecimalQuantity,
+
+			PackSize: item.PackSize,
+			Stale:    item.Stale,
+		}
+	}
+	return items
+}
+
+// recordToCart converts a stored record to a cart, decrypting free-text fields
+// written by an encryptor and merging duplicate product rows.
+func (r *Repository) recordToCart(ctx context.Context, record *cartRecord) (*cart.Cart, error) {
+	if len(record.Encrypted) > 0 {
+		if err := r.decryptFields(ctx, record); err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to decrypt cart", err)
 		}
 	}
 
-	createdAt, err := time.Parse(time.RFC3339, r.CreatedAt)
+	createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
 	if err != nil {
 		createdAt = time.Now().UTC()
 	}
 
-	updatedAt, err := time.Parse(time.RFC3339, r.UpdatedAt)
+	updatedAt, err := time.Parse(time.RFC3339, record.UpdatedAt)
 	if err != nil {
 		updatedAt = time.Now().UTC()
 	}
 
-	expiresAt, err := time.Parse(time.RFC3339, r.ExpiresAt)
+	expiresAt, err := time.Parse(time.RFC3339, record.ExpiresAt)
 	if err != nil {
 		expiresAt = time.Now().UTC().Add(7 * 24 * time.Hour)
 	}
 
-	return &cart.Cart{
-		ID:        r.ID,
-		UserID:    r.UserID,
-		Items:     items,
-		Version:   r.Version,
+	c := &cart.Cart{
+		ID:        record.ID,
+		TenantID:  record.TenantID,
+		UserID:    record.UserID,
+		Items:     recordsToItems(record.Items),
+		Version:   record.Version,
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
 		ExpiresAt: expiresAt,
-	}, nil
+
+		GiftMessage: record.GiftMessage,
+
+		Name:      record.Name,
+		Secondary: record.Secondary,
+	}
+
+	if len(record.LastClearedItems) > 0 {
+		if clearedAt, err := time.Parse(time.RFC3339, record.ClearedAt); err == nil {
+			c.LastClearedItems = recordsToItems(record.LastClearedItems)
+			c.ClearedAt = clearedAt
+		}
+	}
+
+	if record.Locked {
+		if lockedUntil, err := time.Parse(time.RFC3339, record.LockedUntil); err == nil {
+			c.Locked = true
+			c.LockedUntil = lockedUntil
+		}
+	}
+
+	if merged := c.Normalize(); merged > 0 && r.logger != nil {
+		r.logger.WithContext(ctx).WithFields(map[string]interface{}{
+			"cart_id":      c.ID,
+			"merged_rows":  merged,
+			"stored_items": len(record.Items),
+		}).Warn("Merged duplicate product rows in stored cart")
+	}
+
+	return c, nil
+}
+
+// productEntryRecord indexes a product held in a user's cart on GSI1.
+type productEntryRecord struct {
+	PK     string `dynamodbav:"PK"`
+	SK     string `dynamodbav:"SK"`
+	GSI1PK string `dynamodbav:"GSI1PK"`
+	GSI1SK string `dynamodbav:"GSI1SK"`
+	Type   string `dynamodbav:"type"`
+	UserID string `dynamodbav:"user_id"`
+	TTL    int64  `dynamodbav:"ttl"`
+}
+
+// indexProducts writes a GSI1 entry for each product in the cart so carts can be
+// found by product. Indexing is best-effort: unprocessed entries are retried,
+// and a write that still fails is logged and only delays bulk operations until
+// the cart is next saved. Stale entries are filtered on read.
+// Only default carts are indexed, since entries are keyed by user.
+func (r *Repository) indexProducts(ctx context.Context, c *cart.Cart) {
+	if !c.IsDefault() {
+		return
+	}
+
+	requests := make([]types.WriteRequest, 0, len(c.Items))
+	for _, item := range c.Items {
+		entry, err := attributevalue.MarshalMap(productEntryRecord{
+			PK:     userPK(c.TenantID, c.UserID),
+			SK:     ProductKeyPrefix + item.ProductID,
+			GSI1PK: ProductKeyPrefix + item.ProductID,
+			GSI1SK: userPK(c.TenantID, c.UserID),
+			Type:   "CART_PRODUCT",
+			UserID: c.UserID,
+			TTL:    c.ExpiresAt.Unix(),
+		})
+		if err != nil {
+			continue
+		}
+		requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: entry}})
+	}
+
+	// BatchWriteItem allows max 25 requests per call
+	for i := 0; i < len(requests); i += 25 {
+		end := min(i+25, len(requests))
+		err := batchWrite(ctx, r, map[string][]types.WriteRequest{
+			r.client.tableName: requests[i:end],
+		})
+		if err != nil && r.logger != nil {
+			r.logger.WithContext(ctx).WithFields(map[string]interface{}{
+				"cart_id": c.ID,
+				"user_id": c.UserID,
+				"entries": end - i,
+			}).WithError(err).Warn("Failed to index cart products")
+		}
+	}
+}
+
+// userPK returns the partition key of a user's items within a tenant. The
+// default tenant, including an empty one, keeps the unprefixed USER#{userID}
+// key, so single-tenant tables need no migration.
+func userPK(tenantID, userID string) string {
+	if tenantID = cart.NormalizeTenantID(tenantID); tenantID == cart.DefaultTenantID {
+		return UserKeyPrefix + userID
+	}
+	return TenantKeyPrefix + tenantID + "#" + UserKeyPrefix + userID
+}
+
+// cartSortKey returns the sort key of a cart: CART#{userID} for the default
+// cart, CART#{userID}#{cartID} for additional carts.
+func cartSortKey(c *cart.Cart) string {
+	if c.IsDefault() {
+		return CartKeyPrefix + c.UserID
+	}
+	return additionalCartSK(c.UserID, c.ID)
+}
+
+func additionalCartSK(userID, cartID string) string {
+	return CartKeyPrefix + userID + "#" + cartID
+}
+
+func expiryNoticeSK(expiresAt time.Time) string {
+	return ExpiryNoticeKeyPrefix + strconv.FormatInt(expiresAt.Unix(), 10)
 }
 
 func isConditionalCheckFailedException(err error, target **types.ConditionalCheckFailedException) bool {
@@ -0,0 +1,159 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// xorEncryptor is a reversible stand-in for a real Encryptor.
+type xorEncryptor struct {
+	calls int
+}
+
+func (e *xorEncryptor) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	e.calls++
+	return xor(plaintext), nil
+}
+
+func (e *xorEncryptor) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	e.calls++
+	return xor(ciphertext), nil
+}
+
+func xor(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i, b := range in {
+		out[i] = b ^ 0x5a
+	}
+	return out
+}
+
+func newEncryptedTestCart() *cart.Cart {
+	c := cart.NewCart("user-123")
+	item := cart.NewCartItem("product-1", 2, 1500)
+	item.Attributes = map[string]string{"engraving": "For Sam"}
+	c.AddItem(item)
+	c.AddItem(cart.NewCartItem("product-2", 1, 500))
+	c.GiftMessage = "Happy birthday!"
+	return c
+}
+
+func TestRepository_FieldEncryption(t *testing.T) {
+	ctx := context.Background()
+	encryptor := &xorEncryptor{}
+	repo := NewRepository(nil, WithEncryptor(encryptor))
+	c := newEncryptedTestCart()
+
+	record, err := repo.cartToRecord(ctx, c)
+	require.NoError(t, err)
+	assert.Equal(t, 1, encryptor.calls)
+	assert.Empty(t, record.GiftMessage)
+	assert.Nil(t, record.Items[0].Attributes)
+	assert.False(t, bytes.Contains(record.Encrypted, []byte("Happy birthday!")))
+
+	// Keys and prices stay queryable
+	assert.Equal(t, "product-1", record.Items[0].ProductID)
+	assert.Equal(t, int64(1500), record.Items[0].UnitPrice)
+
+	// The cart itself is not modified
+	assert.Equal(t, "Happy birthday!", c.GiftMessage)
+	assert.Equal(t, "For Sam", c.Items[0].Attributes["engraving"])
+
+	restored, err := repo.recordToCart(ctx, record)
+	require.NoError(t, err)
+	assert.Equal(t, "Happy birthday!", restored.GiftMessage)
+	assert.Equal(t, map[string]string{"engraving": "For Sam"}, restored.Items[0].Attributes)
+	assert.Nil(t, restored.Items[1].Attributes)
+}
+
+func TestRepository_FieldEncryption_Plaintext(t *testing.T) {
+	ctx := context.Background()
+	plaintext := NewRepository(nil)
+
+	record, err := plaintext.cartToRecord(ctx, newEncryptedTestCart())
+	require.NoError(t, err)
+	assert.Equal(t, "Happy birthday!", record.GiftMessage)
+	assert.Empty(t, record.Encrypted)
+
+	// Plaintext records written before encryption was enabled remain readable
+	restored, err := NewRepository(nil, WithEncryptor(&xorEncryptor{})).recordToCart(ctx, record)
+	require.NoError(t, err)
+	assert.Equal(t, "Happy birthday!", restored.GiftMessage)
+
+	// Encrypted records can't be read without an encryptor
+	encrypted, err := NewRepository(nil, WithEncryptor(&xorEncryptor{})).cartToRecord(ctx, newEncryptedTestCart())
+	require.NoError(t, err)
+	_, err = plaintext.recordToCart(ctx, encrypted)
+	assert.True(t, errors.IsCode(err, errors.CodePersistenceError))
+}
+
+func TestScanCursor(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "USER#user-123"},
+		"SK": &types.AttributeValueMemberS{Value: "CART#user-123"},
+	}
+
+	cursor, err := encodeScanCursor(key)
+	require.NoError(t, err)
+	assert.NotContains(t, cursor, "USER#")
+
+	decoded, err := decodeScanCursor(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, key, decoded)
+
+	decoded, err = decodeScanCursor("")
+	require.NoError(t, err)
+	assert.Nil(t, decoded)
+
+	for _, invalid := range []string{"not base64!", "e30", "bm90IGpzb24"} {
+		_, err := decodeScanCursor(invalid)
+		assert.True(t, errors.IsCode(err, errors.CodeValidationError), invalid)
+	}
+}
+
+func TestRepository_TenantKeys(t *testing.T) {
+	repo := &Repository{}
+
+	c := cart.NewCart("user-123")
+	record, err := repo.cartToRecord(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, "USER#user-123", record.PK)
+	assert.Equal(t, cart.DefaultTenantID, record.TenantID)
+
+	c.TenantID = "acme"
+	record, err = repo.cartToRecord(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, "TENANT#acme#USER#user-123", record.PK)
+	assert.Equal(t, "CART#user-123", record.SK)
+
+	restored, err := repo.recordToCart(context.Background(), record)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", restored.TenantID)
+}
+
+func TestRepository_RecordToCartMergesDuplicates(t *testing.T) {
+	record := &cartRecord{
+		ID:     "user-123",
+		UserID: "user-123",
+		Items: []cartItemRecord{
+			{ItemID: "item-1", ProductID: "product-1", Quantity: 2, UnitPrice: 1000},
+			{ItemID: "item-2", ProductID: "product-2", Quantity: 1, UnitPrice: 500},
+			{ItemID: "item-3", ProductID: "product-1", Quantity: 3, UnitPrice: 1000},
+			{ItemID: "item-4", ProductID: "product-1", Quantity: 98, UnitPrice: 1000},
+		},
+	}
+
+	restored, err := (&Repository{}).recordToCart(context.Background(), record)
+	require.NoError(t, err)
+	require.Len(t, restored.Items, 2)
+	assert.Equal(t, "item-1", restored.Items[0].ItemID)
+	assert.Equal(t, cart.MaxQuantityPerItem, restored.Items[0].Quantity)
+	assert.Equal(t, "product-2", restored.Items[1].ProductID)
+}
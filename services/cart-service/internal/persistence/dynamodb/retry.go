@@ -0,0 +1,133 @@
+package dynamodb
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
+)
+
+// retryableErrorCodes are DynamoDB error codes for throttling and transient
+// server failures.
+var retryableErrorCodes = map[string]bool{
+	"ProvisionedThroughputExceededException": true,
+	"RequestLimitExceeded":                   true,
+	"ThrottlingException":                    true,
+	"InternalServerError":                    true,
+	"ServiceUnavailable":                     true,
+	"RequestTimeout":                         true,
+	"RequestTimeoutException":                true,
+}
+
+// IsRetryableError reports whether a DynamoDB error is worth retrying:
+// throttling, timeouts and 5xx responses. Conditional check failures,
+// cancelled transactions and other client errors are never retried, since
+// retrying them can't succeed and would hide conflicts.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if stderrors.Is(err, context.Canceled) {
+		return false
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if stderrors.As(err, &apiErr) {
+		if retryableErrorCodes[apiErr.ErrorCode()] {
+			return true
+		}
+		if apiErr.ErrorFault() == smithy.FaultClient {
+			return false
+		}
+	}
+
+	var respErr *awshttp.ResponseError
+	if stderrors.As(err, &respErr) {
+		return respErr.HTTPStatusCode() >= 500
+	}
+
+	var netErr net.Error
+	if stderrors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+
+	return false
+}
+
+// DefaultRetryConfig returns the resilience default retry configuration
+// with RetryableFunc set to IsRetryableError.
+func DefaultRetryConfig() resilience.RetryConfig {
+	cfg := resilience.DefaultRetryConfig()
+	cfg.RetryableFunc = IsRetryableError
+	return cfg
+}
+
+// WithRetryConfig sets how DynamoDB operations are retried. A nil
+// RetryableFunc defaults to IsRetryableError.
+func WithRetryConfig(cfg resilience.RetryConfig) RepositoryOption {
+	return func(r *Repository) {
+		if cfg.RetryableFunc == nil {
+			cfg.RetryableFunc = IsRetryableError
+		}
+		r.retry = cfg
+	}
+}
+
+// execute runs a DynamoDB operation with the repository's retry policy,
+// bounding each attempt by timeout.
+func execute[In, Out any](ctx context.Context, r *Repository, timeout time.Duration, op func(context.Context, In, ...func(*dynamodb.Options)) (Out, error), input In) (Out, error) {
+	return resilience.RetryWithResult(ctx, r.retry, func() (Out, error) {
+		return resilience.ExecuteWithTimeoutResult(ctx, timeout, func(ctx context.Context) (Out, error) {
+			return op(ctx, input)
+		})
+	})
+}
+
+// nextPage fetches a paginator's next page like execute. A failed page leaves
+// the paginator where it was, so retrying it is safe.
+func nextPage[Out any](ctx context.Context, r *Repository, timeout time.Duration, next func(context.Context, ...func(*dynamodb.Options)) (Out, error)) (Out, error) {
+	return resilience.RetryWithResult(ctx, r.retry, func() (Out, error) {
+		return resilience.ExecuteWithTimeoutResult(ctx, timeout, func(ctx context.Context) (Out, error) {
+			return next(ctx)
+		})
+	})
+}
+
+// errUnprocessedItems is returned by an attempt of batchWrite that left
+// requests unprocessed, usually because the table was throttled.
+var errUnprocessedItems = stderrors.New("batch write left unprocessed items")
+
+// batchWrite writes requests with BatchWriteItem like execute, resubmitting
+// any UnprocessedItems with backoff until they are written or the retry
+// attempts run out.
+func batchWrite(ctx context.Context, r *Repository, requests map[string][]types.WriteRequest) error {
+	cfg := r.retry
+	retryable := cfg.RetryableFunc
+	cfg.RetryableFunc = func(err error) bool {
+		return stderrors.Is(err, errUnprocessedItems) || (retryable != nil && retryable(err))
+	}
+
+	pending := requests
+	return resilience.Retry(ctx, cfg, func() error {
+		out, err := resilience.ExecuteWithTimeoutResult(ctx, r.writeTimeout, func(ctx context.Context) (*dynamodb.BatchWriteItemOutput, error) {
+			return r.client.db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.UnprocessedItems) > 0 {
+			pending = out.UnprocessedItems
+			return errUnprocessedItems
+		}
+		return nil
+	})
+}
@@ -0,0 +1,161 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// responseError wraps err the way the SDK does for a response with status.
+func responseError(status int, err error) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      err,
+		},
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"throughput exceeded", &types.ProvisionedThroughputExceededException{}, true},
+		{"request limit exceeded", &types.RequestLimitExceeded{}, true},
+		{"internal server error", &types.InternalServerError{}, true},
+		{"throttling", &smithy.GenericAPIError{Code: "ThrottlingException"}, true},
+		{"wrapped throttling", fmt.Errorf("put: %w", responseError(400, &types.ProvisionedThroughputExceededException{})), true},
+		{"5xx response", responseError(503, &smithy.GenericAPIError{Code: "Unknown"}), true},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"conditional check failed", responseError(400, &types.ConditionalCheckFailedException{}), false},
+		{"transaction canceled", &types.TransactionCanceledException{}, false},
+		{"validation", responseError(400, &smithy.GenericAPIError{Code: "ValidationException", Fault: smithy.FaultClient}), false},
+		{"resource not found", &types.ResourceNotFoundException{}, false},
+		{"unknown", fmt.Errorf("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryableError(tt.err))
+		})
+	}
+}
+
+func TestWithRetryConfig_DefaultsClassifier(t *testing.T) {
+	r := NewRepository(nil, WithRetryConfig(resilience.RetryConfig{MaxAttempts: 5}))
+
+	assert.Equal(t, 5, r.retry.MaxAttempts)
+	assert.False(t, r.retry.RetryableFunc(&types.ConditionalCheckFailedException{}))
+	assert.True(t, r.retry.RetryableFunc(&types.InternalServerError{}))
+}
+
+// slowAPI answers GetItem after delay, or fails once ctx is done.
+type slowAPI struct {
+	API
+	delay time.Duration
+	calls int
+}
+
+func (a *slowAPI) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	a.calls++
+	select {
+	case <-time.After(a.delay):
+		return &dynamodb.GetItemOutput{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestRepository_OperationTimeout(t *testing.T) {
+	api := &slowAPI{delay: time.Second}
+	retry := DefaultRetryConfig()
+	retry.MaxAttempts = 2
+	retry.InitialDelay = time.Millisecond
+	r := NewRepository(NewClientWithAPI(api, "carts"),
+		WithRetryConfig(retry),
+		WithTimeouts(20*time.Millisecond, 20*time.Millisecond),
+	)
+
+	start := time.Now()
+	_, err := r.GetCart(context.Background(), "user-123")
+
+	require.Error(t, err)
+	assert.True(t, errors.IsCode(err, errors.CodePersistenceError), "got %v", err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, api.calls, "timeouts are retried")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestRepository_OperationWithinTimeout(t *testing.T) {
+	api := &slowAPI{delay: time.Millisecond}
+	r := NewRepository(NewClientWithAPI(api, "carts"), WithTimeouts(time.Second, time.Second))
+
+	_, err := r.GetCart(context.Background(), "user-123")
+
+	assert.True(t, errors.IsCode(err, errors.CodeCartNotFound), "got %v", err)
+	assert.Equal(t, 1, api.calls)
+}
+
+// throttledBatchAPI leaves the last request of its first throttled batches
+// unprocessed, like a throttled table does.
+type throttledBatchAPI struct {
+	API
+	throttled int
+	written   []types.WriteRequest
+}
+
+func (a *throttledBatchAPI) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	out := &dynamodb.BatchWriteItemOutput{}
+	for table, requests := range params.RequestItems {
+		if a.throttled > 0 && len(requests) > 0 {
+			a.throttled--
+			out.UnprocessedItems = map[string][]types.WriteRequest{table: requests[len(requests)-1:]}
+			requests = requests[:len(requests)-1]
+		}
+		a.written = append(a.written, requests...)
+	}
+	return out, nil
+}
+
+func TestBatchWrite_RetriesUnprocessedItems(t *testing.T) {
+	requests := make([]types.WriteRequest, 3)
+	for i := range requests {
+		requests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("item-%d", i)},
+		}}}
+	}
+	retry := DefaultRetryConfig()
+	retry.InitialDelay = time.Millisecond
+
+	t.Run("writes the unprocessed items", func(t *testing.T) {
+		api := &throttledBatchAPI{throttled: 2}
+		r := NewRepository(NewClientWithAPI(api, "carts"), WithRetryConfig(retry))
+
+		require.NoError(t, batchWrite(context.Background(), r, map[string][]types.WriteRequest{"carts": requests}))
+		assert.ElementsMatch(t, requests, api.written)
+	})
+
+	t.Run("fails once the attempts run out", func(t *testing.T) {
+		api := &throttledBatchAPI{throttled: retry.MaxAttempts}
+		r := NewRepository(NewClientWithAPI(api, "carts"), WithRetryConfig(retry))
+
+		err := batchWrite(context.Background(), r, map[string][]types.WriteRequest{"carts": requests})
+		assert.ErrorIs(t, err, errUnprocessedItems)
+	})
+}
@@ -0,0 +1,135 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// SnapshotKeyPrefix prefixes cart snapshots, stored in the user's partition
+// and sorted by cart version.
+const SnapshotKeyPrefix = "SNAP#"
+
+// snapshotRecord represents a cart snapshot stored in DynamoDB.
+type snapshotRecord struct {
+	PK        string               `dynamodbav:"PK"`
+	SK        string               `dynamodbav:"SK"`
+	Type      string               `dynamodbav:"type"`
+	TenantID  string               `dynamodbav:"tenant_id"`
+	UserID    string               `dynamodbav:"user_id"`
+	CartID    string               `dynamodbav:"cart_id"`
+	Version   int64                `dynamodbav:"version"`
+	Items     []snapshotItemRecord `dynamodbav:"items"`
+	Total     int64                `dynamodbav:"total"`
+	Timestamp string               `dynamodbav:"timestamp"`
+	Operation string               `dynamodbav:"operation"`
+	TTL       int64                `dynamodbav:"ttl"`
+}
+
+type snapshotItemRecord struct {
+	ItemID    string `dynamodbav:"item_id"`
+	ProductID string `dynamodbav:"product_id"`
+	Quantity  int    `dynamodbav:"quantity"`
+	UnitPrice int64  `dynamodbav:"unit_price"`
+}
+
+// snapshotSK returns the sort key of a snapshot. Versions are zero-padded so
+// snapshots sort in version order.
+func snapshotSK(version int64) string {
+	return fmt.Sprintf("%s%020d", SnapshotKeyPrefix, version)
+}
+
+// SaveSnapshot stores a cart snapshot, expiring it after ttl through the
+// table's TTL attribute.
+func (r *Repository) SaveSnapshot(ctx context.Context, snapshot cart.CartSnapshot, ttl time.Duration) error {
+	items := make([]snapshotItemRecord, len(snapshot.Items))
+	for i, item := range snapshot.Items {
+		items[i] = snapshotItemRecord(item)
+	}
+
+	item, err := attributevalue.MarshalMap(snapshotRecord{
+		PK:        userPK(snapshot.TenantID, snapshot.UserID),
+		SK:        snapshotSK(snapshot.Version),
+		Type:      "SNAPSHOT",
+		TenantID:  cart.NormalizeTenantID(snapshot.TenantID),
+		UserID:    snapshot.UserID,
+		CartID:    snapshot.CartID,
+		Version:   snapshot.Version,
+		Items:     items,
+		Total:     snapshot.Total,
+		Timestamp: snapshot.Timestamp.UTC().Format(time.RFC3339Nano),
+		Operation: snapshot.Operation,
+		TTL:       snapshot.Timestamp.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return errors.Wrap(errors.CodePersistenceError, "failed to marshal cart snapshot", err)
+	}
+
+	_, err = execute(ctx, r, r.writeTimeout, r.client.db.PutItem, &dynamodb.PutItemInput{
+		TableName: aws.String(r.client.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return errors.Wrap(errors.CodePersistenceError, "failed to save cart snapshot", err)
+	}
+
+	return nil
+}
+
+// ListSnapshots returns a user's cart snapshots, oldest first.
+func (r *Repository) ListSnapshots(ctx context.Context, userID string) ([]cart.CartSnapshot, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.client.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: userPK(cart.TenantIDFromContext(ctx), userID)},
+			":sk": &types.AttributeValueMemberS{Value: SnapshotKeyPrefix},
+		},
+	}
+
+	var snapshots []cart.CartSnapshot
+	paginator := dynamodb.NewQueryPaginator(r.client.db, input)
+	for paginator.HasMorePages() {
+		page, err := nextPage(ctx, r, r.readTimeout, paginator.NextPage)
+		if err != nil {
+			return nil, errors.Wrap(errors.CodePersistenceError, "failed to query cart snapshots", err)
+		}
+
+		for _, item := range page.Items {
+			var record snapshotRecord
+			if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+				return nil, errors.Wrap(errors.CodePersistenceError, "failed to unmarshal cart snapshot", err)
+			}
+			snapshots = append(snapshots, recordToSnapshot(&record))
+		}
+	}
+
+	return snapshots, nil
+}
+
+// recordToSnapshot converts a snapshotRecord to a cart.CartSnapshot.
+func recordToSnapshot(record *snapshotRecord) cart.CartSnapshot {
+	items := make([]cart.SnapshotItem, len(record.Items))
+	for i, item := range record.Items {
+		items[i] = cart.SnapshotItem(item)
+	}
+	timestamp, _ := time.Parse(time.RFC3339Nano, record.Timestamp)
+
+	return cart.CartSnapshot{
+		TenantID:  record.TenantID,
+		UserID:    record.UserID,
+		CartID:    record.CartID,
+		Version:   record.Version,
+		Items:     items,
+		Total:     record.Total,
+		Timestamp: timestamp,
+		Operation: record.Operation,
+	}
+}
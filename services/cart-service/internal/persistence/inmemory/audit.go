@@ -0,0 +1,41 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/audit"
+)
+
+// AuditLog is an in-memory implementation of audit.Auditor.
+type AuditLog struct {
+	entries []audit.AuditEntry
+	mu      sync.RWMutex
+}
+
+// NewAuditLog creates a new in-memory audit log.
+func NewAuditLog() *AuditLog {
+	return &AuditLog{}
+}
+
+// Record appends an entry to the log.
+func (l *AuditLog) Record(ctx context.Context, entry audit.AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Entries returns the entries recorded for a user, oldest first.
+func (l *AuditLog) Entries(userID string) []audit.AuditEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var entries []audit.AuditEntry
+	for _, entry := range l.entries {
+		if entry.UserID == userID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,47 @@
+package inmemory
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ExpiryMarkerStore is an in-memory implementation of the expiry marker store.
+type ExpiryMarkerStore struct {
+	markers map[string]struct{}
+	mu      sync.Mutex
+}
+
+// NewExpiryMarkerStore creates a new in-memory expiry marker store.
+func NewExpiryMarkerStore() *ExpiryMarkerStore {
+	return &ExpiryMarkerStore{
+		markers: make(map[string]struct{}),
+	}
+}
+
+// MarkExpiryNotified claims the marker, returning false if it already exists.
+func (s *ExpiryMarkerStore) MarkExpiryNotified(ctx context.Context, cartID string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := markerKey(cartID, expiresAt)
+	if _, ok := s.markers[key]; ok {
+		return false, nil
+	}
+	s.markers[key] = struct{}{}
+	return true, nil
+}
+
+// UnmarkExpiryNotified releases a marker.
+func (s *ExpiryMarkerStore) UnmarkExpiryNotified(ctx context.Context, cartID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.markers, markerKey(cartID, expiresAt))
+	return nil
+}
+
+func markerKey(cartID string, expiresAt time.Time) string {
+	return cartID + "#" + strconv.FormatInt(expiresAt.Unix(), 10)
+}
@@ -0,0 +1,96 @@
+package inmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
+)
+
+// SaveCartWithOutbox saves a cart and appends its events to the outbox atomically.
+// A non-zero expectedVersion applies the same optimistic locking as SaveCartWithVersion.
+func (r *Repository) SaveCartWithOutbox(ctx context.Context, c *cart.Cart, expectedVersion int64, evts []events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if expectedVersion > 0 {
+		if existing, ok := r.carts[keyOf(c)]; ok && existing.Version != expectedVersion {
+			return errors.ErrConflict(expectedVersion, existing.Version)
+		}
+	}
+
+	r.carts[keyOf(c)] = copyCart(c)
+
+	now := time.Now().UTC()
+	for _, event := range evts {
+		r.outbox = append(r.outbox, events.OutboxRecord{
+			TenantID:  cart.NormalizeTenantID(c.TenantID),
+			UserID:    c.UserID,
+			Event:     event,
+			CreatedAt: now,
+		})
+	}
+	return nil
+}
+
+// FindPendingOutbox returns up to limit undispatched outbox records, oldest first.
+func (r *Repository) FindPendingOutbox(ctx context.Context, limit int) ([]events.OutboxRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n := min(limit, len(r.outbox))
+	return append([]events.OutboxRecord(nil), r.outbox[:n]...), nil
+}
+
+// MarkOutboxDispatched removes a record from the outbox.
+func (r *Repository) MarkOutboxDispatched(ctx context.Context, record events.OutboxRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, pending := range r.outbox {
+		if pending.Event.ID == record.Event.ID {
+			r.outbox = append(r.outbox[:i], r.outbox[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// SaveMergedCart saves a merged cart, deletes the guest cart and appends the
+// events to the outbox atomically. It fails with a conflict, changing nothing,
+// if the merged cart is no longer at expectedVersion or the guest cart is gone
+// or no longer at guestVersion.
+func (r *Repository) SaveMergedCart(ctx context.Context, merged *cart.Cart, expectedVersion int64, guestID string, guestVersion int64, evts []events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if expectedVersion > 0 {
+		if existing, ok := r.carts[keyOf(merged)]; ok && existing.Version != expectedVersion {
+			return errors.ErrConflict(expectedVersion, existing.Version)
+		}
+	}
+	guestKey := userKey(merged.TenantID, guestID)
+	guest, ok := r.carts[guestKey]
+	if !ok {
+		return errors.ErrConflict(guestVersion, 0).WithDetail("guest_id", guestID)
+	}
+	if guest.Version != guestVersion {
+		return errors.ErrConflict(guestVersion, guest.Version).WithDetail("guest_id", guestID)
+	}
+
+	r.carts[keyOf(merged)] = copyCart(merged)
+	delete(r.carts, guestKey)
+
+	now := time.Now().UTC()
+	for _, event := range evts {
+		r.outbox = append(r.outbox, events.OutboxRecord{
+			TenantID:  cart.NormalizeTenantID(merged.TenantID),
+			UserID:    merged.UserID,
+			Event:     event,
+			CreatedAt: now,
+		})
+	}
+	return nil
+}
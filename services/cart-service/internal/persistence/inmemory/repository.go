@@ -3,22 +3,30 @@ package inmemory
 
 import (
 	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/events"
 )
 
 // Repository is an in-memory implementation of the cart repository.
 type Repository struct {
-	carts map[string]*cart.Cart
-	mu    sync.RWMutex
+	carts     map[string]*cart.Cart // By keyOf
+	outbox    []events.OutboxRecord
+	snapshots map[string][]cart.CartSnapshot // By userKey, oldest first
+	mu        sync.RWMutex
 }
 
 // NewRepository creates a new in-memory repository.
 func NewRepository() *Repository {
 	return &Repository{
-		carts: make(map[string]*cart.Cart),
+		carts:     make(map[string]*cart.Cart),
+		snapshots: make(map[string][]cart.CartSnapshot),
 	}
 }
 
@@ -27,7 +35,7 @@ func (r *Repository) GetCart(ctx context.Context, userID string) (*cart.Cart, er
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	c, ok := r.carts[userID]
+	c, ok := r.carts[userKey(cart.TenantIDFromContext(ctx), userID)]
 	if !ok {
 		return nil, errors.ErrCartNotFound(userID)
 	}
@@ -41,7 +49,7 @@ func (r *Repository) SaveCart(ctx context.Context, c *cart.Cart) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.carts[c.UserID] = copyCart(c)
+	r.carts[keyOf(c)] = copyCart(c)
 	return nil
 }
 
@@ -50,12 +58,12 @@ func (r *Repository) SaveCartWithVersion(ctx context.Context, c *cart.Cart, expe
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	existing, ok := r.carts[c.UserID]
+	existing, ok := r.carts[keyOf(c)]
 	if ok && existing.Version != expectedVersion {
 		return errors.ErrConflict(expectedVersion, existing.Version)
 	}
 
-	r.carts[c.UserID] = copyCart(c)
+	r.carts[keyOf(c)] = copyCart(c)
 	return nil
 }
 
@@ -64,14 +72,140 @@ func (r *Repository) DeleteCart(ctx context.Context, userID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.carts[userID]; !ok {
+	key := userKey(cart.TenantIDFromContext(ctx), userID)
+	if _, ok := r.carts[key]; !ok {
 		return errors.ErrCartNotFound(userID)
 	}
 
-	delete(r.carts, userID)
+	delete(r.carts, key)
 	return nil
 }
 
+// GetCartByID retrieves one of a user's additional carts.
+func (r *Repository) GetCartByID(ctx context.Context, userID, cartID string) (*cart.Cart, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.carts[cartKey(cart.TenantIDFromContext(ctx), userID, cartID)]
+	if !ok {
+		return nil, errors.ErrCartNotFound(userID).WithDetail("cart_id", cartID)
+	}
+	return copyCart(c), nil
+}
+
+// ListCarts returns all of a user's carts, the default cart first and
+// additional carts in creation order.
+func (r *Repository) ListCarts(ctx context.Context, userID string) ([]*cart.Cart, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := cart.TenantIDFromContext(ctx)
+	var carts []*cart.Cart
+	for _, c := range r.carts {
+		if c.UserID == userID && cart.NormalizeTenantID(c.TenantID) == tenantID {
+			carts = append(carts, copyCart(c))
+		}
+	}
+	sort.SliceStable(carts, func(i, j int) bool {
+		if carts[i].IsDefault() != carts[j].IsDefault() {
+			return carts[i].IsDefault()
+		}
+		return carts[i].CreatedAt.Before(carts[j].CreatedAt)
+	})
+	return carts, nil
+}
+
+// DeleteCartByID deletes one of a user's additional carts.
+func (r *Repository) DeleteCartByID(ctx context.Context, userID, cartID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := cartKey(cart.TenantIDFromContext(ctx), userID, cartID)
+	if _, ok := r.carts[key]; !ok {
+		return errors.ErrCartNotFound(userID).WithDetail("cart_id", cartID)
+	}
+
+	delete(r.carts, key)
+	return nil
+}
+
+// FindExpiringCarts returns carts of all tenants expiring after from and no
+// later than to.
+func (r *Repository) FindExpiringCarts(ctx context.Context, from, to time.Time) ([]*cart.Cart, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var carts []*cart.Cart
+	for _, c := range r.carts {
+		if c.ExpiresAt.After(from) && !c.ExpiresAt.After(to) {
+			carts = append(carts, copyCart(c))
+		}
+	}
+	return carts, nil
+}
+
+// FindCartsByProduct returns up to limit carts of the tenant holding the product.
+func (r *Repository) FindCartsByProduct(ctx context.Context, productID string, limit int) ([]*cart.Cart, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := cart.TenantIDFromContext(ctx)
+	var carts []*cart.Cart
+	for _, c := range r.carts {
+		if len(carts) >= limit {
+			break
+		}
+		if cart.NormalizeTenantID(c.TenantID) != tenantID {
+			continue
+		}
+		if item, _ := c.FindItemByProductID(productID); item != nil {
+			carts = append(carts, copyCart(c))
+		}
+	}
+	return carts, nil
+}
+
+// ScanCarts returns up to limit carts of the tenant following cursor. Carts are
+// ordered by map key so pages are deterministic; the cursor encodes the last
+// key returned.
+func (r *Repository) ScanCarts(ctx context.Context, cursor string, limit int) ([]*cart.Cart, string, error) {
+	after, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", errors.ErrValidation("Invalid cursor", nil)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prefix := cart.TenantIDFromContext(ctx) + "#"
+	keys := make([]string, 0, len(r.carts))
+	for key := range r.carts {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if cursor == "" || key > string(after) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	more := len(keys) > limit
+	if more {
+		keys = keys[:limit]
+	}
+
+	carts := make([]*cart.Cart, len(keys))
+	for i, key := range keys {
+		carts[i] = copyCart(r.carts[key])
+	}
+
+	var next string
+	if more && len(keys) > 0 {
+		next = base64.RawURLEncoding.EncodeToString([]byte(keys[len(keys)-1]))
+	}
+	return carts, next, nil
+}
+
 // HealthCheck verifies repository is healthy (always returns nil for in-memory).
 func (r *Repository) HealthCheck(ctx context.Context) error {
 	return nil
@@ -91,6 +225,24 @@ func (r *Repository) Count() int {
 	return len(r.carts)
 }
 
+// userKey returns the map key of a user's default cart within a tenant.
+func userKey(tenantID, userID string) string {
+	return cart.NormalizeTenantID(tenantID) + "#" + userID
+}
+
+// cartKey returns the map key of a user's additional cart within a tenant.
+func cartKey(tenantID, userID, cartID string) string {
+	return userKey(tenantID, userID) + "#" + cartID
+}
+
+// keyOf returns the map key of c.
+func keyOf(c *cart.Cart) string {
+	if c.IsDefault() {
+		return userKey(c.TenantID, c.UserID)
+	}
+	return cartKey(c.TenantID, c.UserID, c.ID)
+}
+
 // copyCart creates a deep copy of a cart.
 func copyCart(c *cart.Cart) *cart.Cart {
 	if c == nil {
@@ -100,13 +252,26 @@ func copyCart(c *cart.Cart) *cart.Cart {
 	items := make([]cart.CartItem, len(c.Items))
 	copy(items, c.Items)
 
+	var clearedItems []cart.CartItem
+	if len(c.LastClearedItems) > 0 {
+		clearedItems = make([]cart.CartItem, len(c.LastClearedItems))
+		copy(clearedItems, c.LastClearedItems)
+	}
+
 	return &cart.Cart{
-		ID:        c.ID,
-		UserID:    c.UserID,
-		Items:     items,
-		Version:   c.Version,
-		CreatedAt: c.CreatedAt,
-		UpdatedAt: c.UpdatedAt,
-		ExpiresAt: c.ExpiresAt,
+		ID:               c.ID,
+		TenantID:         c.TenantID,
+		UserID:           c.UserID,
+		Items:            items,
+		Version:          c.Version,
+		CreatedAt:        c.CreatedAt,
+		UpdatedAt:        c.UpdatedAt,
+		ExpiresAt:        c.ExpiresAt,
+		LastClearedItems: clearedItems,
+		ClearedAt:        c.ClearedAt,
+		Name:             c.Name,
+		Secondary:        c.Secondary,
+		Locked:           c.Locked,
+		LockedUntil:      c.LockedUntil,
 	}
 }
@@ -0,0 +1,28 @@
+package inmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+)
+
+// SaveSnapshot appends a cart snapshot. The TTL is ignored; snapshots are
+// kept for the life of the repository.
+func (r *Repository) SaveSnapshot(ctx context.Context, snapshot cart.CartSnapshot, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := userKey(snapshot.TenantID, snapshot.UserID)
+	r.snapshots[key] = append(r.snapshots[key], snapshot)
+	return nil
+}
+
+// ListSnapshots returns a user's cart snapshots, oldest first.
+func (r *Repository) ListSnapshots(ctx context.Context, userID string) ([]cart.CartSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshots := r.snapshots[userKey(cart.TenantIDFromContext(ctx), userID)]
+	return append([]cart.CartSnapshot(nil), snapshots...), nil
+}
@@ -0,0 +1,63 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/errors"
+)
+
+// TemplateStore is an in-memory implementation of the cart template store.
+type TemplateStore struct {
+	templates map[string]*cart.Template
+	mu        sync.RWMutex
+}
+
+// NewTemplateStore creates a new in-memory template store.
+func NewTemplateStore() *TemplateStore {
+	return &TemplateStore{
+		templates: make(map[string]*cart.Template),
+	}
+}
+
+// GetTemplate retrieves a user template, falling back to a global template.
+func (s *TemplateStore) GetTemplate(ctx context.Context, userID, templateID string) (*cart.Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if t, ok := s.templates[templateKey(userID, templateID)]; ok {
+		return copyTemplate(t), nil
+	}
+	if t, ok := s.templates[templateKey("", templateID)]; ok {
+		return copyTemplate(t), nil
+	}
+
+	return nil, errors.ErrTemplateNotFound(templateID)
+}
+
+// SaveTemplate saves a template. Templates with an empty UserID are global.
+func (s *TemplateStore) SaveTemplate(ctx context.Context, t *cart.Template) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.templates[templateKey(t.UserID, t.ID)] = copyTemplate(t)
+	return nil
+}
+
+func templateKey(userID, templateID string) string {
+	return userID + "#" + templateID
+}
+
+// copyTemplate creates a deep copy of a template.
+func copyTemplate(t *cart.Template) *cart.Template {
+	lines := make([]cart.TemplateLine, len(t.Lines))
+	copy(lines, t.Lines)
+
+	return &cart.Template{
+		ID:     t.ID,
+		UserID: t.UserID,
+		Name:   t.Name,
+		Lines:  lines,
+	}
+}
@@ -2,10 +2,15 @@ package resilience
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
+// ErrBulkheadFull is returned when a bulkhead's waiting queue is full.
+var ErrBulkheadFull = errors.New("max waiting requests exceeded")
+
 // BulkheadConfig holds bulkhead configuration.
 type BulkheadConfig struct {
 	Name          string
@@ -29,6 +34,8 @@ type Bulkhead struct {
 	maxConcurrent int
 	maxWaiting    int
 	waiting       int
+	lastUsed      time.Time
+	now           func() time.Time
 	mu            sync.Mutex
 }
 
@@ -39,6 +46,8 @@ func NewBulkhead(cfg BulkheadConfig) *Bulkhead {
 		semaphore:     make(chan struct{}, cfg.MaxConcurrent),
 		maxConcurrent: cfg.MaxConcurrent,
 		maxWaiting:    cfg.MaxWaiting,
+		lastUsed:      time.Now(),
+		now:           time.Now,
 	}
 }
 
@@ -48,9 +57,10 @@ func (b *Bulkhead) Execute(ctx context.Context, fn func() error) error {
 	b.mu.Lock()
 	if b.waiting >= b.maxWaiting {
 		b.mu.Unlock()
-		return fmt.Errorf("bulkhead %s: max waiting requests exceeded", b.name)
+		return fmt.Errorf("bulkhead %s: %w", b.name, ErrBulkheadFull)
 	}
 	b.waiting++
+	b.lastUsed = b.now()
 	b.mu.Unlock()
 
 	// Decrement waiting count when done
@@ -76,9 +86,10 @@ func (b *Bulkhead) ExecuteWithResult(ctx context.Context, fn func() (interface{}
 	b.mu.Lock()
 	if b.waiting >= b.maxWaiting {
 		b.mu.Unlock()
-		return nil, fmt.Errorf("bulkhead %s: max waiting requests exceeded", b.name)
+		return nil, fmt.Errorf("bulkhead %s: %w", b.name, ErrBulkheadFull)
 	}
 	b.waiting++
+	b.lastUsed = b.now()
 	b.mu.Unlock()
 
 	defer func() {
@@ -109,6 +120,21 @@ func (b *Bulkhead) Stats() BulkheadStats {
 	}
 }
 
+// touch marks the bulkhead as used at now.
+func (b *Bulkhead) touch(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastUsed = now
+}
+
+// idleSince reports whether the bulkhead has no requests in flight and has not
+// been entered since cutoff.
+func (b *Bulkhead) idleSince(cutoff time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.waiting == 0 && b.lastUsed.Before(cutoff)
+}
+
 // BulkheadStats contains bulkhead statistics.
 type BulkheadStats struct {
 	Name          string
@@ -121,20 +147,31 @@ type BulkheadStats struct {
 // BulkheadManager manages multiple bulkheads.
 type BulkheadManager struct {
 	bulkheads map[string]*Bulkhead
+	now       func() time.Time
 	mu        sync.RWMutex
 }
 
 // NewBulkheadManager creates a new bulkhead manager.
 func NewBulkheadManager() *BulkheadManager {
+	return NewBulkheadManagerWithClock(time.Now)
+}
+
+// NewBulkheadManagerWithClock creates a bulkhead manager that tracks idle
+// bulkheads by the given clock.
+func NewBulkheadManagerWithClock(now func() time.Time) *BulkheadManager {
 	return &BulkheadManager{
 		bulkheads: make(map[string]*Bulkhead),
+		now:       now,
 	}
 }
 
-// Get returns a bulkhead by name, creating it if it doesn't exist.
+// Get returns a bulkhead by name, creating it if it doesn't exist. The
+// bulkhead is marked as used while the manager is locked, so a concurrent
+// RemoveIdle can't remove it before the caller enters it.
 func (m *BulkheadManager) Get(name string, cfg BulkheadConfig) *Bulkhead {
 	m.mu.RLock()
 	if b, ok := m.bulkheads[name]; ok {
+		b.touch(m.now())
 		m.mu.RUnlock()
 		return b
 	}
@@ -145,15 +182,37 @@ func (m *BulkheadManager) Get(name string, cfg BulkheadConfig) *Bulkhead {
 
 	// Double-check after acquiring write lock
 	if b, ok := m.bulkheads[name]; ok {
+		b.touch(m.now())
 		return b
 	}
 
 	cfg.Name = name
 	b := NewBulkhead(cfg)
+	b.now = m.now
+	b.lastUsed = m.now()
 	m.bulkheads[name] = b
 	return b
 }
 
+// RemoveIdle removes bulkheads that have had no requests in flight for at
+// least maxIdle and returns how many were removed. A caller still holding a
+// removed bulkhead keeps using it, while later Get calls create a new one.
+func (m *BulkheadManager) RemoveIdle(maxIdle time.Duration) int {
+	cutoff := m.now().Add(-maxIdle)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for name, b := range m.bulkheads {
+		if b.idleSince(cutoff) {
+			delete(m.bulkheads, name)
+			removed++
+		}
+	}
+	return removed
+}
+
 // AllStats returns stats for all bulkheads.
 func (m *BulkheadManager) AllStats() map[string]BulkheadStats {
 	m.mu.RLock()
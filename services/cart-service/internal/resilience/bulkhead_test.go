@@ -0,0 +1,78 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkhead_FullQueue(t *testing.T) {
+	b := NewBulkhead(BulkheadConfig{Name: "cart", MaxConcurrent: 1, MaxWaiting: 1})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Execute(context.Background(), func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	err := b.Execute(context.Background(), func() error { return nil })
+	assert.True(t, errors.Is(err, ErrBulkheadFull))
+
+	close(release)
+	assert.NoError(t, <-done)
+}
+
+func TestBulkheadManager_RemoveIdle(t *testing.T) {
+	m := NewBulkheadManager()
+	cfg := BulkheadConfig{MaxConcurrent: 1, MaxWaiting: 1}
+
+	idle := m.Get("idle", cfg)
+	_ = idle.Execute(context.Background(), func() error { return nil })
+	time.Sleep(5 * time.Millisecond)
+
+	busy := m.Get("busy", cfg)
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- busy.Execute(context.Background(), func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	time.Sleep(5 * time.Millisecond)
+
+	assert.Equal(t, 1, m.RemoveIdle(time.Millisecond))
+	stats := m.AllStats()
+	assert.NotContains(t, stats, "idle")
+	assert.Contains(t, stats, "busy")
+
+	close(release)
+	assert.NoError(t, <-done)
+}
+
+func TestBulkheadManager_RemoveIdleUsesClock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewBulkheadManagerWithClock(func() time.Time { return now })
+	cfg := BulkheadConfig{MaxConcurrent: 1, MaxWaiting: 1}
+
+	m.Get("stale", cfg)
+	m.Get("fresh", cfg)
+	now = now.Add(time.Minute)
+
+	// Getting a bulkhead marks it in use before the caller enters it
+	m.Get("fresh", cfg)
+	assert.Equal(t, 1, m.RemoveIdle(30*time.Second))
+	stats := m.AllStats()
+	assert.NotContains(t, stats, "stale")
+	assert.Contains(t, stats, "fresh")
+}
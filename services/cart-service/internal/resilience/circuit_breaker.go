@@ -5,6 +5,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/metrics"
 	"github.com/sony/gobreaker"
 )
 
@@ -17,6 +19,14 @@ type CircuitBreakerConfig struct {
 	FailureThreshold  uint32        // Failures before opening
 	SuccessThreshold  uint32        // Successes needed to close
 	FailureRatio      float64       // Ratio of failures to total requests
+
+	// OnStateChange is called whenever the breaker transitions between states.
+	// States are reported as "closed", "half-open" or "open".
+	OnStateChange func(name string, from string, to string)
+
+	// Optional observability hooks for state transitions
+	Logger  *logging.Logger
+	Metrics metrics.Collector
 }
 
 // DefaultCircuitBreakerConfig returns default configuration.
@@ -58,7 +68,21 @@ func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
 			return false
 		},
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
-			// This could log or emit metrics
+			if cfg.Logger != nil {
+				cfg.Logger.WithFields(map[string]interface{}{
+					"circuit_breaker": name,
+					"from":            stateName(from),
+					"to":              stateName(to),
+				}).Warn("Circuit breaker state changed")
+			}
+			if cfg.Metrics != nil {
+				cfg.Metrics.SetGauge(metrics.MetricCircuitBreakerState, stateCode(to), map[string]string{
+					"name": name,
+				})
+			}
+			if cfg.OnStateChange != nil {
+				cfg.OnStateChange(name, stateName(from), stateName(to))
+			}
 		},
 	}
 
@@ -81,9 +105,24 @@ func (cb *CircuitBreaker) ExecuteWithResult(ctx context.Context, fn func() (inte
 	return cb.breaker.Execute(fn)
 }
 
+// ExecuteWithFallback runs primary through the circuit breaker. If the breaker
+// is open or primary fails, fallback is invoked with the resulting error and its
+// result is returned instead, so callers can degrade gracefully.
+func (cb *CircuitBreaker) ExecuteWithFallback(ctx context.Context, primary func() error, fallback func(error) error) error {
+	err := cb.Execute(ctx, primary)
+	if err == nil || fallback == nil {
+		return err
+	}
+	return fallback(err)
+}
+
 // State returns the current state of the circuit breaker.
 func (cb *CircuitBreaker) State() string {
-	state := cb.breaker.State()
+	return stateName(cb.breaker.State())
+}
+
+// stateName converts a gobreaker state to its string representation.
+func stateName(state gobreaker.State) string {
 	switch state {
 	case gobreaker.StateClosed:
 		return "closed"
@@ -96,6 +135,19 @@ func (cb *CircuitBreaker) State() string {
 	}
 }
 
+// stateCode converts a gobreaker state to its gauge value.
+// closed=0, half-open=1, open=2.
+func stateCode(state gobreaker.State) float64 {
+	switch state {
+	case gobreaker.StateHalfOpen:
+		return 1
+	case gobreaker.StateOpen:
+		return 2
+	default:
+		return 0
+	}
+}
+
 // Name returns the circuit breaker name.
 func (cb *CircuitBreaker) Name() string {
 	return cb.name
@@ -106,6 +158,25 @@ func (cb *CircuitBreaker) Counts() gobreaker.Counts {
 	return cb.breaker.Counts()
 }
 
+// CircuitBreakerStats contains circuit breaker statistics.
+type CircuitBreakerStats struct {
+	Name      string
+	State     string
+	StateCode float64 // Value of the circuit breaker state gauge
+	Counts    gobreaker.Counts
+}
+
+// Stats returns current circuit breaker statistics.
+func (cb *CircuitBreaker) Stats() CircuitBreakerStats {
+	state, counts := cb.breaker.State(), cb.breaker.Counts()
+	return CircuitBreakerStats{
+		Name:      cb.name,
+		State:     stateName(state),
+		StateCode: stateCode(state),
+		Counts:    counts,
+	}
+}
+
 // IsOpen returns true if the circuit is open.
 func (cb *CircuitBreaker) IsOpen() bool {
 	return cb.breaker.State() == gobreaker.StateOpen
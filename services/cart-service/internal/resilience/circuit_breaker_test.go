@@ -0,0 +1,78 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_StateChangeSetsGauge(t *testing.T) {
+	collector := metrics.NewInMemoryCollector()
+	cfg := DefaultCircuitBreakerConfig("dynamodb")
+	cfg.FailureThreshold = 2
+	cfg.Metrics = collector
+
+	cb := NewCircuitBreaker(cfg)
+	labels := map[string]string{"name": "dynamodb"}
+	assert.Equal(t, float64(0), collector.GetGauge(metrics.MetricCircuitBreakerState, labels))
+
+	failure := errors.New("boom")
+	for i := 0; i < 2; i++ {
+		_ = cb.Execute(context.Background(), func() error { return failure })
+	}
+
+	assert.True(t, cb.IsOpen())
+	assert.Equal(t, float64(2), collector.GetGauge(metrics.MetricCircuitBreakerState, labels))
+}
+
+func TestCircuitBreaker_ExecuteWithFallback(t *testing.T) {
+	cfg := DefaultCircuitBreakerConfig("dynamodb")
+	cfg.FailureThreshold = 1
+	cb := NewCircuitBreaker(cfg)
+
+	failure := errors.New("boom")
+	var fallbackErrs []error
+	fallback := func(err error) error {
+		fallbackErrs = append(fallbackErrs, err)
+		return nil
+	}
+
+	// Primary failure trips the breaker and falls back
+	err := cb.ExecuteWithFallback(context.Background(), func() error { return failure }, fallback)
+	assert.NoError(t, err)
+
+	// Open breaker falls back without calling primary
+	called := false
+	err = cb.ExecuteWithFallback(context.Background(), func() error {
+		called = true
+		return nil
+	}, fallback)
+	assert.NoError(t, err)
+	assert.False(t, called)
+	assert.Len(t, fallbackErrs, 2)
+	assert.ErrorIs(t, fallbackErrs[0], failure)
+}
+
+func TestCircuitBreaker_Stats(t *testing.T) {
+	cfg := DefaultCircuitBreakerConfig("dynamodb")
+	cfg.FailureThreshold = 2
+	cb := NewCircuitBreaker(cfg)
+
+	_ = cb.Execute(context.Background(), func() error { return nil })
+	_ = cb.Execute(context.Background(), func() error { return errors.New("boom") })
+
+	stats := cb.Stats()
+	assert.Equal(t, "dynamodb", stats.Name)
+	assert.Equal(t, "closed", stats.State)
+	assert.Equal(t, float64(0), stats.StateCode)
+	assert.Equal(t, uint32(2), stats.Counts.Requests)
+	assert.Equal(t, uint32(1), stats.Counts.ConsecutiveFailures)
+
+	_ = cb.Execute(context.Background(), func() error { return errors.New("boom") })
+	stats = cb.Stats()
+	assert.Equal(t, "open", stats.State)
+	assert.Equal(t, float64(2), stats.StateCode)
+}
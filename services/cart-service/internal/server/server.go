@@ -3,6 +3,8 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -10,7 +12,13 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/redis/go-redis/v9"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/docs"
+	apimiddleware "github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/middleware"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/v1/handlers"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/app"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/config"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/resilience"
 )
 
 // Config holds server configuration.
@@ -20,6 +28,24 @@ type Config struct {
 	WriteTimeout   time.Duration
 	IdleTimeout    time.Duration
 	MaxHeaderBytes int
+
+	// PreDrainDelay is how long Shutdown keeps serving after readiness starts
+	// failing, so load balancers stop routing new requests first.
+	PreDrainDelay time.Duration
+
+	// Cart serves the /v1 cart routes, which aren't registered without it
+	Cart *handlers.CartHandler
+
+	// Admin serves the /internal cart routes, which aren't registered
+	// without it
+	Admin *handlers.AdminHandler
+
+	// GraphQL serves POST /v1/graphql, which isn't registered without it
+	GraphQL http.Handler
+
+	// IdempotencyStore keeps /v1 responses for replay to retried requests
+	// carrying the same Idempotency-Key; without it the header is ignored
+	IdempotencyStore apimiddleware.IdempotencyStore
 }
 
 // Server wraps the HTTP server with application context.
@@ -27,6 +53,27 @@ type Server struct {
 	httpServer *http.Server
 	app        *app.Application
 	router     *chi.Mux
+	limiter    rateLimiter
+
+	// Validates /v1 requests against the OpenAPI document, when enabled
+	openAPI func(http.Handler) http.Handler
+
+	// Per-route request deadlines; zero disables them
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+
+	preDrainDelay time.Duration
+
+	cart        *handlers.CartHandler
+	admin       *handlers.AdminHandler
+	graphql     http.Handler
+	idempotency apimiddleware.IdempotencyStore
+}
+
+// rateLimiter limits requests per client in each tier, in memory or in Redis.
+type rateLimiter interface {
+	MiddlewareFor(tier string) func(next http.Handler) http.Handler
+	Close() error
 }
 
 // New creates a new Server instance.
@@ -34,21 +81,55 @@ func New(cfg Config, application *app.Application) (*Server, error) {
 	router := chi.NewRouter()
 
 	// Base middleware stack
-	router.Use(middleware.RequestID)
+	router.Use(apimiddleware.RequestID)
+	router.Use(apimiddleware.Tracing)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.Timeout(60 * time.Second))
+
+	var limiter rateLimiter
+	var openAPI func(http.Handler) http.Handler
 
 	// CORS configuration
 	if application.Config != nil {
+		origins, err := apimiddleware.NewOriginMatcher(application.Config.CORSAllowedOrigins)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CORS configuration: %w", err)
+		}
+
+		// Credentials can't be allowed for every origin
+		allowCredentials := true
+		if origins.AllowsAll() {
+			allowCredentials = false
+			if application.Logger != nil {
+				application.Logger.Warn("CORS allows all origins (\"*\"); credentials are disabled for cross-origin requests")
+			}
+		}
+
 		router.Use(cors.Handler(cors.Options{
-			AllowedOrigins:   application.Config.CORSAllowedOrigins,
+			AllowOriginFunc:  origins.Allow,
 			AllowedMethods:   application.Config.CORSAllowedMethods,
 			AllowedHeaders:   application.Config.CORSAllowedHeaders,
-			ExposedHeaders:   []string{"Link", "X-Request-ID"},
-			AllowCredentials: true,
+			ExposedHeaders:   []string{"ETag", "Link", "X-Request-ID", "X-Correlation-ID"},
+			AllowCredentials: allowCredentials,
 			MaxAge:           300,
 		}))
+
+		// Rate limiting; writes get a tighter tier than reads. The limiter's cleanup
+		// goroutine is stopped on shutdown.
+		limiter, err = newRateLimiter(application.Config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit configuration: %w", err)
+		}
+		application.RegisterShutdown(func(context.Context) error {
+			return limiter.Close()
+		})
+
+		if application.Config.OpenAPIValidation {
+			openAPI, err = apimiddleware.OpenAPIValidation(docs.OpenAPI)
+			if err != nil {
+				return nil, fmt.Errorf("invalid OpenAPI validation configuration: %w", err)
+			}
+		}
 	}
 
 	srv := &Server{
@@ -60,8 +141,19 @@ func New(cfg Config, application *app.Application) (*Server, error) {
 			IdleTimeout:    cfg.IdleTimeout,
 			MaxHeaderBytes: cfg.MaxHeaderBytes,
 		},
-		app:    application,
-		router: router,
+		app:           application,
+		router:        router,
+		limiter:       limiter,
+		openAPI:       openAPI,
+		preDrainDelay: cfg.PreDrainDelay,
+		cart:          cfg.Cart,
+		admin:         cfg.Admin,
+		graphql:       cfg.GraphQL,
+		idempotency:   cfg.IdempotencyStore,
+	}
+	if application.Config != nil {
+		srv.readTimeout = application.Config.RequestReadTimeout
+		srv.writeTimeout = application.Config.RequestWriteTimeout
 	}
 
 	// Register routes
@@ -72,73 +164,302 @@ func New(cfg Config, application *app.Application) (*Server, error) {
 
 // registerRoutes sets up all HTTP routes.
 func (s *Server) registerRoutes() {
+	// Request deadlines by route group
+	readTimeout, writeTimeout := apimiddleware.Timeout(s.readTimeout), apimiddleware.Timeout(s.writeTimeout)
+
 	// Health check endpoints (no auth required)
-	s.router.Get("/health", s.handleHealth)
-	s.router.Get("/ready", s.handleReady)
+	s.router.With(readTimeout).Get("/health", s.handleHealth)
+	s.router.With(readTimeout).Get("/ready", s.app.Health.ReadinessHandler)
+
+	// Rate limit tiers (pass-through when no limiter is configured), each
+	// behind its group's deadline
+	read := chain(readTimeout, s.rateLimit(apimiddleware.TierRead))
+	write := chain(writeTimeout, s.rateLimit(apimiddleware.TierWrite))
+
+	// Internal endpoints (API key required)
+	s.router.Route("/internal", func(r chi.Router) {
+		r.Use(s.internalAuth())
+		r.With(readTimeout).Get("/resilience", s.handleResilienceStats)
+		if s.admin != nil {
+			r.With(read).Get("/carts", s.admin.ExportCarts)
+			r.With(write).Post("/cart/{userID}/replay-events", s.admin.ReplayEvents)
+			r.With(read).Get("/cart/{userID}/history", s.admin.GetCartHistory)
+		}
+	})
 
 	// API v1 routes
 	s.router.Route("/v1", func(r chi.Router) {
+		// Callers may only access their own carts once they are authenticated
+		owner := func(next http.Handler) http.Handler { return next }
+		// Routes adding items honor X-Override-Limits from override groups
+		override := owner
+		if s.app.Config != nil {
+			if auth := s.userAuth(); auth != nil {
+				r.Use(auth)
+				owner = apimiddleware.OwnershipGuard(apimiddleware.OwnershipConfig{
+					AdminGroups: s.app.Config.CartAdminGroups,
+				})
+				if s.app.Logger != nil {
+					override = handlers.OverrideLimits(s.app.Config.CartLimitOverrideGroups, s.app.Logger)
+				}
+			}
+			r.Use(apimiddleware.RequestSizeLimit(s.app.Config.MaxRequestSize))
+			r.Use(apimiddleware.JSONLimits(s.app.Config.MaxJSONDepth, s.app.Config.MaxJSONArrayLength))
+			r.Use(apimiddleware.DebugLogging(apimiddleware.DebugLogConfig{
+				APIKeys:     s.app.Config.DebugLogAPIKeys,
+				AdminGroups: s.app.Config.DebugLogAdminGroups,
+			}))
+			if s.app.Config.ResponseEnvelopeEnabled {
+				r.Use(handlers.ResponseEnvelope)
+			}
+			if !s.app.Config.StrictJSON {
+				r.Use(handlers.LenientJSON)
+			}
+			if s.app.Config.IsProduction() && len(s.app.Config.ErrorDetailRedactKeys) > 0 {
+				r.Use(handlers.RedactErrorDetails(s.app.Config.ErrorDetailRedactKeys))
+			}
+			if s.idempotency != nil {
+				r.Use(apimiddleware.Idempotency(apimiddleware.IdempotencyConfig{
+					Enabled:       s.app.Config.IdempotencyEnabled,
+					TTL:           s.app.Config.IdempotencyTTL,
+					Store:         s.idempotency,
+					IncludeDelete: s.app.Config.IdempotencyIncludeDelete,
+					Namespace:     s.app.Config.IdempotencyNamespace,
+				}))
+			}
+		}
+		if s.openAPI != nil {
+			r.Use(s.openAPI)
+		}
+		r.Use(handlers.ScopeTenant)
+
 		// Cart routes
-		r.Route("/cart/{userID}", func(r chi.Router) {
-			r.Get("/", s.handleGetCart)
-			r.Delete("/", s.handleClearCart)
-			r.Post("/items", s.handleAddItem)
-			r.Patch("/items/{itemID}", s.handleUpdateItem)
-			r.Delete("/items/{itemID}", s.handleRemoveItem)
-		})
+		if s.cart != nil {
+			r.With(write).Post("/cart", s.cart.CreateGuestCart)
+			r.Route("/cart/{userID}", func(r chi.Router) {
+				r.Use(handlers.PathParamValidator("userID", handlers.ValidateUserID))
+				r.Use(owner)
+				itemID := handlers.PathParamValidator("itemID", handlers.ValidateItemID)
+
+				r.With(read).Get("/", s.cart.GetCart)
+				r.With(read).Get("/summary", s.cart.GetSummary)
+				r.With(read).Get("/count", s.cart.GetCount)
+				// Streams outlive any request deadline, so only the rate limit applies
+				r.With(s.rateLimit(apimiddleware.TierRead)).Get("/stream", s.cart.StreamCart)
+				r.With(read).Get("/price-changes", s.cart.GetPriceChanges)
+				r.With(write).Post("/touch", s.cart.TouchCart)
+				r.With(write).Delete("/", s.cart.ClearCart)
+				r.With(write, override).Patch("/", s.cart.PatchCart)
+				r.With(write, override).Post("/restore", s.cart.RestoreCart)
+				r.With(write).Post("/lock", s.cart.LockCart)
+				r.With(write).Post("/unlock", s.cart.UnlockCart)
+				r.With(write).Put("/gift-message", s.cart.SetGiftMessage)
+				r.With(write).Post("/validate", s.cart.ValidateCart)
+				r.With(write, override).Post("/merge", s.cart.MergeCart)
+				r.With(write, override).Post("/items", s.cart.AddItem)
+				r.With(write).Put("/items/order", s.cart.ReorderItems)
+				r.With(write, override).Post("/templates/{templateID}:apply", s.cart.ApplyTemplate)
+				r.With(write, itemID).Patch("/items/{itemID}", s.cart.UpdateItem)
+				r.With(write, itemID).Delete("/items/{itemID}", s.cart.RemoveItem)
+				r.With(write, itemID).Post("/items/{itemID}/adjust", s.cart.AdjustItem)
+				r.With(read).Get("/carts", s.cart.ListCarts)
+				r.With(write).Post("/carts", s.cart.CreateCart)
+				// Additional carts, served by the handlers above
+				r.Route("/carts/{cartID}", func(r chi.Router) {
+					r.Use(handlers.SelectCart)
+					r.With(read).Get("/", s.cart.GetCart)
+					r.With(write).Delete("/", s.cart.ClearCart)
+					r.With(write, override).Post("/items", s.cart.AddItem)
+					r.With(write, itemID).Patch("/items/{itemID}", s.cart.UpdateItem)
+					r.With(write, itemID).Delete("/items/{itemID}", s.cart.RemoveItem)
+				})
+			})
+		}
+
+		// GraphQL API, authenticated like the cart routes
+		if s.graphql != nil {
+			r.With(write).Post("/graphql", s.graphql.ServeHTTP)
+		}
+
+		// Admin routes, which reject every user request without JWT auth
+		if s.admin != nil {
+			var groups []string
+			if s.app.Config != nil {
+				groups = s.app.Config.CartAdminGroups
+			}
+			r.With(apimiddleware.RequireGroups(groups), write).Post("/admin/products/{productID}/reprice", s.admin.RepriceProduct)
+		}
 	})
 }
 
-// handleHealth is the liveness probe endpoint.
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status":"ok"}`))
+// newRateLimiter returns the tiered rate limiter for cfg. With Redis enabled the
+// limits are shared by every instance; otherwise each instance limits alone.
+func newRateLimiter(cfg *config.Config) (rateLimiter, error) {
+	tiers := map[string]apimiddleware.RateLimitTier{
+		apimiddleware.TierRead: {
+			RPS:   float64(cfg.RateLimitRPS),
+			Burst: cfg.RateLimitBurst,
+		},
+		apimiddleware.TierWrite: {
+			RPS:   float64(cfg.RateLimitWriteRPS),
+			Burst: cfg.RateLimitWriteBurst,
+		},
+	}
+
+	if !cfg.RedisEnabled || cfg.RedisURL == "" {
+		return apimiddleware.NewTieredRateLimiter(tiers, cfg.RateLimitIdleTTL), nil
+	}
+
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	limiter, err := apimiddleware.NewTieredRedisRateLimiter(client, tiers, cfg.RateLimitIdleTTL)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &redisRateLimiter{RedisRateLimiter: limiter, client: client}, nil
+}
+
+// redisRateLimiter closes the Redis client along with the limiter.
+type redisRateLimiter struct {
+	*apimiddleware.RedisRateLimiter
+	client *redis.Client
 }
 
-// handleReady is the readiness probe endpoint.
-func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
-	if err := s.app.ReadinessCheck(r.Context()); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusServiceUnavailable)
-		w.Write([]byte(fmt.Sprintf(`{"status":"not ready","error":"%s"}`, err.Error())))
-		return
+// Close stops the limiter and closes its Redis client.
+func (l *redisRateLimiter) Close() error {
+	return errors.Join(l.RedisRateLimiter.Close(), l.client.Close())
+}
+
+// chain composes middlewares, the first outermost.
+func chain(mws ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status":"ready"}`))
+// rateLimit returns the rate limiting middleware for a tier.
+func (s *Server) rateLimit(tier string) func(http.Handler) http.Handler {
+	if s.limiter == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return s.limiter.MiddlewareFor(tier)
 }
 
-// Placeholder handlers - will be implemented in Phase 4
-func (s *Server) handleGetCart(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte(`{"error":"not implemented"}`))
+// internalAuth returns the API key middleware for internal endpoints. Without
+// configured keys every request is rejected.
+func (s *Server) internalAuth() func(http.Handler) http.Handler {
+	keys := make(map[string]string)
+	if s.app.Config != nil {
+		for _, key := range s.app.Config.InternalAPIKeys {
+			keys[key] = "internal"
+		}
+	}
+	return apimiddleware.APIKeyAuth(keys)
 }
 
-func (s *Server) handleClearCart(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte(`{"error":"not implemented"}`))
+// userAuth returns the JWT authentication middleware for /v1, which ScopeTenant
+// and DebugLogging rely on for the caller's claims, or nil when no signing key
+// is configured.
+func (s *Server) userAuth() func(http.Handler) http.Handler {
+	cfg := s.app.Config
+	if cfg.JWTSecretKey == "" && cfg.JWKSEndpoint == "" {
+		return nil
+	}
+	return apimiddleware.JWTAuth(apimiddleware.AuthConfig{
+		JWTSecretKey:        cfg.JWTSecretKey,
+		JWTIssuer:           cfg.JWTIssuer,
+		JWTAudience:         cfg.JWTAudience,
+		JWKSEndpoint:        cfg.JWKSEndpoint,
+		JWKSRefreshInterval: cfg.JWKSRefreshInterval,
+		ClockSkewLeeway:     cfg.JWTClockSkewLeeway,
+	})
 }
 
-func (s *Server) handleAddItem(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte(`{"error":"not implemented"}`))
+// resilienceStats is the response of the resilience stats endpoint.
+type resilienceStats struct {
+	CircuitBreakers map[string]circuitBreakerStats `json:"circuit_breakers"`
+	Bulkheads       map[string]bulkheadStats       `json:"bulkheads"`
+}
+
+type circuitBreakerStats struct {
+	State     string                `json:"state"`
+	StateCode float64               `json:"state_code"` // circuit_breaker_state gauge: closed=0, half-open=1, open=2
+	Counts    *circuitBreakerCounts `json:"counts,omitempty"`
 }
 
-func (s *Server) handleUpdateItem(w http.ResponseWriter, r *http.Request) {
+type circuitBreakerCounts struct {
+	Requests             uint32 `json:"requests"`
+	TotalSuccesses       uint32 `json:"total_successes"`
+	TotalFailures        uint32 `json:"total_failures"`
+	ConsecutiveSuccesses uint32 `json:"consecutive_successes"`
+	ConsecutiveFailures  uint32 `json:"consecutive_failures"`
+}
+
+type bulkheadStats struct {
+	Active        int     `json:"active"`
+	MaxConcurrent int     `json:"max_concurrent"`
+	Waiting       int     `json:"waiting"`
+	MaxWaiting    int     `json:"max_waiting"`
+	Utilization   float64 `json:"utilization"` // Active / MaxConcurrent
+}
+
+// handleResilienceStats reports the state of circuit breakers and bulkheads.
+func (s *Server) handleResilienceStats(w http.ResponseWriter, r *http.Request) {
+	stats := resilienceStats{
+		CircuitBreakers: make(map[string]circuitBreakerStats),
+		Bulkheads:       make(map[string]bulkheadStats),
+	}
+
+	for name, cb := range s.app.AllCircuitBreakers() {
+		breaker := circuitBreakerStats{State: cb.State()}
+		if detailed, ok := cb.(interface {
+			Stats() resilience.CircuitBreakerStats
+		}); ok {
+			cbStats := detailed.Stats()
+			breaker.StateCode = cbStats.StateCode
+			breaker.Counts = &circuitBreakerCounts{
+				Requests:             cbStats.Counts.Requests,
+				TotalSuccesses:       cbStats.Counts.TotalSuccesses,
+				TotalFailures:        cbStats.Counts.TotalFailures,
+				ConsecutiveSuccesses: cbStats.Counts.ConsecutiveSuccesses,
+				ConsecutiveFailures:  cbStats.Counts.ConsecutiveFailures,
+			}
+		}
+		stats.CircuitBreakers[name] = breaker
+	}
+
+	if s.app.Bulkheads != nil {
+		for name, b := range s.app.Bulkheads.AllStats() {
+			bulkhead := bulkheadStats{
+				Active:        b.Active,
+				MaxConcurrent: b.MaxConcurrent,
+				Waiting:       b.Waiting,
+				MaxWaiting:    b.MaxWaiting,
+			}
+			if b.MaxConcurrent > 0 {
+				bulkhead.Utilization = float64(b.Active) / float64(b.MaxConcurrent)
+			}
+			stats.Bulkheads[name] = bulkhead
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte(`{"error":"not implemented"}`))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
 }
 
-func (s *Server) handleRemoveItem(w http.ResponseWriter, r *http.Request) {
+// handleHealth is the liveness probe endpoint.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte(`{"error":"not implemented"}`))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"ok"}`))
 }
 
 // ListenAndServe starts the HTTP server.
@@ -146,8 +467,23 @@ func (s *Server) ListenAndServe() error {
 	return s.httpServer.ListenAndServe()
 }
 
-// Shutdown gracefully shuts down the server.
+// Shutdown gracefully shuts down the server. Readiness fails immediately,
+// requests keep being served for the pre-drain delay, then in-flight requests
+// are drained. Liveness stays healthy throughout.
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.app.Health != nil {
+		s.app.Health.StartDraining()
+	}
+
+	if s.preDrainDelay > 0 {
+		timer := time.NewTimer(s.preDrainDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
 	return s.httpServer.Shutdown(ctx)
 }
 
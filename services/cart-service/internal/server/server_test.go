@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/graphql"
+	apimiddleware "github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/middleware"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/v1/handlers"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/app"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/config"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer creates a server on the configuration loaded from the
+// environment, serving carts from an in-memory repository.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	return newTestServerWithStore(t, apimiddleware.NewInMemoryIdempotencyStore())
+}
+
+// newTestServerWithStore is newTestServer with the given idempotency store.
+func newTestServerWithStore(t *testing.T, store apimiddleware.IdempotencyStore) *Server {
+	t.Helper()
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	logger := logging.New(logging.Config{Level: "error", ServiceName: "cart-service-test", Environment: "test", Output: io.Discard})
+	application, err := app.New(context.Background(), app.WithConfig(cfg), app.WithLogger(logger))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = application.Shutdown(context.Background()) })
+
+	repo := inmemory.NewRepository()
+	service := cart.NewService(repo, nil, app.CartServiceConfig(cfg), cart.WithProductCartFinder(repo))
+	graphqlHandler, err := graphql.NewHandler(service, logger)
+	require.NoError(t, err)
+	srv, err := New(Config{
+		Cart:    handlers.NewCartHandler(service, logger),
+		Admin:   handlers.NewAdminHandler(service, logger),
+		GraphQL: graphqlHandler,
+
+		IdempotencyStore: store,
+	}, application)
+	require.NoError(t, err)
+	return srv
+}
+
+func serve(srv *Server, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, req)
+	return rec
+}
+
+// bearerHeader returns an Authorization header with an HS256 token for userID.
+func bearerHeader(t *testing.T, secret, userID string, groups ...string) http.Header {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &apimiddleware.UserClaims{
+		UserID: userID,
+		Groups: groups,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+func TestServer_CartRoutes(t *testing.T) {
+	srv := newTestServer(t)
+
+	rec := serve(srv, http.MethodPost, "/v1/cart/user-123/items", `{"product_id": "product-1", "quantity": 2, "unit_price": 1999}`, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	for _, tt := range []struct {
+		method, path string
+		wantStatus   int
+	}{
+		{http.MethodGet, "/v1/cart/user-123", http.StatusOK},
+		{http.MethodGet, "/v1/cart/user-123/summary", http.StatusOK},
+		{http.MethodGet, "/v1/cart/user-123/count", http.StatusOK},
+		{http.MethodPost, "/v1/cart/user-123/touch", http.StatusOK},
+		{http.MethodGet, "/v1/cart/user-123/carts", http.StatusOK},
+	} {
+		rec := serve(srv, tt.method, tt.path, "", nil)
+		assert.Equal(t, tt.wantStatus, rec.Code, "%s %s: %s", tt.method, tt.path, rec.Body.String())
+	}
+}
+
+func TestServer_CartOwnership(t *testing.T) {
+	const secret = "ownership-test-secret"
+	t.Setenv("JWT_SECRET_KEY", secret)
+	t.Setenv("CART_ADMIN_GROUPS", "support")
+	srv := newTestServer(t)
+
+	bearer := func(userID string, groups ...string) http.Header {
+		return bearerHeader(t, secret, userID, groups...)
+	}
+
+	addItem := `{"product_id": "product-1", "quantity": 1, "unit_price": 1000}`
+	rec := serve(srv, http.MethodPost, "/v1/cart/user-123/items", addItem, bearer("user-123"))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// Another user's token can neither read nor modify the cart
+	rec = serve(srv, http.MethodGet, "/v1/cart/user-123", "", bearer("user-456"))
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "FORBIDDEN")
+	rec = serve(srv, http.MethodPost, "/v1/cart/user-123/items", addItem, bearer("user-456"))
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	rec = serve(srv, http.MethodDelete, "/v1/cart/user-123", "", bearer("user-456"))
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+
+	// Without a token the request isn't authenticated at all
+	rec = serve(srv, http.MethodGet, "/v1/cart/user-123", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, rec.Body.String())
+
+	// Owners and admin groups get through
+	rec = serve(srv, http.MethodGet, "/v1/cart/user-123", "", bearer("user-123"))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = serve(srv, http.MethodGet, "/v1/cart/user-123", "", bearer("agent-1", "support"))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Only admin groups may reprice products
+	reprice := `{"unit_price": 900}`
+	rec = serve(srv, http.MethodPost, "/v1/admin/products/product-1/reprice", reprice, bearer("user-123"))
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	rec = serve(srv, http.MethodPost, "/v1/admin/products/product-1/reprice", reprice, bearer("agent-1", "support"))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = serve(srv, http.MethodGet, "/v1/cart/user-123", "", bearer("user-123"))
+	assert.Contains(t, rec.Body.String(), `"unit_price":900`)
+}
+
+func TestServer_RedisRateLimit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	t.Setenv("REDIS_ENABLED", "true")
+	t.Setenv("REDIS_URL", "redis://"+mr.Addr())
+	t.Setenv("RATE_LIMIT_WRITE_BURST", "1")
+	srv := newTestServer(t)
+
+	// The write bucket lives in Redis, so it is shared with other instances
+	addItem := `{"product_id": "product-1", "quantity": 1, "unit_price": 1000}`
+	rec := serve(srv, http.MethodPost, "/v1/cart/user-123/items", addItem, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = serve(srv, http.MethodPost, "/v1/cart/user-123/items", addItem, nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	assert.NotEmpty(t, mr.Keys())
+
+	rec = serve(srv, http.MethodGet, "/v1/cart/user-123", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestServer_InvalidRedisURL(t *testing.T) {
+	t.Setenv("REDIS_ENABLED", "true")
+	t.Setenv("REDIS_URL", "not-a-redis-url")
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	application, err := app.New(context.Background(), app.WithConfig(cfg))
+	require.NoError(t, err)
+	_, err = New(Config{}, application)
+	assert.Error(t, err)
+}
+
+func TestServer_IdempotentDelete(t *testing.T) {
+	for _, includeDelete := range []bool{false, true} {
+		t.Run(fmt.Sprintf("include delete %t", includeDelete), func(t *testing.T) {
+			t.Setenv("IDEMPOTENCY_INCLUDE_DELETE", strconv.FormatBool(includeDelete))
+			srv := newTestServer(t)
+
+			rec := serve(srv, http.MethodPost, "/v1/cart/user-123/items", `{"product_id": "product-1", "quantity": 1, "unit_price": 1000}`, nil)
+			require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+			var created struct {
+				Items []struct {
+					ItemID string `json:"item_id"`
+				} `json:"items"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+			require.Len(t, created.Items, 1)
+
+			remove := "/v1/cart/user-123/items/" + created.Items[0].ItemID
+			key := http.Header{"Idempotency-Key": {"remove-1"}}
+			rec = serve(srv, http.MethodDelete, remove, "", key)
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			// A retry replays the removal only when DELETEs are covered
+			rec = serve(srv, http.MethodDelete, remove, "", key)
+			if includeDelete {
+				assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+				assert.Equal(t, "true", rec.Header().Get("X-Idempotent-Replayed"))
+			} else {
+				assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+// keyRecordingStore records the keys responses are stored under.
+type keyRecordingStore struct {
+	*apimiddleware.InMemoryIdempotencyStore
+	keys []string
+}
+
+func (s *keyRecordingStore) Set(ctx context.Context, key string, record *apimiddleware.IdempotencyRecord, ttl time.Duration) error {
+	s.keys = append(s.keys, key)
+	return s.InMemoryIdempotencyStore.Set(ctx, key, record, ttl)
+}
+
+func TestServer_IdempotencyNamespace(t *testing.T) {
+	t.Setenv("SERVICE_NAME", "cart-service")
+	t.Setenv("ENV_NAME", "staging")
+	store := &keyRecordingStore{InMemoryIdempotencyStore: apimiddleware.NewInMemoryIdempotencyStore()}
+	srv := newTestServerWithStore(t, store)
+
+	header := http.Header{"Idempotency-Key": {"add-1"}}
+	rec := serve(srv, http.MethodPost, "/v1/cart/user-123/items", `{"product_id": "product-1", "quantity": 1, "unit_price": 1000}`, header)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	require.Len(t, store.keys, 1)
+	assert.True(t, strings.HasPrefix(store.keys[0], "cart-service:staging:"), store.keys[0])
+}
+
+func TestServer_OverrideLimits(t *testing.T) {
+	const secret = "override-test-secret"
+	t.Setenv("JWT_SECRET_KEY", secret)
+	t.Setenv("CART_ADMIN_GROUPS", "support")
+	t.Setenv("CART_LIMIT_OVERRIDE_GROUPS", "support")
+	t.Setenv("MAX_ITEMS_PER_CART", "1")
+	t.Setenv("ADMIN_MAX_ITEMS_PER_CART", "2")
+	srv := newTestServer(t)
+
+	add := func(productID string, header http.Header) int {
+		body := `{"product_id": "` + productID + `", "quantity": 1, "unit_price": 1000}`
+		return serve(srv, http.MethodPost, "/v1/cart/user-123/items", body, header).Code
+	}
+	withOverride := func(header http.Header) http.Header {
+		header.Set(handlers.HeaderOverrideLimits, "true")
+		return header
+	}
+
+	require.Equal(t, http.StatusCreated, add("product-1", bearerHeader(t, secret, "user-123")))
+
+	// Only override groups lift the limit, and only when asking to
+	assert.Equal(t, http.StatusBadRequest, add("product-2", withOverride(bearerHeader(t, secret, "user-123"))))
+	assert.Equal(t, http.StatusBadRequest, add("product-2", bearerHeader(t, secret, "agent-1", "support")))
+	assert.Equal(t, http.StatusCreated, add("product-2", withOverride(bearerHeader(t, secret, "agent-1", "support"))))
+}
+
+func TestServer_GraphQL(t *testing.T) {
+	const secret = "graphql-test-secret"
+	t.Setenv("JWT_SECRET_KEY", secret)
+	srv := newTestServer(t)
+
+	query := `{"query": "mutation { addItem(userID: \"user-123\", productId: \"product-1\", quantity: 1) { itemCount } }"}`
+	rec := serve(srv, http.MethodPost, "/v1/graphql", query, bearerHeader(t, secret, "user-123"))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"itemCount":1`)
+
+	rec = serve(srv, http.MethodPost, "/v1/graphql", query, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, rec.Body.String())
+}
+
+func TestServer_AdditionalCarts(t *testing.T) {
+	srv := newTestServer(t)
+
+	rec := serve(srv, http.MethodPost, "/v1/cart/user-123/carts", `{"name": "gifts"}`, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.NotEmpty(t, created.ID)
+
+	path := "/v1/cart/user-123/carts/" + created.ID
+	rec = serve(srv, http.MethodPost, path+"/items", `{"product_id": "product-1", "quantity": 1, "unit_price": 1000}`, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// The item lands in the selected cart, not the default cart
+	rec = serve(srv, http.MethodGet, path, "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "product-1")
+	rec = serve(srv, http.MethodGet, "/v1/cart/user-123", "", nil)
+	assert.NotContains(t, rec.Body.String(), "product-1")
+}
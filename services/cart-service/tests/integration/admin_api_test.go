@@ -0,0 +1,225 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/v1/handlers"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher records price_corrected events for assertions.
+type recordingPublisher struct {
+	mu             sync.Mutex
+	priceCorrected map[string]int64 // user ID -> previous price
+}
+
+func (p *recordingPublisher) PublishCartCreated(ctx context.Context, c *cart.Cart) error { return nil }
+func (p *recordingPublisher) PublishItemAdded(ctx context.Context, c *cart.Cart, item *cart.CartItem) error {
+	return nil
+}
+func (p *recordingPublisher) PublishItemRemoved(ctx context.Context, c *cart.Cart, itemID, productID string) error {
+	return nil
+}
+func (p *recordingPublisher) PublishItemUpdated(ctx context.Context, c *cart.Cart, item *cart.CartItem, prevQuantity int) error {
+	return nil
+}
+func (p *recordingPublisher) PublishCartCleared(ctx context.Context, c *cart.Cart, itemsRemoved int, previousTotal int64) error {
+	return nil
+}
+func (p *recordingPublisher) PublishCartMerged(ctx context.Context, c *cart.Cart, guestID string, itemsMerged int) error {
+	return nil
+}
+func (p *recordingPublisher) PublishItemsPruned(ctx context.Context, c *cart.Cart, items []cart.CartItem, action cart.StaleItemAction) error {
+	return nil
+}
+func (p *recordingPublisher) PublishItemsReordered(ctx context.Context, c *cart.Cart) error {
+	return nil
+}
+func (p *recordingPublisher) PublishPriceCorrected(ctx context.Context, c *cart.Cart, item *cart.CartItem, previousPrice int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.priceCorrected == nil {
+		p.priceCorrected = make(map[string]int64)
+	}
+	p.priceCorrected[c.UserID] = previousPrice
+	return nil
+}
+
+func setupAdminTestRouter() (*chi.Mux, *cart.Service, *recordingPublisher) {
+	repo := inmemory.NewRepository()
+	publisher := &recordingPublisher{}
+	logger := logging.New(logging.Config{
+		Level:       "debug",
+		ServiceName: "cart-service-test",
+		Environment: "test",
+	})
+
+	service := cart.NewService(repo, publisher, cart.ServiceConfig{
+		PublishEvents:   true,
+		SnapshotHistory: true,
+	}, cart.WithProductCartFinder(repo), cart.WithCartScanner(repo))
+
+	handler := handlers.NewAdminHandler(service, logger)
+
+	r := chi.NewRouter()
+	r.Route("/v1/admin", func(r chi.Router) {
+		r.Post("/products/{productID}/reprice", handler.RepriceProduct)
+	})
+	r.Get("/internal/carts", handler.ExportCarts)
+	r.Get("/internal/cart/{userID}/history", handler.GetCartHistory)
+
+	return r, service, publisher
+}
+
+func TestAdminAPI_RepriceProduct(t *testing.T) {
+	router, service, publisher := setupAdminTestRouter()
+	ctx := context.Background()
+
+	// Seed carts: two hold the product at stale prices, one already has the new price,
+	// and one doesn't hold it at all
+	_, err := service.AddItem(ctx, "user-1", cart.AddItemRequest{ProductID: "product-1", Quantity: 2, UnitPrice: 1000})
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, "user-2", cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 900})
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, "user-3", cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 1200})
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, "user-4", cart.AddItemRequest{ProductID: "product-2", Quantity: 1, UnitPrice: 500})
+	require.NoError(t, err)
+
+	body, _ := json.Marshal(map[string]interface{}{"unit_price": 1200})
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/products/product-1/reprice", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result cart.RepriceResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, 3, result.CartsMatched)
+	assert.Equal(t, 2, result.CartsUpdated)
+	assert.Equal(t, 0, result.CartsFailed)
+
+	for _, userID := range []string{"user-1", "user-2", "user-3"} {
+		c, err := service.GetCart(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1200), c.Items[0].UnitPrice, userID)
+	}
+
+	// Versions are bumped only for carts that changed
+	c, err := service.GetCart(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), c.Version)
+
+	other, err := service.GetCart(ctx, "user-4")
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), other.Items[0].UnitPrice)
+
+	assert.Equal(t, map[string]int64{"user-1": 1000, "user-2": 900}, publisher.priceCorrected)
+}
+
+func TestAdminAPI_RepriceProduct_InvalidRequest(t *testing.T) {
+	router, _, _ := setupAdminTestRouter()
+
+	body, _ := json.Marshal(map[string]interface{}{"unit_price": -1})
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/products/product-1/reprice", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAdminAPI_ExportCarts(t *testing.T) {
+	router, service, _ := setupAdminTestRouter()
+	ctx := context.Background()
+
+	for _, userID := range []string{"user-c", "user-a", "user-e", "user-b", "user-d"} {
+		_, err := service.AddItem(ctx, userID, cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 1000})
+		require.NoError(t, err)
+	}
+
+	export := func(query string) (int, handlers.CartListResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/internal/carts"+query, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var resp handlers.CartListResponse
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		}
+		return rec.Code, resp
+	}
+
+	var users []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5, "export did not terminate")
+
+		status, resp := export("?limit=2&cursor=" + cursor)
+		require.Equal(t, http.StatusOK, status)
+		assert.LessOrEqual(t, len(resp.Carts), 2)
+		for _, c := range resp.Carts {
+			users = append(users, c.UserID)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	assert.Equal(t, []string{"user-a", "user-b", "user-c", "user-d", "user-e"}, users)
+
+	status, _ := export("?limit=0")
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = export("?cursor=%25%25")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestAdminAPI_GetCartHistory(t *testing.T) {
+	router, service, _ := setupAdminTestRouter()
+	ctx := context.Background()
+
+	c, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 2, UnitPrice: 1000})
+	require.NoError(t, err)
+	_, err = service.UpdateItemQuantity(ctx, "user-123", cart.UpdateItemRequest{ItemID: c.Items[0].ItemID, Quantity: 3})
+	require.NoError(t, err)
+	require.NoError(t, service.ClearCart(ctx, "user-123"))
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/cart/user-123/history", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handlers.CartHistoryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Snapshots, 3)
+
+	var operations []string
+	for _, snapshot := range resp.Snapshots {
+		operations = append(operations, snapshot.Operation)
+	}
+	assert.Equal(t, []string{"add_item", "update_item", "clear_cart"}, operations)
+	assert.Equal(t, int64(3000), resp.Snapshots[1].Total)
+	assert.Equal(t, 3, resp.Snapshots[1].Items[0].Quantity)
+	assert.Empty(t, resp.Snapshots[2].Items)
+	assert.Less(t, resp.Snapshots[0].Version, resp.Snapshots[2].Version)
+
+	// A user without changes has an empty history
+	req = httptest.NewRequest(http.MethodGet, "/internal/cart/user-456/history", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Snapshots)
+}
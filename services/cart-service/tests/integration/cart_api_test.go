@@ -2,23 +2,34 @@
 package integration
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	apimiddleware "github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/middleware"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/v1/handlers"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
 	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/inmemory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 func setupTestRouter() (*chi.Mux, *cart.Service) {
+	return setupTestRouterWithOptions()
+}
+
+func setupTestRouterWithOptions(opts ...cart.ServiceOption) (*chi.Mux, *cart.Service) {
 	repo := inmemory.NewRepository()
 	logger := logging.New(logging.Config{
 		Level:       "debug",
@@ -28,17 +39,44 @@ func setupTestRouter() (*chi.Mux, *cart.Service) {
 
 	service := cart.NewService(repo, nil, cart.ServiceConfig{
 		PublishEvents: false,
-	})
+	}, opts...)
 
 	handler := handlers.NewCartHandler(service, logger)
 
 	r := chi.NewRouter()
 	r.Route("/v1/cart/{userID}", func(r chi.Router) {
+		r.Use(handlers.PathParamValidator("userID", handlers.ValidateUserID))
+		itemID := handlers.PathParamValidator("itemID", handlers.ValidateItemID)
+
 		r.Get("/", handler.GetCart)
+		r.Get("/summary", handler.GetSummary)
+		r.Get("/count", handler.GetCount)
+		r.Get("/stream", handler.StreamCart)
+		r.Post("/touch", handler.TouchCart)
 		r.Delete("/", handler.ClearCart)
+		r.Patch("/", handler.PatchCart)
+		r.Post("/restore", handler.RestoreCart)
+		r.Post("/lock", handler.LockCart)
+		r.Post("/unlock", handler.UnlockCart)
+		r.Put("/gift-message", handler.SetGiftMessage)
+		r.Post("/validate", handler.ValidateCart)
+		r.Get("/price-changes", handler.GetPriceChanges)
 		r.Post("/items", handler.AddItem)
-		r.Patch("/items/{itemID}", handler.UpdateItem)
-		r.Delete("/items/{itemID}", handler.RemoveItem)
+		r.Put("/items/order", handler.ReorderItems)
+		r.Post("/templates/{templateID}:apply", handler.ApplyTemplate)
+		r.With(itemID).Patch("/items/{itemID}", handler.UpdateItem)
+		r.With(itemID).Delete("/items/{itemID}", handler.RemoveItem)
+		r.With(itemID).Post("/items/{itemID}/adjust", handler.AdjustItem)
+		r.Get("/carts", handler.ListCarts)
+		r.Post("/carts", handler.CreateCart)
+		r.Route("/carts/{cartID}", func(r chi.Router) {
+			r.Use(handlers.SelectCart)
+			r.Get("/", handler.GetCart)
+			r.Delete("/", handler.ClearCart)
+			r.Post("/items", handler.AddItem)
+			r.With(itemID).Patch("/items/{itemID}", handler.UpdateItem)
+			r.With(itemID).Delete("/items/{itemID}", handler.RemoveItem)
+		})
 	})
 
 	return r, service
@@ -80,6 +118,39 @@ func TestCartAPI_AddItem(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name:   "if_not_exists rejects product already in cart",
+			userID: "user-123",
+			body: map[string]interface{}{
+				"product_id":    "product-1",
+				"quantity":      1,
+				"unit_price":    1999,
+				"if_not_exists": true,
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:   "if_not_exists adds new product",
+			userID: "user-123",
+			body: map[string]interface{}{
+				"product_id":    "product-2",
+				"quantity":      1,
+				"unit_price":    999,
+				"if_not_exists": true,
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:   "if_not_exists with add_mode",
+			userID: "user-123",
+			body: map[string]interface{}{
+				"product_id":    "product-3",
+				"quantity":      1,
+				"add_mode":      "set",
+				"if_not_exists": true,
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -237,3 +308,1144 @@ func TestCartAPI_InvalidUserID(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestCartAPI_InvalidPathParams(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "user ID on item route", method: http.MethodPost, path: "/v1/cart/bad$user/items"},
+		{name: "user ID on cart subroute", method: http.MethodGet, path: "/v1/cart/bad$user/carts/cart-1/"},
+		{name: "item ID on update", method: http.MethodPatch, path: "/v1/cart/user-123/items/bad$item"},
+		{name: "item ID on remove", method: http.MethodDelete, path: "/v1/cart/user-123/items/bad$item"},
+		{name: "item ID on adjust", method: http.MethodPost, path: "/v1/cart/user-123/items/bad$item/adjust"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No body: the path is rejected before the handler decodes one
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			var resp handlers.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Contains(t, resp.Message, "Invalid")
+		})
+	}
+}
+
+func TestCartAPI_ReorderItems(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	var itemIDs []string
+	for _, productID := range []string{"product-1", "product-2", "product-3"} {
+		c, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{
+			ProductID: productID,
+			Quantity:  1,
+			UnitPrice: 1000,
+		})
+		require.NoError(t, err)
+		itemIDs = append(itemIDs, c.Items[len(c.Items)-1].ItemID)
+	}
+
+	// Move the last item to the front, leaving the others unlisted
+	body, _ := json.Marshal(map[string]interface{}{
+		"item_ids": []string{itemIDs[2]},
+	})
+	req := httptest.NewRequest(http.MethodPut, "/v1/cart/user-123/items/order", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Verify the order survives a subsequent read
+	c, err := service.GetCart(ctx, "user-123")
+	require.NoError(t, err)
+	require.Len(t, c.Items, 3)
+	assert.Equal(t, itemIDs[2], c.Items[0].ItemID)
+	assert.Equal(t, itemIDs[0], c.Items[1].ItemID)
+	assert.Equal(t, itemIDs[1], c.Items[2].ItemID)
+}
+
+func TestCartAPI_ApplyTemplate_PartiallyExceedsLimits(t *testing.T) {
+	templates := inmemory.NewTemplateStore()
+	router, service := setupTestRouterWithOptions(cart.WithTemplateStore(templates))
+	ctx := context.Background()
+
+	require.NoError(t, templates.SaveTemplate(ctx, &cart.Template{
+		ID:   "office-supplies",
+		Name: "Office supplies",
+		Lines: []cart.TemplateLine{
+			{ProductID: "paper", Quantity: 10, UnitPrice: 500},
+			{ProductID: "pens", Quantity: 5, UnitPrice: 200},
+			{ProductID: "toner", Quantity: 2, UnitPrice: 4500},
+		},
+	}))
+
+	// Existing pens push the merged quantity over the per-item limit
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{
+		ProductID: "pens",
+		Quantity:  cart.MaxQuantityPerItem,
+		UnitPrice: 200,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/templates/office-supplies:apply", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response handlers.ApplyTemplateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	require.Len(t, response.Added, 2)
+	assert.Equal(t, "paper", response.Added[0].ProductID)
+	assert.Equal(t, "toner", response.Added[1].ProductID)
+
+	require.Len(t, response.Skipped, 1)
+	assert.Equal(t, "pens", response.Skipped[0].ProductID)
+	assert.Equal(t, "QUANTITY_LIMIT_EXCEEDED", response.Skipped[0].Reason)
+
+	assert.Len(t, response.Cart.Items, 3)
+}
+
+func TestCartAPI_ApplyTemplate_NotFound(t *testing.T) {
+	router, _ := setupTestRouterWithOptions(cart.WithTemplateStore(inmemory.NewTemplateStore()))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/templates/missing:apply", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCartAPI_GetSummary_ConditionalGet(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{
+		ProductID: "product-1",
+		Quantity:  2,
+		UnitPrice: 1999,
+	})
+	require.NoError(t, err)
+
+	// First request returns the summary and its ETag
+	req := httptest.NewRequest(http.MethodGet, "/v1/cart/user-123/summary", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// Matching ETag yields 304 with no body
+	req = httptest.NewRequest(http.MethodGet, "/v1/cart/user-123/summary", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+
+	// A changed cart yields 200 with a new ETag
+	_, err = service.AddItem(ctx, "user-123", cart.AddItemRequest{
+		ProductID: "product-2",
+		Quantity:  1,
+		UnitPrice: 500,
+	})
+	require.NoError(t, err)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/cart/user-123/summary", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	var summary cart.CartSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 2, summary.ItemCount)
+}
+
+func TestCartAPI_TouchCart(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	c, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 1000})
+	require.NoError(t, err)
+	previousExpiry := c.ExpiresAt
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/touch", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handlers.CartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.False(t, resp.ExpiresAt.Before(previousExpiry))
+
+	// Touching a missing cart is a 404
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-404/touch", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCartAPI_RestoreCart(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 2, UnitPrice: 1000})
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-2", Quantity: 1, UnitPrice: 500})
+	require.NoError(t, err)
+
+	// Nothing to restore before a clear
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/restore", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.NoError(t, service.ClearCart(ctx, "user-123"))
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/restore", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handlers.CartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.ItemCount)
+	assert.Equal(t, int64(2500), resp.TotalPrice)
+
+	// The snapshot is consumed by the restore
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/restore", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCartAPI_SetGiftMessage(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 1000})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{"sets message", `{"gift_message": "Happy birthday!\u0007"}`, http.StatusOK, "Happy birthday!"},
+		{"rejects markup", `{"gift_message": "<script>alert(1)</script>"}`, http.StatusBadRequest, ""},
+		{"rejects script url", `{"gift_message": "javascript:alert(1)"}`, http.StatusBadRequest, ""},
+		{"rejects too long", `{"gift_message": "` + strings.Repeat("a", 501) + `"}`, http.StatusBadRequest, ""},
+		{"clears message", `{"gift_message": ""}`, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/v1/cart/user-123/gift-message", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			require.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp handlers.CartResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, tt.expectedMsg, resp.GiftMessage)
+			}
+		})
+	}
+}
+
+func TestCartAPI_ResponseEnvelope(t *testing.T) {
+	router, _ := setupTestRouter()
+	handler := apimiddleware.RequestID(handlers.ResponseEnvelope(router))
+
+	body := `{"product_id": "product-1", "quantity": 1, "unit_price": 1000}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var created struct {
+		Data handlers.CartResponse `json:"data"`
+		Meta handlers.EnvelopeMeta `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "user-123", created.Data.UserID)
+	assert.Equal(t, "req-1", created.Meta.RequestID)
+	assert.Equal(t, created.Data.Version, created.Meta.Version)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/cart/user-456", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	var failed handlers.Envelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &failed))
+	require.NotNil(t, failed.Error)
+	assert.Equal(t, "CART_NOT_FOUND", failed.Error.Code)
+	assert.Nil(t, failed.Data)
+	assert.NotEmpty(t, failed.Meta.RequestID)
+}
+
+func TestCartAPI_AdjustItem(t *testing.T) {
+	router, service := setupTestRouter()
+
+	c, err := service.AddItem(context.Background(), "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 2, UnitPrice: 1000})
+	require.NoError(t, err)
+	path := "/v1/cart/user-123/items/" + c.Items[0].ItemID + "/adjust"
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{"increment", `{"delta": 3}`, http.StatusOK, 1},
+		{"zero delta", `{"delta": 0}`, http.StatusBadRequest, 1},
+		{"out of range", `{"delta": -100}`, http.StatusBadRequest, 1},
+		{"decrement to zero removes", `{"delta": -5}`, http.StatusOK, 0},
+		{"removed item", `{"delta": 1}`, http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			require.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp handlers.CartResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, tt.expectedCount, resp.ItemCount)
+			}
+		})
+	}
+}
+
+func TestCartAPI_LocalizedErrors(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		locale         string
+		message        string
+	}{
+		{"default", "", "en", "Cart not found"},
+		{"unsupported locale", "fr-FR", "en", "Cart not found"},
+		{"base language match", "fr;q=0.8, de-AT", "de", "Warenkorb nicht gefunden"},
+		{"weighted preference", "de;q=0.5, en;q=0.9", "en", "Cart not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/cart/user-404", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusNotFound, rec.Code)
+
+			var resp handlers.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "CART_NOT_FOUND", resp.Code)
+			assert.Equal(t, tt.message, resp.Message)
+			assert.Equal(t, tt.locale, rec.Header().Get("Content-Language"))
+		})
+	}
+}
+
+func TestCartAPI_GetCart_Sort(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	for _, req := range []cart.AddItemRequest{
+		{ProductID: "product-b", Quantity: 1, UnitPrice: 500},
+		{ProductID: "product-c", Quantity: 1, UnitPrice: 2000},
+		{ProductID: "product-a", Quantity: 1, UnitPrice: 1000},
+	} {
+		_, err := service.AddItem(ctx, "user-123", req)
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+
+	tests := []struct {
+		sort     string
+		expected []string
+	}{
+		{"", []string{"product-b", "product-c", "product-a"}},
+		{"added_asc", []string{"product-b", "product-c", "product-a"}},
+		{"added_desc", []string{"product-a", "product-c", "product-b"}},
+		{"product_asc", []string{"product-a", "product-b", "product-c"}},
+		{"price_desc", []string{"product-c", "product-a", "product-b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("sort="+tt.sort, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/cart/user-123?sort="+tt.sort, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var resp handlers.CartResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			products := make([]string, len(resp.Items))
+			for i, item := range resp.Items {
+				products[i] = item.ProductID
+			}
+			assert.Equal(t, tt.expected, products)
+		})
+	}
+
+	// Stored order is unchanged
+	c, err := service.GetCart(ctx, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, "product-b", c.Items[0].ProductID)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/cart/user-123?sort=newest", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCartAPI_GetCart_ConditionalRequests(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	c, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{
+		ProductID: "product-1",
+		Quantity:  2,
+		UnitPrice: 1999,
+	})
+	require.NoError(t, err)
+	itemID := c.Items[0].ItemID
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/cart/user-123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, fmt.Sprintf(`W/"v%d"`, c.Version), etag)
+
+	// Unchanged cart yields 304
+	req = httptest.NewRequest(http.MethodGet, "/v1/cart/user-123", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// Matching If-Match updates the item and returns the new ETag
+	body, _ := json.Marshal(map[string]interface{}{"quantity": 3})
+	req = httptest.NewRequest(http.MethodPatch, "/v1/cart/user-123/items/"+itemID, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// The stale ETag no longer matches
+	body, _ = json.Marshal(map[string]interface{}{"quantity": 4})
+	req = httptest.NewRequest(http.MethodPatch, "/v1/cart/user-123/items/"+itemID, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	var errResp handlers.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, "PRECONDITION_FAILED", errResp.Code)
+}
+
+func TestCartAPI_GetCount(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	getCount := func() (int, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/cart/user-123/count", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response handlers.CartCountResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Count, w
+	}
+
+	// Missing cart counts as empty
+	count, w := getCount()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, count)
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 2, UnitPrice: 1000})
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-2", Quantity: 3, UnitPrice: 500})
+	require.NoError(t, err)
+
+	count, w = getCount()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 5, count)
+	assert.Contains(t, w.Header().Get("Cache-Control"), "no-store")
+}
+
+func TestCartAPI_StreamCart(t *testing.T) {
+	router, service := setupTestRouterWithOptions(cart.WithChangeFeed(cart.NewChangeFeed()))
+	_, err := service.AddItem(context.Background(), "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 500})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/cart/user-123/stream", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, ": connected\n", line)
+
+	_, err = service.AddItem(context.Background(), "user-123", cart.AddItemRequest{ProductID: "product-2", Quantity: 2, UnitPrice: 1000})
+	require.NoError(t, err)
+
+	// Skip to the event's data line
+	for !strings.HasPrefix(line, "data: ") {
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+	}
+
+	var change cart.CartChange
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &change))
+	assert.Equal(t, "cart.item_added", change.Type)
+	assert.Equal(t, "product-2", change.ProductID)
+	assert.Equal(t, 3, change.TotalQuantity)
+	assert.Equal(t, int64(2500), change.TotalPrice)
+}
+
+func TestCartAPI_AddItem_ProductDetails(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"product_id": "product-1",
+		"quantity":   1,
+		"unit_price": 2500,
+		"name":       "Trail Runner",
+		"image_url":  "https://cdn.example.com/products/product-1.jpg",
+		"sku":        "TR-42-BLU",
+		"attributes": map[string]string{"size": "42", "color": "blue"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var response handlers.CartResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Items, 1)
+	item := response.Items[0]
+	assert.Equal(t, "Trail Runner", item.Name)
+	assert.Equal(t, "https://cdn.example.com/products/product-1.jpg", item.ImageURL)
+	assert.Equal(t, "TR-42-BLU", item.SKU)
+	assert.Equal(t, map[string]string{"size": "42", "color": "blue"}, item.Attributes)
+
+	// Details are optional but validated when present
+	body, _ = json.Marshal(map[string]interface{}{
+		"product_id": "product-2",
+		"quantity":   1,
+		"image_url":  "not a url",
+	})
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCartAPI_PatchCart(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	c, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 1000})
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-2", Quantity: 1, UnitPrice: 500})
+	require.NoError(t, err)
+	c, err = service.GetCart(ctx, "user-123")
+	require.NoError(t, err)
+	first, second := c.Items[0].ItemID, c.Items[1].ItemID
+
+	patch := func(ops string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/v1/cart/user-123", bytes.NewReader([]byte(ops)))
+		req.Header.Set("Content-Type", "application/json-patch+json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch(fmt.Sprintf(`[
+		{"op": "test", "path": "/version", "value": %d},
+		{"op": "replace", "path": "/items/%s/quantity", "value": 4},
+		{"op": "remove", "path": "/items/%s"},
+		{"op": "add", "path": "/items/-", "value": {"product_id": "product-3", "quantity": 2, "unit_price": 250}}
+	]`, c.Version, first, second))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response handlers.CartResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Items, 2)
+	assert.Equal(t, 4, response.Items[0].Quantity)
+	assert.Equal(t, "product-3", response.Items[1].ProductID)
+	assert.Equal(t, c.Version+1, response.Version)
+
+	// A patch that breaks a limit is rejected as a whole
+	w = patch(fmt.Sprintf(`[
+		{"op": "remove", "path": "/items/%s"},
+		{"op": "add", "path": "/items/-", "value": {"product_id": "product-3", "quantity": 98, "unit_price": 250}}
+	]`, first))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	unchanged, err := service.GetCart(ctx, "user-123")
+	require.NoError(t, err)
+	assert.Len(t, unchanged.Items, 2)
+	assert.Equal(t, response.Version, unchanged.Version)
+
+	// Unsupported paths are validation errors
+	w = patch(`[{"op": "replace", "path": "/items/-", "value": 1}]`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCartAPI_MultipleCarts(t *testing.T) {
+	router, service := setupTestRouterWithOptions()
+	ctx := context.Background()
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{
+		ProductID: "product-1",
+		Quantity:  1,
+		UnitPrice: 1000,
+	})
+	require.NoError(t, err)
+
+	// Create a named cart
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/carts", strings.NewReader(`{"name":"  Wishlist "}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var created handlers.CartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "Wishlist", created.Name)
+	assert.False(t, created.Default)
+
+	// Items added to the named cart stay out of the default cart
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/carts/"+created.ID+"/items",
+		strings.NewReader(`{"product_id":"product-2","quantity":2,"unit_price":500}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	defaultCart, err := service.GetCart(ctx, "user-123")
+	require.NoError(t, err)
+	require.Len(t, defaultCart.Items, 1)
+	assert.Equal(t, "product-1", defaultCart.Items[0].ProductID)
+
+	named, err := service.GetCart(cart.WithCartID(ctx, created.ID), "user-123")
+	require.NoError(t, err)
+	require.Len(t, named.Items, 1)
+	assert.Equal(t, "product-2", named.Items[0].ProductID)
+
+	// List returns the default cart first
+	req = httptest.NewRequest(http.MethodGet, "/v1/cart/user-123/carts", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var list handlers.CartListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Carts, 2)
+	assert.True(t, list.Carts[0].Default)
+	assert.Equal(t, created.ID, list.Carts[1].ID)
+
+	// Unknown carts are not created on demand
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/carts/unknown-cart/items",
+		strings.NewReader(`{"product_id":"product-3","quantity":1,"unit_price":100}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCartAPI_MultipleCarts_Limit(t *testing.T) {
+	router, service := setupTestRouterWithOptions()
+	ctx := context.Background()
+
+	for i := 0; i < cart.DefaultMaxCartsPerUser; i++ {
+		_, err := service.CreateCart(ctx, "user-123", fmt.Sprintf("List %d", i))
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/carts", strings.NewReader(`{"name":"One too many"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "CART_COUNT_LIMIT_EXCEEDED")
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/carts",
+		strings.NewReader(`{"name":"`+strings.Repeat("x", 101)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCartAPI_SetCartName(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 1000})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedName   string
+	}{
+		{"sets name", `{"name": "  Birthday\n list\u0007 "}`, http.StatusOK, "Birthday list"},
+		{"rejects markup", `{"name": "<b>list</b>"}`, http.StatusBadRequest, ""},
+		{"rejects too long", `{"name": "` + strings.Repeat("a", 101) + `"}`, http.StatusBadRequest, ""},
+		{"rejects unknown fields", `{"name": "list", "items": []}`, http.StatusBadRequest, ""},
+		{"requires name", `{}`, http.StatusBadRequest, ""},
+		{"clears name", `{"name": ""}`, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/v1/cart/user-123", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			require.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp handlers.CartResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, tt.expectedName, resp.Name)
+
+				c, err := service.GetCart(ctx, "user-123")
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedName, c.Name)
+				assert.Equal(t, tt.expectedName, c.Summary().Name)
+			}
+		})
+	}
+}
+
+func TestCartAPI_TenantIsolation(t *testing.T) {
+	router, service := setupTestRouter()
+	secret := "tenant-test-secret"
+
+	r := chi.NewRouter()
+	r.Use(apimiddleware.JWTAuth(apimiddleware.AuthConfig{JWTSecretKey: secret}))
+	r.Use(handlers.ScopeTenant)
+	r.Mount("/", router)
+
+	call := func(tenantID, method, path, body string) *httptest.ResponseRecorder {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &apimiddleware.UserClaims{
+			UserID:   "user-123",
+			TenantID: tenantID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}).SignedString([]byte(secret))
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call("acme", http.MethodPost, "/v1/cart/user-123/items", `{"product_id":"product-1","quantity":1,"unit_price":1000}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = call("globex", http.MethodPost, "/v1/cart/user-123/items", `{"product_id":"product-2","quantity":3,"unit_price":500}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// The same user ID holds a separate cart in each tenant
+	for tenantID, product := range map[string]string{"acme": "product-1", "globex": "product-2"} {
+		rec = call(tenantID, http.MethodGet, "/v1/cart/user-123", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp handlers.CartResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Len(t, resp.Items, 1)
+		assert.Equal(t, product, resp.Items[0].ProductID, tenantID)
+	}
+
+	// Tokens without a tenant use the default tenant
+	rec = call("", http.MethodGet, "/v1/cart/user-123", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	_, err := service.GetCart(cart.WithTenantID(context.Background(), "acme"), "user-123")
+	require.NoError(t, err)
+	_, err = service.GetCart(context.Background(), "user-123")
+	assert.Error(t, err)
+
+	// Clearing one tenant's cart leaves the other alone
+	rec = call("acme", http.MethodDelete, "/v1/cart/user-123", "")
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	c, err := service.GetCart(cart.WithTenantID(context.Background(), "globex"), "user-123")
+	require.NoError(t, err)
+	assert.Len(t, c.Items, 1)
+
+	rec = call("bad#tenant", http.MethodGet, "/v1/cart/user-123", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCartAPI_Msgpack(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	body, err := msgpack.Marshal(map[string]interface{}{
+		"product_id": "product-1",
+		"quantity":   2,
+		"unit_price": 1999,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/msgpack, application/json;q=0.5")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+	var resp map[string]interface{}
+	require.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "user-123", resp["user_id"])
+	assert.Len(t, resp["items"], 1)
+
+	// JSON stays the default
+	req = httptest.NewRequest(http.MethodGet, "/v1/cart/user-123/", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.True(t, json.Valid(w.Body.Bytes()))
+
+	// Unknown fields are rejected like in JSON bodies
+	body, err = msgpack.Marshal(map[string]interface{}{"product_id": "product-2", "quantity": 1, "price": 1})
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/msgpack")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+}
+
+func TestCartAPI_UnknownFields(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	tests := []struct {
+		name       string
+		lenient    bool
+		body       string
+		wantStatus int
+		wantField  string
+	}{
+		{name: "strict rejects unknown field", body: `{"product_id":"product-1","quantity":1,"color":"red"}`, wantStatus: http.StatusBadRequest, wantField: "color"},
+		{name: "lenient ignores unknown field", lenient: true, body: `{"product_id":"product-1","quantity":1,"color":"red"}`, wantStatus: http.StatusCreated},
+		{name: "strict rejects duplicate key", body: `{"product_id":"product-1","quantity":1,"quantity":2}`, wantStatus: http.StatusBadRequest, wantField: "quantity"},
+		{name: "lenient rejects duplicate key", lenient: true, body: `{"product_id":"product-1","quantity":1,"quantity":2}`, wantStatus: http.StatusBadRequest, wantField: "quantity"},
+		{name: "lenient rejects nested duplicate key", lenient: true, body: `{"product_id":"product-1","quantity":1,"attributes":{"size":"M","size":"L"}}`, wantStatus: http.StatusBadRequest, wantField: "size"},
+		{name: "lenient rejects malformed JSON", lenient: true, body: `{"product_id":"product-1",`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handler http.Handler = router
+			if tt.lenient {
+				handler = handlers.LenientJSON(router)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantField != "" {
+				var resp handlers.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantField, resp.Details["field"])
+			}
+		})
+	}
+}
+
+func TestCartAPI_GuestCart(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "debug", ServiceName: "cart-service-test", Environment: "test"})
+	service := cart.NewService(inmemory.NewRepository(), nil, cart.ServiceConfig{AnonymousCarts: true})
+	handler := handlers.NewCartHandler(service, logger)
+
+	r := chi.NewRouter()
+	r.Post("/v1/cart", handler.CreateGuestCart)
+	r.Route("/v1/cart/{userID}", func(r chi.Router) {
+		r.Use(handlers.PathParamValidator("userID", handlers.ValidateUserID))
+		r.Post("/items", handler.AddItem)
+		r.Post("/merge", handler.MergeCart)
+	})
+
+	// A new guest gets a server-issued ID in the body and in a cookie
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/cart", nil))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var created handlers.GuestCartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.GuestID, cart.DefaultGuestUserIDPrefix))
+	assert.Equal(t, created.GuestID, created.Cart.UserID)
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, handlers.GuestCookieName, cookie.Name)
+	assert.Equal(t, created.GuestID, cookie.Value)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+
+	// The cookie brings the guest back to the same cart
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var again handlers.GuestCartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &again))
+	assert.Equal(t, created.GuestID, again.GuestID)
+
+	body := `{"product_id": "product-1", "quantity": 1, "unit_price": 1000}`
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/"+created.GuestID+"/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// At login the cookie names the guest cart to merge and is then cleared
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/merge", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var merged handlers.CartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &merged))
+	assert.Equal(t, "user-123", merged.UserID)
+	require.Len(t, merged.Items, 1)
+	require.Len(t, rec.Result().Cookies(), 1)
+	assert.Equal(t, -1, rec.Result().Cookies()[0].MaxAge)
+}
+
+func TestCartAPI_BodyLongerThanContentLength(t *testing.T) {
+	router, _ := setupTestRouter()
+	handler := apimiddleware.RequestSizeLimit(1024)(router)
+
+	body := `{"product_id": "product-1", "quantity": 1, "unit_price": 1000}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = 10
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+
+	var resp handlers.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "REQUEST_TOO_LARGE", resp.Code)
+	assert.EqualValues(t, 1024, resp.Details["max_bytes"], "the configured limit, not the declared length")
+}
+
+func TestCartAPI_RedactErrorDetails(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	get := func(handler http.Handler) handlers.ErrorResponse {
+		req := httptest.NewRequest(http.MethodGet, "/v1/cart/user-404", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		var resp handlers.ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	assert.Equal(t, "user-404", get(router).Details["user_id"])
+
+	redacted := get(handlers.RedactErrorDetails([]string{"user_id"})(router))
+	assert.Equal(t, "CART_NOT_FOUND", redacted.Code)
+	assert.NotEqual(t, "user-404", redacted.Details["user_id"])
+	assert.True(t, strings.HasPrefix(redacted.Details["user_id"].(string), "sha256:"))
+	assert.Equal(t, redacted.Details["user_id"], get(handlers.RedactErrorDetails([]string{"user_id"})(router)).Details["user_id"])
+}
+
+func TestCartAPI_OverrideLimits(t *testing.T) {
+	secret := "override-test-secret"
+	logger := logging.New(logging.Config{Level: "debug", ServiceName: "cart-service-test", Environment: "test"})
+	service := cart.NewService(inmemory.NewRepository(), nil, cart.ServiceConfig{
+		MaxItemsPerCart:      1,
+		AdminMaxItemsPerCart: 2,
+	})
+	handler := handlers.NewCartHandler(service, logger)
+
+	r := chi.NewRouter()
+	r.Use(apimiddleware.JWTAuth(apimiddleware.AuthConfig{JWTSecretKey: secret}))
+	r.Route("/v1/cart/{userID}", func(r chi.Router) {
+		r.Use(handlers.PathParamValidator("userID", handlers.ValidateUserID))
+		r.Use(handlers.OverrideLimits([]string{"support"}, logger))
+		r.Post("/items", handler.AddItem)
+	})
+
+	add := func(productID string, groups []string, override bool) int {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &apimiddleware.UserClaims{
+			UserID: "agent-1",
+			Groups: groups,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}).SignedString([]byte(secret))
+		require.NoError(t, err)
+
+		body := `{"product_id":"` + productID + `","quantity":1,"unit_price":100}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		if override {
+			req.Header.Set(handlers.HeaderOverrideLimits, "true")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusCreated, add("product-1", nil, false))
+
+	// The header alone doesn't lift the limit, nor does the group alone
+	assert.Equal(t, http.StatusBadRequest, add("product-2", nil, true))
+	assert.Equal(t, http.StatusBadRequest, add("product-2", []string{"support"}, false))
+
+	assert.Equal(t, http.StatusCreated, add("product-2", []string{"support"}, true))
+	assert.Equal(t, http.StatusBadRequest, add("product-3", []string{"support"}, true))
+}
+
+func TestCartAPI_LockCart(t *testing.T) {
+	router, service := setupTestRouter()
+	ctx := context.Background()
+
+	_, err := service.AddItem(ctx, "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 1000})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/lock", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handlers.CartResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.Locked)
+	assert.NotNil(t, resp.LockedUntil)
+
+	body := `{"product_id":"product-2","quantity":1,"unit_price":500}`
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	var errResp handlers.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, "CART_LOCKED", errResp.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/unlock", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestCartAPI_ValidationErrorsListAllFields(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	body := `{"product_id":"bad id","quantity":150,"unit_price":-1,"unit_type":"box"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/cart/user-123/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp struct {
+		Code    string                         `json:"code"`
+		Details map[string]handlers.FieldError `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "VALIDATION_ERROR", resp.Code)
+	require.Len(t, resp.Details, 4)
+
+	quantity := resp.Details["quantity"]
+	assert.Equal(t, "max", quantity.Tag)
+	assert.Equal(t, "99", quantity.Param)
+	assert.Equal(t, float64(150), quantity.Value)
+	assert.Equal(t, "must be at most 99", quantity.Message)
+
+	assert.Equal(t, "oneof", resp.Details["unit_type"].Tag)
+	assert.Equal(t, "range", resp.Details["unit_price"].Tag)
+	assert.Equal(t, "format", resp.Details["product_id"].Tag)
+}
@@ -0,0 +1,97 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/api/graphql"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/core/cart"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/logging"
+	"github.com/sinavosooghi/ecommerce/services/cart-service/internal/persistence/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func setupGraphQL(t *testing.T) (http.Handler, *cart.Service) {
+	service := cart.NewService(inmemory.NewRepository(), nil, cart.ServiceConfig{})
+	logger := logging.New(logging.Config{Level: "debug", ServiceName: "cart-service-test", Environment: "test"})
+
+	handler, err := graphql.NewHandler(service, logger)
+	require.NoError(t, err)
+	return handler, service
+}
+
+func doGraphQL(t *testing.T, handler http.Handler, query string, variables map[string]interface{}) graphqlResponse {
+	body, _ := json.Marshal(graphql.Request{Query: query, Variables: variables})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response graphqlResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestGraphQL_CartQueryAndMutations(t *testing.T) {
+	handler, _ := setupGraphQL(t)
+
+	resp := doGraphQL(t, handler, `mutation($userID: String!) {
+		addItem(userID: $userID, productId: "product-1", quantity: 2, unitPrice: 1999) { itemCount totalPrice items { itemId } }
+	}`, map[string]interface{}{"userID": "user-123"})
+	require.Empty(t, resp.Errors)
+
+	var added struct {
+		ItemCount  int   `json:"itemCount"`
+		TotalPrice int64 `json:"totalPrice"`
+		Items      []struct {
+			ItemID string `json:"itemId"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data["addItem"], &added))
+	assert.Equal(t, 1, added.ItemCount)
+	assert.Equal(t, int64(3998), added.TotalPrice)
+
+	resp = doGraphQL(t, handler, `mutation($itemId: String!) {
+		updateItem(userID: "user-123", itemId: $itemId, quantity: 3) { totalQuantity }
+	}`, map[string]interface{}{"itemId": added.Items[0].ItemID})
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"totalQuantity": 3}`, string(resp.Data["updateItem"]))
+
+	resp = doGraphQL(t, handler, `{ cart(userID: "user-123") { userId items { productId quantity subtotal } } }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"userId": "user-123", "items": [{"productId": "product-1", "quantity": 3, "subtotal": 5997}]}`, string(resp.Data["cart"]))
+
+	resp = doGraphQL(t, handler, `mutation { clearCart(userID: "user-123") }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `true`, string(resp.Data["clearCart"]))
+}
+
+func TestGraphQL_ErrorCodes(t *testing.T) {
+	handler, service := setupGraphQL(t)
+
+	resp := doGraphQL(t, handler, `{ cart(userID: "missing") { id } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "CART_NOT_FOUND", resp.Errors[0].Extensions["code"])
+
+	_, err := service.AddItem(context.Background(), "user-123", cart.AddItemRequest{ProductID: "product-1", Quantity: 1, UnitPrice: 100})
+	require.NoError(t, err)
+
+	// Reuses the REST request validation
+	resp = doGraphQL(t, handler, `mutation { addItem(userID: "user-123", productId: "product-1", quantity: 500) { id } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "VALIDATION_ERROR", resp.Errors[0].Extensions["code"])
+}
@@ -26,16 +26,16 @@ func NewTestEnv(t *testing.T) *TestEnv {
 
 	cfg := &config.Config{
 		Port:                  8080,
-		Environment:          "test",
-		ServiceName:          "cart-service-test",
-		LogLevel:             "debug",
-		AWSRegion:            "us-east-1",
-		DynamoDBTable:        "test-carts",
-		RateLimitRPS:         100,
-		RateLimitBurst:       200,
-		MaxRequestSize:       1048576,
-		IdempotencyEnabled:   true,
-		IdempotencyTTL:       300,
+		Environment:           "test",
+		ServiceName:           "cart-service-test",
+		LogLevel:              "debug",
+		AWSRegion:             "us-east-1",
+		DynamoDBTable:         "test-carts",
+		RateLimitRPS:          100,
+		RateLimitBurst:        200,
+		MaxRequestSize:        1048576,
+		IdempotencyEnabled:    true,
+		IdempotencyTTL:        300,
 		CircuitBreakerEnabled: true,
 	}
 